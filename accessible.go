@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The accessible mode is for low-vision users, screen magnifiers and
+// terminals that can't show colors or box drawing. It is on when the NO_COLOR
+// environment variable is set (see no-color.org) or accessible = true in the
+// [theme] section of the config. Styles then drop their colors and mark what
+// colors told apart with bold, underline and reverse video instead, and every
+// glyph is shown as an ASCII character of the same width: [x] for a done
+// task, > for the cursor, r for a recurring task and so on.
+
+// accessible is whether the accessible mode is on, set from the config by
+// setAccessible before the theme is applied.
+var accessible bool
+
+func setAccessible(cfg config) {
+	accessible = cfg.accessible || os.Getenv("NO_COLOR") != ""
+}
+
+// themeColor is the color a style takes from the theme: none in the
+// accessible mode.
+func themeColor(c lipgloss.AdaptiveColor) lipgloss.TerminalColor {
+	if accessible {
+		return lipgloss.NoColor{}
+	}
+	return c
+}
+
+// asciiMarkers spell the glyphs of the interface in ASCII, one column for
+// each column of the glyph so layouts keep their widths.
+var asciiMarkers = strings.NewReplacer(
+	"✓", "x",
+	"▸", ">",
+	"·", "-",
+	"•", "*",
+	"●", "*",
+	"★", "*",
+	"■", "#",
+	"█", "#",
+	"░", ".",
+	"←", "<",
+	"→", ">",
+	"…", ".",
+	"↻", "r", // Recurring
+	"☀", "o", // Planned for today
+	"⊘", "b", // Blocked
+	"▷", "s", // Starts later
+	"☾", "z", // Snoozed
+	"⏱", "t", // Timer running
+	"✎", "n", // Has notes
+	"🔗", "->",
+	"📄", "[]",
+	"│", "|",
+	"┃", "|",
+	"─", "-",
+	"╭", "+",
+	"╮", "+",
+	"╰", "+",
+	"╯", "+",
+	"┌", "+",
+	"┐", "+",
+	"└", "+",
+	"┘", "+",
+)
+
+// plainMarkers replaces the glyphs of a rendered view in the accessible
+// mode.
+func plainMarkers(view string) string {
+	if !accessible {
+		return view
+	}
+	return asciiMarkers.Replace(view)
+}
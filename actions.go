@@ -0,0 +1,310 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Mutating operations shared by single-task bindings and visual mode. They
+// take indices into tasksModel.items and keep the list and database in sync.
+
+// addTask creates a task from what was typed in the input: the title with
+// #tags, @context, +project, due:, every:, !p and remind: tokens and a
+// written out due date taken out of it.
+func (m *model) addTask(input string, parentID int) {
+	before := m.snapshot()
+	newItem := m.parseTask(input)
+	newItem.parentID = parentID
+	err := m.store.Save(&newItem)
+	if err != nil {
+		m.showError("save task", err)
+	} else {
+		m.setReminders(newItem.id, input)
+		m.fireHook(hookAdd, newItem)
+	}
+	m.tasksModel.items = append(m.tasksModel.items, newItem)
+	if newItem.parentID != 0 {
+		// A new open subtask reopens its parents
+		for _, i := range m.tasksModel.syncParents(newItem.id) {
+			err := m.store.Update(m.tasksModel.items[i])
+			if err != nil {
+				m.showError("update task", err)
+			}
+		}
+	}
+	m.record("add", before)
+}
+
+// parseTask builds a new task from input typed with the quick-add syntax,
+// in the GTD list being shown.
+func (m model) parseTask(input string) item {
+	task := parseTaskInput(input)
+	if m.currentView == Tasks && m.tasksModel.listView != "" {
+		task.list = m.tasksModel.listView // Stay in the list being shown
+	}
+	return task
+}
+
+// parseTaskInput builds a new Inbox task from the quick-add syntax.
+func parseTaskInput(input string) item {
+	title, due := parseTitleAndDue(input)
+	return item{
+		title:      title,
+		status:     todo,
+		tags:       parseTags(input),
+		createdAt:  time.Now(), // Record creation time
+		dueAt:      due,
+		startAt:    parseStart(input),
+		estimate:   parseEstimate(input),
+		recurrence: parseRecurrence(input),
+		priority:   parsePriority(input),
+		context:    parseContext(input),
+		project:    parseProject(input),
+		list:       inbox,
+	}
+}
+
+// removeTasks deletes the tasks and their subtasks in one transaction and
+// pushes them onto the undo stack as a single entry.
+func (m *model) removeTasks(indices []int) {
+	before := m.snapshot()
+	removed := make(map[int]bool)
+	var deleted []item
+	for _, index := range indices {
+		for _, i := range append([]int{index}, m.tasksModel.descendants(m.tasksModel.items[index].id)...) {
+			task := m.tasksModel.items[i]
+			if !removed[task.id] {
+				removed[task.id] = true
+				deleted = append(deleted, task)
+			}
+		}
+	}
+	if len(deleted) == 0 {
+		return
+	}
+
+	ids := make([]int, 0, len(deleted))
+	for _, task := range deleted {
+		ids = append(ids, task.id)
+	}
+	err := m.store.Delete(ids...)
+	if err != nil {
+		m.showError("delete tasks", err)
+	} else {
+		m.fireHook(hookDelete, deleted...)
+	}
+
+	var remaining []item
+	for _, task := range m.tasksModel.items {
+		if !removed[task.id] {
+			remaining = append(remaining, task)
+		}
+	}
+	m.tasksModel.items = remaining
+	m.tasksModel.clampSelection()
+	m.record("delete", before)
+}
+
+// setStatus marks the tasks as s. Subtasks follow their parent unless it is
+// only started, parents are derived from their children, and completed
+// recurring tasks spawn their next occurrence.
+func (m *model) setStatus(indices []int, s status) {
+	if s == done {
+		if indices = m.withoutBlocked(indices); len(indices) == 0 {
+			return
+		}
+	}
+	wasBlocked := m.blockedIDs()
+	before := m.snapshot()
+	now := time.Now()
+	changed := make(map[int]bool)
+	var spawned []item
+
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		if task.status != s && s == done && task.recurrence != "" {
+			if next, ok := nextOccurrence(*task, now); ok {
+				spawned = append(spawned, next)
+			}
+		}
+		task.status = s
+		if s == done {
+			task.completedAt = now // Record completion time
+		}
+		changed[index] = true
+		if s == doing {
+			continue // Starting a task leaves its subtasks as they are
+		}
+
+		for _, child := range m.tasksModel.descendants(task.id) {
+			m.tasksModel.items[child].status = s
+			m.tasksModel.items[child].completedAt = task.completedAt
+			changed[child] = true
+		}
+	}
+	for _, index := range indices {
+		for _, parent := range m.tasksModel.syncParents(m.tasksModel.items[index].id) {
+			changed[parent] = true
+		}
+	}
+
+	var updates, completed []item
+	for index := range changed {
+		updates = append(updates, m.tasksModel.items[index])
+		if m.tasksModel.items[index].status == done && before[index].status != done {
+			completed = append(completed, m.tasksModel.items[index])
+		}
+	}
+	err := m.store.Update(updates...)
+	if err != nil {
+		m.showError("update tasks", err)
+	} else {
+		m.fireHook(hookDone, completed...)
+	}
+
+	// Spawn the next occurrence of recurring tasks
+	for _, next := range spawned {
+		err := m.store.Save(&next)
+		if err != nil {
+			m.showError("save task", err)
+		} else {
+			m.fireHook(hookAdd, next)
+		}
+		m.tasksModel.items = append(m.tasksModel.items, next)
+	}
+	m.record("status", before)
+	m.announceUnblocked(wasBlocked)
+}
+
+// moveTask swaps the task at index with its previous (direction -1) or next
+// (direction 1) sibling in the manual order. Reordering switches the list to
+// manual sorting so the move is visible.
+func (m *model) moveTask(index, direction int) {
+	items := m.tasksModel.items
+	target := -1
+	for i := index + direction; i >= 0 && i < len(items); i += direction {
+		if items[i].parentID == items[index].parentID {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		return
+	}
+
+	before := m.snapshot()
+	id := items[index].id
+	items[index], items[target] = items[target], items[index]
+
+	// Renumber so every task has a distinct position, then save what changed
+	var changed []item
+	for i := range items {
+		if items[i].sortOrder != i+1 {
+			items[i].sortOrder = i + 1
+			changed = append(changed, items[i])
+		}
+	}
+	err := m.store.SaveOrder(changed)
+	if err != nil {
+		m.showError("save order", err)
+	}
+	m.record("move", before)
+
+	if m.tasksModel.sortBy != sortManual {
+		m.tasksModel.sortBy = sortManual
+		err := m.store.SaveSetting("sort", sortManual)
+		if err != nil {
+			m.showError("save setting", err)
+		}
+	}
+	m.tasksModel.selectID(id)
+}
+
+// startRetag opens the tag prompt for a set of tasks.
+func (m *model) startRetag(indices []int) tea.Cmd {
+	m.tasksModel.bulkTargets = indices
+	m.tasksModel.mode = bulkTagMode
+	m.tasksModel.input.Placeholder = "#add -remove..."
+	m.tasksModel.input.Focus()
+	return textinput.Blink
+}
+
+// parseRetag splits the tag prompt into tags to add (#tag, +tag or a bare
+// word) and tags to remove (-tag).
+func parseRetag(input string) (add, remove []string) {
+	for _, word := range strings.Fields(input) {
+		switch {
+		case strings.HasPrefix(word, "-"):
+			remove = append(remove, word[1:])
+		case strings.HasPrefix(word, "#"), strings.HasPrefix(word, "+"):
+			add = append(add, word[1:])
+		default:
+			add = append(add, word)
+		}
+	}
+	return add, remove
+}
+
+// retag adds and removes tags on the tasks in one transaction.
+func (m *model) retag(indices []int, add, remove []string) {
+	before := m.snapshot()
+	var updates []item
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		var tags []string
+		for _, tag := range task.tags {
+			keep := true
+			for _, r := range remove {
+				if strings.EqualFold(tag, r) {
+					keep = false
+				}
+			}
+			if keep {
+				tags = append(tags, tag)
+			}
+		}
+		task.tags = tags
+		for _, tag := range add {
+			if !hasTag(*task, tag) {
+				task.tags = append(task.tags, tag)
+			}
+		}
+		updates = append(updates, *task)
+	}
+	err := m.store.Update(updates...)
+	if err != nil {
+		m.showError("update tasks", err)
+	}
+	m.record("retag", before)
+}
+
+// visualRange returns the item indices covered by the visual selection.
+func (t tasksModel) visualRange() []int {
+	rows := t.rows()
+	start, end := t.anchor, t.selected
+	if start > end {
+		start, end = end, start
+	}
+	var indices []int
+	for i := start; i <= end && i < len(rows); i++ {
+		if i >= 0 {
+			indices = append(indices, rows[i].index)
+		}
+	}
+	return indices
+}
+
+// inVisualRange reports whether row i is part of the visual selection.
+func (t tasksModel) inVisualRange(i int) bool {
+	if t.mode != visualMode {
+		return false
+	}
+	start, end := t.anchor, t.selected
+	if start > end {
+		start, end = end, start
+	}
+	return i >= start && i <= end
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The Activity tab is a feed of what was done, newest first and grouped by
+// day, read from the history of changes (see events.go): "Completed "ship
+// v0.2"", "Added 3 tasks". Changes made together, like a paste or a bulk
+// completion, are one line. It is meant for looking back, say when writing a
+// standup; enter shows the task of the highlighted line in the list.
+
+const activityLimit = 500 // Most recent events read for the feed
+
+// activityVerbs say what each kind of event did.
+var activityVerbs = map[string]string{
+	eventCreated:   "Added",
+	eventEdited:    "Edited",
+	eventCompleted: "Completed",
+	eventReopened:  "Reopened",
+	eventDeleted:   "Deleted",
+}
+
+type activityModel struct {
+	entries  []activityEntry
+	selected int
+}
+
+// activityEntry is a line of the feed: events of the same kind from the
+// same change.
+type activityEntry struct {
+	events []taskEvent
+}
+
+func (s *sqliteStore) Events(limit int) ([]taskEvent, error) {
+	return s.events("", limit)
+}
+
+// groupActivity joins the events of one change into an entry. The events
+// come newest first.
+func groupActivity(events []taskEvent) []activityEntry {
+	var entries []activityEntry
+	for _, e := range events {
+		if n := len(entries); n > 0 {
+			last := entries[n-1].events[0]
+			if last.kind == e.kind && last.detail == e.detail && last.at.Sub(e.at) < time.Second {
+				entries[n-1].events = append(entries[n-1].events, e)
+				continue
+			}
+		}
+		entries = append(entries, activityEntry{events: []taskEvent{e}})
+	}
+	return entries
+}
+
+func (m *model) refreshActivity() {
+	events, err := m.store.Events(activityLimit)
+	if err != nil {
+		m.showError("load activity", err)
+	}
+	m.activity.entries = groupActivity(events)
+	m.activity.selected = min(m.activity.selected, max(0, len(m.activity.entries)-1))
+}
+
+// updateActivity handles keys on the Activity tab.
+func (m *model) updateActivity(key string) {
+	a := &m.activity
+	switch key {
+	case "k", "up":
+		if a.selected > 0 {
+			a.selected--
+		}
+	case "j", "down":
+		if a.selected < len(a.entries)-1 {
+			a.selected++
+		}
+	case "enter": // Show the task on the Tasks tab
+		if a.selected >= len(a.entries) {
+			return
+		}
+		id := a.entries[a.selected].events[0].taskID
+		if m.tasksModel.indexOf(id) < 0 {
+			m.showMessage("That task is no longer in the list")
+			return
+		}
+		m.currentView = Tasks
+		m.tasksModel.selectID(id)
+	}
+}
+
+// describe says what the entry's change did, e.g. Completed "ship v0.2" or
+// Added 3 tasks (paste).
+func (e activityEntry) describe() string {
+	first := e.events[0]
+	text := activityVerbs[first.kind] + " " + fmt.Sprintf("%q", first.title)
+	if len(e.events) > 1 {
+		text = activityVerbs[first.kind] + " " + countTasks(len(e.events))
+	}
+	if first.detail != "" && first.detail != plainChanges[first.kind] {
+		text += " (" + first.detail + ")"
+	}
+	return text
+}
+
+// activityDay heads the entries of a day.
+func activityDay(at, now time.Time) string {
+	switch midnight(at) {
+	case midnight(now):
+		return "Today"
+	case midnight(now.AddDate(0, 0, -1)):
+		return "Yesterday"
+	}
+	if at.Year() != now.Year() {
+		return formatDate(at, "Mon 2 Jan 2006")
+	}
+	return formatDate(at, "Mon 2 Jan")
+}
+
+func (m model) renderActivity() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Activity") + "\n")
+	if len(m.activity.entries) == 0 {
+		s.WriteString("\n" + helpStyle.Render("Nothing done yet. Changes to tasks show up here."))
+		return s.String()
+	}
+
+	// Start far enough down for the highlighted entry to be on the screen,
+	// and stop when it is full
+	now := time.Now()
+	height := max(m.height-12, 5)
+	first := 0
+	for first < m.activity.selected && m.activityLines(first, m.activity.selected, now) > height {
+		first++
+	}
+
+	day := ""
+	for i := first; i < len(m.activity.entries) && m.activityLines(first, i, now) <= height; i++ {
+		entry := m.activity.entries[i]
+		at := entry.events[0].at.Local()
+		if heading := activityDay(at, now); heading != day {
+			day = heading
+			s.WriteString("\n" + titleStyle.Render(day) + "\n")
+		}
+		line := helpStyle.Render(formatClock(at)) + "  " + entry.describe()
+		if i == m.activity.selected {
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+line) + "\n")
+		} else {
+			s.WriteString(itemStyle.Render(cursorMark(false)+line) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// activityLines counts the lines the entries from first to last take, two
+// more for each day heading.
+func (m model) activityLines(first, last int, now time.Time) int {
+	lines, day := 0, ""
+	for _, entry := range m.activity.entries[first : last+1] {
+		if heading := activityDay(entry.events[0].at.Local(), now); heading != day {
+			day = heading
+			lines += 2
+		}
+		lines++
+	}
+	return lines
+}
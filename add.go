@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// xtui add adds tasks from the shell without opening the interface. The
+// words after add make one task in the syntax of the task input (xtui add
+// call mom #family tomorrow 5pm); with --stdin each non-empty line read from
+// stdin is a task, so a scratch list moves in with
+// cat ideas.txt | xtui add --stdin. List bullets and checkboxes in front of
+// the lines are dropped, as when pasting. In the interface, :import text
+// <path> adds the lines of a file the same way.
+
+// listLine drops the list bullet or checkbox in front of a line.
+func listLine(line string) string {
+	line = strings.TrimSpace(line)
+	if _, rest, ok := parseCheckbox(line); ok {
+		return rest
+	}
+	if len(line) > 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return strings.TrimSpace(line[2:])
+	}
+	if rest, ok := strings.CutPrefix(line, "• "); ok {
+		return strings.TrimSpace(rest)
+	}
+	return line
+}
+
+// readText reads a task from each non-empty line, for the text import
+// format.
+func readText(r io.Reader) ([]item, error) {
+	var tasks []item
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20) // Allow long lines
+	for scanner.Scan() {
+		task := parseTaskInput(listLine(scanner.Text()))
+		if task.title != "" {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, scanner.Err()
+}
+
+// runAdd implements xtui add.
+func runAdd(args []string) (int, error) {
+	flags := flag.NewFlagSet("add", flag.ExitOnError)
+	stdin := flags.Bool("stdin", false, "add a task for each line read from stdin")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: xtui add <task>\n       xtui add --stdin < tasks.txt")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	var tasks []item
+	if *stdin {
+		var err error
+		if tasks, err = readText(os.Stdin); err != nil {
+			return 0, err
+		}
+	} else if flags.NArg() > 0 {
+		tasks = []item{parseTaskInput(strings.Join(flags.Args(), " "))}
+	} else {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	m := newModel()
+	defer m.close()
+	existing, err := m.store.Load(liveTasks)
+	if err != nil {
+		return 0, err
+	}
+	m.tasksModel.items = existing
+	return m.addTasks(tasks)
+}
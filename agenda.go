@@ -0,0 +1,159 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The Agenda tab lists the open tasks by when they are due: overdue, today,
+// tomorrow, the rest of this week (up to Sunday), later, and without a due
+// date. j/k move, space completes a task and enter shows it in the list.
+
+type agendaModel struct {
+	selected int
+}
+
+// agendaGroup is a heading with the indices of its tasks, earliest first.
+type agendaGroup struct {
+	title string
+	tasks []int
+}
+
+func (m model) agendaGroups(now time.Time) []agendaGroup {
+	today := midnight(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	afterTomorrow := today.AddDate(0, 0, 2)
+	nextWeek := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+
+	groups := []agendaGroup{{title: "Overdue"}, {title: "Today"}, {title: "Tomorrow"}, {title: "This week"}, {title: "Later"}, {title: "No date"}}
+	for i, task := range m.tasksModel.items {
+		if task.status == done || task.snoozed(now) || task.notStarted(now) {
+			continue
+		}
+		var group int
+		switch due := task.dueAt; {
+		case due.IsZero():
+			group = 5
+		case task.overdue(now):
+			group = 0
+		case due.Before(tomorrow):
+			group = 1
+		case due.Before(afterTomorrow):
+			group = 2
+		case due.Before(nextWeek):
+			group = 3
+		default:
+			group = 4
+		}
+		groups[group].tasks = append(groups[group].tasks, i)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.tasks, func(a, b int) bool {
+			return m.tasksModel.items[g.tasks[a]].dueAt.Before(m.tasksModel.items[g.tasks[b]].dueAt)
+		})
+	}
+	return groups
+}
+
+// agendaTasks returns the tasks in the order they are listed.
+func (m model) agendaTasks() []int {
+	var tasks []int
+	for _, g := range m.agendaGroups(time.Now()) {
+		tasks = append(tasks, g.tasks...)
+	}
+	return tasks
+}
+
+// updateAgenda handles keys on the Agenda tab.
+func (m *model) updateAgenda(key string) {
+	tasks := m.agendaTasks()
+	switch key {
+	case "k", "up":
+		if m.agenda.selected > 0 {
+			m.agenda.selected--
+		}
+	case "j", "down":
+		if m.agenda.selected < len(tasks)-1 {
+			m.agenda.selected++
+		}
+	case " ": // Complete the task, it leaves the agenda
+		if m.agenda.selected < len(tasks) {
+			m.setStatus([]int{tasks[m.agenda.selected]}, done)
+			m.agenda.selected = min(m.agenda.selected, max(0, len(tasks)-2))
+		}
+	case "enter": // Show the task on the Tasks tab
+		if m.agenda.selected < len(tasks) {
+			m.currentView = Tasks
+			m.tasksModel.selectID(m.tasksModel.items[tasks[m.agenda.selected]].id)
+		}
+	}
+}
+
+func (m model) renderAgenda() string {
+	now := time.Now()
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Agenda") + "\n")
+
+	row := 0
+	for _, g := range m.agendaGroups(now) {
+		if len(g.tasks) == 0 {
+			continue
+		}
+		heading := titleStyle
+		if g.title == "Overdue" {
+			heading = overdueStyle
+		}
+		s.WriteString("\n" + heading.Render(g.title))
+		if left := m.remainingWork(g.tasks); left > 0 {
+			s.WriteString(helpStyle.Render(" ~" + formatEstimate(left)))
+		}
+		s.WriteString("\n")
+		for _, index := range g.tasks {
+			task := m.tasksModel.items[index]
+			cursor := cursorMark(row == m.agenda.selected)
+			style := itemStyle
+			if row == m.agenda.selected {
+				style = selectedItemStyle
+			}
+			rest := ""
+			if due := agendaDue(task.dueAt, g.title); due != "" {
+				rest += " " + helpStyle.Render(due)
+			}
+			for _, tag := range task.tags {
+				rest += " " + tagStyle.Render(tagLabel(tag))
+			}
+			title := task.title
+			if m.width > 0 {
+				title = truncate(title, max(m.width-4-5-cursorWidth()-markerWidth()-lipgloss.Width(rest), minTitleWidth)) // The padding takes 5 columns beside the cursor and marker
+			}
+			s.WriteString(style.Render(cursor+statusMarker(task.status)+" "+title) + rest + "\n")
+			row++
+		}
+	}
+	if row == 0 {
+		s.WriteString("\n" + helpStyle.Render("Nothing to do. Add due:YYYY-MM-DD to a task to plan it.") + "\n")
+	}
+	return s.String()
+}
+
+// agendaDue says when a task is due in the words that fit its group: the time
+// for today and tomorrow, the weekday this week and the date otherwise.
+func agendaDue(due time.Time, group string) string {
+	if due.IsZero() {
+		return ""
+	}
+	clock := ""
+	if !isEndOfDay(due) {
+		clock = formatClock(due)
+	}
+	switch group {
+	case "Today", "Tomorrow":
+		return clock
+	case "This week":
+		return strings.TrimSpace(due.Format("Monday") + " " + clock)
+	}
+	return strings.TrimSpace(formatDate(due, "Mon 2 Jan") + " " + clock)
+}
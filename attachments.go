@@ -0,0 +1,249 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Files and links can be attached to a task. A lists the selected task's
+// attachments: a adds a file path or URL, enter opens the highlighted one
+// with the desktop's default application (xdg-open, open or start) and d
+// removes it. The detail view lists them too.
+
+const attachMode = "attach"
+
+type attachment struct {
+	id      int
+	taskID  int
+	target  string // Absolute file path or URL
+	addedAt time.Time
+}
+
+// attachState is the attachment list being shown.
+type attachState struct {
+	taskID   int
+	items    []attachment
+	selected int
+	input    textinput.Model // Path or URL being attached
+	adding   bool
+}
+
+func createAttachmentsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			target TEXT NOT NULL,
+			added_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS attachments_task ON attachments (task_id);
+	`)
+	return err
+}
+
+func (s *sqliteStore) Attachments(taskID int) ([]attachment, error) {
+	rows, err := s.db.Query("SELECT id, task_id, target, added_at FROM attachments WHERE task_id = ? ORDER BY id", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []attachment
+	for rows.Next() {
+		var a attachment
+		var addedAt sql.NullTime
+		if err := rows.Scan(&a.id, &a.taskID, &a.target, &addedAt); err != nil {
+			return nil, err
+		}
+		a.addedAt = addedAt.Time
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func (s *sqliteStore) AddAttachment(a *attachment) error {
+	res, err := s.db.Exec("INSERT INTO attachments (task_id, target, added_at) VALUES (?, ?, ?)", a.taskID, a.target, a.addedAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	a.id = int(id)
+	return err
+}
+
+func (s *sqliteStore) RemoveAttachment(id int) error {
+	_, err := s.db.Exec("DELETE FROM attachments WHERE id = ?", id)
+	return err
+}
+
+// isURL reports whether an attachment is a link rather than a file.
+func isURL(target string) bool {
+	return strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:")
+}
+
+// attachmentTarget checks what was typed: URLs are kept as they are, paths
+// must exist and are made absolute so they still work from another
+// directory.
+func attachmentTarget(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || isURL(value) {
+		return value, nil
+	}
+	path, err := filepath.Abs(expandHome(value))
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// openInDefaultApp opens a file or URL with the default application.
+func openInDefaultApp(target string) tea.Cmd {
+	return func() tea.Msg {
+		var cmd *exec.Cmd
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("open", target)
+		case "windows":
+			cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+		default:
+			cmd = exec.Command("xdg-open", target)
+		}
+		if err := cmd.Run(); err != nil {
+			return errorMsg{"open " + target, err}
+		}
+		return nil
+	}
+}
+
+// loadAttachments reads the attachments of a task for the attachment list
+// and the detail view.
+func (m *model) loadAttachments(taskID int) {
+	input := textinput.New()
+	input.Prompt = "Attach: "
+	input.Placeholder = "file path or URL"
+	m.attach = attachState{taskID: taskID, input: input}
+	m.refreshAttachments()
+}
+
+// startAttachments lists the attachments of the selected task.
+func (m *model) startAttachments() {
+	if index := m.tasksModel.selectedIndex(); index >= 0 {
+		m.loadAttachments(m.tasksModel.items[index].id)
+		m.tasksModel.mode = attachMode
+	}
+}
+
+func (m *model) refreshAttachments() {
+	items, err := m.store.Attachments(m.attach.taskID)
+	if err != nil {
+		m.showError("load attachments", err)
+	}
+	m.attach.items = items
+	m.attach.selected = min(m.attach.selected, max(0, len(items)-1))
+}
+
+// updateAttachments handles keys in the attachment list.
+func (m *model) updateAttachments(msg tea.KeyMsg) tea.Cmd {
+	a := &m.attach
+	if a.adding {
+		switch msg.String() {
+		case "esc":
+			a.adding = false
+			a.input.Blur()
+		case "enter":
+			target, err := attachmentTarget(a.input.Value())
+			if err != nil {
+				m.showError("attach", err)
+				return nil
+			}
+			if target != "" {
+				added := attachment{taskID: a.taskID, target: target, addedAt: time.Now()}
+				if err := m.store.AddAttachment(&added); err != nil {
+					m.showError("attach", err)
+				}
+				m.refreshAttachments()
+				a.selected = len(a.items) - 1
+			}
+			a.adding = false
+			a.input.Blur()
+		default:
+			var cmd tea.Cmd
+			a.input, cmd = a.input.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "k", "up":
+		if a.selected > 0 {
+			a.selected--
+		}
+	case "j", "down":
+		if a.selected < len(a.items)-1 {
+			a.selected++
+		}
+	case "a":
+		a.adding = true
+		a.input.Reset()
+		return a.input.Focus()
+	case "enter", "o":
+		if a.selected < len(a.items) {
+			return openInDefaultApp(a.items[a.selected].target)
+		}
+	case "d":
+		if a.selected < len(a.items) {
+			if err := m.store.RemoveAttachment(a.items[a.selected].id); err != nil {
+				m.showError("remove attachment", err)
+			}
+			m.refreshAttachments()
+		}
+	case "esc", "q":
+		m.tasksModel.mode = normalMode
+	}
+	return nil
+}
+
+func (m model) renderAttachments() string {
+	a := m.attach
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Attachments of "+m.titleOf(a.taskID)) + "\n\n")
+	if len(a.items) == 0 && !a.adding {
+		s.WriteString(helpStyle.Render("Nothing attached. Press a to attach a file or URL.") + "\n")
+	}
+	for i, attached := range a.items {
+		if i == a.selected {
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+describeAttachment(attached)) + "\n")
+		} else {
+			s.WriteString(itemStyle.Render(cursorMark(false)+describeAttachment(attached)) + "\n")
+		}
+	}
+	if a.adding {
+		s.WriteString("\n" + a.input.View() + "\n")
+	}
+	return s.String()
+}
+
+// describeAttachment shows a link as it is and a file by its path, with the
+// home directory shortened to ~.
+func describeAttachment(a attachment) string {
+	if isURL(a.target) {
+		return "🔗 " + a.target
+	}
+	path := a.target
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(path, home+string(filepath.Separator)) {
+		path = "~" + path[len(home):]
+	}
+	return "📄 " + path
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Backups copy the database into the [backup] directory every interval hours
+// while Xtui runs (right at startup when one is overdue), before an upgrade
+// changes the schema, and on :backup now. Each copy is named after the time
+// it was taken, xtui-20261015-093000.db, and the oldest beyond keep are
+// deleted. An encrypted database is backed up sealed with the same
+// passphrase. To restore one, pick it on :restore, which shows what would
+// change and backs up the current database first.
+
+// backupMsg is sent when a scheduled backup is due.
+type backupMsg struct{}
+
+// backupDoneMsg is sent to Update when a backup finishes.
+type backupDoneMsg struct {
+	path      string
+	err       error
+	scheduled bool
+}
+
+func (s *sqliteStore) Backup(path string) error {
+	_, err := s.db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// backupDatabase writes a backup to the backup directory and prunes the old
+// ones, returning the path of the new one.
+func backupDatabase(store TaskStore, v *vault, cfg config, now time.Time) (string, error) {
+	if err := os.MkdirAll(cfg.backupDir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(cfg.backupDir, "xtui-"+now.Format("20060102-150405")+".db")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+
+	// The copy is written next to the working copy when encrypted, so the
+	// database is never in the clear outside the runtime directory
+	tmp := path + ".tmp"
+	if v != nil {
+		tmp = v.working + ".backup"
+	}
+	os.Remove(tmp) // Left by a backup that was interrupted
+	defer os.Remove(tmp)
+	if err := store.Backup(tmp); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		return "", err
+	}
+	if v != nil {
+		if err := v.sealFile(tmp, path); err != nil {
+			return "", err
+		}
+	} else if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return path, pruneBackups(cfg.backupDir, cfg.backupKeep)
+}
+
+// pruneBackups deletes all but the newest keep backups in dir.
+func pruneBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "xtui-*.db"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths) // Oldest first, the names start with the time
+	for _, path := range paths[:max(0, len(paths)-keep)] {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scheduleBackup waits until the next backup is due, hours after the last.
+func scheduleBackup(last time.Time, hours int) tea.Cmd {
+	if hours <= 0 {
+		return nil
+	}
+	wait := max(time.Until(last.Add(time.Duration(hours)*time.Hour)), 0)
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		return backupMsg{}
+	})
+}
+
+// startBackup backs up the database in the background.
+func (m model) startBackup(scheduled bool) tea.Cmd {
+	store, v, cfg := m.store, m.vault, m.config
+	return func() tea.Msg {
+		path, err := backupDatabase(store, v, cfg, time.Now())
+		return backupDoneMsg{path: path, err: err, scheduled: scheduled}
+	}
+}
+
+// finishBackup records a finished backup and schedules the next one. Only
+// backups asked for with :backup now are announced.
+func (m *model) finishBackup(msg backupDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		m.showError("back up database", msg.err)
+	} else {
+		m.lastBackup = time.Now()
+		if err := m.store.SaveSetting("last_backup", m.lastBackup.Format(time.RFC3339)); err != nil {
+			m.showError("save setting", err)
+		}
+		if !msg.scheduled {
+			m.showMessage("Backed up to " + msg.path)
+		}
+	}
+	if !msg.scheduled {
+		return nil
+	}
+	// A failed backup is tried again after the interval too
+	return scheduleBackup(time.Now(), m.config.backupInterval)
+}
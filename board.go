@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The Board tab lays the tasks out in columns, Todo / Doing / Done or one per
+// tag, like a kanban board. h/l pick a column and j/k a task in it, H/L move
+// the task to the column on the left or right: a new status, or for tags the
+// column's tag in place of the one it came from. g switches the grouping.
+
+const (
+	boardWidth  = 28 // Characters per column
+	untaggedKey = "" // Tag column of tasks without tags
+)
+
+type boardModel struct {
+	byTag    bool // Columns per tag instead of per status
+	column   int
+	selected int // Cursor in the column
+}
+
+// boardColumn is a column with the indices of its tasks.
+type boardColumn struct {
+	title string
+	key   string // Tag of the column when grouped by tag
+	state status // Status of the column when grouped by status
+	tasks []int
+}
+
+func (m model) boardColumns() []boardColumn {
+	if !m.board.byTag {
+		columns := []boardColumn{{title: "Todo", state: todo}, {title: "Doing", state: doing}, {title: "Done", state: done}}
+		for i, task := range m.tasksModel.items {
+			for c := range columns {
+				if columns[c].state == task.status {
+					columns[c].tasks = append(columns[c].tasks, i)
+				}
+			}
+		}
+		return columns
+	}
+
+	byTag := make(map[string][]int)
+	for i, task := range m.tasksModel.items {
+		if len(task.tags) == 0 {
+			byTag[untaggedKey] = append(byTag[untaggedKey], i)
+		}
+		for _, tag := range task.tags {
+			byTag[tag] = append(byTag[tag], i)
+		}
+	}
+	var tags []string
+	for tag := range byTag {
+		if tag != untaggedKey {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	columns := []boardColumn{{title: "No tag", key: untaggedKey, tasks: byTag[untaggedKey]}}
+	for _, tag := range tags {
+		columns = append(columns, boardColumn{title: tagLabel(tag), key: tag, tasks: byTag[tag]})
+	}
+	return columns
+}
+
+// updateBoard handles keys on the Board tab.
+func (m *model) updateBoard(key string) {
+	b := &m.board
+	columns := m.boardColumns()
+	b.column = min(b.column, len(columns)-1)
+	tasks := columns[b.column].tasks
+
+	switch key {
+	case "h":
+		if b.column > 0 {
+			b.column--
+			b.selected = 0
+		}
+	case "l":
+		if b.column < len(columns)-1 {
+			b.column++
+			b.selected = 0
+		}
+	case "k", "up":
+		if b.selected > 0 {
+			b.selected--
+		}
+	case "j", "down":
+		if b.selected < len(tasks)-1 {
+			b.selected++
+		}
+	case "H", "L": // Move the task to the next column over
+		target := b.column - 1
+		if key == "L" {
+			target = b.column + 1
+		}
+		if b.selected >= len(tasks) || target < 0 || target >= len(columns) {
+			return
+		}
+		id := m.tasksModel.items[tasks[b.selected]].id
+		m.moveToColumn(tasks[b.selected], columns[b.column], columns[target])
+		b.column = target
+		for i, index := range m.boardColumns()[target].tasks {
+			if m.tasksModel.items[index].id == id {
+				b.selected = i
+			}
+		}
+	case "g": // Group by status or by tag
+		b.byTag = !b.byTag
+		b.column, b.selected = 0, 0
+	}
+}
+
+// moveToColumn gives a task the status or tag of another column.
+func (m *model) moveToColumn(index int, from, to boardColumn) {
+	if !m.board.byTag {
+		m.setStatus([]int{index}, to.state)
+		return
+	}
+
+	before := m.snapshot()
+	task := &m.tasksModel.items[index]
+	var tags []string
+	for _, tag := range task.tags {
+		if tag != from.key && tag != to.key {
+			tags = append(tags, tag)
+		}
+	}
+	if to.key != untaggedKey {
+		tags = append(tags, to.key)
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	task.tags = tags
+	if err := m.store.Update(*task); err != nil {
+		m.showError("update task", err)
+	}
+	m.record("move", before)
+}
+
+func (m model) renderBoard() string {
+	columns := m.boardColumns()
+	b := m.board
+	column := min(b.column, len(columns)-1)
+
+	// Show as many columns as fit, keeping the selected one in view
+	fit := max(1, (m.width-8)/(boardWidth+2))
+	first := max(0, column-fit+1)
+	last := min(len(columns), first+fit)
+
+	now := time.Now()
+	var rendered []string
+	for c := first; c < last; c++ {
+		col := columns[c]
+		var s strings.Builder
+		header := fmt.Sprintf("%s (%d)", col.title, len(col.tasks))
+		if c == column {
+			s.WriteString(titleStyle.Render(header) + "\n\n")
+		} else {
+			s.WriteString(helpStyle.Render(header) + "\n\n")
+		}
+		for i, index := range col.tasks {
+			task := m.tasksModel.items[index]
+			line := truncate(task.title, boardWidth-cursorWidth())
+			switch {
+			case c == column && i == b.selected:
+				s.WriteString(selectedItemStyle.UnsetPaddingLeft().Render(cursorMark(true)+line) + "\n")
+			case task.status == done:
+				s.WriteString(helpStyle.Render(cursorMark(false)+line) + "\n")
+			case task.overdue(now):
+				s.WriteString(overdueStyle.Render(cursorMark(false)+line) + "\n")
+			default:
+				s.WriteString(cursorMark(false) + line + "\n")
+			}
+		}
+		rendered = append(rendered, lipgloss.NewStyle().Width(boardWidth).MarginRight(2).Render(s.String()))
+	}
+
+	var s strings.Builder
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, rendered...) + "\n")
+	if first > 0 || last < len(columns) {
+		s.WriteString(helpStyle.Render(fmt.Sprintf("columns %d-%d of %d", first+1, last, len(columns))) + "\n")
+	}
+	return s.String()
+}
+
+// boardKeys lists the keys that apply to the Board tab.
+func (m model) boardKeys() string {
+	grouping := "g: group by tag"
+	if m.board.byTag {
+		grouping = "g: group by status"
+	}
+	return "←/→: switch tabs | h/l: column | j/k: task | H/L: move task | " + grouping + " | u: undo | q: quit"
+}
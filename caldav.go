@@ -0,0 +1,505 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Two-way sync of tasks with a CalDAV calendar (Nextcloud Tasks, Radicale,
+// ...). Every synced task has a row in the caldav table with the href and
+// etag of its remote VTODO and a hash of the local task as of the last sync.
+// A changed etag means the task was edited remotely, a changed hash that it
+// was edited locally. When both changed the remote version wins and the
+// local one is kept as a new "(conflict)" task, so no edit is lost.
+
+// caldavLink ties a local task to a remote resource.
+type caldavLink struct {
+	taskID int
+	uid    string
+	href   string
+	etag   string
+	hash   string
+}
+
+type caldavResource struct {
+	href string
+	etag string
+	data string
+}
+
+// syncResult summarizes one sync for the User tab.
+type syncResult struct {
+	pulled    int
+	pushed    int
+	deleted   int
+	conflicts int
+}
+
+// caldavSyncMsg is sent to Update when a background sync finishes.
+type caldavSyncMsg struct {
+	result syncResult
+	err    error
+}
+
+func createCalDAVTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS caldav (
+			task_id INTEGER PRIMARY KEY,
+			uid TEXT NOT NULL,
+			href TEXT NOT NULL,
+			etag TEXT,
+			hash TEXT
+		);
+	`)
+	return err
+}
+
+// syncCalDAV runs a sync in the background.
+func (m model) syncCalDAV() tea.Cmd {
+	return func() tea.Msg {
+		result, err := m.runCalDAVSync()
+		return caldavSyncMsg{result: result, err: err}
+	}
+}
+
+type caldavClient struct {
+	base     *url.URL
+	username string
+	password string
+	http     *http.Client
+}
+
+func newCalDAVClient(cfg config) (*caldavClient, error) {
+	if cfg.caldavURL == "" {
+		return nil, fmt.Errorf("no CalDAV server, set url in the [caldav] section of the config")
+	}
+	base, err := url.Parse(cfg.caldavURL)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	return &caldavClient{
+		base:     base,
+		username: cfg.caldavUsername,
+		password: cfg.caldavPassword,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *caldavClient) request(method, href, body string, header map[string]string) (*http.Response, error) {
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, c.base.ResolveReference(ref).String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return c.http.Do(req)
+}
+
+// list fetches every VTODO in the calendar.
+func (c *caldavClient) list() ([]caldavResource, error) {
+	const query = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+	resp, err := c.request("REPORT", c.base.Path, query, map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("caldav: listing tasks: %s", resp.Status)
+	}
+
+	var status struct {
+		Responses []struct {
+			Href     string `xml:"href"`
+			Propstat []struct {
+				Status string `xml:"status"`
+				Prop   struct {
+					ETag string `xml:"getetag"`
+					Data string `xml:"calendar-data"`
+				} `xml:"prop"`
+			} `xml:"propstat"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("caldav: %w", err)
+	}
+
+	var resources []caldavResource
+	for _, r := range status.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Prop.Data != "" && strings.Contains(ps.Status, " 200 ") {
+				resources = append(resources, caldavResource{href: r.Href, etag: ps.Prop.ETag, data: ps.Prop.Data})
+			}
+		}
+	}
+	return resources, nil
+}
+
+// put uploads a VTODO. An empty etag creates the resource, otherwise the
+// upload only succeeds if the remote copy is unchanged. It returns the new
+// etag, which is empty if the server did not send one.
+func (c *caldavClient) put(href, data, etag string) (string, error) {
+	header := map[string]string{"Content-Type": "text/calendar; charset=utf-8"}
+	if etag == "" {
+		header["If-None-Match"] = "*"
+	} else {
+		header["If-Match"] = etag
+	}
+	resp, err := c.request(http.MethodPut, href, data, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return "", errCalDAVConflict
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("caldav: uploading %s: %s", href, resp.Status)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (c *caldavClient) delete(href, etag string) error {
+	header := map[string]string{}
+	if etag != "" {
+		header["If-Match"] = etag
+	}
+	resp, err := c.request(http.MethodDelete, href, "", header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errCalDAVConflict
+	}
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("caldav: deleting %s: %s", href, resp.Status)
+	}
+	return nil
+}
+
+var errCalDAVConflict = fmt.Errorf("caldav: changed on the server")
+
+// taskHash fingerprints the synced fields of a task.
+func taskHash(task item) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%d|%d|%d|%s|%d|%s|%d",
+		task.title, strings.Join(task.tags, ","), task.status, task.completedAt.Unix(),
+		task.dueAt.Unix(), task.recurrence, task.parentID, task.notes, task.priority)))
+	return hex.EncodeToString(sum[:])
+}
+
+func newUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@xtui"
+}
+
+func (s *sqliteStore) CalDAVLinks() (map[int]*caldavLink, error) {
+	rows, err := s.db.Query("SELECT task_id, uid, href, COALESCE(etag, ''), COALESCE(hash, '') FROM caldav")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make(map[int]*caldavLink)
+	for rows.Next() {
+		var link caldavLink
+		if err := rows.Scan(&link.taskID, &link.uid, &link.href, &link.etag, &link.hash); err != nil {
+			return nil, err
+		}
+		links[link.taskID] = &link
+	}
+	return links, rows.Err()
+}
+
+func (s *sqliteStore) SaveCalDAVLink(link *caldavLink) error {
+	_, err := s.db.Exec(`
+		INSERT INTO caldav (task_id, uid, href, etag, hash) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(task_id) DO UPDATE SET uid = excluded.uid, href = excluded.href, etag = excluded.etag, hash = excluded.hash
+	`, link.taskID, link.uid, link.href, link.etag, link.hash)
+	return err
+}
+
+func (s *sqliteStore) DeleteCalDAVLink(taskID int) error {
+	_, err := s.db.Exec("DELETE FROM caldav WHERE task_id = ?", taskID)
+	return err
+}
+
+// runCalDAVSync reconciles the tasks table with the server.
+func (m model) runCalDAVSync() (syncResult, error) {
+	var result syncResult
+	client, err := newCalDAVClient(m.config)
+	if err != nil {
+		return result, err
+	}
+	remote, err := client.list()
+	if err != nil {
+		return result, err
+	}
+
+	all, err := m.store.Load(allTasks)
+	if err != nil {
+		return result, err
+	}
+	tasks := make(map[int]item, len(all))
+	for _, task := range all {
+		tasks[task.id] = task
+	}
+	links, err := m.store.CalDAVLinks()
+	if err != nil {
+		return result, err
+	}
+	byHref := make(map[string]*caldavLink, len(links))
+	byUID := make(map[string]int, len(links))
+	for _, link := range links {
+		byHref[link.href] = link
+		byUID[link.uid] = link.taskID
+	}
+
+	// Parents are resolved once every remote task has a local id
+	parents := make(map[int]string)
+	pull := func(task *item, parentUID string) error {
+		if task.status == done && task.completedAt.IsZero() {
+			task.completedAt = time.Now()
+		}
+		if task.id == 0 {
+			if err := m.store.Save(task); err != nil {
+				return err
+			}
+		} else {
+			*task = withVTODO(tasks[task.id], *task)
+			if err := m.store.Update(*task); err != nil {
+				return err
+			}
+		}
+		parents[task.id] = parentUID
+		tasks[task.id] = *task
+		result.pulled++
+		return nil
+	}
+
+	seen := make(map[int]bool)
+	for _, r := range remote {
+		remoteTask, uid, parentUID, ok := readVTODO(r.data)
+		if !ok || uid == "" {
+			continue
+		}
+		link, known := byHref[r.href]
+		if !known {
+			if err := pull(&remoteTask, parentUID); err != nil {
+				return result, err
+			}
+			link = &caldavLink{taskID: remoteTask.id, uid: uid, href: r.href, etag: r.etag}
+			links[link.taskID] = link
+			byUID[uid] = link.taskID
+			seen[link.taskID] = true
+			continue
+		}
+		seen[link.taskID] = true
+
+		local, exists := tasks[link.taskID]
+		localGone := !exists || !local.deletedAt.IsZero()
+		remoteChanged := r.etag != link.etag
+		localChanged := !localGone && taskHash(local) != link.hash
+
+		switch {
+		case localGone && !remoteChanged: // Deleted here
+			if err := client.delete(r.href, r.etag); err != nil && err != errCalDAVConflict {
+				return result, err
+			}
+			if err := m.store.DeleteCalDAVLink(link.taskID); err != nil {
+				return result, err
+			}
+			delete(links, link.taskID)
+			result.deleted++
+			continue
+		case localGone: // Deleted here but edited remotely, keep the edit
+			if exists {
+				if err := m.store.Restore(&local); err != nil {
+					return result, err
+				}
+				local.deletedAt = time.Time{}
+				tasks[local.id] = local
+				remoteTask.id, remoteTask.sortOrder = local.id, local.sortOrder
+			}
+		case remoteChanged && localChanged: // Edited on both sides
+			local.id, local.sortOrder = 0, 0
+			local.title += " (conflict)"
+			if err := m.store.Save(&local); err != nil {
+				return result, err
+			}
+			tasks[local.id] = local
+			result.conflicts++
+			remoteTask.id, remoteTask.sortOrder = link.taskID, tasks[link.taskID].sortOrder
+		case remoteChanged:
+			remoteTask.id, remoteTask.sortOrder = local.id, local.sortOrder
+		case localChanged:
+			etag, err := client.put(r.href, m.vtodoData(local, link.uid, links), link.etag)
+			if err == errCalDAVConflict {
+				continue // Changed in the meantime, picked up next time
+			}
+			if err != nil {
+				return result, err
+			}
+			link.etag, link.hash = etag, taskHash(local)
+			result.pushed++
+			if err := m.store.SaveCalDAVLink(link); err != nil {
+				return result, err
+			}
+			continue
+		default:
+			continue
+		}
+
+		if remoteTask.id == 0 {
+			if err := m.store.DeleteCalDAVLink(link.taskID); err != nil {
+				return result, err
+			}
+			delete(links, link.taskID)
+		}
+		if err := pull(&remoteTask, parentUID); err != nil {
+			return result, err
+		}
+		link.taskID, link.etag = remoteTask.id, r.etag
+		links[link.taskID] = link
+		byUID[link.uid] = link.taskID
+	}
+
+	// Links whose resource is gone were deleted remotely
+	for id, link := range links {
+		if seen[id] {
+			continue
+		}
+		local, exists := tasks[id]
+		if exists && local.deletedAt.IsZero() && taskHash(local) != link.hash {
+			// Edited here after the remote delete, upload it again
+			link.etag = ""
+			continue
+		}
+		if exists && local.deletedAt.IsZero() {
+			if err := m.store.Delete(id); err != nil {
+				return result, err
+			}
+			local.deletedAt = time.Now()
+			tasks[id] = local
+			result.deleted++
+		}
+		if err := m.store.DeleteCalDAVLink(id); err != nil {
+			return result, err
+		}
+		delete(links, id)
+	}
+
+	// Upload tasks that are new here, including conflict copies, and tasks
+	// edited here after a remote delete. UIDs are assigned first so subtasks
+	// can refer to new parents.
+	var uploads []int
+	for id, task := range tasks {
+		if !task.deletedAt.IsZero() {
+			continue
+		}
+		if _, pulled := parents[id]; pulled {
+			continue
+		}
+		link, ok := links[id]
+		if !ok {
+			uid := newUID()
+			link = &caldavLink{taskID: id, uid: uid, href: client.base.Path + strings.TrimSuffix(uid, "@xtui") + ".ics"}
+			links[id] = link
+			byUID[uid] = id
+		} else if link.etag != "" {
+			continue
+		}
+		uploads = append(uploads, id)
+	}
+	for _, id := range uploads {
+		link := links[id]
+		etag, err := client.put(link.href, m.vtodoData(tasks[id], link.uid, links), "")
+		if err == errCalDAVConflict {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		link.etag, link.hash = etag, taskHash(tasks[id])
+		if err := m.store.SaveCalDAVLink(link); err != nil {
+			return result, err
+		}
+		result.pushed++
+	}
+
+	// Link pulled tasks to their parents and remember their state
+	for id, parentUID := range parents {
+		task := tasks[id]
+		task.parentID = byUID[parentUID]
+		if task.parentID == task.id {
+			task.parentID = 0
+		}
+		if err := m.store.Update(task); err != nil {
+			return result, err
+		}
+		link := links[id]
+		link.hash = taskHash(task)
+		if err := m.store.SaveCalDAVLink(link); err != nil {
+			return result, err
+		}
+	}
+
+	err = m.store.SaveSetting("caldav_last_sync", time.Now().Format(time.RFC3339))
+	return result, err
+}
+
+// withVTODO puts the fields of a task read from a VTODO onto the local task,
+// keeping those a VTODO has no property for: context, project, list, start
+// date, estimate, star, plan, snooze and archive.
+func withVTODO(local, remote item) item {
+	local.title, local.notes, local.tags = remote.title, remote.notes, remote.tags
+	local.status, local.completedAt = remote.status, remote.completedAt
+	local.dueAt, local.priority, local.recurrence = remote.dueAt, remote.priority, remote.recurrence
+	return local
+}
+
+// vtodoData renders a task as a calendar object for upload.
+func (m model) vtodoData(task item, uid string, links map[int]*caldavLink) string {
+	var b strings.Builder
+	out := icsWriter{bufio.NewWriter(&b)}
+	parentUID := ""
+	if link, ok := links[task.parentID]; ok && task.parentID != 0 {
+		parentUID = link.uid
+	}
+	out.begin()
+	out.component(task, "VTODO", uid, parentUID)
+	out.end()
+	out.Flush()
+	return b.String()
+}
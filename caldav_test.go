@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCalDAVPullKeepsLocalFields(t *testing.T) {
+	m := newTestModel(t)
+	snoozed := time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)
+	task := item{
+		title:        "Book flights",
+		tags:         []string{"travel"},
+		createdAt:    time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC),
+		context:      "laptop",
+		project:      "holiday",
+		list:         nextAction,
+		estimate:     30,
+		starred:      true,
+		snoozedUntil: snoozed,
+	}
+	if err := m.store.Save(&task); err != nil {
+		t.Fatal(err)
+	}
+	link := &caldavLink{taskID: task.id, uid: "flights@xtui", href: "/cal/flights.ics", etag: `"1"`, hash: taskHash(task)}
+	if err := m.store.SaveCalDAVLink(link); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server has a newer version with another title and a due date
+	vtodo := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:flights@xtui\r\nSUMMARY:Book flights to Lisbon\r\nCATEGORIES:travel\r\nDUE;VALUE=DATE:20261025\r\nPRIORITY:1\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "REPORT" {
+			w.Header().Set("ETag", `"3"`)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprintf(w, `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:response><d:href>/cal/flights.ics</d:href><d:propstat><d:prop><d:getetag>"2"</d:getetag><c:calendar-data>%s</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>`, vtodo)
+	}))
+	defer server.Close()
+	m.config.caldavURL = server.URL + "/cal/"
+
+	result, err := m.runCalDAVSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.pulled != 1 {
+		t.Errorf("pulled %d tasks, want 1", result.pulled)
+	}
+	tasks, err := m.store.Load(allTasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(tasks))
+	}
+	got := tasks[0]
+	if got.title != "Book flights to Lisbon" || got.priority != 1 || got.dueAt.Format("2006-01-02") != "2026-10-25" {
+		t.Errorf("remote changes not pulled: %q priority %d due %v", got.title, got.priority, got.dueAt)
+	}
+	if got.context != task.context || got.project != task.project || got.list != task.list || got.estimate != task.estimate || !got.starred || !got.snoozedUntil.Equal(snoozed) {
+		t.Errorf("local fields lost: %+v", got)
+	}
+}
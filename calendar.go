@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The Calendar tab shows the month around the selected day with the number of
+// open and completed tasks due on each day. h/l and j/k move by a day and a
+// week (the arrow keys keep switching tabs), enter lists the tasks due on the
+// selected day and a adds one.
+
+const calendarCellWidth = 8
+
+type calendarModel struct {
+	day      time.Time // Selected day, at midnight
+	open     bool      // Listing the tasks due on the selected day
+	selected int       // Cursor in that list
+	input    textinput.Model
+}
+
+func newCalendarModel() calendarModel {
+	ti := textinput.New()
+	ti.Prompt = "New task: "
+	ti.Placeholder = "title #tag !p1"
+	return calendarModel{day: midnight(time.Now()), input: ti}
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// dueOn returns the indices of the tasks due on a day, open ones first.
+func (m model) dueOn(day time.Time) []int {
+	var open, finished []int
+	for i, task := range m.tasksModel.items {
+		if task.dueAt.IsZero() || !midnight(task.dueAt).Equal(day) {
+			continue
+		}
+		if task.status == done {
+			finished = append(finished, i)
+		} else {
+			open = append(open, i)
+		}
+	}
+	return append(open, finished...)
+}
+
+// updateCalendar handles keys on the Calendar tab.
+func (m *model) updateCalendar(msg tea.KeyMsg) tea.Cmd {
+	c := &m.calendar
+	if m.tasksModel.mode == insertMode {
+		switch msg.String() {
+		case "esc":
+			m.tasksModel.mode = normalMode
+			c.input.Blur()
+		case "enter":
+			if value := c.input.Value(); strings.TrimSpace(value) != "" {
+				if _, due := parseTitleAndDue(value); due.IsZero() {
+					value += " due:" + c.day.Format("2006-01-02")
+				}
+				m.addTask(value, 0)
+			}
+			m.tasksModel.mode = normalMode
+			c.input.Blur()
+		default:
+			var cmd tea.Cmd
+			c.input, cmd = c.input.Update(msg)
+			return cmd
+		}
+		return nil
+	}
+
+	tasks := m.dueOn(c.day)
+	switch msg.String() {
+	case "h":
+		c.day = c.day.AddDate(0, 0, -1)
+	case "l":
+		c.day = c.day.AddDate(0, 0, 1)
+	case "k", "up":
+		if c.open {
+			if c.selected > 0 {
+				c.selected--
+			}
+			return nil
+		}
+		c.day = c.day.AddDate(0, 0, -7)
+	case "j", "down":
+		if c.open {
+			if c.selected < len(tasks)-1 {
+				c.selected++
+			}
+			return nil
+		}
+		c.day = c.day.AddDate(0, 0, 7)
+	case "H": // Same day in the previous month
+		c.day = c.day.AddDate(0, -1, 0)
+	case "L":
+		c.day = c.day.AddDate(0, 1, 0)
+	case "t": // Back to today
+		c.day = midnight(time.Now())
+	case "enter":
+		c.open = true
+	case "esc":
+		c.open = false
+	case " ": // Toggle the selected task of the day
+		if c.open && c.selected < len(tasks) {
+			index := tasks[c.selected]
+			s := done
+			if m.tasksModel.items[index].status == done {
+				s = todo
+			}
+			m.setStatus([]int{index}, s)
+		}
+		return nil
+	case "a":
+		c.input.Reset()
+		m.tasksModel.mode = insertMode
+		return c.input.Focus()
+	default:
+		return nil
+	}
+	c.selected = 0
+	return nil
+}
+
+func (m model) renderCalendar() string {
+	c := m.calendar
+	today := midnight(time.Now())
+	first := time.Date(c.day.Year(), c.day.Month(), 1, 0, 0, 0, 0, time.Local)
+	// Weeks start on Monday
+	start := first.AddDate(0, 0, -(int(first.Weekday())+6)%7)
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(c.day.Format("January 2006")) + "\n\n")
+
+	cell := lipgloss.NewStyle().Width(calendarCellWidth)
+	var header []string
+	for i := 0; i < 7; i++ {
+		header = append(header, cell.Inherit(helpStyle).Render(start.AddDate(0, 0, i).Format("Mon")))
+	}
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, header...) + "\n")
+
+	for week := start; week.Month() == c.day.Month() || week.Equal(start); week = week.AddDate(0, 0, 7) {
+		var cells []string
+		for i := 0; i < 7; i++ {
+			day := week.AddDate(0, 0, i)
+			if day.Month() != c.day.Month() {
+				cells = append(cells, cell.Render("\n"))
+				continue
+			}
+			cells = append(cells, cell.Render(m.renderDay(day, today)))
+		}
+		s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, cells...) + "\n")
+	}
+
+	if c.open || m.tasksModel.mode == insertMode {
+		s.WriteString("\n" + m.renderDayTasks())
+	}
+	return s.String()
+}
+
+// renderDay draws the day number over the count of open (●) and completed
+// (✓) tasks due that day.
+func (m model) renderDay(day, today time.Time) string {
+	number := fmt.Sprintf("%2d", day.Day())
+	switch {
+	case day.Equal(m.calendar.day):
+		number = visualItemStyle.UnsetPaddingLeft().Render(number)
+	case day.Equal(today):
+		number = activeTabStyle.UnsetPadding().Render(number)
+	default:
+		number = itemStyle.UnsetPaddingLeft().Render(number)
+	}
+
+	open, finished := 0, 0
+	for _, i := range m.dueOn(day) {
+		if m.tasksModel.items[i].status == done {
+			finished++
+		} else {
+			open++
+		}
+	}
+	var counts []string
+	if open > 0 {
+		style := selectedItemStyle.UnsetPaddingLeft()
+		if day.Before(today) {
+			style = overdueStyle
+		}
+		counts = append(counts, style.Render(fmt.Sprintf("●%d", open)))
+	}
+	if finished > 0 {
+		counts = append(counts, helpStyle.Render(fmt.Sprintf("✓%d", finished)))
+	}
+	return number + "\n" + strings.Join(counts, " ")
+}
+
+// renderDayTasks lists the tasks due on the selected day.
+func (m model) renderDayTasks() string {
+	c := m.calendar
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Due "+c.day.Format("Monday 2 January")) + "\n")
+	tasks := m.dueOn(c.day)
+	if len(tasks) == 0 {
+		s.WriteString(helpStyle.Render("Nothing due. Press a to add a task.") + "\n")
+	}
+	for i, index := range tasks {
+		task := m.tasksModel.items[index]
+		marker := statusMarker(todo)
+		if task.status == done {
+			marker = statusMarker(done)
+		}
+		cursor := cursorMark(c.open && i == c.selected)
+		style := itemStyle
+		if c.open && i == c.selected {
+			style = selectedItemStyle
+		}
+		line := cursor + marker + " " + task.title
+		if !isEndOfDay(task.dueAt) {
+			line += " " + formatClock(task.dueAt)
+		}
+		s.WriteString(style.Render(line) + "\n")
+	}
+	if m.tasksModel.mode == insertMode {
+		s.WriteString("\n" + c.input.View() + "\n")
+	}
+	return s.String()
+}
+
+// calendarKeys lists the keys that apply to the Calendar tab.
+func (m model) calendarKeys() string {
+	if m.tasksModel.mode == insertMode {
+		return "enter: add task due " + m.calendar.day.Format("Jan 2") + " | esc: cancel"
+	}
+	if m.calendar.open {
+		return "j/k: move | space: toggle | h/l: previous/next day | a: add task | esc: back to the month | q: quit"
+	}
+	return "←/→: switch tabs | h/j/k/l: move | H/L: previous/next month | t: today | enter: tasks due | a: add task | q: quit"
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A checklist holds the small steps of a task that don't deserve tasks of
+// their own, like the items to pack for a trip. It is edited in the detail
+// view (o): tab moves between the notes and the checklist, where a adds an
+// item, space checks or unchecks the highlighted one, e renames it and d
+// removes it. The list shows a task's progress, like 3/5, after its title.
+// Checklists stay in the local database and are not synced.
+
+type checkItem struct {
+	id     int
+	taskID int
+	text   string
+	done   bool
+}
+
+// checklistState is the checklist being edited in the detail view.
+type checklistState struct {
+	focused  bool // Keys go to the checklist rather than the notes
+	selected int
+	input    textinput.Model // Item being added or renamed
+	editID   int             // Item being renamed, 0 when adding
+	typing   bool
+}
+
+func createChecklistTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS checklist_items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			done INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS checklist_items_task ON checklist_items (task_id);
+	`)
+	return err
+}
+
+// ChecklistItems returns the items of every checklist, in the order they
+// were added.
+func (s *sqliteStore) ChecklistItems() ([]checkItem, error) {
+	rows, err := s.db.Query("SELECT id, task_id, text, done FROM checklist_items ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []checkItem
+	for rows.Next() {
+		var c checkItem
+		if err := rows.Scan(&c.id, &c.taskID, &c.text, &c.done); err != nil {
+			return nil, err
+		}
+		items = append(items, c)
+	}
+	return items, rows.Err()
+}
+
+func (s *sqliteStore) AddCheckItem(c *checkItem) error {
+	res, err := s.db.Exec("INSERT INTO checklist_items (task_id, text, done) VALUES (?, ?, ?)", c.taskID, c.text, c.done)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	c.id = int(id)
+	return err
+}
+
+func (s *sqliteStore) UpdateCheckItem(c checkItem) error {
+	_, err := s.db.Exec("UPDATE checklist_items SET text = ?, done = ? WHERE id = ?", c.text, c.done, c.id)
+	return err
+}
+
+func (s *sqliteStore) RemoveCheckItem(id int) error {
+	_, err := s.db.Exec("DELETE FROM checklist_items WHERE id = ?", id)
+	return err
+}
+
+func (m *model) refreshChecklists() {
+	items, err := m.store.ChecklistItems()
+	if err != nil {
+		m.showError("load checklists", err)
+		return
+	}
+	m.checklists = items
+}
+
+// checklistOf returns the checklist of a task.
+func (m model) checklistOf(taskID int) []checkItem {
+	var items []checkItem
+	for _, c := range m.checklists {
+		if c.taskID == taskID {
+			items = append(items, c)
+		}
+	}
+	return items
+}
+
+// checklistProgress returns how many items of a task's checklist are checked,
+// and how many there are.
+func (m model) checklistProgress(taskID int) (checked, total int) {
+	for _, c := range m.checklists {
+		if c.taskID == taskID {
+			total++
+			if c.done {
+				checked++
+			}
+		}
+	}
+	return checked, total
+}
+
+// resetChecklist leaves the checklist of the detail view unfocused, for a
+// newly opened task.
+func (m *model) resetChecklist() {
+	input := textinput.New()
+	input.Prompt = "Item: "
+	input.Placeholder = "what needs doing"
+	m.checklist = checklistState{input: input}
+}
+
+// updateChecklist handles keys in the detail view while the checklist has
+// the focus.
+func (m *model) updateChecklist(msg tea.KeyMsg, taskID int) tea.Cmd {
+	c := &m.checklist
+	items := m.checklistOf(taskID)
+	if c.typing {
+		switch msg.String() {
+		case "esc":
+		case "enter":
+			text := strings.TrimSpace(c.input.Value())
+			if text == "" {
+				break
+			}
+			if c.editID != 0 {
+				for _, item := range items {
+					if item.id == c.editID {
+						item.text = text
+						if err := m.store.UpdateCheckItem(item); err != nil {
+							m.showError("rename checklist item", err)
+						}
+					}
+				}
+			} else {
+				added := checkItem{taskID: taskID, text: text}
+				if err := m.store.AddCheckItem(&added); err != nil {
+					m.showError("add checklist item", err)
+				}
+				c.selected = len(items)
+			}
+			m.refreshChecklists()
+		default:
+			var cmd tea.Cmd
+			c.input, cmd = c.input.Update(msg)
+			return cmd
+		}
+		c.typing = false
+		c.input.Reset()
+		c.input.Blur()
+		return nil
+	}
+
+	switch msg.String() {
+	case "k", "up":
+		if c.selected > 0 {
+			c.selected--
+		}
+	case "j", "down":
+		if c.selected < len(items)-1 {
+			c.selected++
+		}
+	case "a":
+		c.typing, c.editID = true, 0
+		c.input.Reset()
+		return c.input.Focus()
+	case "e":
+		if c.selected < len(items) {
+			c.typing, c.editID = true, items[c.selected].id
+			c.input.SetValue(items[c.selected].text)
+			c.input.CursorEnd()
+			return c.input.Focus()
+		}
+	case " ", "x":
+		if c.selected < len(items) {
+			item := items[c.selected]
+			item.done = !item.done
+			if err := m.store.UpdateCheckItem(item); err != nil {
+				m.showError("check item", err)
+			}
+			m.refreshChecklists()
+		}
+	case "d":
+		if c.selected < len(items) {
+			if err := m.store.RemoveCheckItem(items[c.selected].id); err != nil {
+				m.showError("remove checklist item", err)
+			}
+			m.refreshChecklists()
+			c.selected = min(c.selected, max(0, len(items)-2))
+		}
+	case "tab", "esc":
+		c.focused = false
+		return m.tasksModel.notes.Focus()
+	}
+	return nil
+}
+
+// renderChecklist shows a task's checklist in the detail view.
+func (m model) renderChecklist(taskID int) string {
+	c := m.checklist
+	items := m.checklistOf(taskID)
+	if len(items) == 0 && !c.focused {
+		return ""
+	}
+	var s strings.Builder
+	checked, total := m.checklistProgress(taskID)
+	s.WriteString(helpStyle.Render(fmt.Sprintf("Checklist %d/%d", checked, total)) + "\n")
+	if len(items) == 0 && !c.typing {
+		s.WriteString(helpStyle.Render("  Nothing to check yet. Press a to add an item.") + "\n")
+	}
+	for i, item := range items {
+		line := statusMarker(todo) + " " + item.text
+		if item.done {
+			line = statusMarker(done) + " " + item.text
+		}
+		switch {
+		case c.focused && i == c.selected:
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+line) + "\n")
+		case item.done:
+			s.WriteString(itemStyle.Render(cursorMark(false)+helpStyle.Render(line)) + "\n")
+		default:
+			s.WriteString(itemStyle.Render(cursorMark(false)+line) + "\n")
+		}
+	}
+	if c.typing {
+		s.WriteString(c.input.View() + "\n")
+	}
+	return s.String()
+}
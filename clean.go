@@ -0,0 +1,147 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// :clean <days> clears out completed tasks done more than that many days
+// ago, moving them to the trash in one transaction, or with :clean <days>
+// archive into the archive, where they stay out of the list but count in
+// the Stats tab. The prompt first says how many tasks it would take and waits
+// for a second enter. Completed tasks with subtasks still open or newer stay,
+// so nothing is left without its parent. The tasks come from the database,
+// including those older than the completed tasks loaded into the list.
+
+// oldCompleted returns the live tasks completed before the given time that
+// have no live subtasks outside of them.
+func oldCompleted(tx *sql.Tx, before time.Time) ([]item, error) {
+	rows, err := tx.Query(`
+		SELECT id, title, COALESCE(parent_id, 0), status = ? AND completed_at IS NOT NULL AND julianday(completed_at) < julianday(?)
+		FROM tasks WHERE deleted_at IS NULL AND archived_at IS NULL`, done, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []item
+	old := make(map[int]bool)
+	parents := make(map[int]int)
+	for rows.Next() {
+		var task item
+		var isOld bool
+		if err := rows.Scan(&task.id, &task.title, &task.parentID, &isOld); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+		old[task.id] = isOld
+		parents[task.id] = task.parentID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A task that stays keeps its ancestors
+	for _, task := range tasks {
+		if old[task.id] {
+			continue
+		}
+		for id := task.parentID; id != 0 && old[id]; id = parents[id] {
+			old[id] = false
+		}
+	}
+	var result []item
+	for _, task := range tasks {
+		if old[task.id] {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (s *sqliteStore) OldCompleted(before time.Time) ([]item, error) {
+	var tasks []item
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		tasks, err = oldCompleted(tx, before)
+		return err
+	})
+	return tasks, err
+}
+
+func (s *sqliteStore) CleanCompleted(before time.Time, archive bool) ([]item, error) {
+	var tasks []item
+	now := time.Now()
+	err := inTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		if tasks, err = oldCompleted(tx, before); err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if archive {
+				_, err = tx.Exec("UPDATE tasks SET archived_at = ?, updated_at = ? WHERE id = ?", now, now, task.id)
+			} else {
+				_, err = tx.Exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", now, task.id)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return tasks, err
+}
+
+// clean runs :clean, asking first with the count of tasks it would take.
+func (m *model) clean(args []string) error {
+	usage := fmt.Errorf("usage: :clean <days> [archive]")
+	if len(args) == 0 || len(args) > 2 || (len(args) == 2 && args[1] != "archive") {
+		return usage
+	}
+	days, err := strconv.Atoi(args[0])
+	if err != nil || days < 0 {
+		return usage
+	}
+	archive := len(args) == 2
+	where := "the trash"
+	if archive {
+		where = "the archive"
+	}
+	before := midnight(time.Now()).AddDate(0, 0, -days)
+
+	line := strings.Join(args, " ")
+	if m.tasksModel.confirmClean != line {
+		tasks, err := m.store.OldCompleted(before)
+		if err != nil {
+			return err
+		}
+		if len(tasks) == 0 {
+			return fmt.Errorf("no tasks completed more than %d days ago", days)
+		}
+		m.tasksModel.confirmClean = line
+		return fmt.Errorf("move %s completed more than %d days ago to %s? enter to confirm, esc to cancel", countTasks(len(tasks)), days, where)
+	}
+
+	cleaned, err := m.store.CleanCompleted(before, archive)
+	if err != nil {
+		return err
+	}
+	removed := make(map[int]bool, len(cleaned))
+	for _, task := range cleaned {
+		removed[task.id] = true
+	}
+	var remaining []item
+	for _, task := range m.tasksModel.items {
+		if !removed[task.id] {
+			remaining = append(remaining, task)
+		}
+	}
+	m.tasksModel.items = remaining
+	m.tasksModel.clampSelection()
+	m.logChange("clean", cleaned, nil)
+	m.showMessage(fmt.Sprintf("Moved %s to %s", countTasks(len(cleaned)), where))
+	return nil
+}
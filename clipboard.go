@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// yy copies the selected task, or y the visual selection, to the system
+// clipboard as a Markdown checklist, ready to paste into a chat or an issue
+// (see registers.go). Without a clipboard tool (xclip, pbcopy ...) the text
+// is sent to the terminal with OSC 52, which also works over SSH.
+//
+// ctrl+v goes the other way: every non-empty line on the clipboard becomes a
+// task, with the same #tag, due: and @context syntax as the input, so a
+// meeting's action items go in at once. Pasting into the terminal while the
+// list is shown does the same.
+
+// pastedMsg carries the text read from the clipboard.
+type pastedMsg string
+
+// yankText formats tasks the way the Markdown export writes them.
+func yankText(tasks []item) string {
+	var lines []string
+	for _, task := range tasks {
+		box := "[ ]"
+		if task.status == done {
+			box = "[x]"
+		}
+		lines = append(lines, "- "+box+" "+formatTaskInput(task))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// copyToClipboard puts text on the system clipboard.
+func copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		if err := clipboard.WriteAll(text); err == nil {
+			return nil
+		}
+		seq := osc52.New(text)
+		if os.Getenv("TMUX") != "" {
+			seq = seq.Tmux()
+		}
+		if _, err := seq.WriteTo(os.Stderr); err != nil {
+			return errorMsg{"copy to clipboard", err}
+		}
+		return nil
+	}
+}
+
+// readClipboard reads the system clipboard for pasteTasks.
+func readClipboard() tea.Msg {
+	text, err := clipboard.ReadAll()
+	if err != nil {
+		return errorMsg{"read clipboard", err}
+	}
+	return pastedMsg(text)
+}
+
+// pasteTasks adds a task for each non-empty line as one undo step. List
+// bullets and checkboxes in front of the lines are dropped.
+func (m *model) pasteTasks(text string) {
+	before := m.snapshot()
+	var added []item
+	for _, line := range strings.Split(text, "\n") {
+		line = listLine(line)
+		task := m.parseTask(line)
+		if task.title == "" {
+			continue
+		}
+		if err := m.store.Save(&task); err != nil {
+			m.showError("save task", err)
+			break
+		}
+		m.setReminders(task.id, line)
+		m.fireHook(hookAdd, task)
+		m.tasksModel.items = append(m.tasksModel.items, task)
+		added = append(added, task)
+	}
+	switch len(added) {
+	case 0:
+		m.showMessage("Nothing to paste")
+		return
+	case 1:
+		m.showMessage(fmt.Sprintf("Added %q", added[0].title))
+	default:
+		m.showMessage(fmt.Sprintf("Added %d tasks", len(added)))
+	}
+	m.record("paste", before)
+}
@@ -0,0 +1,553 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sync of the whole task list with an Xtui sync server, configured in the
+// [sync] section. The server keeps one JSON document per account:
+//
+//	GET <url>  {"tasks": [...]} with an ETag, or 404 before the first sync
+//	PUT <url>  replaces the document, guarded by If-Match or If-None-Match
+//
+// Local ids differ between machines, so tasks are matched by UID; the cloud
+// table maps ids to UIDs. The document as of the last sync is kept in the
+// cloud_base setting, which makes this a three-way merge: a task changed on
+// one side takes that side's version, and a task changed on both is merged
+// field by field (see merge.go).
+
+// cloudTask is a task as stored on the server.
+type cloudTask struct {
+	UID         string     `json:"uid"`
+	ParentUID   string     `json:"parent_uid,omitempty"`
+	Title       string     `json:"title"`
+	Tags        []string   `json:"tags"`
+	Done        bool       `json:"done"`
+	Doing       bool       `json:"doing,omitempty"` // Started but not done
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	StartAt     *time.Time `json:"start_at,omitempty"`
+	Estimate    int        `json:"estimate,omitempty"`
+	Starred     bool       `json:"starred,omitempty"`
+	PlannedFor  *time.Time `json:"planned_for,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Notes       string     `json:"notes,omitempty"`
+	Priority    int        `json:"priority,omitempty"`
+	Context     string     `json:"context,omitempty"`
+	Project     string     `json:"project,omitempty"`
+	List        gtdList    `json:"list,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at,omitempty"` // Last change, which wins conflicts
+}
+
+type cloudDocument struct {
+	Tasks []cloudTask `json:"tasks"`
+}
+
+// cloudSyncMsg is sent to Update when a background sync finishes.
+type cloudSyncMsg struct {
+	result syncResult
+	err    error
+}
+
+var (
+	errCloudConflict     = fmt.Errorf("sync: changed on the server, will retry")
+	errCloudUnauthorized = fmt.Errorf("sync: not signed in or the token expired")
+)
+
+func createCloudTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS cloud (
+			task_id INTEGER PRIMARY KEY,
+			uid TEXT NOT NULL UNIQUE
+		);
+	`)
+	return err
+}
+
+// syncCloud runs a sync in the background.
+func (m model) syncCloud() tea.Cmd {
+	return func() tea.Msg {
+		result, err := m.runCloudSync()
+		return cloudSyncMsg{result: result, err: err}
+	}
+}
+
+func toCloudTask(task item, uid, parentUID string) cloudTask {
+	t := cloudTask{
+		UID:        uid,
+		ParentUID:  parentUID,
+		Title:      task.title,
+		Tags:       task.tags,
+		Done:       task.status == done,
+		Doing:      task.status == doing,
+		CreatedAt:  task.createdAt.UTC(),
+		Recurrence: task.recurrence,
+		Notes:      task.notes,
+		Priority:   task.priority,
+		Context:    task.context,
+		Project:    task.project,
+		List:       task.list,
+		Estimate:   task.estimate,
+		Starred:    task.starred,
+		UpdatedAt:  task.updatedAt.UTC(),
+	}
+	if t.Done && !task.completedAt.IsZero() {
+		completedAt := task.completedAt.UTC()
+		t.CompletedAt = &completedAt
+	}
+	if !task.dueAt.IsZero() {
+		dueAt := task.dueAt.UTC()
+		t.DueAt = &dueAt
+	}
+	if !task.startAt.IsZero() {
+		startAt := task.startAt.UTC()
+		t.StartAt = &startAt
+	}
+	if !task.plannedFor.IsZero() {
+		plannedFor := task.plannedFor.UTC()
+		t.PlannedFor = &plannedFor
+	}
+	return t
+}
+
+func (t cloudTask) item() item {
+	task := item{
+		title:      t.Title,
+		tags:       t.Tags,
+		status:     todo,
+		createdAt:  t.CreatedAt,
+		recurrence: t.Recurrence,
+		notes:      t.Notes,
+		priority:   clampPriority(t.Priority),
+		context:    t.Context,
+		project:    t.Project,
+		list:       t.List,
+		estimate:   t.Estimate,
+		starred:    t.Starred,
+	}
+	if task.tags == nil {
+		task.tags = []string{}
+	}
+	if task.list == "" {
+		task.list = inbox
+	}
+	if t.Done {
+		task.status = done
+		task.completedAt = time.Now()
+		if t.CompletedAt != nil {
+			task.completedAt = *t.CompletedAt
+		}
+	} else if t.Doing {
+		task.status = doing
+	}
+	if t.DueAt != nil {
+		task.dueAt = *t.DueAt
+	}
+	if t.StartAt != nil {
+		task.startAt = *t.StartAt
+	}
+	if t.PlannedFor != nil {
+		task.plannedFor = *t.PlannedFor
+	}
+	return task
+}
+
+// key is used to compare versions of a task. When it was last changed
+// doesn't count, only what changed.
+func (t cloudTask) key() string {
+	t = t.normalized()
+	t.UpdatedAt = time.Time{}
+	b, _ := json.Marshal(t)
+	return string(b)
+}
+
+// normalized returns the task with its times in UTC, since other clients may
+// write them with an offset, and no tags as an empty list.
+func (t cloudTask) normalized() cloudTask {
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	t.CreatedAt = t.CreatedAt.UTC()
+	t.UpdatedAt = t.UpdatedAt.UTC()
+	if t.CompletedAt != nil {
+		completedAt := t.CompletedAt.UTC()
+		t.CompletedAt = &completedAt
+	}
+	if t.DueAt != nil {
+		dueAt := t.DueAt.UTC()
+		t.DueAt = &dueAt
+	}
+	if t.StartAt != nil {
+		startAt := t.StartAt.UTC()
+		t.StartAt = &startAt
+	}
+	if t.PlannedFor != nil {
+		plannedFor := t.PlannedFor.UTC()
+		t.PlannedFor = &plannedFor
+	}
+	return t
+}
+
+// sameCloudTask compares two possibly missing versions of a task.
+func sameCloudTask(a cloudTask, aOK bool, b cloudTask, bOK bool) bool {
+	if !aOK || !bOK {
+		return aOK == bOK
+	}
+	return a.key() == b.key()
+}
+
+func byUID(tasks []cloudTask) map[string]cloudTask {
+	result := make(map[string]cloudTask, len(tasks))
+	for _, task := range tasks {
+		if task.UID != "" {
+			result[task.UID] = task
+		}
+	}
+	return result
+}
+
+type cloudClient struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+func newCloudClient(cfg config) (*cloudClient, error) {
+	if cfg.syncURL == "" {
+		return nil, fmt.Errorf("no sync server, set url in the [sync] section of the config")
+	}
+	return &cloudClient{
+		url:   cfg.syncURL,
+		token: cfg.syncToken,
+		http:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *cloudClient) request(method string, body []byte, header map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return c.http.Do(req)
+}
+
+// get downloads the task list and its etag. An account that never synced
+// has an empty list and no etag.
+func (c *cloudClient) get() (cloudDocument, string, error) {
+	var doc cloudDocument
+	resp, err := c.request(http.MethodGet, nil, map[string]string{"Accept": "application/json"})
+	if err != nil {
+		return doc, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return doc, "", nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return doc, "", errCloudUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return doc, "", fmt.Errorf("sync: downloading tasks: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return doc, "", fmt.Errorf("sync: %w", err)
+	}
+	return doc, resp.Header.Get("ETag"), nil
+}
+
+// put uploads the task list if the server still has the version with the
+// given etag, or no version at all if etag is empty. It returns the new etag.
+func (c *cloudClient) put(doc cloudDocument, etag string) (string, error) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	header := map[string]string{"Content-Type": "application/json"}
+	if etag == "" {
+		header["If-None-Match"] = "*"
+	} else {
+		header["If-Match"] = etag
+	}
+	resp, err := c.request(http.MethodPut, body, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return "", errCloudConflict
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", errCloudUnauthorized
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("sync: uploading tasks: %s", resp.Status)
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// linkTable is the table that maps task ids to the UIDs of a sync. The sync
+// server and git sync each have their own, so signing out of the server
+// doesn't change the UIDs in the git file.
+type linkTable string
+
+const (
+	cloudLinks linkTable = "cloud"
+	gitLinks   linkTable = "git_links"
+)
+
+// SyncLinks returns the UIDs of local tasks that still exist.
+func (s *sqliteStore) SyncLinks(table linkTable) (map[int]string, error) {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT l.task_id, l.uid FROM %s l JOIN tasks ON tasks.id = l.task_id", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make(map[int]string)
+	for rows.Next() {
+		var (
+			id  int
+			uid string
+		)
+		if err := rows.Scan(&id, &uid); err != nil {
+			return nil, err
+		}
+		links[id] = uid
+	}
+	return links, rows.Err()
+}
+
+func (s *sqliteStore) SaveSyncLink(table linkTable, id int, uid string) error {
+	_, err := s.db.Exec(fmt.Sprintf("INSERT OR REPLACE INTO %s (task_id, uid) VALUES (?, ?)", table), id, uid)
+	return err
+}
+
+func (s *sqliteStore) ClearSyncLinks(table linkTable) error {
+	_, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s", table))
+	return err
+}
+
+// runCloudSync merges the local tasks with the server's and uploads the
+// result.
+func (m model) runCloudSync() (syncResult, error) {
+	client, err := newCloudClient(m.config)
+	if err != nil {
+		return syncResult{}, err
+	}
+	doc, etag, err := client.get()
+	if err != nil {
+		return syncResult{}, err
+	}
+	base, err := m.syncBase("cloud_base")
+	if err != nil {
+		return syncResult{}, err
+	}
+	next, result, err := m.mergeTasks(cloudLinks, byUID(doc.Tasks), base)
+	if err != nil {
+		return result, err
+	}
+
+	// Upload the merge if the server doesn't have it yet
+	if result.pushed > 0 || etag == "" {
+		if _, err = client.put(next, etag); err != nil {
+			return result, err
+		}
+	}
+	if err := m.saveSyncBase("cloud_base", next); err != nil {
+		return result, err
+	}
+	err = m.store.SaveSetting("cloud_last_sync", time.Now().Format(time.RFC3339))
+	return result, err
+}
+
+// syncBase returns the task list as of the last sync, kept in the setting
+// with the given key.
+func (m model) syncBase(key string) (map[string]cloudTask, error) {
+	var doc cloudDocument
+	if data := m.store.Setting(key, ""); data != "" {
+		if err := json.Unmarshal([]byte(data), &doc); err != nil {
+			return nil, fmt.Errorf("sync: reading last synced state: %w", err)
+		}
+	}
+	return byUID(doc.Tasks), nil
+}
+
+func (m model) saveSyncBase(key string, doc cloudDocument) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return m.store.SaveSetting(key, string(data))
+}
+
+// mergeTasks merges the local tasks with another copy of the list, given
+// the version both started from, and applies the merge here. Tasks are
+// matched by the UIDs in table. It returns the
+// merged list, sorted by creation time, for the other side. pushed counts
+// the tasks the other side doesn't have like that yet.
+func (m model) mergeTasks(table linkTable, remote, base map[string]cloudTask) (cloudDocument, syncResult, error) {
+	var result syncResult
+	all, err := m.store.Load(allTasks)
+	if err != nil {
+		return cloudDocument{}, result, err
+	}
+	links, err := m.store.SyncLinks(table)
+	if err != nil {
+		return cloudDocument{}, result, err
+	}
+	tasks := make(map[int]item, len(all))
+	ids := make(map[string]int, len(links))
+	for _, task := range all {
+		tasks[task.id] = task
+		if uid, ok := links[task.id]; ok {
+			ids[uid] = task.id
+		} else if task.deletedAt.IsZero() {
+			// New here, UIDs are assigned first so subtasks can refer to them
+			uid := newUID()
+			if err := m.store.SaveSyncLink(table, task.id, uid); err != nil {
+				return cloudDocument{}, result, err
+			}
+			links[task.id] = uid
+			ids[uid] = task.id
+		}
+	}
+	local := make(map[string]cloudTask, len(all))
+	for _, task := range all {
+		if task.deletedAt.IsZero() {
+			uid := links[task.id]
+			local[uid] = toCloudTask(task, uid, links[task.parentID])
+		}
+	}
+
+	uids := make(map[string]bool)
+	for _, set := range []map[string]cloudTask{local, remote, base} {
+		for uid := range set {
+			uids[uid] = true
+		}
+	}
+	merged := make(map[string]cloudTask, len(uids))
+	conflicts := make(map[string][]syncConflict)
+	for uid := range uids {
+		l, inLocal := local[uid]
+		r, inRemote := remote[uid]
+		b, inBase := base[uid]
+		switch {
+		case sameCloudTask(l, inLocal, b, inBase): // Unchanged here
+			if inRemote {
+				merged[uid] = r
+			}
+		case sameCloudTask(r, inRemote, b, inBase) || sameCloudTask(l, inLocal, r, inRemote): // Unchanged remotely
+			if inLocal {
+				merged[uid] = l
+			}
+		case !inRemote: // Deleted remotely but edited here, keep the edit
+			merged[uid] = l
+		case !inLocal: // Deleted here but edited remotely, keep the edit
+			merged[uid] = r
+		default: // Edited on both sides
+			t, lost := mergeCloudTask(l, r, b)
+			merged[uid] = t
+			conflicts[uid] = lost
+			result.conflicts += len(lost)
+		}
+	}
+
+	// Apply the merge here. Parents are resolved once every task has an id.
+	parents := make(map[int]string)
+	for uid, t := range merged {
+		l, inLocal := local[uid]
+		if sameCloudTask(t, true, l, inLocal) {
+			continue
+		}
+		task := t.item()
+		if id, ok := ids[uid]; ok {
+			existing := tasks[id]
+			task.id, task.sortOrder = id, existing.sortOrder
+			// Snoozing and archiving stay on this machine
+			task.snoozedUntil, task.archivedAt = existing.snoozedUntil, existing.archivedAt
+			if !existing.deletedAt.IsZero() {
+				err = m.store.Restore(&task)
+			} else {
+				err = m.store.Update(task)
+			}
+			if err != nil {
+				return cloudDocument{}, result, err
+			}
+		} else {
+			if err := m.store.Save(&task); err != nil {
+				return cloudDocument{}, result, err
+			}
+			if err := m.store.SaveSyncLink(table, task.id, uid); err != nil {
+				return cloudDocument{}, result, err
+			}
+			ids[uid] = task.id
+		}
+		tasks[task.id] = task
+		parents[task.id] = t.ParentUID
+		result.pulled++
+	}
+	for uid := range local {
+		if _, ok := merged[uid]; !ok {
+			if err := m.store.Delete(ids[uid]); err != nil {
+				return cloudDocument{}, result, err
+			}
+			result.deleted++
+		}
+	}
+	for id, parentUID := range parents {
+		task := tasks[id]
+		task.parentID = ids[parentUID]
+		if task.parentID == task.id {
+			task.parentID = 0
+		}
+		if err := m.store.Update(task); err != nil {
+			return cloudDocument{}, result, err
+		}
+	}
+
+	// Keep what lost a conflict for :conflicts
+	for uid, lost := range conflicts {
+		for _, c := range lost {
+			if err := m.store.SaveConflict(ids[uid], c); err != nil {
+				return cloudDocument{}, result, err
+			}
+		}
+	}
+
+	next := cloudDocument{Tasks: make([]cloudTask, 0, len(merged))}
+	for _, task := range merged {
+		next.Tasks = append(next.Tasks, task)
+	}
+	sort.Slice(next.Tasks, func(i, j int) bool {
+		a, b := next.Tasks[i], next.Tasks[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.UID < b.UID
+	})
+	for uid := range uids {
+		t, inMerged := merged[uid]
+		r, inRemote := remote[uid]
+		if !sameCloudTask(t, inMerged, r, inRemote) {
+			result.pushed++
+		}
+	}
+	return next, result, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestModel returns a model on a new database in a temporary directory.
+func newTestModel(t *testing.T) model {
+	t.Helper()
+	store, err := openSQLiteStore(filepath.Join(t.TempDir(), "xtui.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return model{store: store}
+}
+
+func TestCloudPullKeepsLocalFields(t *testing.T) {
+	m := newTestModel(t)
+	snoozed := time.Date(2026, time.October, 20, 9, 0, 0, 0, time.UTC)
+	archived := time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+	task := item{title: "Renew passport", tags: []string{}, createdAt: archived, list: inbox, snoozedUntil: snoozed, archivedAt: archived}
+	if err := m.store.Save(&task); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.store.SaveSyncLink(cloudLinks, task.id, "passport@xtui"); err != nil {
+		t.Fatal(err)
+	}
+
+	base := toCloudTask(task, "passport@xtui", "")
+	remote := base
+	remote.Title = "Renew passport and ID"
+	remote.UpdatedAt = time.Now()
+	_, result, err := m.mergeTasks(cloudLinks, map[string]cloudTask{"passport@xtui": remote}, map[string]cloudTask{"passport@xtui": base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.pulled != 1 {
+		t.Errorf("pulled %d tasks, want 1", result.pulled)
+	}
+
+	tasks, err := m.store.Load(allTasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("got %d tasks, want 1", len(tasks))
+	}
+	got := tasks[0]
+	if got.title != remote.Title {
+		t.Errorf("title %q, want the remote %q", got.title, remote.Title)
+	}
+	if !got.snoozedUntil.Equal(snoozed) {
+		t.Errorf("snoozed until %v, want %v", got.snoozedUntil, snoozed)
+	}
+	if !got.archivedAt.Equal(archived) {
+		t.Errorf("archived at %v, want %v", got.archivedAt, archived)
+	}
+}
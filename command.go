@@ -0,0 +1,464 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const commandHistoryLimit = 50
+
+// command is an ex-style command run from the : prompt.
+type command struct {
+	name string
+	args func(m model) []string // Completions for the arguments, nil if none
+	run  func(m *model, args []string) (tea.Cmd, error)
+}
+
+var commands = []command{
+	{
+		name: "quit",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			clearScreen()
+			return tea.Quit, nil
+		},
+	},
+	{
+		name: "sort",
+		args: func(m model) []string { return sortOrders },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 1 || !contains(sortOrders, args[0]) {
+				return nil, fmt.Errorf("usage: :sort %s", strings.Join(sortOrders, "|"))
+			}
+			m.tasksModel.sortBy = args[0]
+			return nil, m.store.SaveSetting("sort", m.tasksModel.sortBy)
+		},
+	},
+	{
+		name: "filter",
+		args: func(m model) []string {
+			tags, _ := m.store.Tags()
+			for i, tag := range tags {
+				tags[i] = "#" + tag
+			}
+			contexts, _ := m.store.Contexts()
+			for _, context := range contexts {
+				tags = append(tags, "@"+context)
+			}
+			return tags
+		},
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			// Without arguments the filters are cleared
+			m.tasksModel.tagFilter = ""
+			m.tasksModel.contextFilter = ""
+			var words []string
+			for _, arg := range args {
+				if strings.HasPrefix(arg, "#") && m.tasksModel.tagFilter == "" {
+					m.tasksModel.tagFilter = arg[1:]
+				} else if strings.HasPrefix(arg, "@") && len(arg) > 1 && m.tasksModel.contextFilter == "" {
+					m.tasksModel.contextFilter = arg[1:]
+				} else {
+					words = append(words, arg)
+				}
+			}
+			m.tasksModel.query = strings.Join(words, " ")
+			m.tasksModel.selected = 0
+			return nil, nil
+		},
+	},
+	{
+		name: "query",
+		args: func(m model) []string { return queryFields },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			// Without arguments the query filter is cleared
+			return nil, m.applyQuery(strings.Join(args, " "))
+		},
+	},
+	{
+		name: "more",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if m.tasksModel.doneCursor.id == 0 {
+				m.showMessage("All completed tasks are loaded")
+			}
+			return m.loadHistory(), nil
+		},
+	},
+	{
+		name: "backup",
+		args: func(m model) []string { return []string{"now"} },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			switch {
+			case len(args) == 1 && args[0] == "now":
+				return m.startBackup(false), nil
+			case len(args) > 0:
+				return nil, fmt.Errorf("usage: :backup [now]")
+			case m.lastBackup.IsZero():
+				m.showMessage("No backups yet, they go to " + m.config.backupDir)
+			default:
+				m.showMessage(fmt.Sprintf("Last backup %s in %s", formatTime(m.lastBackup, "2006-01-02 15:04"), m.config.backupDir))
+			}
+			return nil, nil
+		},
+	},
+	{
+		name: "restore",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			return nil, m.openRestore()
+		},
+	},
+	{
+		name: "snoozed",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.toggleSnoozed()
+			return nil, nil
+		},
+	},
+	{
+		name: "focus",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.toggleFocus()
+			return nil, nil
+		},
+	},
+	{
+		name: "upcoming",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.toggleUpcoming()
+			return nil, nil
+		},
+	},
+	{
+		name: "profile",
+		args: func(m model) []string { return m.config.profileNames() },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("usage: :profile %s", strings.Join(m.config.profileNames(), "|"))
+			}
+			return m.switchProfile(args[0])
+		},
+	},
+	{
+		name: "plugin",
+		args: func(m model) []string {
+			var views []string
+			for _, p := range m.plugins {
+				views = append(views, p.info.Views...)
+			}
+			return views
+		},
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) == 0 {
+				m.showMessage(m.describePlugins())
+				return nil, nil
+			}
+			return m.openPluginView(args[0])
+		},
+	},
+	{
+		name: "conflicts",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			return nil, m.openConflicts()
+		},
+	},
+	{
+		name: "done",
+		args: func(m model) []string { return []string{"hide", "show"} },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 1 || (args[0] != "hide" && args[0] != "show") {
+				return nil, fmt.Errorf("usage: :done hide|show")
+			}
+			m.tasksModel.hideDone = args[0] == "hide"
+			m.tasksModel.clampSelection()
+			return nil, m.store.SaveSetting("hide_done", fmt.Sprint(m.tasksModel.hideDone))
+		},
+	},
+	{
+		name: "theme",
+		args: func(m model) []string { return themeNames },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("usage: :theme %s", strings.Join(themeNames, "|"))
+			}
+			if _, ok := themes[args[0]]; !ok {
+				return nil, fmt.Errorf("unknown theme %q", args[0])
+			}
+			m.setTheme(args[0])
+			return nil, m.store.SaveSetting("theme", m.theme.name)
+		},
+	},
+	{
+		name: "clean",
+		args: func(m model) []string { return []string{"archive"} },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			return nil, m.clean(args)
+		},
+	},
+	{
+		name: "density",
+		args: func(m model) []string { return densities },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("usage: :density %s", strings.Join(densities, "|"))
+			}
+			return nil, m.setDensity(args[0])
+		},
+	},
+	{
+		name: "export",
+		args: func(m model) []string { return formatNames(exporters) },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("usage: :export %s <path>", strings.Join(formatNames(exporters), "|"))
+			}
+			return nil, m.exportTasks(args[0], args[1])
+		},
+	},
+	{
+		name: "import",
+		args: func(m model) []string { return formatNames(importers) },
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if len(args) != 2 {
+				return nil, fmt.Errorf("usage: :import %s <path>", strings.Join(formatNames(importers), "|"))
+			}
+			_, err := m.importTasks(args[0], args[1])
+			return nil, err
+		},
+	},
+	{
+		name: "todoist",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			if m.config.todoistToken == "" {
+				return nil, fmt.Errorf("no Todoist token, set token in the [todoist] section of the config")
+			}
+			return importTodoist(m.config.todoistToken), nil
+		},
+	},
+	{
+		name: "reminders",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.tasksModel.mode = remindersMode
+			return nil, nil
+		},
+	},
+	{
+		name: "report",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.tasksModel.mode = reportMode
+			return nil, nil
+		},
+	},
+	{
+		name: "review",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			days := m.config.reviewDays
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("usage: :review [days]")
+				}
+				days = n
+			} else if len(args) > 1 {
+				return nil, fmt.Errorf("usage: :review [days]")
+			}
+			return nil, m.startReview(days)
+		},
+	},
+	{
+		name: "undo",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.undo()
+			return nil, nil
+		},
+	},
+	{
+		name: "redo",
+		run: func(m *model, args []string) (tea.Cmd, error) {
+			m.redo()
+			return nil, nil
+		},
+	},
+}
+
+// findCommand looks up a command by name or by an unambiguous prefix, so
+// :q works like :quit.
+func findCommand(name string) (command, bool) {
+	var found []command
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+		if strings.HasPrefix(c.name, name) {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return command{}, false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// startCommand opens the : prompt.
+func (m *model) startCommand() tea.Cmd {
+	m.tasksModel.command.Reset()
+	m.tasksModel.commandErr = ""
+	m.tasksModel.confirmClean = ""
+	m.tasksModel.completions = nil
+	m.tasksModel.historyIndex = len(m.tasksModel.history)
+	m.tasksModel.mode = commandMode
+	return m.tasksModel.command.Focus()
+}
+
+// runCommand executes the text in the prompt. On failure the prompt stays
+// open with the error so the command can be corrected.
+func (m *model) runCommand() tea.Cmd {
+	line := strings.TrimSpace(m.tasksModel.command.Value())
+	if line == "" {
+		m.closeCommand()
+		return nil
+	}
+	m.addHistory(line)
+
+	fields := strings.Fields(line)
+	c, ok := findCommand(fields[0])
+	if !ok {
+		m.tasksModel.commandErr = fmt.Sprintf("unknown command %q", fields[0])
+		return nil
+	}
+	cmd, err := c.run(m, fields[1:])
+	if err != nil {
+		m.tasksModel.commandErr = err.Error()
+		return nil
+	}
+	m.closeCommand()
+	return cmd
+}
+
+func (m *model) closeCommand() {
+	m.tasksModel.command.Reset()
+	m.tasksModel.command.Blur()
+	m.tasksModel.commandErr = ""
+	m.tasksModel.confirmClean = ""
+	m.tasksModel.completions = nil
+	if m.tasksModel.mode == commandMode {
+		m.tasksModel.mode = normalMode // Unless the command opened another mode
+	}
+}
+
+// addHistory appends a command line to the history, skipping repeats of the
+// previous entry, and saves it for the next session.
+func (m *model) addHistory(line string) {
+	history := m.tasksModel.history
+	if len(history) == 0 || history[len(history)-1] != line {
+		history = append(history, line)
+	}
+	if len(history) > commandHistoryLimit {
+		history = history[len(history)-commandHistoryLimit:]
+	}
+	m.tasksModel.history = history
+	m.tasksModel.historyIndex = len(history)
+
+	err := m.store.SaveSetting("command_history", strings.Join(history, "\n"))
+	if err != nil {
+		m.showError("save command history", err)
+	}
+}
+
+// browseHistory replaces the prompt with an older (dir -1) or newer (dir 1)
+// history entry. Moving past the newest entry clears the prompt.
+func (m *model) browseHistory(dir int) {
+	index := m.tasksModel.historyIndex + dir
+	if index < 0 || index > len(m.tasksModel.history) {
+		return
+	}
+	m.tasksModel.historyIndex = index
+	if index == len(m.tasksModel.history) {
+		m.tasksModel.command.SetValue("")
+	} else {
+		m.tasksModel.command.SetValue(m.tasksModel.history[index])
+	}
+	m.tasksModel.command.CursorEnd()
+}
+
+// completeCommand completes the word before the cursor: a command name for
+// the first word, an argument of that command after it. With several
+// candidates the common prefix is filled in and the candidates are listed.
+func (m *model) completeCommand() {
+	value := m.tasksModel.command.Value()
+	head, word := "", value
+	if i := strings.LastIndex(value, " "); i >= 0 {
+		head, word = value[:i+1], value[i+1:]
+	}
+
+	var options []string
+	if head == "" {
+		for _, c := range commands {
+			options = append(options, c.name)
+		}
+	} else if c, ok := findCommand(strings.Fields(head)[0]); ok && c.args != nil {
+		options = c.args(*m)
+	}
+
+	var candidates []string
+	for _, option := range options {
+		if strings.HasPrefix(option, word) {
+			candidates = append(candidates, option)
+		}
+	}
+	m.tasksModel.completions = nil
+	switch len(candidates) {
+	case 0:
+		return
+	case 1:
+		m.tasksModel.command.SetValue(head + candidates[0] + " ")
+	default:
+		m.tasksModel.command.SetValue(head + commonPrefix(candidates))
+		m.tasksModel.completions = candidates
+	}
+	m.tasksModel.command.CursorEnd()
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, v := range values[1:] {
+		for !strings.HasPrefix(v, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// updateCommand handles keys typed at the : prompt.
+func (m *model) updateCommand(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.closeCommand()
+		return nil
+	case "enter":
+		return m.runCommand()
+	case "tab":
+		m.completeCommand()
+		return nil
+	case "up":
+		m.browseHistory(-1)
+		return nil
+	case "down":
+		m.browseHistory(1)
+		return nil
+	}
+	var cmd tea.Cmd
+	m.tasksModel.command, cmd = m.tasksModel.command.Update(msg)
+	m.tasksModel.commandErr = ""
+	m.tasksModel.completions = nil
+	return cmd
+}
@@ -0,0 +1,36 @@
+package main
+
+import "time"
+
+// Done tasks show in the list when they were completed, "Completed 2 hours
+// ago". With completed = "duration" in the [display] section of the config
+// they also show how long they took from being added, and "plain" leaves
+// just "Completed" for a quieter list.
+
+const (
+	completedPlain    = "plain"
+	completedTime     = "time"
+	completedDuration = "duration"
+)
+
+// formatCompleted describes when a done task was completed, as much as the
+// config asks for. Tasks completed before completion times were kept only
+// say they are.
+func formatCompleted(task item, verbosity string) string {
+	if verbosity == completedPlain || task.completedAt.IsZero() {
+		return "Completed"
+	}
+	text := "Completed " + formatRelativeTime(task.completedAt)
+	if took := task.completedAt.Sub(task.createdAt); verbosity == completedDuration && took > 0 {
+		text += ", took " + formatTook(took)
+	}
+	return text
+}
+
+// formatTook shows how long a task took, in the largest whole unit.
+func formatTook(took time.Duration) string {
+	if took < time.Minute {
+		return "under a minute"
+	}
+	return formatDuration(took)
+}
@@ -0,0 +1,760 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config is read from ~/.config/xtui/config.toml (or $XDG_CONFIG_HOME). Only
+// the subset of TOML used by the default file is understood: [sections],
+// key = value pairs with quoted strings, integers and booleans, and comments.
+type config struct {
+	path string // File the config was loaded from
+
+	databasePath string
+	encrypt      bool // Keep the database encrypted at rest, see vault.go
+	asciiArtPath string
+	theme        string
+	background   string            // auto, light or dark
+	accessible   bool              // No colors and ASCII markers, see accessible.go
+	themeColors  map[string]string // Color overrides for the configured theme
+
+	// Defaults for preferences that can also be changed at runtime
+	sortBy     string
+	hideDone   bool
+	trashDays  int
+	reviewDays int // Days without changes before :review brings a task up
+
+	completedInfo string // What the list says about done tasks, see completion.go
+	density       string // compact, comfortable or detailed, see density.go
+	titles        string // truncate or wrap long titles, see titles.go
+	nerdFont      bool   // Nerd Font icons, see icons.go
+	todoMarker    string // Markers in place of the icons', empty for theirs
+	doingMarker   string
+	doneMarker    string
+	cursor        string
+
+	// How times are shown, see timeformat.go
+	times      string // relative, absolute or locale
+	timeLayout string // Go layouts for absolute times
+	dateLayout string
+	locale     string // Locale for locale times, empty for the environment's
+
+	notify        bool   // Desktop notifications for due tasks, see notify.go
+	notifyBackend string // Notifier to send them through, auto to pick one
+
+	// SMTP server the digest is sent through, see digest.go
+	smtpHost     string
+	smtpPort     int
+	smtpUsername string
+	smtpPassword string
+	emailFrom    string // Empty for smtpUsername
+	emailTo      string // Comma-separated addresses
+	digestTime   string // Time of day to send the digest, 15:04, empty for never
+
+	// Telegram bot of xtui -telegram, see telegram.go
+	telegramToken string
+	telegramChat  int64 // The only chat the bot listens to, 0 for none yet
+
+	// Discord channel to post to, see discord.go
+	discordWebhook string
+	discordTag     string // Completed tasks with this tag are posted, empty for none
+	discordSummary string // Time of day to post the summary, 15:04, empty for never
+
+	// Slack workspaces by name, see slack.go
+	slack map[string]slackWorkspace
+
+	todoistToken string // API token for importing from Todoist
+
+	// CalDAV calendar collection to sync tasks with
+	caldavURL      string
+	caldavUsername string
+	caldavPassword string
+
+	// Xtui sync server account, see cloud.go
+	syncURL      string
+	syncToken    string
+	syncInterval int // Minutes between background syncs, 0 to only sync on demand
+
+	// Git repository to sync through, see gitsync.go
+	gitRepo string
+	gitFile string // Task file, relative to the repository
+
+	// Copies of the database, see backup.go
+	backupDir      string
+	backupInterval int // Hours between backups, 0 to only back up on demand
+	backupKeep     int // Backups kept, 0 keeps them all
+
+	// Other databases by profile name, see profiles.go
+	profiles map[string]string
+	profile  string // Active profile, "default" for databasePath from [database]
+
+	// keys maps an action name to the key that triggers it
+	keys map[string]string
+}
+
+const defaultConfig = `# Xtui configuration
+
+[database]
+# Where tasks are stored
+path = %q
+# Encrypt the database with a passphrase asked for on startup (or taken from
+# XTUI_PASSPHRASE). Setting it back to false decrypts it on the next start.
+encrypt = false
+
+[paths]
+# ASCII art shown on the About tab
+ascii_art = "/usr/local/share/xtui/faqs_ascii.txt"
+
+[theme]
+# default, gruvbox, catppuccin, nord or monochrome
+name = "default"
+# auto detects the terminal background, or force light or dark
+background = "auto"
+# No colors, and ASCII in place of symbols. Also on when NO_COLOR is set
+accessible = false
+# Override individual colors, e.g.
+# accent = "#FFA500"
+
+[defaults]
+# manual, created, completed, title, due or priority
+sort = "manual"
+hide_done = false
+# Days before trashed tasks are deleted for good, 0 keeps them forever
+trash_days = 30
+# Days without changes before :review brings up a task
+review_days = 14
+
+[display]
+# What the list shows of done tasks: plain ("Completed"), time ("Completed 2
+# hours ago") or duration (also how long they took from being added)
+completed = "time"
+# How much the list shows of each task: compact (one line, no padding or
+# created and completed times), comfortable or detailed (tags and dates on a
+# second line). gd switches while Xtui runs
+density = "comfortable"
+# Titles too long for the window are cut short with an ellipsis (truncate) or
+# continue on the next lines (wrap)
+titles = "truncate"
+# Icons for checkboxes, tags, priorities and tabs, for terminals with a Nerd
+# Font
+nerd_font = false
+# Checkboxes and the cursor in place of the icons', e.g. done_marker = "✔" when
+# the font has no ✓. Empty keeps the icons'
+todo_marker = ""
+doing_marker = ""
+done_marker = ""
+cursor = ""
+# How times are shown: relative ("2 hours ago"), absolute (in the layouts
+# below, written as Go layouts) or locale (the date order and clock of the
+# locale below, or of LC_TIME or LANG when it is empty)
+times = "relative"
+layout = "2006-01-02 15:04"
+date_layout = "2006-01-02"
+locale = ""
+
+[notifications]
+# Show a desktop notification when a task with a due time falls due
+enabled = true
+# How: auto picks what the system has, or notify-send, dbus (through gdbus),
+# osascript (macOS) or toast (Windows, and from WSL)
+backend = "auto"
+
+[email]
+# SMTP server for the daily digest of overdue tasks and those for today,
+# sent with xtui digest --email, e.g.
+# host = "smtp.example.com"
+host = ""
+# 587 for STARTTLS, 465 for TLS
+port = 587
+username = ""
+# Prefer the XTUI_SMTP_PASSWORD environment variable
+password = ""
+# Sender, empty for the username
+from = ""
+# Recipients, separated by commas
+to = ""
+# Time of day Xtui sends the digest while it is open, e.g. "08:00", empty
+# to send it only with xtui digest --email
+digest_time = ""
+
+[telegram]
+# Bot for adding tasks and getting reminders in Telegram while
+# xtui -telegram runs. Prefer the XTUI_TELEGRAM_TOKEN environment variable
+# for the token from @BotFather
+token = ""
+# Chat the bot listens and sends reminders to, which the bot tells you when
+# you first message it
+chat = 0
+
+[discord]
+# Webhook of a Discord channel to post to, for an accountability channel or
+# the team, from Server settings > Integrations. Prefer the
+# XTUI_DISCORD_WEBHOOK environment variable
+webhook = ""
+# Completed tasks with this tag are posted, empty to post none
+share_tag = "share"
+# Time of day Xtui posts a summary of the day while it is open, e.g. "18:00",
+# empty for none
+summary_time = ""
+
+# Slack workspaces, a section each named after the workspace's domain, e.g.
+# [slack.acme] for acme.slack.com, with
+# webhook = "https://hooks.slack.com/services/..."  to post completed tasks,
+#     due tasks and reminders to a channel
+# signing_secret = "..."  to add tasks with the slash command of
+#     xtui -serve-slack, from the app's Basic Information page
+# tag = "work"  to post only tasks with the tag, and tag those added
+# Prefer XTUI_SLACK_ACME_WEBHOOK and XTUI_SLACK_ACME_SIGNING_SECRET for the
+# secrets
+
+[todoist]
+# API token from Todoist settings > Integrations, used by :todoist
+token = ""
+
+[caldav]
+# Task list to sync with from the User tab, e.g.
+# url = "https://cloud.example.com/remote.php/dav/calendars/me/tasks/"
+url = ""
+username = ""
+# Prefer the CALDAV_PASSWORD environment variable
+password = ""
+
+[sync]
+# Sync server that keeps the task list in step across machines, e.g.
+# url = "https://sync.example.com/v1/tasks"
+url = ""
+# Sign in from the User tab to keep the token in the OS keyring instead
+token = ""
+# Minutes between background syncs while Xtui is open, 0 to sync only from
+# the User tab
+interval = 15
+
+[git]
+# Clone of a git repository to sync the tasks through, e.g.
+# repo = "~/notes"
+repo = ""
+# File in the repository the tasks are kept in, as JSON lines
+file = "tasks.jsonl"
+
+[backup]
+# Where copies of the database go, empty for a backups directory next to it
+dir = ""
+# Hours between backups, 0 to back up only with :backup now (and before the
+# database is upgraded)
+interval = 24
+# Backups kept, older ones are deleted. 0 keeps them all
+keep = 10
+
+[profiles]
+# Separate task lists in databases of their own, picked with -profile or gp,
+# e.g.
+# work = "~/work/xtui.db"
+
+[keys]
+# Rebind actions, e.g.
+# delete = "x"
+# undo = "U"
+`
+
+// defaultKeys are the built-in bindings for actions that can be remapped in
+// the [keys] section.
+var defaultKeys = map[string]string{
+	"quit":             "q",
+	"next_tab":         "l",
+	"prev_tab":         "h",
+	"up":               "k",
+	"down":             "j",
+	"add":              "enter",
+	"add_subtask":      "a",
+	"edit":             "e",
+	"toggle":           " ",
+	"delete":           "d",
+	"undo":             "u",
+	"redo":             "ctrl+r",
+	"notes":            "o",
+	"search":           "/",
+	"tag_filter":       "t",
+	"context_filter":   "@",
+	"group_by_context": "gc",
+	"hide_done":        "c",
+	"sort":             "s",
+	"visual":           "v",
+	"move_up":          "K",
+	"move_down":        "J",
+	"theme":            "ctrl+t",
+	"track":            "T",
+	"duplicate":        "D",
+	"star":             "*",
+	"plan_today":       "+",
+	"focus":            "gf",
+}
+
+func configDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "xtui")
+}
+
+func defaultDatabasePath() string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "tui-do.db"
+		}
+		dir = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(dir, "xtui", "tui-do.db")
+}
+
+// loadConfig reads the config file, creating it with defaults on first run.
+// DATABASE_PATH and ASCII_ART_PATH environment variables still override the
+// file for existing setups, TODOIST_TOKEN, CALDAV_PASSWORD,
+// XTUI_SMTP_PASSWORD, XTUI_TELEGRAM_TOKEN, XTUI_DISCORD_WEBHOOK and the
+// XTUI_SLACK_<WORKSPACE>_ variables keep secrets out of the file.
+// XTUI_PROFILE picks a profile from [profiles].
+func loadConfig() (config, error) {
+	cfg := config{
+		path:           filepath.Join(configDir(), "config.toml"),
+		databasePath:   defaultDatabasePath(),
+		asciiArtPath:   "/usr/local/share/xtui/faqs_ascii.txt",
+		theme:          "default",
+		background:     "auto",
+		sortBy:         sortManual,
+		trashDays:      defaultTrashRetentionDays,
+		reviewDays:     defaultReviewDays,
+		completedInfo:  completedTime,
+		density:        comfortableDensity,
+		titles:         truncateTitles,
+		times:          relativeTimes,
+		timeLayout:     isoLayouts.moment,
+		dateLayout:     isoLayouts.date,
+		syncInterval:   15,
+		gitFile:        "tasks.jsonl",
+		backupInterval: 24,
+		backupKeep:     10,
+		notify:         true,
+		notifyBackend:  "auto",
+		smtpPort:       587,
+		discordTag:     "share",
+		themeColors:    make(map[string]string),
+		profiles:       make(map[string]string),
+		slack:          make(map[string]slackWorkspace),
+		keys:           make(map[string]string),
+	}
+
+	f, err := os.Open(cfg.path)
+	if os.IsNotExist(err) {
+		err = writeDefaultConfig(cfg.path, cfg.databasePath)
+		if err != nil {
+			return cfg, err
+		}
+		f, err = os.Open(cfg.path)
+	}
+	if err != nil {
+		return cfg, err
+	}
+	defer f.Close()
+	// Tokens and passwords go in here, so files written readable by others
+	// before are made private
+	if info, err := f.Stat(); err == nil && info.Mode().Perm()&0o077 != 0 && runtime.GOOS != "windows" {
+		os.Chmod(cfg.path, 0o600)
+	}
+
+	values, err := parseTOML(f)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", cfg.path, err)
+	}
+	if err := cfg.apply(values); err != nil {
+		return cfg, fmt.Errorf("%s: %w", cfg.path, err)
+	}
+
+	if path := os.Getenv("DATABASE_PATH"); path != "" {
+		cfg.databasePath = path
+	}
+	if path := os.Getenv("ASCII_ART_PATH"); path != "" {
+		cfg.asciiArtPath = path
+	}
+	if token := os.Getenv("TODOIST_TOKEN"); token != "" {
+		cfg.todoistToken = token
+	}
+	if password := os.Getenv("CALDAV_PASSWORD"); password != "" {
+		cfg.caldavPassword = password
+	}
+	if password := os.Getenv("XTUI_SMTP_PASSWORD"); password != "" {
+		cfg.smtpPassword = password
+	}
+	if token := os.Getenv("XTUI_TELEGRAM_TOKEN"); token != "" {
+		cfg.telegramToken = token
+	}
+	if webhook := os.Getenv("XTUI_DISCORD_WEBHOOK"); webhook != "" {
+		cfg.discordWebhook = webhook
+	}
+	for name, w := range cfg.slack {
+		prefix := "XTUI_SLACK_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+		if webhook := os.Getenv(prefix + "_WEBHOOK"); webhook != "" {
+			w.webhook = webhook
+		}
+		if secret := os.Getenv(prefix + "_SIGNING_SECRET"); secret != "" {
+			w.signingSecret = secret
+		}
+		cfg.slack[name] = w
+	}
+	if token := os.Getenv("XTUI_SYNC_TOKEN"); token != "" {
+		cfg.syncToken = token
+	}
+	if err := cfg.useProfile(os.Getenv("XTUI_PROFILE")); err != nil {
+		return cfg, err
+	}
+	cfg.databasePath = expandHome(cfg.databasePath)
+	cfg.asciiArtPath = expandHome(cfg.asciiArtPath)
+	cfg.gitRepo = expandHome(cfg.gitRepo)
+	if cfg.backupDir == "" {
+		cfg.backupDir = filepath.Join(filepath.Dir(cfg.databasePath), "backups")
+	}
+	cfg.backupDir = expandHome(cfg.backupDir)
+	if cfg.profile != defaultProfile {
+		cfg.backupDir = filepath.Join(cfg.backupDir, cfg.profile)
+	}
+	return cfg, nil
+}
+
+// writeDefaultConfig writes the default config only the user can read, as
+// the tokens and passwords of the integrations go in it.
+func writeDefaultConfig(path, databasePath string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(fmt.Sprintf(defaultConfig, databasePath)), 0o600)
+}
+
+// apply copies parsed values into the config, keyed as "section.key".
+func (c *config) apply(values map[string]string) error {
+	for key, value := range values {
+		section, name, _ := strings.Cut(key, ".")
+		switch {
+		case key == "database.path":
+			c.databasePath = value
+		case key == "database.encrypt":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("database.encrypt: %w", err)
+			}
+			c.encrypt = b
+		case key == "paths.ascii_art":
+			c.asciiArtPath = value
+		case key == "theme.name":
+			c.theme = value
+		case key == "theme.background":
+			switch value {
+			case "auto", "light", "dark":
+				c.background = value
+			default:
+				return fmt.Errorf("theme.background must be auto, light or dark")
+			}
+		case key == "theme.accessible":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("theme.accessible: %w", err)
+			}
+			c.accessible = b
+		case key == "display.completed":
+			switch value {
+			case completedPlain, completedTime, completedDuration:
+				c.completedInfo = value
+			default:
+				return fmt.Errorf("display.completed must be plain, time or duration")
+			}
+		case key == "display.density":
+			if !validDensity(value) {
+				return fmt.Errorf("display.density must be compact, comfortable or detailed")
+			}
+			c.density = value
+		case key == "display.titles":
+			switch value {
+			case truncateTitles, wrapTitles:
+				c.titles = value
+			default:
+				return fmt.Errorf("display.titles must be truncate or wrap")
+			}
+		case key == "display.nerd_font":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("display.nerd_font: %w", err)
+			}
+			c.nerdFont = b
+		case key == "display.todo_marker":
+			c.todoMarker = value
+		case key == "display.doing_marker":
+			c.doingMarker = value
+		case key == "display.done_marker":
+			c.doneMarker = value
+		case key == "display.cursor":
+			c.cursor = value
+		case key == "display.times":
+			switch value {
+			case relativeTimes, absoluteTimes, localeTimes:
+				c.times = value
+			default:
+				return fmt.Errorf("display.times must be relative, absolute or locale")
+			}
+		case key == "display.layout":
+			if err := checkLayout(key, value); err != nil {
+				return err
+			}
+			c.timeLayout = value
+		case key == "display.date_layout":
+			if err := checkLayout(key, value); err != nil {
+				return err
+			}
+			c.dateLayout = value
+		case key == "display.locale":
+			c.locale = value
+		case key == "notifications.enabled":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("notifications.enabled: %w", err)
+			}
+			c.notify = b
+		case key == "notifications.backend":
+			switch value {
+			case "auto", "notify-send", "dbus", "osascript", "toast":
+				c.notifyBackend = value
+			default:
+				return fmt.Errorf("notifications.backend must be auto, notify-send, dbus, osascript or toast")
+			}
+		case key == "email.host":
+			c.smtpHost = value
+		case key == "email.port":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("email.port: %w", err)
+			}
+			c.smtpPort = n
+		case key == "email.username":
+			c.smtpUsername = value
+		case key == "email.password":
+			c.smtpPassword = value
+		case key == "email.from":
+			c.emailFrom = value
+		case key == "email.to":
+			c.emailTo = value
+		case key == "email.digest_time":
+			if value != "" {
+				if _, err := time.Parse("15:04", value); err != nil {
+					return fmt.Errorf("email.digest_time must be a time of day like 08:00")
+				}
+			}
+			c.digestTime = value
+		case key == "telegram.token":
+			c.telegramToken = value
+		case key == "telegram.chat":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("telegram.chat: %w", err)
+			}
+			c.telegramChat = n
+		case key == "discord.webhook":
+			c.discordWebhook = value
+		case key == "discord.share_tag":
+			c.discordTag = strings.TrimPrefix(value, "#")
+		case key == "discord.summary_time":
+			if value != "" {
+				if _, err := time.Parse("15:04", value); err != nil {
+					return fmt.Errorf("discord.summary_time must be a time of day like 18:00")
+				}
+			}
+			c.discordSummary = value
+		case key == "todoist.token":
+			c.todoistToken = value
+		case key == "caldav.url":
+			c.caldavURL = value
+		case key == "caldav.username":
+			c.caldavUsername = value
+		case key == "caldav.password":
+			c.caldavPassword = value
+		case key == "sync.url":
+			c.syncURL = value
+		case key == "sync.token":
+			c.syncToken = value
+		case key == "sync.interval":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("sync.interval: %w", err)
+			}
+			c.syncInterval = n
+		case key == "git.repo":
+			c.gitRepo = value
+		case key == "git.file":
+			c.gitFile = value
+		case key == "backup.dir":
+			c.backupDir = value
+		case key == "backup.interval":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("backup.interval: %w", err)
+			}
+			c.backupInterval = n
+		case key == "backup.keep":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("backup.keep: %w", err)
+			}
+			c.backupKeep = n
+		case section == "theme":
+			c.themeColors[name] = value
+		case key == "defaults.sort":
+			c.sortBy = value
+		case key == "defaults.hide_done":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("defaults.hide_done: %w", err)
+			}
+			c.hideDone = b
+		case key == "defaults.trash_days":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("defaults.trash_days: %w", err)
+			}
+			c.trashDays = n
+		case key == "defaults.review_days":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("defaults.review_days: %w", err)
+			}
+			c.reviewDays = n
+		case section == "profiles":
+			if name == defaultProfile {
+				return fmt.Errorf("profiles.default: the default profile is the [database] path")
+			}
+			c.profiles[name] = value
+		case section == "slack":
+			workspace, field, ok := strings.Cut(name, ".")
+			if !ok {
+				return fmt.Errorf("slack.%s: Slack settings go in a [slack.<workspace>] section", name)
+			}
+			w := c.slack[workspace]
+			switch field {
+			case "webhook":
+				w.webhook = value
+			case "signing_secret":
+				w.signingSecret = value
+			case "tag":
+				w.tag = strings.TrimPrefix(value, "#")
+			default:
+				return fmt.Errorf("unknown key %q in [slack.%s]", field, workspace)
+			}
+			c.slack[workspace] = w
+		case section == "keys":
+			if _, ok := defaultKeys[name]; !ok {
+				return fmt.Errorf("unknown action %q in [keys]", name)
+			}
+			c.keys[name] = value
+		}
+	}
+	return nil
+}
+
+// resolveKey translates a key pressed in a navigation mode into the built-in
+// key of the action it is bound to, so the Update switches only need to know
+// the defaults.
+func (c config) resolveKey(key string) string {
+	for action, bound := range c.keys {
+		if bound == key {
+			return defaultKeys[action]
+		}
+	}
+	return key
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// parseTOML reads the supported TOML subset into a flat map keyed by
+// "section.key".
+func parseTOML(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			section = strings.TrimSpace(line[1:end])
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.Trim(strings.TrimSpace(key), `"`)
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// parseTOMLValue unquotes a string value and strips trailing comments.
+func parseTOMLValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := 1
+		for ; end < len(raw); end++ {
+			if raw[end] == '\\' {
+				end++
+				continue
+			}
+			if raw[end] == '"' {
+				break
+			}
+		}
+		if end >= len(raw) {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strconv.Unquote(raw[:end+1])
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return raw[1 : end+1], nil
+	}
+
+	if i := strings.Index(raw, "#"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	if raw == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	return raw, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Contexts are the GTD "where or with what": @home, @phone, @errands. A task
+// has at most one, set with @context in the task input. @ picks a context to
+// filter the list by and gc groups the list under a heading per context, so
+// the tasks that can be done right here are together. Subtasks stay under
+// their parent whatever their own context.
+
+// openContextPicker lists the contexts in use with the current filter
+// highlighted.
+func (m *model) openContextPicker() {
+	contexts, err := m.store.Contexts()
+	if err != nil {
+		m.showError("load contexts", err)
+	}
+	t := &m.tasksModel
+	t.contextOptions = append([]string{""}, contexts...) // "" clears the filter
+	t.contextCursor = 0
+	for i, context := range t.contextOptions {
+		if context == t.contextFilter {
+			t.contextCursor = i
+		}
+	}
+	t.mode = contextMode
+}
+
+// updateContextPicker handles keys in the context picker.
+func (m *model) updateContextPicker(key string) {
+	t := &m.tasksModel
+	switch key {
+	case "up", "k":
+		if t.contextCursor > 0 {
+			t.contextCursor--
+		}
+	case "down", "j":
+		if t.contextCursor < len(t.contextOptions)-1 {
+			t.contextCursor++
+		}
+	case "enter":
+		t.contextFilter = t.contextOptions[t.contextCursor]
+		t.selected = 0
+		t.mode = normalMode
+	case "esc":
+		t.mode = normalMode
+	}
+}
+
+func (m model) renderContextPicker() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Filter by context") + "\n\n")
+	for i, context := range m.tasksModel.contextOptions {
+		label := "@" + context
+		if context == "" {
+			label = "All tasks"
+		}
+		if i == m.tasksModel.contextCursor {
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+label) + "\n")
+		} else {
+			s.WriteString(itemStyle.Render(cursorMark(false)+label) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// groupByContext orders top-level tasks by context, keeping their order
+// within a context. Tasks without a context come last.
+func (t tasksModel) groupByContext(roots []int) {
+	sort.SliceStable(roots, func(i, j int) bool {
+		a, b := t.items[roots[i]].context, t.items[roots[j]].context
+		if (a == "") != (b == "") {
+			return a != ""
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+}
+
+// contextHeading names the group a top-level task starts.
+func contextHeading(context string) string {
+	if context == "" {
+		return "No context"
+	}
+	return "@" + context
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+)
+
+// The database may be shared by several processes, e.g. two terminals with
+// Xtui open or an -export running next to the interface. WAL mode lets
+// readers work alongside a writer, the busy timeout makes a connection wait
+// for a lock instead of failing at once, and transactions take the write
+// lock up front so two of them can't deadlock upgrading their locks.
+const sqliteOptions = "?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate"
+
+func openDatabase(path string) (*sql.DB, error) {
+	return sql.Open("sqlite3", path+sqliteOptions)
+}
+
+// busyRetries is how often a transaction is retried when the database stays
+// locked past the busy timeout.
+const busyRetries = 3
+
+// inTx runs fn in a transaction, rolling back if it fails. A transaction
+// that fails because the database is busy is retried.
+func inTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	var err error
+	for attempt := 0; attempt <= busyRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+		}
+		if err = runTx(db, fn); !isBusy(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func runTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isBusy matches SQLITE_BUSY and SQLITE_LOCKED by their messages, since the
+// driver's error type only exists in cgo builds.
+func isBusy(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "database is locked") || strings.Contains(err.Error(), "database table is locked"))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The density of the task list trades detail for room. Comfortable is the
+// usual list, compact leaves out the padding and the created and completed
+// times to fit more tasks on the screen, and detailed gives each task a second
+// line with its tags, context, project and every date it has. gd cycles
+// through them and :density picks one; the choice is remembered, and density
+// in the [display] section of the config sets the first one.
+
+const (
+	compactDensity     = "compact"
+	comfortableDensity = "comfortable"
+	detailedDensity    = "detailed"
+)
+
+// densities are in the order gd cycles through them.
+var densities = []string{compactDensity, comfortableDensity, detailedDensity}
+
+func validDensity(density string) bool {
+	for _, d := range densities {
+		if d == density {
+			return true
+		}
+	}
+	return false
+}
+
+// setDensity switches the list to a density and remembers it.
+func (m *model) setDensity(density string) error {
+	if !validDensity(density) {
+		return fmt.Errorf("unknown density %q, use %s", density, strings.Join(densities, ", "))
+	}
+	m.tasksModel.density = density
+	return m.store.SaveSetting("density", density)
+}
+
+// cycleDensity switches to the density after the current one.
+func (m *model) cycleDensity() {
+	next := densities[0]
+	for i, d := range densities {
+		if d == m.tasksModel.density {
+			next = densities[(i+1)%len(densities)]
+		}
+	}
+	if err := m.setDensity(next); err != nil {
+		m.showError("save setting", err)
+	}
+	m.showMessage("Density: " + next)
+}
+
+// taskLines is how many lines a task takes in the list.
+func (t tasksModel) taskLines() int {
+	if t.density == detailedDensity {
+		return 2
+	}
+	return 1
+}
+
+// titleColumn is where titles start in the list, past the padding, cursor
+// and status marker.
+func (t tasksModel) titleColumn() int {
+	column := cursorWidth() + 1 + markerWidth() + 1
+	if t.density == compactDensity {
+		return column
+	}
+	return 4 + column
+}
+
+// listDates says when a task is due, and when it was created or completed,
+// as much of it as the density shows.
+func (m model) listDates(task item) []string {
+	density := m.tasksModel.density
+	var dates []string
+	if task.status != done && !task.dueAt.IsZero() {
+		due := formatDueTime(task.dueAt)
+		if task.overdue(time.Now()) {
+			due = overdueStyle.Render(due)
+		}
+		dates = append(dates, due)
+	}
+	if task.status == done && density != compactDensity {
+		dates = append(dates, formatCompleted(task, m.config.completedInfo))
+	}
+	if density == detailedDensity || (density == comfortableDensity && len(dates) == 0) {
+		dates = append(dates, "Created "+formatRelativeTime(task.createdAt))
+	}
+	return dates
+}
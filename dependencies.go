@@ -0,0 +1,236 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// A task can wait for other tasks to be done first. B on a task starts
+// picking what it waits for: move to the blocking task and press enter (again
+// to drop it). Blocked tasks are dimmed and can't be completed until their
+// blockers are, at which point they unblock by themselves.
+
+const blockMode = "block" // Picking a task the selected one waits for
+
+// dependency says that a task can't be done before another one.
+type dependency struct {
+	taskID    int
+	blockedBy int
+}
+
+func createDependenciesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS dependencies (
+			task_id INTEGER NOT NULL,
+			blocked_by INTEGER NOT NULL,
+			PRIMARY KEY (task_id, blocked_by)
+		);
+	`)
+	return err
+}
+
+func (s *sqliteStore) Dependencies() ([]dependency, error) {
+	rows, err := s.db.Query("SELECT task_id, blocked_by FROM dependencies ORDER BY task_id, blocked_by")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []dependency
+	for rows.Next() {
+		var d dependency
+		if err := rows.Scan(&d.taskID, &d.blockedBy); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+func (s *sqliteStore) AddDependency(d dependency) error {
+	_, err := s.db.Exec("INSERT OR IGNORE INTO dependencies (task_id, blocked_by) VALUES (?, ?)", d.taskID, d.blockedBy)
+	return err
+}
+
+func (s *sqliteStore) RemoveDependency(d dependency) error {
+	_, err := s.db.Exec("DELETE FROM dependencies WHERE task_id = ? AND blocked_by = ?", d.taskID, d.blockedBy)
+	return err
+}
+
+func (m *model) refreshDependencies() {
+	deps, err := m.store.Dependencies()
+	if err != nil {
+		m.showError("load dependencies", err)
+		return
+	}
+	m.dependencies = deps
+}
+
+// blockers returns the indices of the open tasks a task waits for. Blockers
+// in the trash don't count.
+func (m model) blockers(id int) []int {
+	var indices []int
+	for _, d := range m.dependencies {
+		if d.taskID != id {
+			continue
+		}
+		if index := m.tasksModel.indexOf(d.blockedBy); index >= 0 && m.tasksModel.items[index].status != done {
+			indices = append(indices, index)
+		}
+	}
+	return indices
+}
+
+func (m model) isBlocked(id int) bool {
+	return len(m.blockers(id)) > 0
+}
+
+// waitsFor reports whether a task waits for another, directly or through the
+// tasks in between.
+func (m model) waitsFor(id, other int) bool {
+	seen := make(map[int]bool)
+	var walk func(id int) bool
+	walk = func(id int) bool {
+		if seen[id] {
+			return false
+		}
+		seen[id] = true
+		for _, d := range m.dependencies {
+			if d.taskID == id && (d.blockedBy == other || walk(d.blockedBy)) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(id)
+}
+
+// startBlocking picks what the selected task waits for.
+func (m *model) startBlocking() {
+	if index := m.tasksModel.selectedIndex(); index >= 0 {
+		m.tasksModel.blockID = m.tasksModel.items[index].id
+		m.tasksModel.mode = blockMode
+	}
+}
+
+// updateBlocking handles keys while picking a blocker.
+func (m *model) updateBlocking(key string) {
+	t := &m.tasksModel
+	switch key {
+	case "up", "k":
+		if t.selected > 0 {
+			t.selected--
+		}
+	case "down", "j":
+		if t.selected < len(t.rows())-1 {
+			t.selected++
+		}
+	case "enter":
+		if index := t.selectedIndex(); index >= 0 {
+			m.toggleDependency(dependency{taskID: t.blockID, blockedBy: t.items[index].id})
+		}
+		fallthrough
+	case "esc":
+		t.selectID(t.blockID)
+		t.blockID = 0
+		t.mode = normalMode
+	}
+}
+
+// toggleDependency adds the dependency, or removes it if it exists, refusing
+// ones that would make tasks wait for each other.
+func (m *model) toggleDependency(d dependency) {
+	for _, existing := range m.dependencies {
+		if existing == d {
+			if err := m.store.RemoveDependency(d); err != nil {
+				m.showError("remove dependency", err)
+			}
+			m.refreshDependencies()
+			m.showMessage("No longer waits for " + m.titleOf(d.blockedBy))
+			return
+		}
+	}
+	if d.taskID == d.blockedBy || m.waitsFor(d.blockedBy, d.taskID) {
+		m.showMessage(m.titleOf(d.blockedBy) + " already waits for this task")
+		return
+	}
+	if err := m.store.AddDependency(d); err != nil {
+		m.showError("add dependency", err)
+	}
+	m.refreshDependencies()
+	m.showMessage("Waits for " + m.titleOf(d.blockedBy))
+}
+
+func (m model) titleOf(id int) string {
+	if index := m.tasksModel.indexOf(id); index >= 0 {
+		return fmt.Sprintf("%q", m.tasksModel.items[index].title)
+	}
+	return "a deleted task"
+}
+
+// blockedTitles lists the titles of a task's open blockers.
+func (m model) blockedTitles(id int) string {
+	var titles []string
+	for _, index := range m.blockers(id) {
+		titles = append(titles, fmt.Sprintf("%q", m.tasksModel.items[index].title))
+	}
+	return strings.Join(titles, ", ")
+}
+
+// withoutBlocked drops the tasks that wait for open tasks outside the batch,
+// so completing a blocker and what it blocks together is fine.
+func (m *model) withoutBlocked(indices []int) []int {
+	batch := make(map[int]bool, len(indices))
+	for _, index := range indices {
+		batch[index] = true
+	}
+	var allowed, blocked []int
+	for _, index := range indices {
+		task := m.tasksModel.items[index]
+		waiting := false
+		for _, blocker := range m.blockers(task.id) {
+			if !batch[blocker] {
+				waiting = true
+			}
+		}
+		if waiting && task.status != done {
+			blocked = append(blocked, index)
+		} else {
+			allowed = append(allowed, index)
+		}
+	}
+	switch {
+	case len(blocked) == 1:
+		id := m.tasksModel.items[blocked[0]].id
+		m.showMessage(m.titleOf(id) + " waits for " + m.blockedTitles(id))
+	case len(blocked) > 1:
+		m.showMessage(fmt.Sprintf("%d tasks wait for others and stay open", len(blocked)))
+	}
+	return allowed
+}
+
+// blockedIDs returns the tasks waiting for open tasks.
+func (m model) blockedIDs() map[int]bool {
+	blocked := make(map[int]bool)
+	for _, d := range m.dependencies {
+		if m.isBlocked(d.taskID) {
+			blocked[d.taskID] = true
+		}
+	}
+	return blocked
+}
+
+// announceUnblocked tells which of the tasks that were blocked can now be
+// done.
+func (m *model) announceUnblocked(wasBlocked map[int]bool) {
+	var titles []string
+	for _, task := range m.tasksModel.items {
+		if wasBlocked[task.id] && !m.isBlocked(task.id) {
+			titles = append(titles, fmt.Sprintf("%q", task.title))
+		}
+	}
+	if len(titles) > 0 {
+		m.showMessage("Unblocked " + strings.Join(titles, ", "))
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The digest sums up the day: the overdue tasks, then those due or planned
+// for today. xtui digest prints it and xtui digest --email sends it through
+// the SMTP server under [email], for a cron job or a morning at the inbox.
+// With digest_time set, Xtui sends it itself once a day when the clock has
+// passed that time while it is open, and at startup when that day's digest
+// is still to go. The day it was last sent is kept in the settings table, so
+// several windows or restarts don't send it twice.
+
+// dailySentMsg is sent to Update when something sent once a day, like the
+// digest, has gone out.
+type dailySentMsg struct {
+	key    string // Setting that keeps the day it last went
+	day    string
+	action string // What failed, for the error message
+	err    error
+}
+
+// digestTasks returns the open tasks that are overdue and those for today,
+// each by due time, tasks only planned for today last.
+func digestTasks(tasks []item, now time.Time) (overdue, today []item) {
+	endOfDay := midnight(now).AddDate(0, 0, 1)
+	for _, task := range tasks {
+		switch {
+		case task.status == done:
+		case task.overdue(now):
+			overdue = append(overdue, task)
+		case !task.dueAt.IsZero() && task.dueAt.Before(endOfDay), task.planned(now):
+			today = append(today, task)
+		}
+	}
+	byDue := func(tasks []item) {
+		sort.SliceStable(tasks, func(i, j int) bool {
+			a, b := tasks[i].dueAt, tasks[j].dueAt
+			if a.IsZero() || b.IsZero() {
+				return !a.IsZero() && b.IsZero()
+			}
+			return a.Before(b)
+		})
+	}
+	byDue(overdue)
+	byDue(today)
+	return overdue, today
+}
+
+// digestMessage writes the digest as the subject and plain text body of an
+// email.
+func digestMessage(tasks []item, now time.Time) (subject, body string) {
+	overdue, today := digestTasks(tasks, now)
+	if len(overdue) == 0 && len(today) == 0 {
+		return "Xtui: nothing due today", "Nothing is due or planned for today.\n"
+	}
+	var parts []string
+	if len(overdue) > 0 {
+		parts = append(parts, fmt.Sprintf("%d overdue", len(overdue)))
+	}
+	if len(today) > 0 {
+		parts = append(parts, fmt.Sprintf("%d for today", len(today)))
+	}
+	subject = "Xtui: " + strings.Join(parts, ", ")
+
+	var s strings.Builder
+	section := func(title string, tasks []item, due func(item) string) {
+		if len(tasks) == 0 {
+			return
+		}
+		if s.Len() > 0 {
+			s.WriteString("\n")
+		}
+		s.WriteString(title + "\n")
+		for _, task := range tasks {
+			s.WriteString("- " + task.title)
+			if task.project != "" {
+				s.WriteString(" +" + task.project)
+			}
+			if text := due(task); text != "" {
+				s.WriteString(" (" + text + ")")
+			}
+			s.WriteString("\n")
+		}
+	}
+	section("Overdue", overdue, func(task item) string {
+		return "due " + formatDate(task.dueAt, "Mon 2 Jan")
+	})
+	section("Today", today, func(task item) string {
+		if task.dueAt.IsZero() || isEndOfDay(task.dueAt) {
+			return ""
+		}
+		return "due " + formatClock(task.dueAt)
+	})
+	return subject, s.String()
+}
+
+// emailConfigured reports whether the [email] section has enough to send.
+func emailConfigured(cfg config) bool {
+	return cfg.smtpHost != "" && cfg.emailTo != ""
+}
+
+// sendEmail sends a plain text email through the SMTP server of the config,
+// with STARTTLS when the server offers it, or TLS from the start on port 465.
+func sendEmail(cfg config, subject, body string) error {
+	if !emailConfigured(cfg) {
+		return errors.New("set host and to under [email] in the config")
+	}
+	from := cfg.emailFrom
+	if from == "" {
+		from = cfg.smtpUsername
+	}
+	var to []string
+	for _, address := range strings.Split(cfg.emailTo, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			to = append(to, address)
+		}
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := net.JoinHostPort(cfg.smtpHost, strconv.Itoa(cfg.smtpPort))
+	var auth smtp.Auth
+	if cfg.smtpUsername != "" {
+		auth = smtp.PlainAuth("", cfg.smtpUsername, cfg.smtpPassword, cfg.smtpHost)
+	}
+	if cfg.smtpPort != 465 {
+		return smtp.SendMail(addr, auth, from, to, []byte(msg.String()))
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: cfg.smtpHost})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, cfg.smtpHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// checkDigest sends the day's digest in the background once the clock has
+// passed digest_time, unless it went out already.
+func (m *model) checkDigest(now time.Time) tea.Cmd {
+	if !emailConfigured(m.config) {
+		return nil
+	}
+	day, ok := m.dailyDue(now, m.config.digestTime, &m.lastDigest, "last_digest")
+	if !ok {
+		return nil
+	}
+	cfg := m.config
+	subject, body := digestMessage(m.tasksModel.items, now)
+	return func() tea.Msg {
+		return dailySentMsg{key: "last_digest", day: day, action: "send digest", err: sendEmail(cfg, subject, body)}
+	}
+}
+
+// dailyDue reports whether something sent once a day at the time of day at
+// (15:04, empty for never) is to go now, returning the day. last is the day
+// it last went, kept in the settings table under key. It is marked as gone
+// before it is sent, so the next tick doesn't send it again; one that fails
+// is tried again on the next start.
+func (m *model) dailyDue(now time.Time, at string, last *string, key string) (string, bool) {
+	if at == "" {
+		return "", false
+	}
+	day := now.Format("2006-01-02")
+	due, err := time.ParseInLocation("2006-01-02 15:04", day+" "+at, now.Location())
+	if err != nil || now.Before(due) || *last == day {
+		return "", false
+	}
+	*last = day
+	if m.store.Setting(key, "") == day { // Sent from another window
+		return "", false
+	}
+	return day, true
+}
+
+// finishDaily records the day something sent once a day went out.
+func (m *model) finishDaily(msg dailySentMsg) {
+	if msg.err != nil {
+		m.showError(msg.action, msg.err)
+		return
+	}
+	if err := m.store.SaveSetting(msg.key, msg.day); err != nil {
+		m.showError("save setting", err)
+	}
+}
+
+// runDigest implements xtui digest.
+func runDigest(args []string) (bool, error) {
+	flags := flag.NewFlagSet("digest", flag.ExitOnError)
+	email := flags.Bool("email", false, "send the digest through the [email] config section instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: xtui digest [--email]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	m := newModel()
+	defer m.close()
+	tasks, err := m.store.Load(liveTasks)
+	if err != nil {
+		return false, err
+	}
+	subject, body := digestMessage(tasks, time.Now())
+	if !*email {
+		fmt.Fprintf(os.Stdout, "%s\n\n%s", subject, body)
+		return false, nil
+	}
+	if err := sendEmail(m.config, subject, body); err != nil {
+		return false, err
+	}
+	return true, m.store.SaveSetting("last_digest", time.Now().Format("2006-01-02"))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Xtui posts to a Discord channel through the webhook under [discord], for
+// an accountability channel or the team: tasks completed with the share tag
+// (#share unless set otherwise) as they are completed, and with summary_time
+// set, a summary of the day once the clock passes it while Xtui is open,
+// with the share-tagged tasks done that day by title and the rest only
+// counted. Titles never mention anyone in the channel. Posts go out in the
+// background, and one that fails is shown on the message line.
+
+const discordLimit = 2000 // Characters in a message
+
+// postDiscord posts a message to a Discord webhook.
+func postDiscord(webhook, content string) error {
+	if runes := []rune(content); len(runes) > discordLimit {
+		content = string(runes[:discordLimit-1]) + "…"
+	}
+	body, err := json.Marshal(map[string]any{
+		"username":         "Xtui",
+		"content":          content,
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // The webhook URL is a secret
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var reply struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &reply) == nil && reply.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, reply.Message)
+		}
+		return errors.New(resp.Status)
+	}
+	return nil
+}
+
+// discordEvents posts the tasks completed with the share tag among the
+// queued hook runs.
+func (m model) discordEvents(runs []hookRun) tea.Cmd {
+	webhook, tag := m.config.discordWebhook, m.config.discordTag
+	if webhook == "" || tag == "" {
+		return nil
+	}
+	var lines []string
+	for _, run := range runs {
+		if run.event == hookDone && hasTag(run.task, tag) {
+			lines = append(lines, "✅ Completed: "+run.task.title)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := postDiscord(webhook, strings.Join(lines, "\n")); err != nil {
+			return errorMsg{"post to Discord", err}
+		}
+		return nil
+	}
+}
+
+// discordSummary writes the summary of the day of now.
+func discordSummary(tasks []item, streak int, tag string, now time.Time) string {
+	var completed int
+	var shared []string
+	for _, task := range tasks {
+		if task.status != done || task.completedAt.Before(midnight(now)) {
+			continue
+		}
+		completed++
+		if tag != "" && hasTag(task, tag) {
+			shared = append(shared, "- "+task.title)
+		}
+	}
+	overdue, today := digestTasks(tasks, now)
+
+	parts := []string{fmt.Sprintf("%s done", countTasks(completed))}
+	if len(today) > 0 {
+		parts = append(parts, fmt.Sprintf("%d left for today", len(today)))
+	}
+	if len(overdue) > 0 {
+		parts = append(parts, fmt.Sprintf("%d overdue", len(overdue)))
+	}
+	if streak > 1 {
+		parts = append(parts, fmt.Sprintf("%d day streak", streak))
+	}
+	summary := "**" + formatDate(now, "Monday 2 January") + "**: " + strings.Join(parts, ", ")
+	if len(shared) > 0 {
+		summary += "\n" + strings.Join(shared, "\n")
+	}
+	return summary
+}
+
+// checkDiscordSummary posts the summary of the day in the background once the
+// clock has passed summary_time, unless it went out already.
+func (m *model) checkDiscordSummary(now time.Time) tea.Cmd {
+	if m.config.discordWebhook == "" {
+		return nil
+	}
+	day, ok := m.dailyDue(now, m.config.discordSummary, &m.lastSummary, "last_discord_summary")
+	if !ok {
+		return nil
+	}
+	st, err := m.store.Stats()
+	if err != nil {
+		m.showError("load stats", err)
+		return nil
+	}
+	webhook := m.config.discordWebhook
+	summary := discordSummary(m.tasksModel.items, st.streak(now), m.config.discordTag, now)
+	return func() tea.Msg {
+		return dailySentMsg{key: "last_discord_summary", day: day, action: "post to Discord", err: postDiscord(webhook, summary)}
+	}
+}
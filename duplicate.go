@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// D duplicates the selected task as a new one right below it and its
+// subtasks: same title, tags, notes, dates and the rest, but created now and
+// not started, with its checklist unchecked. It makes a template of a task
+// like "weekly report" quick to reuse, and 3D makes three copies. Unlike yy
+// and p, the subtasks are not copied.
+
+// duplicateTask adds count copies of the selected task below it.
+func (m *model) duplicateTask(count int) {
+	t := &m.tasksModel
+	index := t.selectedIndex()
+	if index < 0 {
+		return
+	}
+	original := t.items[index]
+	position := index + 1
+	for _, i := range t.descendants(original.id) {
+		position = max(position, i+1)
+	}
+
+	snapshot := m.snapshot()
+	var added []item
+	for range count {
+		task := original
+		task.id, task.sortOrder = 0, 0
+		task.tags = append([]string{}, original.tags...)
+		task.status = todo
+		task.createdAt = time.Now()
+		task.completedAt = time.Time{}
+		task.snoozedUntil = time.Time{}
+		if err := m.store.Save(&task); err != nil {
+			m.showError("save task", err)
+			break
+		}
+		for _, c := range m.checklistOf(original.id) {
+			copied := checkItem{taskID: task.id, text: c.text}
+			if err := m.store.AddCheckItem(&copied); err != nil {
+				m.showError("copy checklist", err)
+			}
+		}
+		added = append(added, task)
+		m.fireHook(hookAdd, task)
+	}
+	if len(added) == 0 {
+		return
+	}
+	m.insertAt(position, original.parentID, added)
+	m.refreshChecklists()
+	m.record("duplicate", snapshot)
+	t.selectID(added[0].id)
+	if len(added) == 1 {
+		m.showMessage(fmt.Sprintf("Duplicated %q", original.title))
+	} else {
+		m.showMessage(fmt.Sprintf("Made %s from %q", countTasks(len(added)), original.title))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// E opens the selected task in $VISUAL or $EDITOR (vi, or notepad on
+// Windows, when neither is set), with Xtui suspended until the editor exits.
+// The file reads like a commit message: the first line is the task as e edits
+// it, with its tags, due date and the rest of the quick-add syntax, and the
+// notes follow after a blank line. Saving and quitting applies both as one
+// undoable change; emptying the first line leaves the task as it was.
+
+// editorMsg is sent to Update when the editor exits.
+type editorMsg struct {
+	id   int // Task edited
+	path string
+	err  error
+}
+
+// editorCommand returns the editor to run on path.
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+		if runtime.GOOS == "windows" {
+			args = []string{"notepad"}
+		}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// formatEditorFile writes a task as the file opened in the editor.
+func (m model) formatEditorFile(task item) string {
+	input := m.editInput(task)
+	if task.notes == "" {
+		return input + "\n"
+	}
+	return input + "\n\n" + task.notes + "\n"
+}
+
+// parseEditorFile splits the edited file into the task line and the notes.
+func parseEditorFile(text string) (input, notes string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	input, notes, _ = strings.Cut(text, "\n")
+	return strings.TrimSpace(input), strings.Trim(notes, "\n")
+}
+
+// editInEditor suspends Xtui and opens the selected task in the editor.
+func (m *model) editInEditor() tea.Cmd {
+	index := m.tasksModel.selectedIndex()
+	if index < 0 {
+		return nil
+	}
+	task := m.tasksModel.items[index]
+
+	// Keep the notes of an encrypted database next to its working copy
+	dir := ""
+	if m.vault != nil {
+		dir = filepath.Dir(m.vault.working)
+	}
+	f, err := os.CreateTemp(dir, "xtui-*.txt")
+	if err != nil {
+		m.showError("open editor", err)
+		return nil
+	}
+	path := f.Name()
+	_, err = f.WriteString(m.formatEditorFile(task))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		m.showError("open editor", err)
+		return nil
+	}
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		return editorMsg{id: task.id, path: path, err: err}
+	})
+}
+
+// finishEditor saves the task as left in the editor.
+func (m *model) finishEditor(msg editorMsg) {
+	defer os.Remove(msg.path)
+	if msg.err != nil {
+		m.showError("run editor", msg.err)
+		return
+	}
+	data, err := os.ReadFile(msg.path)
+	if err != nil {
+		m.showError("read edited task", err)
+		return
+	}
+	index := m.tasksModel.indexOf(msg.id)
+	if index < 0 {
+		m.showError("save edited task", errors.New("the task is gone"))
+		return
+	}
+	input, notes := parseEditorFile(string(data))
+	if input == "" {
+		m.showMessage("Empty first line, task left as it was")
+		return
+	}
+	if wasInput, wasNotes := parseEditorFile(m.formatEditorFile(m.tasksModel.items[index])); input == wasInput && notes == wasNotes {
+		return // Not changed
+	}
+
+	before := m.snapshot()
+	task := &m.tasksModel.items[index]
+	task.title, task.dueAt, input = parseEditedTitle(input, task.title)
+	task.tags = parseTags(input)
+	task.startAt = parseStart(input)
+	task.estimate = parseEstimate(input)
+	task.recurrence = editedRecurrence(input, task.recurrence)
+	task.priority = parsePriority(input)
+	task.context = parseContext(input)
+	task.project = parseProject(input)
+	task.notes = notes
+	if err := m.store.Update(*task); err != nil {
+		m.showError("update task", err)
+	}
+	m.setReminders(task.id, input)
+	m.record("edit", before)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ~30m, ~2h or ~1h30m in the task input estimates how long a task will take
+// (a bare ~45 is minutes). The list shows the estimate after the title, the
+// status bar sums what is left of the estimates of the open tasks listed,
+// less the time already tracked on them, and each Agenda day gets the sum of
+// its tasks, so "Today ~3h 20m" says whether the day fits. The Stats tab
+// compares the estimates of completed tasks with the time tracked on them.
+
+// parseEstimate returns the estimate in the input in minutes, 0 if none.
+func parseEstimate(input string) int {
+	for _, word := range strings.Fields(input) {
+		if minutes, ok := estimateMinutes(word); ok {
+			return minutes
+		}
+	}
+	return 0
+}
+
+// estimateMinutes reads a ~ token.
+func estimateMinutes(word string) (int, bool) {
+	value, ok := strings.CutPrefix(word, "~")
+	if !ok || value == "" {
+		return 0, false
+	}
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		return n, true
+	}
+	d, err := time.ParseDuration(strings.ToLower(value))
+	if err != nil || d < time.Minute {
+		return 0, false
+	}
+	return int(d.Minutes()), true
+}
+
+func removeEstimate(input string) string {
+	var result []string
+	for _, word := range strings.Fields(input) {
+		if _, ok := estimateMinutes(word); !ok {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// formatEstimate shows minutes as 45m, 2h or 3h 20m.
+func formatEstimate(d time.Duration) string {
+	minutes := int(d.Round(time.Minute).Minutes())
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
+
+// estimateToken writes an estimate the way parseEstimate reads it.
+func estimateToken(minutes int) string {
+	return "~" + strings.ReplaceAll(formatEstimate(time.Duration(minutes)*time.Minute), " ", "")
+}
+
+func (t item) estimated() time.Duration {
+	return time.Duration(t.estimate) * time.Minute
+}
+
+// remaining returns how much of a task's estimate is left after the time
+// tracked on it.
+func (m model) remaining(task item) time.Duration {
+	if task.estimate == 0 || task.status == done {
+		return 0
+	}
+	return max(task.estimated()-m.trackedTime(task.id, time.Time{}), 0)
+}
+
+// remainingWork sums what is left of the estimates of the tasks.
+func (m model) remainingWork(indices []int) time.Duration {
+	var total time.Duration
+	for _, i := range indices {
+		total += m.remaining(m.tasksModel.items[i])
+	}
+	return total
+}
+
+func (s *sqliteStore) estimateStats(stats *taskStats) error {
+	var estimated, tracked float64
+	err := s.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(t.estimate), 0), COALESCE(SUM(e.seconds), 0)
+		FROM tasks t JOIN (
+			SELECT task_id, SUM((julianday(ended_at) - julianday(started_at)) * 86400) AS seconds
+			FROM time_entries WHERE ended_at IS NOT NULL GROUP BY task_id
+		) e ON e.task_id = t.id
+		WHERE t.status = 1 AND t.deleted_at IS NULL AND t.estimate > 0
+	`).Scan(&stats.estimatedTasks, &estimated, &tracked)
+	stats.estimatedTime = time.Duration(estimated) * time.Minute
+	stats.trackedTime = time.Duration(math.Round(tracked)) * time.Second
+	return err
+}
+
+// renderEstimates compares estimates with tracked time for the Stats tab.
+func (st taskStats) renderEstimates() string {
+	if st.estimatedTasks == 0 {
+		return helpStyle.Render("Estimate tasks with ~30m and track time on them with T to compare") + "\n"
+	}
+	line := fmt.Sprintf("%s took %s against %s estimated", countTasks(st.estimatedTasks), formatEstimate(st.trackedTime), formatEstimate(st.estimatedTime))
+	if st.estimatedTime > 0 {
+		off := int(math.Round((st.trackedTime.Seconds()/st.estimatedTime.Seconds() - 1) * 100))
+		switch {
+		case off > 0:
+			line += fmt.Sprintf(", %d%% over", off)
+		case off < 0:
+			line += fmt.Sprintf(", %d%% under", -off)
+		default:
+			line += ", spot on"
+		}
+	}
+	return line + "\n"
+}
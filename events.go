@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Every change made in Xtui is kept in the task_events table: when a task
+// was created, edited, completed, reopened or deleted, including by undo and
+// redo. Unlike the undo stack, which forgets its oldest entries, the trail
+// grows for the life of the database and outlives the tasks it is about,
+// since each event keeps the task's title. The detail view (o) shows the
+// history of a task. Changes pulled in by sync are not recorded.
+
+const (
+	eventCreated   = "created"
+	eventEdited    = "edited"
+	eventCompleted = "completed"
+	eventReopened  = "reopened"
+	eventDeleted   = "deleted"
+)
+
+const detailEvents = 10 // Most recent events shown in the detail view
+
+type taskEvent struct {
+	id     int
+	taskID int
+	kind   string
+	title  string // The task's title at the time
+	detail string // The change that caused it, e.g. "snooze" or "undo delete"
+	at     time.Time
+}
+
+func createEventsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS task_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			title TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS task_events_task ON task_events (task_id);
+		CREATE INDEX IF NOT EXISTS task_events_at ON task_events (at);
+	`)
+	return err
+}
+
+func (s *sqliteStore) AddEvents(events []taskEvent) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, e := range events {
+			_, err := tx.Exec("INSERT INTO task_events (task_id, kind, title, detail, at) VALUES (?, ?, ?, ?, ?)", e.taskID, e.kind, e.title, e.detail, e.at)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) TaskEvents(taskID, limit int) ([]taskEvent, error) {
+	return s.events("WHERE task_id = ?", taskID, limit)
+}
+
+// events returns the events matching a condition, newest first.
+func (s *sqliteStore) events(condition string, args ...interface{}) ([]taskEvent, error) {
+	rows, err := s.db.Query(`
+		SELECT id, task_id, kind, title, detail, at FROM task_events `+condition+`
+		ORDER BY julianday(at) DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []taskEvent
+	for rows.Next() {
+		var e taskEvent
+		if err := rows.Scan(&e.id, &e.taskID, &e.kind, &e.title, &e.detail, &e.at); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// changeEvents describes what an operation did to each task it touched.
+// Tasks only moved in the list don't make an event.
+func changeEvents(label string, before, after []item, at time.Time) []taskEvent {
+	previous := make(map[int]item, len(before))
+	for _, task := range before {
+		previous[task.id] = task
+	}
+	current := make(map[int]bool, len(after))
+	var events []taskEvent
+	for _, task := range after {
+		current[task.id] = true
+		kind := eventEdited
+		old, existed := previous[task.id]
+		switch {
+		case !existed:
+			kind = eventCreated
+		case old.status != done && task.status == done:
+			kind = eventCompleted
+		case old.status == done && task.status != done:
+			kind = eventReopened
+		default:
+			old.sortOrder = task.sortOrder
+			if sameTask(old, task) {
+				continue
+			}
+		}
+		events = append(events, taskEvent{taskID: task.id, kind: kind, title: task.title, detail: label, at: at})
+	}
+	for _, task := range before {
+		if !current[task.id] {
+			events = append(events, taskEvent{taskID: task.id, kind: eventDeleted, title: task.title, detail: label, at: at})
+		}
+	}
+	return events
+}
+
+// logChange adds the events of an operation to the trail.
+func (m *model) logChange(label string, before, after []item) {
+	events := changeEvents(label, before, after, time.Now())
+	if len(events) == 0 {
+		return
+	}
+	if err := m.store.AddEvents(events); err != nil {
+		m.showError("record history", err)
+	}
+}
+
+// plainChanges are the changes that cause each kind of event without saying
+// more about it.
+var plainChanges = map[string]string{
+	eventCreated:   "add",
+	eventEdited:    "edit",
+	eventCompleted: "status",
+	eventReopened:  "status",
+	eventDeleted:   "delete",
+}
+
+// describeEvent tells what happened, adding the change that caused it when
+// it says more than the kind of event, as in "Edited (snooze)".
+func describeEvent(e taskEvent) string {
+	text := strings.ToUpper(e.kind[:1]) + e.kind[1:]
+	if e.detail != "" && e.detail != plainChanges[e.kind] {
+		text += " (" + e.detail + ")"
+	}
+	return text
+}
+
+// loadEvents reads the history of a task for the detail view.
+func (m *model) loadEvents(taskID int) {
+	events, err := m.store.TaskEvents(taskID, detailEvents)
+	if err != nil {
+		m.showError("load history", err)
+	}
+	m.taskEvents = events
+}
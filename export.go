@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exporters write tasks in the formats accepted by :export, importers read
+// them back for :import.
+var exporters = map[string]func(w io.Writer, tasks []item) error{
+	"json":     writeJSON,
+	"csv":      writeCSV,
+	"markdown": writeMarkdown,
+	"todotxt":  writeTodotxt,
+	"ics":      writeICS,
+}
+
+var importers = map[string]func(r io.Reader) ([]item, error){
+	"json":     readJSON,
+	"markdown": readMarkdown,
+	"todotxt":  readTodotxt,
+	"todoist":  readTodoistCSV,
+	"text":     readText, // A task per line, see add.go
+}
+
+func formatNames[F any](formats map[string]F) []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeJSON(w io.Writer, tasks []item) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(toJSONTasks(tasks))
+}
+
+func readJSON(r io.Reader) ([]item, error) {
+	var tasks []jsonTask
+	if err := json.NewDecoder(r).Decode(&tasks); err != nil {
+		return nil, err
+	}
+	return fromJSONTasks(tasks), nil
+}
+
+// writeCSV writes one row per task for spreadsheets. Timestamps are local
+// time in a format spreadsheets recognize, tags are separated by spaces.
+func writeCSV(w io.Writer, tasks []item) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "title", "tags", "status", "created", "completed", "due"})
+	for _, task := range tasks {
+		completed := ""
+		if task.status == done {
+			completed = formatCSVTime(task.completedAt)
+		}
+		out.Write([]string{
+			strconv.Itoa(task.id),
+			task.title,
+			strings.Join(task.tags, " "),
+			statusName(task.status),
+			formatCSVTime(task.createdAt),
+			completed,
+			formatCSVTime(task.dueAt),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// exportTasks writes every task that is not in the trash to path, or to
+// stdout if path is "-".
+func (m model) exportTasks(format, path string) error {
+	write, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	// Older completed tasks may not be loaded, see history.go
+	tasks, err := m.store.Load(liveTasks)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return write(os.Stdout, tasks)
+	}
+	f, err := os.Create(expandHome(path))
+	if err != nil {
+		return err
+	}
+	if err := write(f, tasks); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// importTasks adds the tasks read from path (stdin for "-") to the list.
+func (m *model) importTasks(format, path string) (int, error) {
+	read, ok := importers[format]
+	if !ok {
+		return 0, fmt.Errorf("unknown import format %q", format)
+	}
+	var err error
+	var tasks []item
+	if path == "-" {
+		tasks, err = read(os.Stdin)
+	} else {
+		f, openErr := os.Open(expandHome(path))
+		if openErr != nil {
+			return 0, openErr
+		}
+		tasks, err = read(f)
+		f.Close()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return m.addTasks(tasks)
+}
+
+// addTasks saves imported tasks as one undoable change and returns how many
+// were added. They get new ids; parent links among them are remapped and
+// links to tasks outside them are dropped.
+func (m *model) addTasks(tasks []item) (int, error) {
+	// Keep the manual order of the file, after the existing tasks
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].sortOrder < tasks[j].sortOrder
+	})
+
+	snapshot := m.snapshot()
+	ids := make(map[int]int, len(tasks))
+	var linked []item
+	for _, task := range tasks {
+		oldID, parentID := task.id, task.parentID
+		task.id, task.parentID, task.sortOrder = 0, 0, 0
+		if strings.TrimSpace(task.title) == "" {
+			continue
+		}
+		if task.tags == nil {
+			task.tags = []string{}
+		}
+		if err := m.store.Save(&task); err != nil {
+			return 0, err
+		}
+		if oldID != 0 {
+			ids[oldID] = task.id
+		}
+		task.parentID = parentID
+		m.tasksModel.items = append(m.tasksModel.items, task)
+		if parentID != 0 {
+			linked = append(linked, task)
+		}
+	}
+
+	var updated []item
+	for _, task := range linked {
+		index := m.tasksModel.indexOf(task.id)
+		m.tasksModel.items[index].parentID = ids[task.parentID]
+		updated = append(updated, m.tasksModel.items[index])
+	}
+	if err := m.store.Update(updated...); err != nil {
+		return 0, err
+	}
+
+	count := len(m.tasksModel.items) - len(snapshot)
+	m.record("import", snapshot)
+	return count, nil
+}
+
+// runExport implements the -export flag: it writes the tasks without
+// starting the interface.
+func runExport(format, path string) error {
+	m := newModel()
+	defer m.close()
+	return m.exportTasks(format, path)
+}
+
+// runImport implements the -import flag. The todoist format without a file
+// imports from the Todoist API instead.
+func runImport(format, path string) (int, error) {
+	m := newModel()
+	defer m.close()
+	tasks, err := m.store.Load(liveTasks)
+	if err != nil {
+		return 0, err
+	}
+	m.tasksModel.items = tasks
+	if format == "todoist" && path == "-" {
+		remote, err := fetchTodoist(m.config.todoistToken)
+		if err != nil {
+			return 0, err
+		}
+		return m.addTasks(remote)
+	}
+	return m.importTasks(format, path)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestJSONToICSPriority(t *testing.T) {
+	tasks, err := readJSON(strings.NewReader(`[
+		{"id": 1, "title": "Too high", "priority": 4, "due_at": "2026-10-20T09:00:00Z"},
+		{"id": 2, "title": "Negative", "priority": -2, "due_at": "2026-10-20T09:00:00Z"},
+		{"id": 3, "title": "Lowest", "priority": 3, "due_at": "2026-10-20T09:00:00Z"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{3, 0, 3}
+	for i, task := range tasks {
+		if task.priority != want[i] {
+			t.Errorf("%s: priority %d, want %d", task.title, task.priority, want[i])
+		}
+	}
+
+	// Tasks already stored out of range still export
+	tasks = append(tasks, item{id: 4, title: "Stored", priority: 7, dueAt: tasks[0].dueAt})
+	var out bytes.Buffer
+	if err := writeICS(&out, tasks); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(out.String(), "PRIORITY:9"); got != 3 {
+		t.Errorf("%d tasks exported with PRIORITY:9, want 3:\n%s", got, out.String())
+	}
+}
+
+func TestCSVStatus(t *testing.T) {
+	var out bytes.Buffer
+	tasks := []item{{id: 1, title: "Waiting"}, {id: 2, title: "Started", status: doing}, {id: 3, title: "Finished", status: done}}
+	if err := writeCSV(&out, tasks); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"todo", "doing", "done"} {
+		if got := rows[i+1][3]; got != want {
+			t.Errorf("%s: status %q, want %q", tasks[i].title, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// matches reports whether a task passes the filters currently applied to the
+// list.
+func (t tasksModel) matches(task item) bool {
+	if t.hideDone && task.status == done {
+		return false
+	}
+	if !t.showSnoozed && task.snoozed(time.Now()) {
+		return false
+	}
+	if !t.showUpcoming && task.notStarted(time.Now()) {
+		return false
+	}
+	if t.tagFilter != "" && !hasTag(task, t.tagFilter) {
+		return false
+	}
+	if t.contextFilter != "" && !strings.EqualFold(task.context, t.contextFilter) {
+		return false
+	}
+	if !t.inListView(task) {
+		return false
+	}
+	if t.focus && !task.inFocus(time.Now()) {
+		return false
+	}
+	if t.queryIDs != nil && !t.queryIDs[task.id] {
+		return false
+	}
+	return matchesQuery(task, t.query)
+}
+
+// filterActive reports whether a search, tag, context, GTD list, Focus or
+// query filter narrows the list, which is required before running bulk
+// operations.
+func (t tasksModel) filterActive() bool {
+	return t.query != "" || t.tagFilter != "" || t.contextFilter != "" || t.listView != "" || t.focus || t.taskQuery != ""
+}
+
+// filteredIndices returns the indices of all tasks matching the filters,
+// including ones hidden inside collapsed parents.
+func (t tasksModel) filteredIndices() []int {
+	var indices []int
+	for i, task := range t.items {
+		if t.matches(task) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// countDone returns how many tasks are completed, i.e. hidden by hideDone.
+func (t tasksModel) countDone() int {
+	count := 0
+	for _, task := range t.items {
+		if task.status == done {
+			count++
+		}
+	}
+	return count
+}
+
+func hasTag(task item, tag string) bool {
+	for _, t := range task.tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesQuery does a case-insensitive substring match on the title and tags.
+func matchesQuery(task item, query string) bool {
+	if query == "" {
+		return true
+	}
+	query = strings.ToLower(query)
+	if strings.Contains(strings.ToLower(task.title), query) {
+		return true
+	}
+	for _, tag := range task.tags {
+		if strings.Contains(strings.ToLower(tag), query) {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(task.context), query) ||
+		strings.Contains(strings.ToLower(task.project), query)
+}
+
+// jumpToMatch moves the cursor to the next (or previous) row that matches the
+// search query, wrapping around the list like vim's n and N.
+func (t *tasksModel) jumpToMatch(forward bool) {
+	if t.query == "" {
+		return
+	}
+	rows := t.rows()
+	for step := 1; step <= len(rows); step++ {
+		i := t.selected - step
+		if forward {
+			i = t.selected + step
+		}
+		i = (i%len(rows) + len(rows)) % len(rows)
+		if matchesQuery(t.items[rows[i].index], t.query) {
+			t.selected = i
+			return
+		}
+	}
+}
+
+// highlightMatches renders text with style, using matchStyle for every
+// case-insensitive occurrence of query.
+func highlightMatches(text, query string, style lipgloss.Style) string {
+	if query == "" {
+		return style.Render(text)
+	}
+
+	var s strings.Builder
+	lower := strings.ToLower(text)
+	query = strings.ToLower(query)
+	for {
+		i := strings.Index(lower, query)
+		if i < 0 || len(lower) != len(text) {
+			// Stop if lowercasing changed byte offsets (non-ASCII edge cases)
+			s.WriteString(style.Render(text))
+			return s.String()
+		}
+		s.WriteString(style.Render(text[:i]))
+		s.WriteString(matchStyle.Render(text[i : i+len(query)]))
+		text, lower = text[i+len(query):], lower[i+len(query):]
+		if text == "" {
+			return s.String()
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ctrl+p opens a fuzzy finder over every task, done or hidden by a filter.
+// The letters typed have to appear in order in the title, a tag or the notes,
+// not next to each other: "rvpr" finds "review pull request". Matches at the
+// start of words and runs of adjacent letters rank higher, and title matches
+// beat tag matches, which beat notes. enter jumps to the chosen task and
+// ctrl+x completes or reopens it without leaving the finder.
+
+const finderMode = "finder"
+
+const finderLimit = 10 // Results shown at once
+
+// finderState is the finder being shown.
+type finderState struct {
+	input   textinput.Model
+	results []finderResult
+	cursor  int
+}
+
+type finderResult struct {
+	index     int   // Into tasksModel.items
+	score     int   // Higher is better
+	positions []int // Byte offsets of the matched letters in the title
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in text in order,
+// with a score rewarding matches at word starts and consecutive runs, and the
+// byte offsets of the matched runes. Case is ignored. Each place the first
+// rune appears is tried as the start and the best scoring one wins.
+func fuzzyMatch(pattern, text string) (int, []int, bool) {
+	want := []rune(strings.ToLower(pattern))
+	if len(want) == 0 {
+		return 0, nil, true
+	}
+	best, found := 0, false
+	var positions []int
+	for start, r := range text {
+		if unicode.ToLower(r) != want[0] {
+			continue
+		}
+		if score, pos, ok := matchFrom(want, text, start); ok && (!found || score > best) {
+			best, positions, found = score, pos, true
+		}
+	}
+	return best, positions, found
+}
+
+// matchFrom matches the runes greedily from the byte offset start.
+func matchFrom(want []rune, text string, start int) (int, []int, bool) {
+	var positions []int
+	score, next, run := 0, 0, 0
+	previous := ' '
+	if start > 0 {
+		previous, _ = utf8.DecodeLastRuneInString(text[:start])
+	}
+	for offset, r := range text[start:] {
+		if next < len(want) && unicode.ToLower(r) == want[next] {
+			positions = append(positions, start+offset)
+			score += 1 + run*2 // Consecutive matches count more and more
+			if !unicode.IsLetter(previous) && !unicode.IsDigit(previous) {
+				score += 4 // Start of a word
+			}
+			next++
+			run++
+		} else {
+			if next < len(want) {
+				score-- // A gap between matched letters
+			}
+			run = 0
+		}
+		previous = r
+	}
+	return score, positions, next == len(want)
+}
+
+// findTasks ranks every task against the pattern. The title counts three
+// times as much as a tag, a tag twice as much as the notes.
+func (t tasksModel) findTasks(pattern string) []finderResult {
+	var results []finderResult
+	for i, task := range t.items {
+		best, found := 0, false
+		var positions []int
+		if score, pos, ok := fuzzyMatch(pattern, task.title); ok {
+			best, positions, found = score*3, pos, true
+		}
+		for _, tag := range task.tags {
+			if score, _, ok := fuzzyMatch(pattern, tag); ok && (!found || score*2 > best) {
+				best, positions, found = score*2, nil, true
+			}
+		}
+		if score, _, ok := fuzzyMatch(pattern, task.notes); ok && (!found || score > best) {
+			best, positions, found = score, nil, true
+		}
+		if !found {
+			continue
+		}
+		if task.status == done {
+			best -= 5 // Open tasks first when the match is as good
+		}
+		results = append(results, finderResult{index: i, score: best, positions: positions})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+	return results
+}
+
+// openFinder shows the finder with every task listed.
+func (m *model) openFinder() tea.Cmd {
+	input := textinput.New()
+	input.Prompt = "Find: "
+	input.Placeholder = "title, #tag or notes"
+	m.finder = finderState{input: input}
+	m.finder.results = m.tasksModel.findTasks("")
+	m.tasksModel.mode = finderMode
+	return m.finder.input.Focus()
+}
+
+// updateFinder handles keys in the finder. Typing narrows the results as it
+// goes.
+func (m *model) updateFinder(msg tea.KeyMsg) tea.Cmd {
+	f := &m.finder
+	switch msg.String() {
+	case "esc", "ctrl+p":
+		m.tasksModel.mode = normalMode
+	case "up", "ctrl+k":
+		if f.cursor > 0 {
+			f.cursor--
+		}
+	case "down", "ctrl+j":
+		if f.cursor < min(len(f.results), finderLimit)-1 {
+			f.cursor++
+		}
+	case "enter":
+		if f.cursor < len(f.results) {
+			m.tasksModel.mode = normalMode
+			m.jumpTo(f.results[f.cursor].index)
+		}
+	case "ctrl+x": // Complete or reopen the chosen task and keep finding
+		if f.cursor < len(f.results) {
+			index := f.results[f.cursor].index
+			m.setStatus([]int{index}, toggleStatus(m.tasksModel.items[index].status))
+		}
+	default:
+		var cmd tea.Cmd
+		f.input, cmd = f.input.Update(msg)
+		f.results = m.tasksModel.findTasks(f.input.Value())
+		f.cursor = 0
+		return cmd
+	}
+	return nil
+}
+
+func (m model) renderFinder() string {
+	f := m.finder
+	width := min(80, max(40, m.width-8)) // Of the box inside its border
+	var s strings.Builder
+	s.WriteString(f.input.View() + "\n\n")
+	if len(f.results) == 0 {
+		s.WriteString(helpStyle.Render("No task matches") + "\n")
+	}
+	for i, result := range f.results {
+		if i == finderLimit {
+			s.WriteString(helpStyle.Render(fmt.Sprintf("  and %d more", len(f.results)-finderLimit)) + "\n")
+			break
+		}
+		task := m.tasksModel.items[result.index]
+		style := itemStyle.PaddingLeft(0)
+		marker := cursorMark(i == f.cursor)
+		if i == f.cursor {
+			style = selectedItemStyle.PaddingLeft(0)
+		}
+		tags := ""
+		if len(task.tags) > 0 {
+			tags = " " + tagStyle.Render(tagLabels(task.tags))
+		}
+		title := truncate(task.title, max(width-3-cursorWidth()-markerWidth()-lipgloss.Width(tags), minTitleWidth)) // The padding takes 3 columns beside the cursor and marker
+		line := style.Render(marker+statusMarker(task.status)+" ") + highlightPositions(title, result.positions, style)
+		s.WriteString(line + tags + "\n")
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(helpStyle.GetForeground()).
+		Padding(0, 1).
+		Width(width).
+		Render(strings.TrimRight(s.String(), "\n"))
+}
+
+// highlightPositions renders text with the runes at the byte offsets in
+// matchStyle.
+func highlightPositions(text string, positions []int, style lipgloss.Style) string {
+	if len(positions) == 0 {
+		return style.Render(text)
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, offset := range positions {
+		matched[offset] = true
+	}
+	var s strings.Builder
+	for offset, r := range text {
+		if matched[offset] {
+			s.WriteString(matchStyle.Render(string(r)))
+		} else {
+			s.WriteString(style.Render(string(r)))
+		}
+	}
+	return s.String()
+}
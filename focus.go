@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// gf (or :focus) switches the list to the Focus view: only the tasks for
+// today, those due today or overdue, starred or planned for today. + plans
+// the selected task for today, or takes it off today, and 3+ three tasks, so
+// the morning is spent picking a few tasks and the day working them down
+// without the rest of the backlog in sight. A task planned for an earlier day
+// stays in the view until it is done, and tasks completed today stay to show
+// how the day went.
+
+// planned reports whether the task is planned for the day of now or an
+// earlier one.
+func (t item) planned(now time.Time) bool {
+	return !t.plannedFor.IsZero() && t.plannedFor.Before(midnight(now).AddDate(0, 0, 1))
+}
+
+// inFocus reports whether the task is one for the day of now.
+func (t item) inFocus(now time.Time) bool {
+	if t.status == done && t.completedAt.Before(midnight(now)) {
+		return false
+	}
+	endOfDay := midnight(now).AddDate(0, 0, 1)
+	return t.starred || t.planned(now) || (!t.dueAt.IsZero() && t.dueAt.Before(endOfDay))
+}
+
+// toggleFocus switches between the Focus view and the whole list.
+func (m *model) toggleFocus() {
+	t := &m.tasksModel
+	t.focus = !t.focus
+	t.selected = 0
+	if t.focus {
+		count := 0
+		for _, task := range t.items {
+			if task.status != done && task.inFocus(time.Now()) {
+				count++
+			}
+		}
+		m.showMessage(fmt.Sprintf("Focus: %s left for today, + plans more", countTasks(count)))
+	}
+}
+
+// planToday plans the tasks for today unless they all are already, in which
+// case it takes them off today, as one undo step.
+func (m *model) planToday(indices []int) {
+	now := time.Now()
+	planned := true
+	for _, index := range indices {
+		if !m.tasksModel.items[index].planned(now) {
+			planned = false
+		}
+	}
+
+	before := m.snapshot()
+	var updates []item
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		if planned {
+			task.plannedFor = time.Time{}
+		} else if !task.planned(now) {
+			task.plannedFor = midnight(now)
+		} else {
+			continue
+		}
+		updates = append(updates, *task)
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := m.store.Update(updates...); err != nil {
+		m.showError("update tasks", err)
+	}
+	if planned {
+		m.record("unplan", before)
+		m.showMessage(fmt.Sprintf("Took %s off today", countTasks(len(updates))))
+	} else {
+		m.record("plan", before)
+		m.showMessage(fmt.Sprintf("Planned %s for today", countTasks(len(updates))))
+	}
+	m.tasksModel.clampSelection() // Taken off today, they may leave the Focus view
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sync through a git repository, configured in the [git] section, for people
+// who already keep their notes in one. The tasks are written to a file in the
+// repository as JSON lines, one task per line in the format of the sync
+// server (see cloud.go), so diffs and history stay readable. Each sync pulls,
+// merges the file with the local tasks the same three-way way as the sync
+// server, commits the file if it changed and pushes. Without an upstream
+// branch the repository only keeps the history.
+//
+// Tasks are matched by UID like on the sync server, with UIDs of their own
+// in the git_links table. The version of the file as of the last sync is
+// kept in the git_base setting. A push that fails, say because another
+// machine pushed first, is rebased onto the other machine's commit on the
+// next sync, which then merges both.
+
+// createGitLinksTable creates the table of the UIDs in the git file. Git
+// sync used the sync server's UIDs before it had its own, so a new table
+// starts with those.
+func createGitLinksTable(db *sql.DB) error {
+	var exists int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'git_links'").Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+	return inTx(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE git_links (
+				task_id INTEGER PRIMARY KEY,
+				uid TEXT NOT NULL UNIQUE
+			);
+		`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO git_links (task_id, uid) SELECT task_id, uid FROM cloud")
+		return err
+	})
+}
+
+// gitSyncMsg is sent to Update when a git sync finishes.
+type gitSyncMsg struct {
+	result syncResult
+	err    error
+}
+
+// syncGit runs a git sync in the background.
+func (m model) syncGit() tea.Cmd {
+	return func() tea.Msg {
+		result, err := m.runGitSync()
+		return gitSyncMsg{result: result, err: err}
+	}
+}
+
+// git runs a git command in the repository and returns its output.
+func git(repo string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// readJSONLines reads the tasks in a JSON lines file, none if it is missing.
+func readJSONLines(path string) ([]cloudTask, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tasks []cloudTask
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20) // Long notes
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var task cloudTask
+		if err := json.Unmarshal(scanner.Bytes(), &task); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filepath.Base(path), line, err)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// writeJSONLines replaces the file with the tasks, one per line.
+func writeJSONLines(path string, tasks []cloudTask) error {
+	var b bytes.Buffer
+	for _, task := range tasks {
+		line, err := json.Marshal(task)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b.Bytes(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runGitSync pulls, merges the task file with the local tasks, and commits
+// and pushes the result.
+func (m model) runGitSync() (syncResult, error) {
+	repo, file := m.config.gitRepo, m.config.gitFile
+	if repo == "" {
+		return syncResult{}, errors.New("no repository, set repo in the [git] section of the config")
+	}
+	if _, err := git(repo, "rev-parse", "--git-dir"); err != nil {
+		return syncResult{}, err
+	}
+	_, noUpstream := git(repo, "rev-parse", "--abbrev-ref", "@{upstream}")
+	if noUpstream == nil {
+		// Lines both sides changed take the other machine's version, the
+		// merge below brings back whatever changed here
+		if _, err := git(repo, "pull", "--rebase", "--autostash", "-X", "ours"); err != nil {
+			git(repo, "rebase", "--abort")
+			return syncResult{}, err
+		}
+	}
+
+	path := filepath.Join(repo, file)
+	tasks, err := readJSONLines(path)
+	if err != nil {
+		return syncResult{}, err
+	}
+	base, err := m.syncBase("git_base")
+	if err != nil {
+		return syncResult{}, err
+	}
+	next, result, err := m.mergeTasks(gitLinks, byUID(tasks), base)
+	if err != nil {
+		return result, err
+	}
+	if err := writeJSONLines(path, next.Tasks); err != nil {
+		return result, err
+	}
+	if err := m.saveSyncBase("git_base", next); err != nil {
+		return result, err
+	}
+
+	if _, err := git(repo, "add", "--", file); err != nil {
+		return result, err
+	}
+	if _, err := git(repo, "diff", "--cached", "--quiet", "--", file); err != nil {
+		host, _ := os.Hostname()
+		message := fmt.Sprintf("Xtui sync from %s: %d changed, %d deleted", host, result.pushed, result.deleted)
+		if _, err := git(repo, "commit", "--quiet", "-m", message, "--", file); err != nil {
+			return result, err
+		}
+	}
+	if noUpstream == nil {
+		if _, err := git(repo, "push", "--quiet"); err != nil {
+			return result, fmt.Errorf("%w, will retry", err)
+		}
+	}
+	err = m.store.SaveSetting("git_last_sync", time.Now().Format(time.RFC3339))
+	return result, err
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestGitSyncSurvivesSignOut(t *testing.T) {
+	m := newTestModel(t)
+	task := item{title: "Sort the receipts", tags: []string{}, list: inbox}
+	if err := m.store.Save(&task); err != nil {
+		t.Fatal(err)
+	}
+	first, result, err := m.mergeTasks(gitLinks, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Tasks) != 1 || result.pushed != 1 {
+		t.Fatalf("first sync wrote %d tasks and pushed %d, want 1 and 1", len(first.Tasks), result.pushed)
+	}
+
+	// Signing out of the sync server leaves the UIDs in the git file alone
+	if err := m.store.ClearSyncLinks(cloudLinks); err != nil {
+		t.Fatal(err)
+	}
+	file := byUID(first.Tasks)
+	next, result, err := m.mergeTasks(gitLinks, file, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (syncResult{}) {
+		t.Errorf("second sync changed something: %+v", result)
+	}
+	if len(next.Tasks) != 1 || next.Tasks[0].UID != first.Tasks[0].UID {
+		t.Errorf("UIDs changed from %v to %v", first.Tasks, next.Tasks)
+	}
+}
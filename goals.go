@@ -0,0 +1,402 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Goals put a layer above the list: a goal like "Ship v1.0 due:2026-09-30"
+// gathers the tasks that get it done, and the Goals tab shows how far along
+// each one is and how long is left until its deadline. a adds a goal on the
+// tab, enter lists its tasks and d deletes it (its tasks stay). In the list
+// mg links the selected task to a goal, 3mg three tasks and g in visual mode
+// the range; a task belongs to one goal at most. Goals don't sync.
+
+const (
+	goalMode      = "goal" // Picking the goal to link tasks to
+	goalBarWidth  = 20     // Characters in a goal's progress bar
+	goalTaskLimit = 10     // Tasks listed under the selected goal
+)
+
+// goal is an outcome tasks are linked to, with how far along they are.
+type goal struct {
+	id        int
+	title     string
+	dueAt     time.Time // Deadline, zero for none
+	createdAt time.Time
+	tasks     int // Linked tasks, those in the trash left out
+	done      int // Linked tasks completed
+	estimate  int // Minutes estimated for the open linked tasks
+}
+
+type goalsModel struct {
+	goals    []goal
+	links    map[int]int // Goal of each linked task, by task id
+	selected int
+	open     bool // Listing the tasks of the selected goal
+	input    textinput.Model
+	cursor   int // Highlighted entry in the goal picker, 0 for no goal
+}
+
+func newGoalsModel() goalsModel {
+	ti := textinput.New()
+	ti.Prompt = "New goal: "
+	ti.Placeholder = "Ship v1.0 due:2026-09-30"
+	return goalsModel{input: ti, links: make(map[int]int)}
+}
+
+func createGoalsTables(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS goals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			due_at DATETIME,
+			created_at DATETIME NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS goal_tasks (
+			task_id INTEGER PRIMARY KEY,
+			goal_id INTEGER NOT NULL
+		);
+	`)
+	return err
+}
+
+func (s *sqliteStore) Goals() ([]goal, error) {
+	rows, err := s.db.Query(`
+		SELECT g.id, g.title, g.due_at, g.created_at, COUNT(t.id),
+			COALESCE(SUM(t.status = ?), 0), COALESCE(SUM(CASE WHEN t.status != ? THEN t.estimate ELSE 0 END), 0)
+		FROM goals g
+		LEFT JOIN goal_tasks l ON l.goal_id = g.id
+		LEFT JOIN tasks t ON t.id = l.task_id AND t.deleted_at IS NULL
+		GROUP BY g.id
+		ORDER BY g.due_at IS NULL, g.due_at, g.id`, done, done)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []goal
+	for rows.Next() {
+		var g goal
+		var dueAt sql.NullTime
+		if err := rows.Scan(&g.id, &g.title, &dueAt, &g.createdAt, &g.tasks, &g.done, &g.estimate); err != nil {
+			return nil, err
+		}
+		if dueAt.Valid {
+			g.dueAt = dueAt.Time
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+func (s *sqliteStore) GoalLinks() (map[int]int, error) {
+	rows, err := s.db.Query("SELECT task_id, goal_id FROM goal_tasks")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make(map[int]int)
+	for rows.Next() {
+		var taskID, goalID int
+		if err := rows.Scan(&taskID, &goalID); err != nil {
+			return nil, err
+		}
+		links[taskID] = goalID
+	}
+	return links, rows.Err()
+}
+
+func (s *sqliteStore) AddGoal(g *goal) error {
+	res, err := s.db.Exec("INSERT INTO goals (title, due_at, created_at) VALUES (?, ?, ?)", g.title, nullTime(g.dueAt), g.createdAt)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	g.id = int(id)
+	return err
+}
+
+func (s *sqliteStore) RemoveGoal(id int) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM goal_tasks WHERE goal_id = ?", id); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM goals WHERE id = ?", id)
+		return err
+	})
+}
+
+func (s *sqliteStore) LinkGoal(goalID int, taskIDs []int) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, id := range taskIDs {
+			var err error
+			if goalID == 0 {
+				_, err = tx.Exec("DELETE FROM goal_tasks WHERE task_id = ?", id)
+			} else {
+				_, err = tx.Exec("INSERT OR REPLACE INTO goal_tasks (task_id, goal_id) VALUES (?, ?)", id, goalID)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *model) refreshGoals() {
+	goals, err := m.store.Goals()
+	if err != nil {
+		m.showError("load goals", err)
+		return
+	}
+	links, err := m.store.GoalLinks()
+	if err != nil {
+		m.showError("load goals", err)
+		return
+	}
+	m.goals.goals, m.goals.links = goals, links
+	m.goals.selected = min(m.goals.selected, max(0, len(goals)-1))
+}
+
+// goalOf returns the goal a task is linked to.
+func (m model) goalOf(taskID int) (goal, bool) {
+	for _, g := range m.goals.goals {
+		if g.id == m.goals.links[taskID] {
+			return g, true
+		}
+	}
+	return goal{}, false
+}
+
+// goalTasks returns the indices of the loaded tasks linked to a goal, open
+// ones first.
+func (m model) goalTasks(id int) []int {
+	var open, finished []int
+	for i, task := range m.tasksModel.items {
+		if m.goals.links[task.id] != id {
+			continue
+		}
+		if task.status == done {
+			finished = append(finished, i)
+		} else {
+			open = append(open, i)
+		}
+	}
+	return append(open, finished...)
+}
+
+// openGoalPicker lists the goals to link the tasks to, with the goal of the
+// first one highlighted.
+func (m *model) openGoalPicker(indices []int) {
+	if len(indices) == 0 {
+		return
+	}
+	m.refreshGoals()
+	if len(m.goals.goals) == 0 {
+		m.showMessage("No goals yet, add one on the Goals tab")
+		return
+	}
+	m.tasksModel.bulkTargets = indices
+	m.goals.cursor = 0
+	for i, g := range m.goals.goals {
+		if g.id == m.goals.links[m.tasksModel.items[indices[0]].id] {
+			m.goals.cursor = i + 1
+		}
+	}
+	m.tasksModel.mode = goalMode
+}
+
+// updateGoalPicker handles keys in the goal picker.
+func (m *model) updateGoalPicker(key string) {
+	g := &m.goals
+	switch key {
+	case "up", "k":
+		if g.cursor > 0 {
+			g.cursor--
+		}
+	case "down", "j":
+		if g.cursor < len(g.goals) {
+			g.cursor++
+		}
+	case "enter":
+		m.linkGoal(m.tasksModel.bulkTargets, g.cursor)
+		fallthrough
+	case "esc":
+		m.tasksModel.bulkTargets = nil
+		m.tasksModel.mode = normalMode
+	}
+}
+
+// linkGoal links the tasks to the goal at a position in the picker, 0
+// unlinking them.
+func (m *model) linkGoal(indices []int, position int) {
+	goalID, title := 0, ""
+	if position > 0 {
+		goalID, title = m.goals.goals[position-1].id, m.goals.goals[position-1].title
+	}
+	ids := make([]int, len(indices))
+	for i, index := range indices {
+		ids[i] = m.tasksModel.items[index].id
+	}
+	if err := m.store.LinkGoal(goalID, ids); err != nil {
+		m.showError("link goal", err)
+		return
+	}
+	m.refreshGoals()
+	if goalID == 0 {
+		m.showMessage(fmt.Sprintf("Took %s off their goal", countTasks(len(ids))))
+	} else {
+		m.showMessage(fmt.Sprintf("Linked %s to %q", countTasks(len(ids)), title))
+	}
+}
+
+func (m model) renderGoalPicker() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(fmt.Sprintf("Link %s to", countTasks(len(m.tasksModel.bulkTargets)))) + "\n\n")
+	labels := []string{"No goal"}
+	for _, g := range m.goals.goals {
+		labels = append(labels, g.title)
+	}
+	for i, label := range labels {
+		if i == m.goals.cursor {
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+label) + "\n")
+		} else {
+			s.WriteString(itemStyle.Render(cursorMark(false)+label) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// updateGoals handles keys on the Goals tab.
+func (m *model) updateGoals(msg tea.KeyMsg) tea.Cmd {
+	g := &m.goals
+	if m.tasksModel.mode == insertMode {
+		switch msg.String() {
+		case "esc":
+		case "enter":
+			if value := strings.TrimSpace(g.input.Value()); value != "" {
+				title, due := parseTitleAndDue(value)
+				added := goal{title: title, dueAt: due, createdAt: time.Now()}
+				if err := m.store.AddGoal(&added); err != nil {
+					m.showError("add goal", err)
+				}
+				m.refreshGoals()
+				for i, existing := range g.goals {
+					if existing.id == added.id {
+						g.selected = i
+					}
+				}
+			}
+		default:
+			var cmd tea.Cmd
+			g.input, cmd = g.input.Update(msg)
+			return cmd
+		}
+		m.tasksModel.mode = normalMode
+		g.input.Blur()
+		return nil
+	}
+
+	switch msg.String() {
+	case "k", "up":
+		if g.selected > 0 {
+			g.selected--
+		}
+	case "j", "down":
+		if g.selected < len(g.goals)-1 {
+			g.selected++
+		}
+	case "enter":
+		g.open = !g.open
+	case "a":
+		g.input.Reset()
+		m.tasksModel.mode = insertMode
+		return g.input.Focus()
+	case "d":
+		if g.selected >= len(g.goals) {
+			return nil
+		}
+		removed := g.goals[g.selected]
+		if err := m.store.RemoveGoal(removed.id); err != nil {
+			m.showError("delete goal", err)
+		}
+		m.refreshGoals()
+		m.showMessage(fmt.Sprintf("Deleted goal %q, its tasks stay", removed.title))
+	}
+	return nil
+}
+
+func (m model) renderGoals() string {
+	g := m.goals
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Goals") + "\n\n")
+	if len(g.goals) == 0 {
+		s.WriteString(helpStyle.Render("No goals yet, press a to add one") + "\n")
+	}
+	now := time.Now()
+	for i, goal := range g.goals {
+		style := itemStyle.UnsetPaddingLeft()
+		if i == g.selected {
+			style = selectedItemStyle.UnsetPaddingLeft()
+		}
+		s.WriteString(style.Render(cursorMark(i == g.selected)+goal.title) + "\n")
+
+		percent := 0
+		if goal.tasks > 0 {
+			percent = goal.done * 100 / goal.tasks
+		}
+		filled := percent * goalBarWidth / 100
+		bar := barStyle.Render(strings.Repeat("█", filled)) + helpStyle.Render(strings.Repeat("░", goalBarWidth-filled))
+		details := []string{fmt.Sprintf("%d/%d tasks", goal.done, goal.tasks)}
+		if !goal.dueAt.IsZero() {
+			due := formatDueTime(goal.dueAt)
+			if goal.dueAt.Before(now) && (goal.tasks == 0 || goal.done < goal.tasks) {
+				due = overdueStyle.Render(due)
+			}
+			details = append(details, due)
+		}
+		if goal.estimate > 0 {
+			details = append(details, "~"+formatEstimate(time.Duration(goal.estimate)*time.Minute)+" of work left")
+		}
+		s.WriteString(fmt.Sprintf("%*s%s %3d%%  %s\n", cursorWidth(), "", bar, percent, helpStyle.Render(strings.Join(details, " · "))))
+
+		if i == g.selected && g.open {
+			tasks := m.goalTasks(goal.id)
+			if len(tasks) == 0 {
+				s.WriteString(helpStyle.Render(fmt.Sprintf("%*sNo tasks yet, link them with mg in the list", cursorWidth()+2, "")) + "\n")
+			}
+			for j, index := range tasks {
+				if j == goalTaskLimit {
+					s.WriteString(helpStyle.Render(fmt.Sprintf("%*s... and %d more", cursorWidth()+2, "", len(tasks)-j)) + "\n")
+					break
+				}
+				task := m.tasksModel.items[index]
+				line := fmt.Sprintf("%*s%s %s", cursorWidth()+2, "", statusMarker(task.status), task.title)
+				if task.status == done {
+					line = helpStyle.Render(line)
+				}
+				s.WriteString(line + "\n")
+			}
+		}
+		s.WriteString("\n")
+	}
+	if m.tasksModel.mode == insertMode {
+		s.WriteString(g.input.View() + "\n")
+	}
+	return s.String()
+}
+
+// goalsKeys lists the keys that apply to the Goals tab.
+func (m model) goalsKeys() string {
+	if m.tasksModel.mode == insertMode {
+		return "enter: add goal (due:YYYY-MM-DD or a date in words for the deadline) | esc: cancel"
+	}
+	return "Press 'h' and 'l' to switch tabs | j/k: move | enter: show tasks | a: add goal | d: delete goal | mg in the list: link tasks | q: quit"
+}
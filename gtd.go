@@ -0,0 +1,75 @@
+package main
+
+// GTD lists: new tasks land in the Inbox until they are triaged into Next
+// actions, Waiting for (someone else has the ball) or Someday/maybe. m
+// followed by i, n, w or s moves the selected task to a list, and w steps the
+// task list through the views of each list. Tasks added while a list is
+// shown go straight into it.
+
+type gtdList string
+
+const (
+	inbox      gtdList = "inbox"
+	nextAction gtdList = "next"
+	waiting    gtdList = "waiting"
+	someday    gtdList = "someday"
+)
+
+// gtdLists are the lists in the order w steps through them.
+var gtdLists = []gtdList{inbox, nextAction, waiting, someday}
+
+// triageKeys map the key after m to the list it moves tasks to.
+var triageKeys = map[string]gtdList{
+	"i": inbox,
+	"n": nextAction,
+	"w": waiting,
+	"s": someday,
+}
+
+func (l gtdList) title() string {
+	switch l {
+	case nextAction:
+		return "Next"
+	case waiting:
+		return "Waiting"
+	case someday:
+		return "Someday"
+	}
+	return "Inbox"
+}
+
+// nextListView returns the view after the current one: all tasks, then each
+// list in turn.
+func nextListView(view gtdList) gtdList {
+	if view == "" {
+		return gtdLists[0]
+	}
+	for i, l := range gtdLists {
+		if l == view && i+1 < len(gtdLists) {
+			return gtdLists[i+1]
+		}
+	}
+	return ""
+}
+
+// triage moves the tasks to a list as one undo step.
+func (m *model) triage(indices []int, list gtdList) {
+	before := m.snapshot()
+	var updates []item
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		if task.list != list {
+			task.list = list
+			updates = append(updates, *task)
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := m.store.Update(updates...); err != nil {
+		m.showError("update tasks", err)
+	}
+	m.record("triage", before)
+	m.showMessage("Moved to " + list.title())
+	m.tasksModel.clampSelection()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Completed tasks pile up over the years, so the list starts with every open
+// task and only the historyPage most recently completed ones. Moving the
+// cursor to the last row loads the next page, as does :more. A page always
+// brings whole trees along, the parents and subtasks of its tasks, so
+// deleting or moving a task never leaves part of it behind in the database.
+// Exports, stats and -list still see every task.
+
+const historyPage = 500 // Completed tasks loaded at a time
+
+// historyCursor is the oldest completed task of the last page loaded: the
+// next page starts after it. The zero cursor means the newest.
+type historyCursor struct {
+	completedAt time.Time
+	id          int
+}
+
+// taskPageMsg carries a page of tasks loaded by loadTasks or loadHistory.
+type taskPageMsg struct {
+	tasks []item
+	first bool          // Replaces the tasks rather than adding to them
+	next  historyCursor // Zero when no older completed tasks are left
+}
+
+func (s *sqliteStore) LoadPage(before historyCursor, limit int) ([]item, historyCursor, error) {
+	// Completed tasks without a completion time sort by when they were added
+	condition, args := "", []interface{}{done}
+	if before.id != 0 {
+		condition = ` AND (julianday(COALESCE(completed_at, created_at)) < julianday(?)
+			OR julianday(COALESCE(completed_at, created_at)) = julianday(?) AND id < ?)`
+		args = append(args, before.completedAt, before.completedAt, before.id)
+	}
+	rows, err := s.db.Query(`
+		SELECT id, completed_at, created_at FROM tasks
+		WHERE deleted_at IS NULL AND archived_at IS NULL AND status = ?`+condition+`
+		ORDER BY julianday(COALESCE(completed_at, created_at)) DESC, id DESC LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, historyCursor{}, err
+	}
+	defer rows.Close()
+
+	var ids []interface{}
+	var next historyCursor
+	for rows.Next() {
+		var completedAt sql.NullTime
+		var createdAt time.Time
+		if err := rows.Scan(&next.id, &completedAt, &createdAt); err != nil {
+			return nil, historyCursor{}, err
+		}
+		next.completedAt = createdAt
+		if completedAt.Valid {
+			next.completedAt = completedAt.Time
+		}
+		ids = append(ids, next.id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, historyCursor{}, err
+	}
+	if len(ids) < limit {
+		next = historyCursor{} // That was the last page
+	}
+
+	// The page, plus the open tasks on the first one, are the seeds. Walk up
+	// to the roots of their trees and back down to every subtask.
+	var seeds []string
+	var seedArgs []interface{}
+	if before.id == 0 {
+		seeds = append(seeds, "status != ?")
+		seedArgs = append(seedArgs, done)
+	}
+	if len(ids) > 0 {
+		seeds = append(seeds, "id IN (?"+strings.Repeat(", ?", len(ids)-1)+")")
+		seedArgs = append(seedArgs, ids...)
+	}
+	if len(seeds) == 0 {
+		return nil, next, nil
+	}
+	const live = "deleted_at IS NULL AND archived_at IS NULL"
+	tasks, err := s.query(live+` AND id IN (
+		WITH RECURSIVE
+			roots(id, parent_id) AS (
+				SELECT id, parent_id FROM tasks WHERE `+live+` AND (`+strings.Join(seeds, " OR ")+`)
+				UNION
+				SELECT tasks.id, tasks.parent_id FROM tasks JOIN roots ON tasks.id = roots.parent_id WHERE `+live+`
+			),
+			trees(id) AS (
+				SELECT id FROM roots
+				UNION
+				SELECT tasks.id FROM tasks JOIN trees ON tasks.parent_id = trees.id WHERE `+live+`
+			)
+		SELECT id FROM trees)`, "sort_order, id", seedArgs...)
+	if err != nil {
+		return nil, historyCursor{}, err
+	}
+	return tasks, next, nil
+}
+
+// loadHistory loads the next page of completed tasks, if there is one. A
+// page loaded twice is only added once.
+func (m model) loadHistory() tea.Cmd {
+	before := m.tasksModel.doneCursor
+	if before.id == 0 {
+		return nil
+	}
+	store := m.store
+	return func() tea.Msg {
+		tasks, next, err := store.LoadPage(before, historyPage)
+		if err != nil {
+			return errorMsg{"load completed tasks", err}
+		}
+		return taskPageMsg{tasks: tasks, next: next}
+	}
+}
+
+// loadHistoryAtEnd loads the next page once the cursor reaches the last row,
+// unless completed tasks are hidden and it would not show anyway.
+func (m model) loadHistoryAtEnd() tea.Cmd {
+	t := m.tasksModel
+	if t.hideDone || t.doneCursor.id == 0 || t.selected < len(t.rows())-1 {
+		return nil
+	}
+	return m.loadHistory()
+}
+
+// addPage adds the tasks of a page that are not loaded yet, keeping the
+// items in manual order.
+func (t *tasksModel) addPage(page taskPageMsg) {
+	t.doneCursor = page.next
+	if page.first {
+		t.items = page.tasks
+		return
+	}
+	loaded := make(map[int]bool, len(t.items))
+	for _, task := range t.items {
+		loaded[task.id] = true
+	}
+	for _, task := range page.tasks {
+		if !loaded[task.id] {
+			t.items = append(t.items, task)
+		}
+	}
+	sort.SliceStable(t.items, func(i, j int) bool {
+		if t.items[i].sortOrder != t.items[j].sortOrder {
+			return t.items[i].sortOrder < t.items[j].sortOrder
+		}
+		return t.items[i].id < t.items[j].id
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLoadPage(t *testing.T) {
+	m := newTestModel(t)
+	day := time.Date(2026, time.October, 1, 9, 0, 0, 0, time.UTC)
+	save := func(task item) item {
+		t.Helper()
+		if task.tags == nil {
+			task.tags = []string{}
+		}
+		if task.createdAt.IsZero() {
+			task.createdAt = day
+		}
+		if err := m.store.Save(&task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+
+	open := save(item{title: "Open"})
+	// Five completed tasks, two of them at the same time so the id breaks the tie
+	var completed []item
+	for i, at := range []time.Time{day, day.Add(time.Hour), day.Add(time.Hour), day.Add(2 * time.Hour), day.Add(3 * time.Hour)} {
+		completed = append(completed, save(item{title: "Done " + string(rune('A'+i)), status: done, completedAt: at}))
+	}
+	// An open parent whose only completed subtask is the oldest task
+	parent := save(item{title: "Parent"})
+	child := save(item{title: "Child", status: done, completedAt: day.Add(-time.Hour), parentID: parent.id})
+
+	ids := func(tasks []item) []int {
+		var ids []int
+		for _, task := range tasks {
+			ids = append(ids, task.id)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+	equal := func(got, want []int) bool {
+		sort.Ints(want)
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// The first page has the open tasks, their trees and the newest two
+	tasks, next, err := m.store.LoadPage(historyCursor{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{open.id, parent.id, child.id, completed[4].id, completed[3].id}; !equal(ids(tasks), want) {
+		t.Errorf("first page %v, want %v", ids(tasks), want)
+	}
+	if next.id != completed[3].id {
+		t.Errorf("first page continues after %d, want %d", next.id, completed[3].id)
+	}
+
+	// Then the two completed at the same time, newest id first
+	tasks, next, err = m.store.LoadPage(next, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{completed[2].id, completed[1].id}; !equal(ids(tasks), want) {
+		t.Errorf("second page %v, want %v", ids(tasks), want)
+	}
+
+	// The last page comes up short, brings the parent along and ends the paging
+	tasks, next, err = m.store.LoadPage(next, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{completed[0].id, child.id, parent.id}; !equal(ids(tasks), want) {
+		t.Errorf("last page %v, want %v", ids(tasks), want)
+	}
+	if next != (historyCursor{}) {
+		t.Errorf("last page continues after %d, want the zero cursor", next.id)
+	}
+}
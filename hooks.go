@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Hooks are scripts in ~/.config/xtui/hooks run when tasks change, in the
+// style of Taskwarrior: on-add when a task is added, on-done when one is
+// completed and on-delete when one is deleted. Every executable file whose
+// name starts with the event runs, in name order (on-add, on-add.notify.sh
+// and on-add-2.py all run for on-add), once per task, with the task as a
+// line of JSON on stdin in the format of the JSON export and the event in
+// XTUI_EVENT. Hooks run in the background after the change is saved, so a
+// slow one doesn't hold up the interface; a hook that exits with an error has
+// its output shown on the message line.
+
+const (
+	hookAdd    = "on-add"
+	hookDone   = "on-done"
+	hookDelete = "on-delete"
+)
+
+const hookTimeout = 30 * time.Second
+
+// hookRun is a task whose hooks are waiting to run.
+type hookRun struct {
+	event string
+	task  item
+}
+
+func hooksDir() string {
+	return filepath.Join(configDir(), "hooks")
+}
+
+// fireHook queues the hooks for the event on each task. Update starts them
+// once the message that caused the change is handled.
+func (m *model) fireHook(event string, tasks ...item) {
+	for _, task := range tasks {
+		m.pendingHooks = append(m.pendingHooks, hookRun{event: event, task: task})
+	}
+}
+
+// hookScripts returns the hooks in dir for the event.
+func hookScripts(dir, event string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var scripts []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), event) || !isExecutable(entry) {
+			continue
+		}
+		scripts = append(scripts, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(scripts)
+	return scripts
+}
+
+// isExecutable reports whether a directory entry is a program that can be
+// run. Any file counts on Windows.
+func isExecutable(entry os.DirEntry) bool {
+	if entry.IsDir() {
+		return false
+	}
+	info, err := entry.Info()
+	return err == nil && (runtime.GOOS == "windows" || info.Mode()&0o111 != 0)
+}
+
+// runHooks runs the queued hooks one after the other in the background,
+// stopping at the first that fails.
+func runHooks(dir string, runs []hookRun) tea.Cmd {
+	return func() tea.Msg {
+		for _, run := range runs {
+			data, err := json.Marshal(toJSONTask(run.task))
+			if err != nil {
+				return errorMsg{"run " + run.event + " hook", err}
+			}
+			for _, script := range hookScripts(dir, run.event) {
+				if err := runHook(script, run.event, append(data, '\n')); err != nil {
+					return errorMsg{"run hook " + filepath.Base(script), err}
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func runHook(script, event string, input []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Dir = filepath.Dir(script)
+	cmd.Env = append(os.Environ(), "XTUI_EVENT="+event)
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return errors.New("timed out")
+	}
+	if err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return errors.New(message)
+		}
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// With a Nerd Font (nerdfonts.com) in the terminal, nerd_font = true in the
+// [display] section of the config swaps the checkboxes, tag and priority
+// markers for its icons and puts an icon before the name of each tab.
+// Without it, or in the accessible mode, the usual markers stay, since the
+// icons are private-use characters other fonts show as boxes.
+
+// iconSet holds the markers the interface draws things with.
+type iconSet struct {
+	todo, doing, done string // Checkboxes for each status
+	cursor            string // In front of the selected row
+	tag               string // In front of a tag's name
+	priority          string // In front of a priority level
+	star              string // After the title of a starred task
+	bracketTags       bool   // The list shows tags as [home, work] rather than after tag icons
+	tabs              map[int]string
+}
+
+var unicodeIcons = iconSet{
+	todo:        "[ ]",
+	doing:       "[~]",
+	done:        "[✓]",
+	cursor:      "▸",
+	tag:         "#",
+	priority:    "!",
+	star:        "★",
+	bracketTags: true,
+}
+
+var nerdIcons = iconSet{
+	todo:     "\uf096",  // nf-fa-square_o
+	doing:    "\uf147",  // nf-fa-minus_square_o
+	done:     "\uf046",  // nf-fa-check_square_o
+	cursor:   "\uf054",  // nf-fa-chevron_right
+	tag:      "\uf02b ", // nf-fa-tag
+	priority: "\uf024 ", // nf-fa-flag
+	star:     "\uf005",  // nf-fa-star
+	tabs: map[int]string{
+		Tasks:    "\uf0ae",
+		Agenda:   "\uf073",
+		Calendar: "\uf133",
+		Board:    "\uf0db",
+		Week:     "\uf274",
+		Goals:    "\uf140",
+		Trash:    "\uf1f8",
+		Pomodoro: "\uf017",
+		Stats:    "\uf080",
+		Activity: "\uf1da",
+		User:     "\uf007",
+		About:    "\uf05a",
+	},
+}
+
+// icons are the markers in use, set from the config by setIcons.
+var icons = unicodeIcons
+
+// setIcons picks the icons for the config, with the markers it sets in
+// place of theirs. It runs after setAccessible.
+func setIcons(cfg config) {
+	icons = unicodeIcons
+	if cfg.nerdFont && !accessible {
+		icons = nerdIcons
+	}
+	for marker, value := range map[*string]string{
+		&icons.todo:   cfg.todoMarker,
+		&icons.doing:  cfg.doingMarker,
+		&icons.done:   cfg.doneMarker,
+		&icons.cursor: cfg.cursor,
+	} {
+		if value != "" {
+			*marker = value
+		}
+	}
+}
+
+// marker returns the checkbox of a status.
+func (set iconSet) marker(s status) string {
+	switch s {
+	case done:
+		return set.done
+	case doing:
+		return set.doing
+	}
+	return set.todo
+}
+
+// markerWidth is the width of the widest checkbox, which the others are
+// padded to so titles line up.
+func markerWidth() int {
+	return max(lipgloss.Width(icons.todo), lipgloss.Width(icons.doing), lipgloss.Width(icons.done))
+}
+
+// statusMarker returns the checkbox of a status, padded to markerWidth.
+func statusMarker(s status) string {
+	marker := icons.marker(s)
+	return marker + strings.Repeat(" ", markerWidth()-lipgloss.Width(marker))
+}
+
+// cursorMark is the cursor and a space in front of the selected row, or
+// blanks as wide in front of the others.
+func cursorMark(selected bool) string {
+	if selected {
+		return icons.cursor + " "
+	}
+	return strings.Repeat(" ", cursorWidth())
+}
+
+// cursorWidth is the width of cursorMark.
+func cursorWidth() int {
+	return lipgloss.Width(icons.cursor) + 1
+}
+
+// tagLabel shows a tag's name after the tag marker.
+func tagLabel(tag string) string {
+	return icons.tag + tag
+}
+
+// tagLabels shows a task's tags the way the list does.
+func tagLabels(tags []string) string {
+	if icons.bracketTags {
+		return "[" + strings.Join(tags, ", ") + "]"
+	}
+	labels := make([]string, len(tags))
+	for i, tag := range tags {
+		labels[i] = tagLabel(tag)
+	}
+	return strings.Join(labels, " ")
+}
+
+// tabLabel puts the icon of a tab, if it has one, before its name.
+func tabLabel(name string, section int) string {
+	if icon, ok := icons.tabs[section]; ok {
+		return icon + " " + name
+	}
+	return name
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iCalendar (RFC 5545) export of tasks with a due date. Tasks are written as
+// VTODO components, which task and reminder apps understand. Calendar apps
+// that ignore VTODO can subscribe to the VEVENT feed of -serve-ics instead.
+
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405Z"
+)
+
+func writeICS(w io.Writer, tasks []item) error {
+	return writeCalendar(w, tasks, "VTODO")
+}
+
+// writeCalendar writes the due-dated tasks as components of the given kind,
+// VTODO or VEVENT. Events are only written for open tasks.
+func writeCalendar(w io.Writer, tasks []item, component string) error {
+	out := icsWriter{bufio.NewWriter(w)}
+	out.begin()
+	for _, task := range tasks {
+		if task.dueAt.IsZero() || (component == "VEVENT" && task.status == done) {
+			continue
+		}
+		out.component(task, component, fmt.Sprintf("%s-%d@xtui", strings.ToLower(component), task.id), "")
+	}
+	out.end()
+	return out.Flush()
+}
+
+// icsWriter writes iCalendar content lines.
+type icsWriter struct {
+	*bufio.Writer
+}
+
+func (out icsWriter) line(s string) {
+	// Lines longer than 75 octets are folded onto continuation lines
+	for len(s) > 75 {
+		cut := 75
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut-- // Don't split a UTF-8 sequence
+		}
+		out.WriteString(s[:cut] + "\r\n")
+		s = " " + s[cut:]
+	}
+	out.WriteString(s + "\r\n")
+}
+
+func (out icsWriter) begin() {
+	out.line("BEGIN:VCALENDAR")
+	out.line("VERSION:2.0")
+	out.line("PRODID:-//xtui//tasks//EN")
+	out.line("CALSCALE:GREGORIAN")
+	out.line("X-WR-CALNAME:Xtui")
+}
+
+func (out icsWriter) end() {
+	out.line("END:VCALENDAR")
+}
+
+// component writes one task. parentUID links a subtask to its parent with
+// RELATED-TO.
+func (out icsWriter) component(task item, component, uid, parentUID string) {
+	out.line("BEGIN:" + component)
+	out.line("UID:" + uid)
+	out.line("DTSTAMP:" + time.Now().UTC().Format(icsDateTime))
+	out.line("SUMMARY:" + escapeICS(task.title))
+	if !task.createdAt.IsZero() {
+		out.line("CREATED:" + task.createdAt.UTC().Format(icsDateTime))
+	}
+
+	if !task.dueAt.IsZero() {
+		property := "DUE"
+		if component == "VEVENT" {
+			property = "DTSTART"
+		}
+		if isEndOfDay(task.dueAt) {
+			out.line(property + ";VALUE=DATE:" + task.dueAt.Format(icsDate))
+		} else {
+			out.line(property + ":" + task.dueAt.UTC().Format(icsDateTime))
+		}
+	}
+
+	if len(task.tags) > 0 {
+		tags := make([]string, len(task.tags))
+		for i, tag := range task.tags {
+			tags[i] = escapeICS(tag)
+		}
+		out.line("CATEGORIES:" + strings.Join(tags, ","))
+	}
+	if task.notes != "" {
+		out.line("DESCRIPTION:" + escapeICS(task.notes))
+	}
+	if task.recurrence != "" {
+		out.line("RRULE:" + task.recurrence)
+	}
+	if parentUID != "" {
+		out.line("RELATED-TO;RELTYPE=PARENT:" + parentUID)
+	}
+	if component == "VTODO" {
+		if priority := clampPriority(task.priority); priority != 0 {
+			// iCalendar priorities run from 1 (highest) to 9
+			out.line(fmt.Sprintf("PRIORITY:%d", []int{1, 5, 9}[priority-1]))
+		}
+		if task.status == done {
+			out.line("STATUS:COMPLETED")
+			if !task.completedAt.IsZero() {
+				out.line("COMPLETED:" + task.completedAt.UTC().Format(icsDateTime))
+			}
+		} else if task.status == doing {
+			out.line("STATUS:IN-PROCESS")
+		} else {
+			out.line("STATUS:NEEDS-ACTION")
+		}
+	}
+	out.line("END:" + component)
+}
+
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// serveICS serves the due-dated tasks at /tasks.ics (VTODO) and /events.ics
+// (VEVENT) so calendar apps can subscribe to them. The database is read on
+// every request, so the feeds follow changes made in the interface.
+func serveICS(addr string) error {
+	m := newModel()
+	defer m.close()
+
+	feed := func(component string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tasks, err := m.store.Load(liveTasks)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].dueAt.Before(tasks[j].dueAt) })
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			writeCalendar(w, tasks, component)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks.ics", feed("VTODO"))
+	mux.HandleFunc("/events.ics", feed("VEVENT"))
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	fmt.Fprintf(os.Stderr, "Serving http://%s/tasks.ics and http://%s/events.ics\n", addr, addr)
+	return serveUntilInterrupted(server)
+}
+
+func unescapeICS(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// icsProperty is one unfolded content line, e.g. DUE;VALUE=DATE:20240501.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseICSLines(data string) []icsProperty {
+	var lines []string
+	for _, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(raw, " ") || strings.HasPrefix(raw, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += raw[1:] // Unfold continuation lines
+			continue
+		}
+		lines = append(lines, raw)
+	}
+
+	var props []icsProperty
+	for _, line := range lines {
+		head, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		parts := strings.Split(head, ";")
+		p := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+		for _, param := range parts[1:] {
+			key, v, _ := strings.Cut(param, "=")
+			p.params[strings.ToUpper(key)] = strings.Trim(v, `"`)
+		}
+		props = append(props, p)
+	}
+	return props
+}
+
+// time parses a DATE or DATE-TIME value. Dates are due at the end of the
+// day like date-only due: tokens.
+func (p icsProperty) time() time.Time {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(icsDate) {
+		t, err := time.ParseInLocation(icsDate, p.value, time.Local)
+		if err != nil {
+			return time.Time{}
+		}
+		return endOfDay(t)
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, _ := time.Parse(icsDateTime, p.value)
+		return t
+	}
+	loc := time.Local
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, _ := time.ParseInLocation(strings.TrimSuffix(icsDateTime, "Z"), p.value, loc)
+	return t
+}
+
+// readVTODO returns the first VTODO of an iCalendar object together with its
+// UID and the UID of its parent.
+func readVTODO(data string) (task item, uid, parentUID string, ok bool) {
+	task = item{status: todo, tags: []string{}, createdAt: time.Now()}
+	inside := false
+	for _, p := range parseICSLines(data) {
+		switch {
+		case p.name == "BEGIN" && p.value == "VTODO":
+			inside = true
+		case p.name == "END" && p.value == "VTODO":
+			return task, uid, parentUID, true
+		case !inside:
+		case p.name == "UID":
+			uid = p.value
+		case p.name == "SUMMARY":
+			task.title = unescapeICS(p.value)
+		case p.name == "DESCRIPTION":
+			task.notes = unescapeICS(p.value)
+		case p.name == "CATEGORIES":
+			for _, tag := range strings.Split(p.value, ",") {
+				if tag = unescapeICS(strings.TrimSpace(tag)); tag != "" {
+					task.tags = append(task.tags, strings.ReplaceAll(tag, " ", "-"))
+				}
+			}
+		case p.name == "DUE":
+			task.dueAt = p.time()
+		case p.name == "CREATED":
+			if created := p.time(); !created.IsZero() {
+				task.createdAt = created
+			}
+		case p.name == "STATUS":
+			switch p.value {
+			case "COMPLETED":
+				task.status = done
+			case "IN-PROCESS":
+				task.status = doing
+			}
+		case p.name == "COMPLETED":
+			task.completedAt = p.time()
+		case p.name == "PRIORITY":
+			switch n, _ := strconv.Atoi(p.value); {
+			case n >= 1 && n <= 4:
+				task.priority = 1
+			case n == 5:
+				task.priority = 2
+			case n >= 6 && n <= 9:
+				task.priority = 3
+			}
+		case p.name == "RRULE":
+			if _, err := parseRule(p.value); err == nil {
+				task.recurrence = p.value
+			}
+		case p.name == "RELATED-TO":
+			if reltype := p.params["RELTYPE"]; reltype == "" || reltype == "PARENT" {
+				parentUID = p.value
+			}
+		}
+	}
+	return task, uid, parentUID, false
+}
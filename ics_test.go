@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateOnlyDueOnDSTChange(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	local := time.Local
+	time.Local = loc
+	defer func() { time.Local = local }()
+
+	// The clocks go forward on 8 March and back on 1 November 2026
+	for _, day := range []string{"2026-03-08", "2026-11-01"} {
+		due := parseDue("due:" + day)
+		if due.Format("2006-01-02 15:04:05") != day+" 23:59:59" || !isEndOfDay(due) {
+			t.Errorf("due:%s is due at %v, want the end of the day", day, due)
+		}
+
+		task := readICSDue(t, strings.ReplaceAll(day, "-", ""))
+		if !task.dueAt.Equal(due) {
+			t.Errorf("DUE;VALUE=DATE on %s read as %v, want %v", day, task.dueAt, due)
+		}
+		var out bytes.Buffer
+		if err := writeICS(&out, []item{task}); err != nil {
+			t.Fatal(err)
+		}
+		if want := "DUE;VALUE=DATE:" + strings.ReplaceAll(day, "-", ""); !strings.Contains(out.String(), want) {
+			t.Errorf("exported without %s:\n%s", want, out.String())
+		}
+	}
+}
+
+func readICSDue(t *testing.T, date string) item {
+	t.Helper()
+	task, _, _, ok := readVTODO("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:a@xtui\r\nSUMMARY:Pay taxes\r\nDUE;VALUE=DATE:" + date + "\r\nEND:VTODO\r\nEND:VCALENDAR\r\n")
+	if !ok {
+		t.Fatal("no VTODO read")
+	}
+	return task
+}
@@ -11,7 +11,6 @@ GO_DEPENDENCIES=(
   "github.com/charmbracelet/bubbles/textinput"
   "github.com/charmbracelet/lipgloss"
   "github.com/mattn/go-sqlite3"
-  "github.com/joho/godotenv" # Added for .env support
 )
 
 # Function to install a dependency if it's not already installed
@@ -138,18 +137,6 @@ else
   exit 1
 fi
 
-# Create the .env file
-echo "Creating .env file at $ASSETS_DIR..."
-sudo bash -c "cat <<EOL > $ASSETS_DIR/.env
-DATABASE_PATH=$ASSETS_DIR/tui-do.db
-ASCII_ART_PATH=$ASSETS_DIR/faqs_ascii.txt
-EOL"
-
-if [[ $? -ne 0 ]]; then
-  echo "Error: Failed to create .env file."
-  exit 1
-fi
-
 # Install the program
 echo "Installing $PROGRAM_NAME to $INSTALL_DIR..."
 sudo mv $PROGRAM_NAME $INSTALL_DIR/
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Credentials are kept in the OS keyring through its command line tools:
+// secret-tool (libsecret) on Linux and security on macOS. Where neither is
+// available they go to a credentials file in the config directory that only
+// the user can read.
+
+const keyringService = "xtui"
+
+var errNoCredential = errors.New("no stored credential")
+
+func keyringGet(name string) (string, error) {
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", name, "-w")
+	case hasCommand("secret-tool"):
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", name)
+	}
+	if cmd != nil {
+		if out, err := cmd.Output(); err == nil && len(out) > 0 {
+			return strings.TrimRight(string(out), "\n"), nil
+		}
+	}
+	credentials, err := readCredentialsFile()
+	if err != nil {
+		return "", err
+	}
+	if secret, ok := credentials[name]; ok {
+		return secret, nil
+	}
+	return "", errNoCredential
+}
+
+func keyringSet(name, secret string) error {
+	var cmd *exec.Cmd
+	switch {
+	case runtime.GOOS == "darwin":
+		// The command goes to security -i on stdin, so the secret doesn't
+		// show up in ps
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keyringService), securityQuote(name), securityQuote(secret)))
+	case hasCommand("secret-tool"):
+		// The secret is read from stdin so it doesn't show up in ps
+		cmd = exec.Command("secret-tool", "store", "--label=Xtui sync", "service", keyringService, "account", name)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	// security -i exits cleanly when the command in it fails, so the secret
+	// is read back to check it was stored
+	if cmd != nil && cmd.Run() == nil {
+		if stored, err := keyringGet(name); err == nil && stored == secret {
+			return nil
+		}
+	}
+
+	// No usable keyring, e.g. no Secret Service running
+	credentials, err := readCredentialsFile()
+	if err != nil {
+		return err
+	}
+	credentials[name] = secret
+	return writeCredentialsFile(credentials)
+}
+
+// securityQuote quotes an argument for the command line security -i reads.
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func keyringDelete(name string) error {
+	switch {
+	case runtime.GOOS == "darwin":
+		exec.Command("security", "delete-generic-password", "-s", keyringService, "-a", name).Run()
+	case hasCommand("secret-tool"):
+		exec.Command("secret-tool", "clear", "service", keyringService, "account", name).Run()
+	}
+	credentials, err := readCredentialsFile()
+	if err != nil {
+		return err
+	}
+	if _, ok := credentials[name]; !ok {
+		return nil
+	}
+	delete(credentials, name)
+	return writeCredentialsFile(credentials)
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+func credentialsPath() string {
+	return filepath.Join(configDir(), "credentials.json")
+}
+
+func readCredentialsFile() (map[string]string, error) {
+	credentials := make(map[string]string)
+	data, err := os.ReadFile(credentialsPath())
+	if os.IsNotExist(err) {
+		return credentials, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func writeCredentialsFile(credentials map[string]string) error {
+	data, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir(), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(credentialsPath(), data, 0o600)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A task refers to another by writing its id in double brackets, [[42]], in
+// its title or notes. The detail view shows the id of the task, the tasks it
+// links to and the ones linking to it. f jumps to the first task the selected
+// one links to and F back to the first one linking to it.
+
+var linkPattern = regexp.MustCompile(`\[\[(\d+)\]\]`)
+
+// parseLinks returns the ids linked from the text, in order and without
+// repeats.
+func parseLinks(text string) []int {
+	var ids []int
+	seen := make(map[int]bool)
+	for _, match := range linkPattern.FindAllStringSubmatch(text, -1) {
+		id, err := strconv.Atoi(match[1])
+		if err == nil && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// links returns the indices of the tasks a task links to, leaving out links
+// to itself and to tasks that are gone.
+func (t tasksModel) links(task item) []int {
+	var indices []int
+	for _, id := range parseLinks(task.title + "\n" + task.notes) {
+		if index := t.indexOf(id); index >= 0 && id != task.id {
+			indices = append(indices, index)
+		}
+	}
+	return indices
+}
+
+// backlinks returns the indices of the tasks linking to a task.
+func (t tasksModel) backlinks(id int) []int {
+	var indices []int
+	for i, task := range t.items {
+		if task.id == id {
+			continue
+		}
+		for _, linked := range parseLinks(task.title + "\n" + task.notes) {
+			if linked == id {
+				indices = append(indices, i)
+				break
+			}
+		}
+	}
+	return indices
+}
+
+// jumpTo selects a task, clearing the filters and unfolding its parents if
+// they hide it.
+func (m *model) jumpTo(index int) {
+	t := &m.tasksModel
+	task := t.items[index]
+	if task.status == done && t.hideDone {
+		m.showMessage(fmt.Sprintf("%q is done and completed tasks are hidden", task.title))
+		return
+	}
+	if !t.matches(task) {
+		t.query, t.tagFilter, t.contextFilter, t.listView = "", "", "", ""
+		t.focus = false
+		if task.list == someday {
+			t.listView = someday
+		}
+	}
+	for parent := task.parentID; parent != 0; {
+		t.collapsed[parent] = false
+		i := t.indexOf(parent)
+		if i < 0 {
+			break
+		}
+		parent = t.items[i].parentID
+	}
+	t.selectID(task.id)
+}
+
+// followLink jumps to the first task the selected one links to, or back to
+// the first one linking to it.
+func (m *model) followLink(back bool) {
+	index := m.tasksModel.selectedIndex()
+	if index < 0 {
+		return
+	}
+	targets := m.tasksModel.links(m.tasksModel.items[index])
+	if back {
+		targets = m.tasksModel.backlinks(m.tasksModel.items[index].id)
+	}
+	if len(targets) == 0 {
+		if back {
+			m.showMessage("No task links here")
+		} else {
+			m.showMessage("No links, write [[id]] in the title or notes")
+		}
+		return
+	}
+	m.jumpTo(targets[0])
+}
+
+// renderLinks lists the tasks a task links to and the ones linking to it.
+func (m model) renderLinks(task item) string {
+	var s strings.Builder
+	for _, section := range []struct {
+		title   string
+		indices []int
+	}{
+		{"Links to", m.tasksModel.links(task)},
+		{"Linked from", m.tasksModel.backlinks(task.id)},
+	} {
+		if len(section.indices) == 0 {
+			continue
+		}
+		s.WriteString(helpStyle.Render(section.title) + "\n")
+		for _, index := range section.indices {
+			linked := m.tasksModel.items[index]
+			s.WriteString(fmt.Sprintf("  %s %s %s\n", statusMarker(linked.status), linked.title, helpStyle.Render(fmt.Sprintf("[[%d]]", linked.id))))
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// writeMarkdown writes the tasks as a GitHub-style checklist. Subtasks are
+// nested under their parent and notes follow their task as indented lines.
+// The task text uses the same #tag, due: and every: syntax as the input.
+func writeMarkdown(w io.Writer, tasks []item) error {
+	ids := make(map[int]bool, len(tasks))
+	for _, task := range tasks {
+		ids[task.id] = true
+	}
+	children := make(map[int][]item)
+	var roots []item
+	for _, task := range tasks {
+		if task.parentID != 0 && ids[task.parentID] && task.parentID != task.id {
+			children[task.parentID] = append(children[task.parentID], task)
+		} else {
+			roots = append(roots, task)
+		}
+	}
+
+	out := bufio.NewWriter(w)
+	var write func(task item, depth int)
+	write = func(task item, depth int) {
+		indent := strings.Repeat("  ", depth)
+		box := "[ ]"
+		if task.status == done {
+			box = "[x]"
+		}
+		fmt.Fprintf(out, "%s- %s %s\n", indent, box, formatTaskInput(task))
+		if task.notes != "" {
+			for _, line := range strings.Split(task.notes, "\n") {
+				fmt.Fprintf(out, "%s  %s\n", indent, line)
+			}
+		}
+		for _, child := range children[task.id] {
+			write(child, depth+1)
+		}
+	}
+	for _, task := range roots {
+		write(task, 0)
+	}
+	return out.Flush()
+}
+
+// readMarkdown reads checklist items ("- [ ]", "- [x]", "* [X]" ...). Items
+// indented below another item become its subtasks, other indented lines
+// become its notes, and everything else is ignored.
+func readMarkdown(r io.Reader) ([]item, error) {
+	type level struct {
+		indent int
+		id     int
+	}
+	var (
+		tasks []item
+		stack []level
+	)
+	now := time.Now()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.ReplaceAll(scanner.Text(), "\t", "    ")
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+
+		checked, rest, ok := parseCheckbox(text)
+		if !ok {
+			if len(tasks) > 0 && indent > 0 && strings.TrimSpace(text) != "" {
+				last := &tasks[len(tasks)-1]
+				if last.notes != "" {
+					last.notes += "\n"
+				}
+				last.notes += strings.TrimSpace(text)
+			}
+			continue
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		task := item{
+			id:         len(tasks) + 1, // Placeholder, remapped on import
+			title:      removePriority(removeRecurrence(removeDue(removeTags(rest)))),
+			tags:       parseTags(rest),
+			status:     todo,
+			createdAt:  now,
+			dueAt:      parseDue(rest),
+			recurrence: parseRecurrence(rest),
+			priority:   parsePriority(rest),
+			sortOrder:  len(tasks) + 1,
+		}
+		if checked {
+			task.status = done
+			task.completedAt = now
+		}
+		if len(stack) > 0 {
+			task.parentID = stack[len(stack)-1].id
+		}
+		stack = append(stack, level{indent: indent, id: task.id})
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// parseCheckbox splits a list item such as "- [x] title" into its state
+// and text.
+func parseCheckbox(text string) (checked bool, rest string, ok bool) {
+	if len(text) < 6 || (text[0] != '-' && text[0] != '*' && text[0] != '+') || text[1] != ' ' {
+		return false, "", false
+	}
+	switch text[2:5] {
+	case "[ ]":
+	case "[x]", "[X]":
+		checked = true
+	default:
+		return false, "", false
+	}
+	return checked, strings.TrimSpace(text[5:]), true
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Xtui sync and git sync merge a task edited on two devices field by field,
+// against the version both had at the last sync: if one device changed the
+// title and the other the due date, the task gets both changes. Only when
+// both changed the same field does one lose, and the device that changed the
+// task last wins, comparing the values when the times are equal, so every
+// device picks the same one. The losing value is not thrown away: :conflicts
+// lists them, enter swaps one in and d drops it.
+
+const conflictsMode = "conflicts"
+
+// syncField is a part of a task merged on its own.
+type syncField struct {
+	name string
+	get  func(t cloudTask) interface{}
+	set  func(to *cloudTask, from cloudTask)
+	show func(t cloudTask) string
+}
+
+var syncFields = []syncField{
+	{
+		name: "title",
+		get:  func(t cloudTask) interface{} { return t.Title },
+		set:  func(to *cloudTask, from cloudTask) { to.Title = from.Title },
+		show: func(t cloudTask) string { return t.Title },
+	},
+	{
+		name: "tags",
+		get:  func(t cloudTask) interface{} { return t.Tags },
+		set:  func(to *cloudTask, from cloudTask) { to.Tags = from.Tags },
+		show: func(t cloudTask) string {
+			if len(t.Tags) == 0 {
+				return "no tags"
+			}
+			return "#" + strings.Join(t.Tags, " #")
+		},
+	},
+	{
+		name: "status",
+		get:  func(t cloudTask) interface{} { return []interface{}{t.Done, t.Doing, t.CompletedAt} },
+		set: func(to *cloudTask, from cloudTask) {
+			to.Done, to.Doing, to.CompletedAt = from.Done, from.Doing, from.CompletedAt
+		},
+		show: func(t cloudTask) string {
+			switch {
+			case t.Done:
+				return "done"
+			case t.Doing:
+				return "doing"
+			}
+			return "todo"
+		},
+	},
+	{
+		name: "due",
+		get:  func(t cloudTask) interface{} { return t.DueAt },
+		set:  func(to *cloudTask, from cloudTask) { to.DueAt = from.DueAt },
+		show: func(t cloudTask) string {
+			if t.DueAt == nil {
+				return "no due date"
+			}
+			return t.DueAt.Local().Format("2006-01-02 15:04")
+		},
+	},
+	{
+		name: "start",
+		get:  func(t cloudTask) interface{} { return t.StartAt },
+		set:  func(to *cloudTask, from cloudTask) { to.StartAt = from.StartAt },
+		show: func(t cloudTask) string {
+			if t.StartAt == nil {
+				return "no start date"
+			}
+			return t.StartAt.Local().Format("2006-01-02 15:04")
+		},
+	},
+	{
+		name: "planned",
+		get:  func(t cloudTask) interface{} { return t.PlannedFor },
+		set:  func(to *cloudTask, from cloudTask) { to.PlannedFor = from.PlannedFor },
+		show: func(t cloudTask) string {
+			if t.PlannedFor == nil {
+				return "not planned"
+			}
+			return t.PlannedFor.Local().Format("2006-01-02")
+		},
+	},
+	{
+		name: "repeat",
+		get:  func(t cloudTask) interface{} { return t.Recurrence },
+		set:  func(to *cloudTask, from cloudTask) { to.Recurrence = from.Recurrence },
+		show: func(t cloudTask) string { return orNone(t.Recurrence) },
+	},
+	{
+		name: "notes",
+		get:  func(t cloudTask) interface{} { return t.Notes },
+		set:  func(to *cloudTask, from cloudTask) { to.Notes = from.Notes },
+		show: func(t cloudTask) string {
+			first, _, more := strings.Cut(t.Notes, "\n")
+			if more {
+				first += " ..."
+			}
+			return orNone(first)
+		},
+	},
+	{
+		name: "priority",
+		get:  func(t cloudTask) interface{} { return t.Priority },
+		set:  func(to *cloudTask, from cloudTask) { to.Priority = from.Priority },
+		show: func(t cloudTask) string {
+			if t.Priority == 0 {
+				return "none"
+			}
+			return fmt.Sprintf("p%d", t.Priority)
+		},
+	},
+	{
+		name: "estimate",
+		get:  func(t cloudTask) interface{} { return t.Estimate },
+		set:  func(to *cloudTask, from cloudTask) { to.Estimate = from.Estimate },
+		show: func(t cloudTask) string {
+			if t.Estimate == 0 {
+				return "none"
+			}
+			return formatEstimate(time.Duration(t.Estimate) * time.Minute)
+		},
+	},
+	{
+		name: "starred",
+		get:  func(t cloudTask) interface{} { return t.Starred },
+		set:  func(to *cloudTask, from cloudTask) { to.Starred = from.Starred },
+		show: func(t cloudTask) string {
+			if t.Starred {
+				return "starred"
+			}
+			return "not starred"
+		},
+	},
+	{
+		name: "context",
+		get:  func(t cloudTask) interface{} { return t.Context },
+		set:  func(to *cloudTask, from cloudTask) { to.Context = from.Context },
+		show: func(t cloudTask) string { return orNone(t.Context) },
+	},
+	{
+		name: "project",
+		get:  func(t cloudTask) interface{} { return t.Project },
+		set:  func(to *cloudTask, from cloudTask) { to.Project = from.Project },
+		show: func(t cloudTask) string { return orNone(t.Project) },
+	},
+	{
+		name: "list",
+		get:  func(t cloudTask) interface{} { return t.List },
+		set:  func(to *cloudTask, from cloudTask) { to.List = from.List },
+		show: func(t cloudTask) string { return orNone(string(t.List)) },
+	},
+	{
+		name: "parent",
+		get:  func(t cloudTask) interface{} { return t.ParentUID },
+		set:  func(to *cloudTask, from cloudTask) { to.ParentUID = from.ParentUID },
+		show: func(t cloudTask) string {
+			if t.ParentUID == "" {
+				return "top level"
+			}
+			return "a subtask"
+		},
+	},
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+// fieldValue encodes a field of a task for comparison.
+func fieldValue(f syncField, t cloudTask) string {
+	b, _ := json.Marshal(f.get(t.normalized()))
+	return string(b)
+}
+
+func findSyncField(name string) (syncField, bool) {
+	for _, f := range syncFields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return syncField{}, false
+}
+
+// syncConflict is a field changed on two devices, with the version that
+// lost.
+type syncConflict struct {
+	id     int
+	taskID int
+	field  string
+	other  cloudTask // The task as the losing device had it
+	at     time.Time
+}
+
+// mergeCloudTask merges the local and remote versions of a task changed on
+// both sides since base, returning the merge and the fields that conflicted.
+func mergeCloudTask(local, remote, base cloudTask) (cloudTask, []syncConflict) {
+	merged := remote
+	var conflicts []syncConflict
+	for _, f := range syncFields {
+		l, r, b := fieldValue(f, local), fieldValue(f, remote), fieldValue(f, base)
+		switch {
+		case l == r || l == b: // Only changed remotely, if at all
+		case r == b: // Only changed here
+			f.set(&merged, local)
+		case local.UpdatedAt.After(remote.UpdatedAt) || local.UpdatedAt.Equal(remote.UpdatedAt) && l > r:
+			f.set(&merged, local)
+			conflicts = append(conflicts, syncConflict{field: f.name, other: remote})
+		default:
+			conflicts = append(conflicts, syncConflict{field: f.name, other: local})
+		}
+	}
+	if local.UpdatedAt.After(merged.UpdatedAt) {
+		merged.UpdatedAt = local.UpdatedAt
+	}
+	return merged, conflicts
+}
+
+func createConflictsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS conflicts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			field TEXT NOT NULL,
+			other TEXT NOT NULL,
+			created_at DATETIME
+		);
+	`)
+	return err
+}
+
+func (s *sqliteStore) SaveConflict(taskID int, c syncConflict) error {
+	other, err := json.Marshal(c.other)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO conflicts (task_id, field, other, created_at) VALUES (?, ?, ?, ?)", taskID, c.field, string(other), time.Now())
+	return err
+}
+
+// Conflicts returns the conflicts of live tasks, oldest first.
+func (s *sqliteStore) Conflicts() ([]syncConflict, error) {
+	rows, err := s.db.Query(`
+		SELECT c.id, c.task_id, c.field, c.other, c.created_at
+		FROM conflicts c JOIN tasks t ON t.id = c.task_id
+		WHERE t.deleted_at IS NULL
+		ORDER BY c.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var conflicts []syncConflict
+	for rows.Next() {
+		var c syncConflict
+		var other string
+		if err := rows.Scan(&c.id, &c.taskID, &c.field, &other, &c.at); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(other), &c.other); err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, c)
+	}
+	return conflicts, rows.Err()
+}
+
+func (s *sqliteStore) DropConflict(id int) error {
+	_, err := s.db.Exec("DELETE FROM conflicts WHERE id = ?", id)
+	return err
+}
+
+// openConflicts lists the conflicts left by syncing.
+func (m *model) openConflicts() error {
+	conflicts, err := m.store.Conflicts()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		m.showMessage("No sync conflicts")
+		return nil
+	}
+	m.conflicts = conflictsState{items: conflicts}
+	m.tasksModel.mode = conflictsMode
+	return nil
+}
+
+// conflictsState is the conflict list being shown.
+type conflictsState struct {
+	items  []syncConflict
+	cursor int
+}
+
+// updateConflicts handles keys in the conflict list.
+func (m *model) updateConflicts(key string) tea.Cmd {
+	c := &m.conflicts
+	switch key {
+	case "esc", "q":
+		m.tasksModel.mode = normalMode
+		return nil
+	case "j", "down":
+		c.cursor = min(c.cursor+1, len(c.items)-1)
+		return nil
+	case "k", "up":
+		c.cursor = max(c.cursor-1, 0)
+		return nil
+	case "enter": // Use the value that lost instead
+		if err := m.takeConflict(c.items[c.cursor]); err != nil {
+			m.showError("resolve conflict", err)
+			return nil
+		}
+	case "d": // Keep the value that won
+	default:
+		return nil
+	}
+	if err := m.store.DropConflict(c.items[c.cursor].id); err != nil {
+		m.showError("resolve conflict", err)
+	}
+	c.items = append(c.items[:c.cursor], c.items[c.cursor+1:]...)
+	if len(c.items) == 0 {
+		m.tasksModel.mode = normalMode
+		m.showMessage("All conflicts resolved")
+	}
+	c.cursor = min(c.cursor, max(len(c.items)-1, 0))
+	return nil
+}
+
+// takeConflict sets the field of a conflict to the value that lost. The
+// change syncs like any other edit.
+func (m *model) takeConflict(c syncConflict) error {
+	index := m.tasksModel.indexOf(c.taskID)
+	f, ok := findSyncField(c.field)
+	if index < 0 || !ok {
+		return fmt.Errorf("the task or field is gone")
+	}
+	task := m.tasksModel.items[index]
+	t := toCloudTask(task, "", "")
+	f.set(&t, c.other)
+	changed := t.item()
+	changed.id, changed.sortOrder, changed.parentID = task.id, task.sortOrder, task.parentID
+	if f.name == "parent" {
+		// The conflict may be from either sync, each with its own UIDs
+		changed.parentID = 0
+		for _, table := range []linkTable{cloudLinks, gitLinks} {
+			links, err := m.store.SyncLinks(table)
+			if err != nil {
+				return err
+			}
+			for id, uid := range links {
+				if uid == t.ParentUID && t.ParentUID != "" {
+					changed.parentID = id
+				}
+			}
+		}
+	}
+
+	before := m.snapshot()
+	if err := m.store.Update(changed); err != nil {
+		return err
+	}
+	m.tasksModel.items[index] = changed
+	m.record("resolve conflict", before)
+	return nil
+}
+
+func (m model) renderConflicts() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Sync conflicts") + "\n\n")
+	for i, c := range m.conflicts.items {
+		f, _ := findSyncField(c.field)
+		title := "(gone)"
+		kept := ""
+		if index := m.tasksModel.indexOf(c.taskID); index >= 0 {
+			task := m.tasksModel.items[index]
+			title = task.title
+			parentUID := ""
+			if task.parentID != 0 {
+				parentUID = "parent" // Only whether there is one is shown
+			}
+			kept = f.show(toCloudTask(task, "", parentUID))
+		}
+		cursor := cursorMark(i == m.conflicts.cursor)
+		style := itemStyle
+		if i == m.conflicts.cursor {
+			style = selectedItemStyle
+		}
+		s.WriteString(style.Render(cursor+title) + helpStyle.Render("  "+c.field+", "+formatRelativeTime(c.at)) + "\n")
+		s.WriteString(helpStyle.Render("      kept:  "+kept) + "\n")
+		s.WriteString(helpStyle.Render("      other: "+f.show(c.other)) + "\n")
+	}
+	return s.String()
+}
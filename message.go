@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Errors and confirmations are shown on a line above the footer instead of
+// being printed, which would scribble over the interface. A message clears
+// itself after messageTimeout or when the next one replaces it.
+
+const messageTimeout = 4 * time.Second
+
+type message struct {
+	text  string
+	isErr bool
+	seq   int // Identifies the message so only its own timer clears it
+}
+
+// errorMsg reports a failure from a background command.
+type errorMsg struct {
+	action string
+	err    error
+}
+
+// dismissMsg clears the message with the given seq if it is still shown.
+type dismissMsg int
+
+func dismissMessage(seq int) tea.Cmd {
+	return tea.Tick(messageTimeout, func(time.Time) tea.Msg {
+		return dismissMsg(seq)
+	})
+}
+
+// showMessage shows a confirmation.
+func (m *model) showMessage(text string) {
+	m.message = message{text: text, seq: m.message.seq + 1}
+}
+
+// showError shows what failed, e.g. "failed to save task: disk full".
+func (m *model) showError(action string, err error) {
+	m.message = message{text: "failed to " + action + ": " + err.Error(), isErr: true, seq: m.message.seq + 1}
+}
+
+func (m model) renderMessage() string {
+	if m.message.isErr {
+		return overdueStyle.Render(m.message.text)
+	}
+	return notesStyle.Render(m.message.text)
+}
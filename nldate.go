@@ -0,0 +1,158 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Due dates can also be written out in the task input, as in "pay rent
+// tomorrow 5pm", "call mom next friday" or "renew passport in 2 weeks". The
+// first such phrase becomes the due date and is taken out of the title:
+//
+//	today, tonight, tomorrow           that day
+//	friday, on friday                  the next friday after today
+//	next friday                        friday of next week
+//	next week, next month              monday of next week, a month from today
+//	in 3 days, in 2 weeks, in a month  that many days, weeks or months ahead
+//	in 2 hours, in 30 minutes          that exact time
+//	5pm, at 5:30pm, 17:00              a time, on its own today or tomorrow
+//
+// A date without a time is due at the end of the day, like due:YYYY-MM-DD.
+// An explicit due: token always wins.
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseNaturalDue returns the due date written out in the input and the input
+// without it, or the zero time and the input unchanged.
+func parseNaturalDue(input string, now time.Time) (time.Time, string) {
+	words := strings.Fields(input)
+	for i := range words {
+		due, n := parseDatePhrase(words[i:], now)
+		if n == 0 {
+			continue
+		}
+		rest := append(append([]string{}, words[:i]...), words[i+n:]...)
+		return due, strings.Join(rest, " ")
+	}
+	return time.Time{}, input
+}
+
+// parseDatePhrase matches a date, a time or both at the start of words and
+// returns the due time and how many words it took.
+func parseDatePhrase(words []string, now time.Time) (time.Time, int) {
+	lower := make([]string, len(words))
+	for i, word := range words {
+		lower[i] = strings.TrimRight(strings.ToLower(word), ",.")
+	}
+	word := func(i int) string {
+		if i < len(lower) {
+			return lower[i]
+		}
+		return ""
+	}
+
+	today := midnight(now)
+	var day time.Time
+	n := 0
+	switch first := word(0); {
+	case first == "today" || first == "tonight":
+		day, n = today, 1
+	case first == "tomorrow" || first == "tmrw":
+		day, n = today.AddDate(0, 0, 1), 1
+	case first == "next" && word(1) == "week":
+		day, n = today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7), 2
+	case first == "next" && word(1) == "month":
+		day, n = today.AddDate(0, 1, 0), 2
+	case first == "next" && isWeekday(word(1)):
+		monday := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7)
+		day, n = monday.AddDate(0, 0, (int(weekdays[word(1)])+6)%7), 2
+	case isWeekday(first):
+		day, n = nextWeekday(today, weekdays[first]), 1
+	case first == "on" && isWeekday(word(1)):
+		day, n = nextWeekday(today, weekdays[word(1)]), 2
+	case first == "in":
+		if due, ok := parseIn(word(1), word(2), now); ok {
+			return due, 3
+		}
+	}
+
+	// A time after the date, or on its own
+	at := n
+	if word(at) == "at" {
+		at++
+	}
+	if hour, minute, ok := parseClock(word(at)); ok {
+		if day.IsZero() {
+			day = today
+			if !time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.Local).After(now) {
+				day = day.AddDate(0, 0, 1)
+			}
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.Local), at + 1
+	}
+	if word(0) == "tonight" {
+		return time.Date(day.Year(), day.Month(), day.Day(), 20, 0, 0, 0, time.Local), n
+	}
+	if day.IsZero() {
+		return time.Time{}, 0
+	}
+	return endOfDay(day), n
+}
+
+func isWeekday(word string) bool {
+	_, ok := weekdays[word]
+	return ok
+}
+
+// nextWeekday returns the first day after today falling on the weekday.
+func nextWeekday(today time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// parseIn reads the "2 weeks" of "in 2 weeks". Days, weeks and months are
+// due at the end of the day, hours and minutes at that exact time.
+func parseIn(count, unit string, now time.Time) (time.Time, bool) {
+	n, err := strconv.Atoi(count)
+	if count == "a" || count == "an" {
+		n, err = 1, nil
+	}
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	switch strings.TrimSuffix(unit, "s") {
+	case "minute", "min":
+		return now.Add(time.Duration(n) * time.Minute).Truncate(time.Minute), true
+	case "hour":
+		return now.Add(time.Duration(n) * time.Hour).Truncate(time.Minute), true
+	case "day":
+		return endOfDay(now.AddDate(0, 0, n)), true
+	case "week":
+		return endOfDay(now.AddDate(0, 0, 7*n)), true
+	case "month":
+		return endOfDay(now.AddDate(0, n, 0)), true
+	}
+	return time.Time{}, false
+}
+
+// parseClock parses 9am, 9:30pm or 14:30.
+func parseClock(value string) (hour, minute int, ok bool) {
+	for _, layout := range []string{"3pm", "3:04pm", "15:04"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Hour(), t.Minute(), true
+		}
+	}
+	return 0, 0, false
+}
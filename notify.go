@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// While Xtui runs it raises a desktop notification when a task falls due,
+// through a Notifier picked at runtime from the platform's tools: notify-send
+// on Linux, or the notification service on D-Bus through gdbus without it,
+// osascript on macOS and a toast through PowerShell on Windows, and from WSL.
+// backend under [notifications] picks one by name instead. A timer wakes the
+// model every notifyInterval to look for due times it has passed since the
+// last check, so every task is announced once and tasks already overdue at
+// startup stay quiet. Date-only due dates have no time to announce and are
+// skipped. Reminders (see reminders.go) go through the same check, and
+// pomodoros (see pomodoro.go) through the same Notifier.
+
+const notifyInterval = 15 * time.Second
+
+// notifyMsg is the notification timer firing.
+type notifyMsg time.Time
+
+func watchDue() tea.Cmd {
+	return tea.Tick(notifyInterval, func(t time.Time) tea.Msg {
+		return notifyMsg(t)
+	})
+}
+
+// alert is one notification to show.
+type alert struct {
+	title string
+	body  string
+	task  item // The task it is about, if any
+}
+
+// checkDue collects the tasks and reminders that fell due since the last
+// check and sends their notifications in the background. Reminders also ring
+// the terminal bell.
+func (m *model) checkDue(now time.Time) tea.Cmd {
+	alerts := m.dueTasks(m.notifiedUntil, now)
+	reminders := m.dueReminders(m.notifiedUntil, now)
+	alerts = append(alerts, reminders...)
+	m.notifiedUntil = now
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	if len(alerts) == 1 {
+		m.showMessage(alerts[0].title + ": " + alerts[0].body)
+	} else {
+		m.showMessage(fmt.Sprintf("%s: %s and %d more", alerts[0].title, alerts[0].body, len(alerts)-1))
+	}
+	var cmds []tea.Cmd
+	if len(reminders) > 0 {
+		cmds = append(cmds, bell)
+	}
+	cmds = append(cmds, m.notify(alerts...), m.slackAlerts(alerts))
+	return tea.Batch(cmds...)
+}
+
+// dueTasks returns the alerts for open tasks whose due time came after since
+// and up to now.
+func (m model) dueTasks(since, now time.Time) []alert {
+	var alerts []alert
+	for _, task := range m.tasksModel.items {
+		if task.status == done || task.dueAt.IsZero() || isEndOfDay(task.dueAt) {
+			continue
+		}
+		if task.dueAt.After(since) && !task.dueAt.After(now) {
+			alerts = append(alerts, alert{title: "Task due", body: task.title, task: task})
+		}
+	}
+	return alerts
+}
+
+// notify sends the alerts as desktop notifications in the background, when
+// they are turned on.
+func (m model) notify(alerts ...alert) tea.Cmd {
+	if !m.config.notify || len(alerts) == 0 {
+		return nil
+	}
+	backend := m.config.notifyBackend
+	return func() tea.Msg {
+		notifier := newNotifier(backend)
+		for _, a := range alerts {
+			if err := notifier.Notify(a.title, a.body); err != nil {
+				return errorMsg{"send notification", err}
+			}
+		}
+		return nil
+	}
+}
+
+// Notifier shows desktop notifications.
+type Notifier interface {
+	Notify(title, body string) error
+}
+
+// newNotifier returns the notifier for a backend name, or for "auto" the
+// first one the system has.
+func newNotifier(backend string) Notifier {
+	switch backend {
+	case "notify-send":
+		return notifySendNotifier{}
+	case "dbus":
+		return dbusNotifier{}
+	case "osascript":
+		return osascriptNotifier{}
+	case "toast":
+		return newToastNotifier()
+	}
+
+	switch {
+	case runtime.GOOS == "darwin":
+		return osascriptNotifier{}
+	case runtime.GOOS == "windows":
+		return newToastNotifier()
+	case hasCommand("notify-send"):
+		return notifySendNotifier{}
+	case hasCommand("gdbus"):
+		return dbusNotifier{}
+	case hasCommand("powershell.exe"): // WSL
+		return newToastNotifier()
+	}
+	return missingNotifier{}
+}
+
+// notifySendNotifier goes through notify-send from libnotify.
+type notifySendNotifier struct{}
+
+func (notifySendNotifier) Notify(title, body string) error {
+	// After --, a title starting with a dash isn't taken for an option
+	return exec.Command("notify-send", "--app-name=Xtui", "--", title, body).Run()
+}
+
+// dbusNotifier calls the freedesktop notification service on the session
+// bus directly, for desktops without notify-send.
+type dbusNotifier struct{}
+
+func (dbusNotifier) Notify(title, body string) error {
+	// The arguments are GVariant text. The timeout is typed so gdbus doesn't
+	// take -1, the server's default, for an option.
+	return exec.Command("gdbus", "call", "--session",
+		"--dest", "org.freedesktop.Notifications",
+		"--object-path", "/org/freedesktop/Notifications",
+		"--method", "org.freedesktop.Notifications.Notify",
+		"'Xtui'", "0", "''", gvariantString(title), gvariantString(body), "[]", "{}", "int32 -1",
+	).Run()
+}
+
+func gvariantString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// osascriptNotifier shows a notification from AppleScript on macOS.
+type osascriptNotifier struct{}
+
+func (osascriptNotifier) Notify(title, body string) error {
+	script := "display notification " + appleScriptString(body) + " with title " + appleScriptString(title)
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// toastNotifier shows a Windows toast through PowerShell, which from WSL is
+// powershell.exe.
+type toastNotifier struct {
+	command string
+}
+
+func newToastNotifier() toastNotifier {
+	if runtime.GOOS == "windows" {
+		return toastNotifier{command: "powershell"}
+	}
+	return toastNotifier{command: "powershell.exe"}
+}
+
+func (n toastNotifier) Notify(title, body string) error {
+	// The text is passed in the environment to avoid quoting it for
+	// PowerShell, and WSLENV lets it through to Windows. Toasts need a
+	// registered app id, so they are shown as coming from PowerShell.
+	cmd := exec.Command(n.command, "-NoProfile", "-NonInteractive", "-Command", windowsToast)
+	wslenv := "XTUI_TITLE:XTUI_BODY"
+	if current := os.Getenv("WSLENV"); current != "" {
+		wslenv = current + ":" + wslenv
+	}
+	cmd.Env = append(os.Environ(), "XTUI_TITLE="+title, "XTUI_BODY="+body, "WSLENV="+wslenv)
+	return cmd.Run()
+}
+
+// missingNotifier stands in when the system has no notification tool.
+type missingNotifier struct{}
+
+func (missingNotifier) Notify(title, body string) error {
+	return errors.New("no notification tool found, install libnotify")
+}
+
+const windowsToast = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:XTUI_TITLE)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:XTUI_BODY)) | Out-Null
+$toast = [Windows.UI.Notifications.ToastNotification]::new($xml)
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe').Show($toast)
+`
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -list and -stats print for people by default. For scripts and status bars,
+// -json prints tasks in the format of the JSON export and stats as one
+// object, and -plain prints tab-separated lines without markers or colours:
+// one per task with the columns below, or a name and a value per line for
+// stats. The task columns are id, status (todo, doing or done), due date
+// (RFC 3339, empty if none), priority (0 for none), comma-separated tags,
+// context, project, GTD list, parent id (0 for none) and title. Columns are
+// only ever added at the end, so scripts can rely on their positions.
+
+type outputFormat int
+
+const (
+	humanOutput outputFormat = iota
+	jsonOutput
+	plainOutput
+)
+
+// chooseOutput returns the format picked by the -json and -plain flags.
+func chooseOutput(asJSON, plain bool) (outputFormat, error) {
+	switch {
+	case asJSON && plain:
+		return humanOutput, fmt.Errorf("-json and -plain can't be used together")
+	case asJSON:
+		return jsonOutput, nil
+	case plain:
+		return plainOutput, nil
+	}
+	return humanOutput, nil
+}
+
+func statusName(s status) string {
+	switch s {
+	case done:
+		return "done"
+	case doing:
+		return "doing"
+	}
+	return "todo"
+}
+
+// plainField keeps a value on its line and in its column.
+func plainField(value string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(value)
+}
+
+func writeTasks(w io.Writer, tasks []item, format outputFormat) error {
+	switch format {
+	case jsonOutput:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(toJSONTasks(tasks))
+	case plainOutput:
+		for _, task := range tasks {
+			due := ""
+			if !task.dueAt.IsZero() {
+				due = task.dueAt.Format(time.RFC3339)
+			}
+			fields := []string{
+				strconv.Itoa(task.id),
+				statusName(task.status),
+				due,
+				strconv.Itoa(task.priority),
+				strings.Join(task.tags, ","),
+				task.context,
+				task.project,
+				string(task.list),
+				strconv.Itoa(task.parentID),
+				task.title,
+			}
+			for i, field := range fields {
+				fields[i] = plainField(field)
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(fields, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, task := range tasks {
+		if _, err := fmt.Fprintf(w, "%d\t%s %s\n", task.id, unicodeIcons.marker(task.status), formatTaskInput(task)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonStats is the -stats -json output.
+type jsonStats struct {
+	Completed     int            `json:"completed"`
+	Open          int            `json:"open"`
+	Streak        int            `json:"streak"`
+	BestStreak    int            `json:"best_streak"`
+	AvgCompletion int64          `json:"avg_completion_seconds"`
+	Today         int            `json:"completed_today"`
+	PerDay        map[string]int `json:"per_day"`
+	PerWeek       map[string]int `json:"per_week"`
+	Tags          []jsonTagCount `json:"tags"`
+	Estimated     int            `json:"estimated_tasks"`
+	EstimateTime  int64          `json:"estimated_seconds"`
+	TrackedTime   int64          `json:"tracked_seconds"`
+}
+
+type jsonTagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+func writeStats(w io.Writer, st taskStats, now time.Time, format outputFormat) error {
+	today := st.perDay[now.Format("2006-01-02")]
+	switch format {
+	case jsonOutput:
+		out := jsonStats{
+			Completed:     st.completed,
+			Open:          st.open,
+			Streak:        st.streak(now),
+			BestStreak:    st.bestStreak(),
+			AvgCompletion: int64(st.avgCompletion.Seconds()),
+			Today:         today,
+			PerDay:        st.perDay,
+			PerWeek:       st.perWeek,
+			Tags:          []jsonTagCount{},
+			Estimated:     st.estimatedTasks,
+			EstimateTime:  int64(st.estimatedTime.Seconds()),
+			TrackedTime:   int64(st.trackedTime.Seconds()),
+		}
+		for _, t := range st.tags {
+			out.Tags = append(out.Tags, jsonTagCount{Tag: t.key, Count: t.count})
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	case plainOutput:
+		lines := []string{
+			fmt.Sprintf("completed\t%d", st.completed),
+			fmt.Sprintf("open\t%d", st.open),
+			fmt.Sprintf("streak\t%d", st.streak(now)),
+			fmt.Sprintf("best_streak\t%d", st.bestStreak()),
+			fmt.Sprintf("avg_completion_seconds\t%d", int64(st.avgCompletion.Seconds())),
+			fmt.Sprintf("completed_today\t%d", today),
+			fmt.Sprintf("estimated_tasks\t%d", st.estimatedTasks),
+			fmt.Sprintf("estimated_seconds\t%d", int64(st.estimatedTime.Seconds())),
+			fmt.Sprintf("tracked_seconds\t%d", int64(st.trackedTime.Seconds())),
+		}
+		for _, t := range st.tags {
+			lines = append(lines, fmt.Sprintf("tag\t%s\t%d", plainField(t.key), t.count))
+		}
+		_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "%d completed, %d open, %d today, %d day streak (best %d)\n", st.completed, st.open, today, st.streak(now), st.bestStreak()); err != nil {
+		return err
+	}
+	if st.completed > 0 {
+		if _, err := fmt.Fprintf(w, "Tasks take %s to complete on average\n", formatDuration(st.avgCompletion)); err != nil {
+			return err
+		}
+	}
+	if st.estimatedTasks > 0 {
+		if _, err := fmt.Fprint(w, st.renderEstimates()); err != nil {
+			return err
+		}
+	}
+	for _, t := range st.tags { // Most used first
+		if _, err := fmt.Fprintf(w, "#%s\t%d\n", t.key, t.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStats implements the -stats flag.
+func runStats(format outputFormat) error {
+	m := newModel()
+	defer m.close()
+	st, err := m.store.Stats()
+	if err != nil {
+		return err
+	}
+	return writeStats(os.Stdout, st, time.Now(), format)
+}
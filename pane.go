@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// | opens a pane right of the task list with everything about the selected
+// task: the full title, tags, dates, notes, subtasks and the recent changes
+// to it. It follows the cursor, and the list gets narrower to make room.
+// ctrl+h widens the pane and ctrl+l narrows it, collapsing it past the
+// narrowest width. The layout is kept across sessions.
+
+const (
+	defaultPaneShare = 40 // Percent of the width taken by the pane
+	minPaneShare     = 20
+	maxPaneShare     = 70
+	paneShareStep    = 5
+)
+
+// paneModes are the modes that show the task list, and so the pane.
+var paneModes = map[string]bool{
+	normalMode:  true,
+	visualMode:  true,
+	searchMode:  true,
+	commandMode: true,
+	insertMode:  true,
+	blockMode:   true,
+}
+
+// paneWidth returns how wide the pane is for the window, 0 when it is not
+// shown.
+func (m model) paneWidth() int {
+	if !m.tasksModel.showPane || !paneModes[m.tasksModel.mode] {
+		return 0
+	}
+	return (m.width - 4) * m.tasksModel.paneShare / 100 // The outer padding takes 4 columns
+}
+
+// togglePane collapses the pane or brings it back at its last width.
+func (m *model) togglePane() {
+	m.tasksModel.showPane = !m.tasksModel.showPane
+	m.savePaneLayout()
+}
+
+// resizePane moves the divider by a step: a positive delta widens the pane,
+// opening it if collapsed, and a negative one narrows it until it collapses.
+func (m *model) resizePane(delta int) {
+	t := &m.tasksModel
+	switch {
+	case !t.showPane && delta > 0:
+		t.showPane = true
+		t.paneShare = minPaneShare
+	case !t.showPane:
+		return
+	case t.paneShare+delta < minPaneShare:
+		t.showPane = false
+	default:
+		t.paneShare = min(t.paneShare+delta, maxPaneShare)
+	}
+	m.savePaneLayout()
+}
+
+func (m *model) savePaneLayout() {
+	err := m.store.SaveSetting("show_pane", fmt.Sprint(m.tasksModel.showPane))
+	if err == nil {
+		err = m.store.SaveSetting("pane_share", strconv.Itoa(m.tasksModel.paneShare))
+	}
+	if err != nil {
+		m.showError("save setting", err)
+	}
+}
+
+// loadPaneShare reads the saved pane width, falling back to the default if
+// it is missing or out of range.
+func loadPaneShare(value string) int {
+	share, err := strconv.Atoi(value)
+	if err != nil || share < minPaneShare || share > maxPaneShare {
+		return defaultPaneShare
+	}
+	return share
+}
+
+// withPane puts the task list and the pane side by side.
+func (m model) withPane(list string) string {
+	width := m.paneWidth()
+	if width == 0 {
+		return list
+	}
+	listWidth := m.width - 4 - width
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(listWidth).MaxWidth(listWidth).Render(list),
+		paneStyle.Width(width-paneStyle.GetHorizontalFrameSize()).Render(m.renderPane()),
+	)
+}
+
+func (m model) renderPane() string {
+	index := m.tasksModel.selectedIndex()
+	if index < 0 {
+		return helpStyle.Render("No task selected")
+	}
+	task := m.tasksModel.items[index]
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(task.title) + "\n")
+	s.WriteString(helpStyle.Render(fmt.Sprintf("[[%d]] %s · %s", task.id, statusMarker(task.status), task.list.title())) + "\n")
+	var labels []string
+	for _, tag := range task.tags {
+		labels = append(labels, tagLabel(tag))
+	}
+	if task.context != "" {
+		labels = append(labels, "@"+task.context)
+	}
+	if task.project != "" {
+		labels = append(labels, "+"+task.project)
+	}
+	if len(labels) > 0 {
+		s.WriteString(tagStyle.Render(strings.Join(labels, " ")) + "\n")
+	}
+	if g, ok := m.goalOf(task.id); ok {
+		s.WriteString(helpStyle.Render("Goal: "+g.title) + "\n")
+	}
+
+	s.WriteString("\n")
+	dates := []struct {
+		label string
+		at    time.Time
+	}{
+		{"Created", task.createdAt},
+		{"Changed", task.updatedAt},
+		{"Completed", task.completedAt},
+	}
+	for _, d := range dates {
+		if !d.at.IsZero() && (d.label != "Completed" || task.status == done) {
+			s.WriteString(helpStyle.Render(d.label+" "+formatRelativeTime(d.at)) + "\n")
+		}
+	}
+	if !task.dueAt.IsZero() {
+		due := formatDueTime(task.dueAt)
+		if task.overdue(time.Now()) {
+			s.WriteString(overdueStyle.Render(due) + "\n")
+		} else {
+			s.WriteString(helpStyle.Render(due) + "\n")
+		}
+	}
+	if !task.startAt.IsZero() {
+		s.WriteString(helpStyle.Render("Starts "+formatTime(task.startAt, "Mon 2 Jan 2006 15:04")) + "\n")
+	}
+	if spec := recurrenceSpec(task.recurrence); spec != "" {
+		s.WriteString(helpStyle.Render("Repeats every "+spec) + "\n")
+	}
+	if tracked := m.trackedTime(task.id, time.Time{}); tracked > 0 {
+		s.WriteString(helpStyle.Render("Tracked "+formatElapsed(tracked)) + "\n")
+	}
+	if task.estimate != 0 {
+		estimate := "Estimated " + formatEstimate(task.estimated())
+		if left := m.remaining(task); left > 0 && left < task.estimated() {
+			estimate += ", " + formatEstimate(left) + " left"
+		}
+		s.WriteString(helpStyle.Render(estimate) + "\n")
+	}
+	if blockers := m.blockedTitles(task.id); blockers != "" {
+		s.WriteString(helpStyle.Render("Waits for "+blockers) + "\n")
+	}
+
+	if task.notes != "" {
+		s.WriteString("\n" + notesStyle.Render(task.notes) + "\n")
+	}
+
+	if children := m.tasksModel.children(task.id); len(children) > 0 {
+		s.WriteString("\n" + helpStyle.Render("Subtasks") + "\n")
+		for _, child := range children {
+			subtask := m.tasksModel.items[child]
+			s.WriteString(statusMarker(subtask.status) + " " + subtask.title + "\n")
+		}
+	}
+
+	if history := m.taskHistory(task.id); len(history) > 0 {
+		s.WriteString("\n" + helpStyle.Render("History") + "\n")
+		for _, label := range history {
+			s.WriteString(helpStyle.Render("  "+label) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// children returns the indices of the direct subtasks of a task.
+func (t tasksModel) children(id int) []int {
+	var indices []int
+	for i, task := range t.items {
+		if task.parentID == id && task.id != id {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// taskHistory returns the labels of the undoable changes that touched a
+// task, most recent first.
+func (m model) taskHistory(id int) []string {
+	var labels []string
+	for i := len(m.undoStack) - 1; i >= 0; i-- {
+		op := m.undoStack[i]
+		for _, task := range append(op.before, op.after...) {
+			if task.id == id {
+				labels = append(labels, op.label)
+				break
+			}
+		}
+	}
+	return labels
+}
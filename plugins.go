@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Plugins are programs in ~/.config/xtui/plugins, written in any language,
+// that Xtui starts with it and talks to over their stdin and stdout with
+// JSON-RPC 1.0, one request or response per line. Xtui first calls
+// Plugin.Register, and the plugin answers with its name and what it adds:
+//
+//   - keys, pressed after \ in the task list: Plugin.Key is called with the
+//     key and the selected task
+//   - views, opened with :plugin <view>: Plugin.View is called with the view,
+//     the loaded tasks and the size of the screen, then again with each key
+//     pressed in it, and answers with the text to show; esc closes it
+//   - events, on-add, on-done and on-delete as for hooks: Plugin.Event is
+//     called with the event and the task
+//
+// Every answer can show a message, change tasks (by id) and add tasks, as one
+// undoable change. Calls run in the background, and a plugin that doesn't
+// answer within pluginTimeout is skipped. Plugins are stopped, by closing
+// their stdin, when Xtui quits.
+
+const pluginMode = "plugin"
+
+const pluginTimeout = 10 * time.Second
+
+// plugin is a running plugin.
+type plugin struct {
+	path   string
+	info   pluginInfo
+	cmd    *exec.Cmd
+	client *rpc.Client
+}
+
+// pluginInfo is a plugin's answer to Plugin.Register.
+type pluginInfo struct {
+	Name   string      `json:"name"`
+	Keys   []pluginKey `json:"keys"`
+	Views  []string    `json:"views"`
+	Events []string    `json:"events"`
+}
+
+type pluginKey struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+}
+
+// pluginRequest is the argument of Plugin.Key, Plugin.View and Plugin.Event.
+type pluginRequest struct {
+	Key    string     `json:"key,omitempty"`
+	View   string     `json:"view,omitempty"`
+	Event  string     `json:"event,omitempty"`
+	Task   *jsonTask  `json:"task,omitempty"`
+	Tasks  []jsonTask `json:"tasks,omitempty"`
+	Width  int        `json:"width,omitempty"`
+	Height int        `json:"height,omitempty"`
+}
+
+// pluginReply is a plugin's answer to a request.
+type pluginReply struct {
+	Message string     `json:"message"`
+	Text    string     `json:"text"`  // What a view shows
+	Close   bool       `json:"close"` // Close the view
+	Update  []jsonTask `json:"update"`
+	Add     []jsonTask `json:"add"`
+}
+
+// pluginReplyMsg is sent to Update when a plugin answers.
+type pluginReplyMsg struct {
+	plugin *plugin
+	view   string // Set for answers to Plugin.View
+	reply  pluginReply
+	err    error
+}
+
+// pluginViewState is the plugin view being shown.
+type pluginViewState struct {
+	plugin *plugin
+	view   string
+	text   string
+}
+
+// pipe joins a process's stdout and stdin into one connection.
+type pipe struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (p pipe) Close() error {
+	err := p.WriteCloser.Close()
+	if rerr := p.ReadCloser.Close(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+func pluginsDir() string {
+	return filepath.Join(configDir(), "plugins")
+}
+
+// startPlugins starts the plugins in the plugins directory. One that fails to
+// start or register is reported and left out.
+func (m *model) startPlugins() {
+	entries, err := os.ReadDir(pluginsDir())
+	if err != nil {
+		return // No plugins
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if !isExecutable(entry) {
+			continue
+		}
+		path := filepath.Join(pluginsDir(), entry.Name())
+		p, err := startPlugin(path)
+		if err != nil {
+			m.showError("start plugin "+entry.Name(), err)
+			continue
+		}
+		m.plugins = append(m.plugins, p)
+	}
+}
+
+func startPlugin(path string) (*plugin, error) {
+	cmd := exec.Command(path)
+	cmd.Dir = filepath.Dir(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &plugin{path: path, cmd: cmd, client: jsonrpc.NewClient(pipe{stdout, stdin})}
+	if err := p.call("Plugin.Register", struct{}{}, &p.info); err != nil {
+		p.stop()
+		return nil, err
+	}
+	if p.info.Name == "" {
+		p.info.Name = filepath.Base(path)
+	}
+	return p, nil
+}
+
+// call calls a method of the plugin, giving up after pluginTimeout.
+func (p *plugin) call(method string, args, reply interface{}) error {
+	select {
+	case call := <-p.client.Go(method, args, reply, make(chan *rpc.Call, 1)).Done:
+		return call.Error
+	case <-time.After(pluginTimeout):
+		return errors.New("no answer to " + method)
+	}
+}
+
+// stop closes the plugin's stdin and waits a moment for it to exit before
+// killing it.
+func (p *plugin) stop() {
+	p.client.Close()
+	exited := make(chan struct{})
+	go func() {
+		p.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		p.cmd.Process.Kill()
+		<-exited
+	}
+}
+
+func (m model) stopPlugins() {
+	for _, p := range m.plugins {
+		p.stop()
+	}
+}
+
+// request calls a method in the background and sends its answer to Update.
+func (p *plugin) request(method string, req pluginRequest) tea.Cmd {
+	return func() tea.Msg {
+		var reply pluginReply
+		err := p.call(method, req, &reply)
+		msg := pluginReplyMsg{plugin: p, reply: reply, err: err}
+		if method == "Plugin.View" {
+			msg.view = req.View
+		}
+		return msg
+	}
+}
+
+// selectedTask returns the selected task for a plugin request, nil if none is.
+func (m model) selectedTask() *jsonTask {
+	index := m.tasksModel.selectedIndex()
+	if index < 0 {
+		return nil
+	}
+	task := toJSONTask(m.tasksModel.items[index])
+	return &task
+}
+
+// pluginKey runs the plugin key pressed after \.
+func (m *model) pluginKey(key string) tea.Cmd {
+	for _, p := range m.plugins {
+		for _, k := range p.info.Keys {
+			if k.Key == key {
+				return p.request("Plugin.Key", pluginRequest{Key: key, Task: m.selectedTask()})
+			}
+		}
+	}
+	m.showMessage(`No plugin uses \` + key)
+	return nil
+}
+
+// openPluginView opens a view added by a plugin.
+func (m *model) openPluginView(view string) (tea.Cmd, error) {
+	for _, p := range m.plugins {
+		if contains(p.info.Views, view) {
+			m.pluginView = pluginViewState{plugin: p, view: view}
+			m.tasksModel.mode = pluginMode
+			return m.requestView(""), nil
+		}
+	}
+	return nil, fmt.Errorf("no plugin view %q", view)
+}
+
+// requestView asks the plugin for the text of its view after a key.
+func (m model) requestView(key string) tea.Cmd {
+	v := m.pluginView
+	return v.plugin.request("Plugin.View", pluginRequest{
+		Key:    key,
+		View:   v.view,
+		Task:   m.selectedTask(),
+		Tasks:  toJSONTasks(m.tasksModel.items),
+		Width:  m.width,
+		Height: m.height,
+	})
+}
+
+// updatePluginView handles keys in a plugin view.
+func (m *model) updatePluginView(key string) tea.Cmd {
+	if key == "esc" {
+		m.tasksModel.mode = normalMode
+		return nil
+	}
+	return m.requestView(key)
+}
+
+// pluginEvents sends the events of the queued hooks to the plugins that asked
+// for them.
+func (m model) pluginEvents(runs []hookRun) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, p := range m.plugins {
+		for _, run := range runs {
+			if contains(p.info.Events, run.event) {
+				task := toJSONTask(run.task)
+				cmds = append(cmds, p.request("Plugin.Event", pluginRequest{Event: run.event, Task: &task}))
+			}
+		}
+	}
+	return tea.Batch(cmds...)
+}
+
+// applyPluginReply shows a plugin's answer and makes the changes it asks for.
+func (m *model) applyPluginReply(msg pluginReplyMsg) {
+	name := msg.plugin.info.Name
+	if msg.err != nil {
+		m.showError("run plugin "+name, msg.err)
+		return
+	}
+	r := msg.reply
+	if m.tasksModel.mode == pluginMode && m.pluginView.plugin == msg.plugin && m.pluginView.view == msg.view {
+		m.pluginView.text = r.Text
+		if r.Close {
+			m.tasksModel.mode = normalMode
+		}
+	}
+	if r.Message != "" {
+		m.showMessage(r.Message)
+	}
+	if len(r.Update) == 0 && len(r.Add) == 0 {
+		return
+	}
+
+	before := m.snapshot()
+	for _, t := range r.Update {
+		index := m.tasksModel.indexOf(t.ID)
+		if index < 0 {
+			continue // Not loaded, or deleted since
+		}
+		changed := t.item()
+		if err := m.store.Update(changed); err != nil {
+			m.showError("update task", err)
+			return
+		}
+		m.tasksModel.items[index] = changed
+	}
+	for _, t := range r.Add {
+		task := t.item()
+		task.id, task.sortOrder = 0, 0
+		if task.createdAt.IsZero() {
+			task.createdAt = time.Now()
+		}
+		if strings.TrimSpace(task.title) == "" {
+			continue
+		}
+		if err := m.store.Save(&task); err != nil {
+			m.showError("save task", err)
+			return
+		}
+		m.tasksModel.items = append(m.tasksModel.items, task)
+		m.fireHook(hookAdd, task)
+	}
+	m.record("plugin "+name, before)
+}
+
+// describePlugins lists the plugins and what they add, for :plugin.
+func (m model) describePlugins() string {
+	if len(m.plugins) == 0 {
+		return "No plugins in " + pluginsDir()
+	}
+	var parts []string
+	for _, p := range m.plugins {
+		var adds []string
+		for _, k := range p.info.Keys {
+			adds = append(adds, `\`+k.Key+" "+k.Description)
+		}
+		for _, view := range p.info.Views {
+			adds = append(adds, ":plugin "+view)
+		}
+		parts = append(parts, p.info.Name+" ("+strings.Join(adds, ", ")+")")
+	}
+	return "Plugins: " + strings.Join(parts, "; ")
+}
+
+func (m model) renderPluginView() string {
+	v := m.pluginView
+	return titleStyle.Render(v.view) + helpStyle.Render("  "+v.plugin.info.Name) + "\n\n" + v.text + "\n"
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The Pomodoro tab runs a focus timer for the task selected in the list: 25
+// minutes of work, then a 5 minute break. Every finished work interval is
+// logged against the task, and the tab sums up today's focus time.
+
+const (
+	pomodoroWork  = 25 * time.Minute
+	pomodoroBreak = 5 * time.Minute
+)
+
+const (
+	pomodoroIdle  = ""
+	pomodoroFocus = "focus"
+	pomodoroRest  = "break"
+)
+
+type pomodoroModel struct {
+	phase     string // pomodoroIdle, pomodoroFocus or pomodoroRest
+	taskID    int
+	title     string    // Title of the task when the timer started
+	startedAt time.Time // Start of the current phase
+	endsAt    time.Time
+	seq       int        // Identifies the running timer so stale ticks stop
+	today     []pomodoro // Pomodoros finished today
+}
+
+// pomodoro is one finished work interval.
+type pomodoro struct {
+	taskID    int
+	startedAt time.Time
+	endedAt   time.Time
+}
+
+// pomodoroTickMsg updates the countdown once a second.
+type pomodoroTickMsg int
+
+func pomodoroTick(seq int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return pomodoroTickMsg(seq)
+	})
+}
+
+// updatePomodoro handles keys on the Pomodoro tab.
+func (m *model) updatePomodoro(key string) tea.Cmd {
+	switch key {
+	case "s", "enter": // Start focusing on the task selected in the list
+		index := m.tasksModel.selectedIndex()
+		if index < 0 {
+			m.showMessage("Select a task on the Tasks tab first")
+			return nil
+		}
+		task := m.tasksModel.items[index]
+		m.pomodoro.taskID = task.id
+		m.pomodoro.title = task.title
+		return m.startPhase(pomodoroFocus)
+	case "b": // Skip to the break
+		if m.pomodoro.phase == pomodoroFocus {
+			return m.startPhase(pomodoroRest)
+		}
+	case "x": // Stop the timer, an unfinished pomodoro isn't logged
+		m.pomodoro.phase = pomodoroIdle
+		m.pomodoro.seq++
+	}
+	return nil
+}
+
+func (m *model) startPhase(phase string) tea.Cmd {
+	length := pomodoroWork
+	if phase == pomodoroRest {
+		length = pomodoroBreak
+	}
+	m.pomodoro.phase = phase
+	m.pomodoro.startedAt = time.Now()
+	m.pomodoro.endsAt = m.pomodoro.startedAt.Add(length)
+	m.pomodoro.seq++
+	return pomodoroTick(m.pomodoro.seq)
+}
+
+// tickPomodoro moves on to the next phase when the current one is over.
+func (m *model) tickPomodoro(msg pomodoroTickMsg) tea.Cmd {
+	if int(msg) != m.pomodoro.seq || m.pomodoro.phase == pomodoroIdle {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(m.pomodoro.endsAt) {
+		return pomodoroTick(m.pomodoro.seq)
+	}
+
+	var text string
+	var next tea.Cmd
+	if m.pomodoro.phase == pomodoroFocus {
+		p := pomodoro{taskID: m.pomodoro.taskID, startedAt: m.pomodoro.startedAt, endedAt: m.pomodoro.endsAt}
+		if err := m.store.SavePomodoro(p); err != nil {
+			m.showError("save pomodoro", err)
+		}
+		m.refreshPomodoros()
+		text = "Pomodoro done, take a break"
+		next = m.startPhase(pomodoroRest)
+	} else {
+		text = "Break over, back to work"
+		m.pomodoro.phase = pomodoroIdle
+	}
+	m.showMessage(text)
+
+	return tea.Batch(next, bell, m.notify(alert{title: "Pomodoro", body: text}))
+}
+
+// refreshPomodoros loads the pomodoros finished today.
+func (m *model) refreshPomodoros() {
+	now := time.Now()
+	today, err := m.store.Pomodoros(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local))
+	if err != nil {
+		m.showError("load pomodoros", err)
+		return
+	}
+	m.pomodoro.today = today
+}
+
+// pomodoroKeys lists the keys that apply to the Pomodoro tab.
+func (m model) pomodoroKeys() string {
+	switch m.pomodoro.phase {
+	case pomodoroFocus:
+		return "b: take a break | x: stop | s: restart on selected task"
+	case pomodoroRest:
+		return "x: stop | s: start the next pomodoro"
+	}
+	return "s: start on selected task"
+}
+
+func (m model) renderPomodoro() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Pomodoro") + "\n\n")
+
+	p := m.pomodoro
+	switch p.phase {
+	case pomodoroIdle:
+		if index := m.tasksModel.selectedIndex(); index >= 0 {
+			s.WriteString("Ready to focus on " + m.tasksModel.items[index].title + "\n")
+		} else {
+			s.WriteString("Select a task on the Tasks tab to focus on\n")
+		}
+	default:
+		remaining := time.Until(p.endsAt).Round(time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		label := "Focus"
+		if p.phase == pomodoroRest {
+			label = "Break"
+		}
+		s.WriteString(helpStyle.Render(label+" · "+p.title) + "\n\n")
+		s.WriteString("    " + titleStyle.Render(fmt.Sprintf("%02d:%02d", int(remaining.Minutes()), int(remaining.Seconds())%60)) + "\n")
+	}
+
+	// Today's focus time, by task
+	var total time.Duration
+	perTask := make(map[int]int)
+	for _, finished := range p.today {
+		total += finished.endedAt.Sub(finished.startedAt)
+		perTask[finished.taskID]++
+	}
+	s.WriteString("\n" + titleStyle.Render("Today") + "\n\n")
+	if len(p.today) == 0 {
+		s.WriteString(helpStyle.Render("No pomodoros yet") + "\n")
+		return s.String()
+	}
+	s.WriteString(fmt.Sprintf("%d pomodoros, %s focused\n", len(p.today), formatFocus(total)))
+	ids := make([]int, 0, len(perTask))
+	for id := range perTask {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return perTask[ids[i]] > perTask[ids[j]] })
+	for _, id := range ids {
+		title := "(deleted task)"
+		if index := m.tasksModel.indexOf(id); index >= 0 {
+			title = m.tasksModel.items[index].title
+		}
+		s.WriteString(helpStyle.Render(fmt.Sprintf("%3d  %s", perTask[id], title)) + "\n")
+	}
+	return s.String()
+}
+
+// formatFocus formats a focus time as 1h40m.
+func formatFocus(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func createPomodorosTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pomodoros (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME NOT NULL
+		);
+	`)
+	return err
+}
+
+func (s *sqliteStore) SavePomodoro(p pomodoro) error {
+	_, err := s.db.Exec("INSERT INTO pomodoros (task_id, started_at, ended_at) VALUES (?, ?, ?)", p.taskID, p.startedAt, p.endedAt)
+	return err
+}
+
+func (s *sqliteStore) Pomodoros(since time.Time) ([]pomodoro, error) {
+	rows, err := s.db.Query("SELECT task_id, started_at, ended_at FROM pomodoros WHERE ended_at >= ? ORDER BY ended_at", since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pomodoros []pomodoro
+	for rows.Next() {
+		var p pomodoro
+		if err := rows.Scan(&p.taskID, &p.startedAt, &p.endedAt); err != nil {
+			return nil, err
+		}
+		pomodoros = append(pomodoros, p)
+	}
+	return pomodoros, rows.Err()
+}
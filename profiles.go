@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Profiles keep separate task lists, say one for work and one for home, each
+// in its own database. They are named in the [profiles] section of the config
+// (work = "~/work/xtui.db") and picked with -profile work or XTUI_PROFILE;
+// the database in [database] is the default profile. The active profile is
+// shown next to the tabs. gp switches to the next profile and :profile <name>
+// to a given one: Xtui closes the database and starts again on the other,
+// asking for its passphrase first if it is encrypted.
+//
+// Backups of a profile go in a directory of their own under the backup
+// directory, and git sync keeps each profile in its own file. The sync server
+// and CalDAV sync only the default profile.
+
+const defaultProfile = "default"
+
+// profileNames lists the profiles, the default first.
+func (c config) profileNames() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{defaultProfile}, names...)
+}
+
+// nextProfile returns the profile after the active one, wrapping around.
+func (c config) nextProfile() string {
+	names := c.profileNames()
+	for i, name := range names {
+		if name == c.profile {
+			return names[(i+1)%len(names)]
+		}
+	}
+	return defaultProfile
+}
+
+// useProfile applies a named profile to the config.
+func (c *config) useProfile(name string) error {
+	if name == "" || name == defaultProfile {
+		c.profile = defaultProfile
+		return nil
+	}
+	path, ok := c.profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile %q, add it to the [profiles] section", name)
+	}
+	c.profile = name
+	c.databasePath = path
+	c.gitFile = name + "-" + c.gitFile
+	c.syncURL, c.caldavURL = "", ""
+	return nil
+}
+
+// switchProfile quits the interface so main starts it again on another
+// profile.
+func (m *model) switchProfile(name string) (tea.Cmd, error) {
+	if name == m.config.profile {
+		m.showMessage("Already using the " + name + " profile")
+		return nil, nil
+	}
+	cfg := m.config // Only checked, the next start loads the profile
+	if err := cfg.useProfile(name); err != nil {
+		return nil, err
+	}
+	// Later calls to loadConfig, from main and from hooks and plugins, pick
+	// it up from here
+	if err := os.Setenv("XTUI_PROFILE", name); err != nil {
+		return nil, err
+	}
+	m.nextProfile = name
+	return tea.Quit, nil
+}
+
+// profileLabel shows the active profile next to the tabs, when there are
+// profiles to tell apart.
+func (m model) profileLabel() string {
+	if len(m.config.profiles) == 0 {
+		return ""
+	}
+	return profileStyle.Render("● " + m.config.profile)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A task belongs to the project named by +project in the task input. mp
+// moves the selected task, 3mp three tasks from the selected one and m in
+// visual mode the range, to another project: pick one of the projects in use,
+// or "No project" to take them out of theirs. They move in one transaction
+// and one undo step; subtasks stay where they are, as with the GTD lists.
+
+const projectMode = "project" // Picking the project to move tasks to
+
+// Projects returns the projects of the tasks in the list, the trash and the
+// archive left out.
+func (s *sqliteStore) Projects() ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT project FROM tasks WHERE project IS NOT NULL AND project != '' AND deleted_at IS NULL AND archived_at IS NULL ORDER BY project")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []string
+	for rows.Next() {
+		var project string
+		if err := rows.Scan(&project); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// openProjectPicker lists the projects to move the tasks to, with the
+// project of the first one highlighted.
+func (m *model) openProjectPicker(indices []int) {
+	if len(indices) == 0 {
+		return
+	}
+	projects, err := m.store.Projects()
+	if err != nil {
+		m.showError("load projects", err)
+		return
+	}
+	if len(projects) == 0 {
+		m.showMessage("No projects yet, add one with +project in the task input")
+		return
+	}
+	t := &m.tasksModel
+	t.bulkTargets = indices
+	t.projectOptions = append([]string{""}, projects...) // "" takes the tasks out of their project
+	t.projectCursor = 0
+	for i, project := range t.projectOptions {
+		if project == t.items[indices[0]].project {
+			t.projectCursor = i
+		}
+	}
+	t.mode = projectMode
+}
+
+// updateProjectPicker handles keys in the project picker.
+func (m *model) updateProjectPicker(key string) {
+	t := &m.tasksModel
+	switch key {
+	case "up", "k":
+		if t.projectCursor > 0 {
+			t.projectCursor--
+		}
+	case "down", "j":
+		if t.projectCursor < len(t.projectOptions)-1 {
+			t.projectCursor++
+		}
+	case "enter":
+		m.moveToProject(t.bulkTargets, t.projectOptions[t.projectCursor])
+		fallthrough
+	case "esc":
+		t.bulkTargets = nil
+		t.mode = normalMode
+	}
+}
+
+// moveToProject moves the tasks to a project, "" for none, as one undo step.
+func (m *model) moveToProject(indices []int, project string) {
+	before := m.snapshot()
+	var updates []item
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		if task.project != project {
+			task.project = project
+			updates = append(updates, *task)
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := m.store.Update(updates...); err != nil {
+		m.showError("update tasks", err)
+	}
+	m.record("move to project", before)
+	if project == "" {
+		m.showMessage(fmt.Sprintf("Took %s out of their project", countTasks(len(updates))))
+	} else {
+		m.showMessage(fmt.Sprintf("Moved %s to +%s", countTasks(len(updates)), project))
+	}
+	m.tasksModel.clampSelection()
+}
+
+func (m model) renderProjectPicker() string {
+	t := m.tasksModel
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(fmt.Sprintf("Move %s to", countTasks(len(t.bulkTargets)))) + "\n\n")
+	for i, project := range t.projectOptions {
+		label := "+" + project
+		if project == "" {
+			label = "No project"
+		}
+		if i == t.projectCursor {
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+label) + "\n")
+		} else {
+			s.WriteString(itemStyle.Render(cursorMark(false)+label) + "\n")
+		}
+	}
+	return s.String()
+}
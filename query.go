@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A query narrows the tasks with field:value terms, all of which must hold:
+//
+//	status:todo tag:work created:<7d priority:>=2 -context:phone report
+//
+// Fields are status (todo, doing, done), tag, context, project, list (inbox,
+// next, waiting, someday), priority (1 is p1, 0 none) and the dates created,
+// updated, completed, due and start. Numbers and dates take <, <=, >, >= or
+// =. A date is YYYY-MM-DD or a span like 3h, 7d or 2w: created:<7d is less
+// than a week ago, due:<3d due within three days. context, project, due and
+// start also take none. A - in front of a term negates it and words without a field
+// must appear in the title or notes.
+//
+// Queries are compiled into an SQL condition so they run in the database:
+// :query filters the list with one, and xtui -list prints the matches.
+
+// taskQuery is a compiled query.
+type taskQuery struct {
+	text       string
+	conditions []string
+	args       []interface{}
+}
+
+// queryFields are the fields a term can name, for completion.
+var queryFields = []string{"status:", "tag:", "context:", "project:", "list:", "priority:", "created:", "updated:", "completed:", "due:", "start:"}
+
+// dateColumns maps the date fields to their columns.
+var dateColumns = map[string]string{
+	"created":   "created_at",
+	"updated":   "updated_at",
+	"completed": "completed_at",
+	"due":       "due_at",
+	"start":     "start_at",
+}
+
+func parseQuery(text string, now time.Time) (taskQuery, error) {
+	q := taskQuery{text: strings.TrimSpace(text)}
+	for _, word := range strings.Fields(text) {
+		negate := strings.HasPrefix(word, "-") && len(word) > 1
+		if negate {
+			word = word[1:]
+		}
+		field, value, hasField := strings.Cut(word, ":")
+		var condition string
+		var args []interface{}
+		var err error
+		if hasField {
+			condition, args, err = compileTerm(strings.ToLower(field), value, now)
+		} else {
+			pattern := likePattern(word)
+			condition = `(title LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\')`
+			args = []interface{}{pattern, pattern}
+		}
+		if err != nil {
+			return taskQuery{}, err
+		}
+		if negate {
+			condition = "NOT COALESCE(" + condition + ", 0)"
+		}
+		q.conditions = append(q.conditions, condition)
+		q.args = append(q.args, args...)
+	}
+	return q, nil
+}
+
+// where returns the SQL condition matching the query.
+func (q taskQuery) where() string {
+	if len(q.conditions) == 0 {
+		return "1 = 1"
+	}
+	return strings.Join(q.conditions, " AND ")
+}
+
+func compileTerm(field, value string, now time.Time) (string, []interface{}, error) {
+	if value == "" {
+		return "", nil, fmt.Errorf("%s: needs a value", field)
+	}
+	switch field {
+	case "status":
+		switch strings.ToLower(value) {
+		case "todo":
+			return "status = ?", []interface{}{todo}, nil
+		case "doing":
+			return "status = ?", []interface{}{doing}, nil
+		case "done":
+			return "status = ?", []interface{}{done}, nil
+		}
+		return "", nil, fmt.Errorf("status: %q is not todo, doing or done", value)
+	case "tag":
+		// Tags are stored comma separated
+		return `(',' || tags || ',') LIKE ? ESCAPE '\'`, []interface{}{"%," + escapeLike(strings.TrimPrefix(value, "#")) + ",%"}, nil
+	case "context", "project":
+		if value == "none" {
+			return "COALESCE(" + field + ", '') = ''", nil, nil
+		}
+		return field + " = ? COLLATE NOCASE", []interface{}{strings.TrimLeft(value, "@+")}, nil
+	case "list":
+		for _, list := range gtdLists {
+			if strings.EqualFold(value, string(list)) {
+				return "COALESCE(NULLIF(gtd_list, ''), 'inbox') = ?", []interface{}{string(list)}, nil
+			}
+		}
+		return "", nil, fmt.Errorf("list: %q is not inbox, next, waiting or someday", value)
+	case "priority":
+		op, number := splitOperator(value)
+		n, err := strconv.Atoi(strings.TrimPrefix(number, "p"))
+		if err != nil {
+			return "", nil, fmt.Errorf("priority: %q is not a number", number)
+		}
+		return "priority " + op + " ?", []interface{}{n}, nil
+	}
+
+	column, ok := dateColumns[field]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown field %q, use one of %s", field, strings.Join(queryFields, " "))
+	}
+	if value == "none" && (field == "due" || field == "start") {
+		return column + " IS NULL", nil, nil
+	}
+	op, date := splitOperator(value)
+	if at, err := time.ParseInLocation("2006-01-02", date, time.Local); err == nil {
+		if op == "=" {
+			// A date matches the whole day
+			return "(julianday(" + column + ") >= julianday(?) AND julianday(" + column + ") < julianday(?))", []interface{}{at, at.AddDate(0, 0, 1)}, nil
+		}
+		return "julianday(" + column + ") " + op + " julianday(?)", []interface{}{at}, nil
+	}
+	span, err := parseSpan(date)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %w", field, err)
+	}
+	at := now.Add(span)
+	if field != "due" && field != "start" {
+		// created:<7d means less than 7 days ago, so after that point in time
+		at = now.Add(-span)
+		op = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "=": "="}[op]
+	}
+	return "julianday(" + column + ") " + op + " julianday(?)", []interface{}{at}, nil
+}
+
+// splitOperator splits a comparison like ">=2" into the operator and the
+// operand, with = when there is none.
+func splitOperator(value string) (string, string) {
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "=", value
+}
+
+// parseSpan reads a span of hours, days or weeks such as 3h, 7d or 2w.
+func parseSpan(value string) (time.Duration, error) {
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if len(value) >= 2 {
+		n, err := strconv.Atoi(value[:len(value)-1])
+		if unit, ok := units[value[len(value)-1]]; ok && err == nil && n >= 0 {
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return 0, fmt.Errorf("%q is not a date (YYYY-MM-DD) or a span like 3h, 7d or 2w", value)
+}
+
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+func likePattern(text string) string {
+	return "%" + escapeLike(text) + "%"
+}
+
+func (s *sqliteStore) Filter(q taskQuery) ([]item, error) {
+	return s.query("deleted_at IS NULL AND archived_at IS NULL AND "+q.where(), "sort_order, id", q.args...)
+}
+
+// applyQuery filters the list by a query, or clears the filter for an empty
+// one.
+func (m *model) applyQuery(text string) error {
+	if strings.TrimSpace(text) == "" {
+		m.tasksModel.taskQuery, m.tasksModel.queryIDs = "", nil
+		m.tasksModel.clampSelection()
+		return nil
+	}
+	q, err := parseQuery(text, time.Now())
+	if err != nil {
+		return err
+	}
+	tasks, err := m.store.Filter(q)
+	if err != nil {
+		return err
+	}
+	m.tasksModel.taskQuery = q.text
+	m.tasksModel.queryIDs = make(map[int]bool, len(tasks))
+	for _, task := range tasks {
+		m.tasksModel.queryIDs[task.id] = true
+	}
+	m.tasksModel.selected = 0
+	return nil
+}
+
+// refreshQuery runs the query filter again after tasks changed.
+func (m *model) refreshQuery() {
+	if m.tasksModel.taskQuery == "" {
+		return
+	}
+	selected := m.tasksModel.selected
+	if err := m.applyQuery(m.tasksModel.taskQuery); err != nil {
+		m.showError("run query", err)
+	}
+	m.tasksModel.selected = selected
+	m.tasksModel.clampSelection()
+}
+
+// runList implements the -list flag: it prints the tasks matching a query
+// without starting the interface.
+func runList(text string, format outputFormat) error {
+	q, err := parseQuery(text, time.Now())
+	if err != nil {
+		return err
+	}
+	m := newModel()
+	defer m.close()
+	tasks, err := m.store.Filter(q)
+	if err != nil {
+		return err
+	}
+	return writeTasks(os.Stdout, tasks, format)
+}
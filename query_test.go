@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryFilter(t *testing.T) {
+	m := newTestModel(t)
+	now := time.Now()
+	tasks := []item{
+		{title: "Write report", tags: []string{"work"}, status: doing, priority: 1, createdAt: now.AddDate(0, 0, -2), dueAt: now.Add(24 * time.Hour), context: "office"},
+		{title: "Call plumber", tags: []string{"home"}, priority: 2, createdAt: now.AddDate(0, 0, -10), context: "phone", project: "house"},
+		{title: "Buy milk", tags: []string{"home", "shop"}, createdAt: now.AddDate(0, -1, 0), notes: "100% skimmed"},
+		{title: "File taxes", tags: []string{"workshop"}, status: done, completedAt: now.AddDate(0, 0, -3), priority: 3, createdAt: now.AddDate(0, 0, -20), dueAt: now.AddDate(0, 0, 10)},
+	}
+	for i := range tasks {
+		if err := m.store.Save(&tasks[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, test := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Write report", "Call plumber", "Buy milk", "File taxes"}},
+		{"status:doing", []string{"Write report"}},
+		{"status:todo", []string{"Call plumber", "Buy milk"}},
+		{"tag:work", []string{"Write report"}},
+		{"tag:#home -tag:shop", []string{"Call plumber"}},
+		{"priority:>=2", []string{"Call plumber", "File taxes"}},
+		{"priority:p1", []string{"Write report"}},
+		{"priority:0", []string{"Buy milk"}},
+		{"context:@phone", []string{"Call plumber"}},
+		{"-context:phone", []string{"Write report", "Buy milk", "File taxes"}},
+		{"project:none", []string{"Write report", "Buy milk", "File taxes"}},
+		{"created:<7d", []string{"Write report"}},
+		{"created:>7d", []string{"Call plumber", "Buy milk", "File taxes"}},
+		{"due:<3d", []string{"Write report"}},
+		{"due:none", []string{"Call plumber", "Buy milk"}},
+		{"completed:" + now.AddDate(0, 0, -3).Format("2006-01-02"), []string{"File taxes"}},
+		{"report", []string{"Write report"}},
+		{"100%", []string{"Buy milk"}},
+		{"call PLUMBER", []string{"Call plumber"}},
+	} {
+		q, err := parseQuery(test.query, now)
+		if err != nil {
+			t.Errorf("%q: %v", test.query, err)
+			continue
+		}
+		found, err := m.store.Filter(q)
+		if err != nil {
+			t.Errorf("%q: %v", test.query, err)
+			continue
+		}
+		var titles []string
+		for _, task := range found {
+			titles = append(titles, task.title)
+		}
+		if !reflect.DeepEqual(titles, test.want) {
+			t.Errorf("%q matched %q, want %q", test.query, titles, test.want)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, query := range []string{"status:later", "list:today", "priority:high", "due:soon", "created:-3d", "colour:red", "tag:"} {
+		if _, err := parseQuery(query, time.Now()); err == nil {
+			t.Errorf("%q compiled, want an error", query)
+		}
+	}
+}
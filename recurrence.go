@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recurrence is a small subset of an iCalendar RRULE: a frequency, an
+// interval and, for weekly rules, an optional set of weekdays.
+type recurrence struct {
+	freq     string // DAILY, WEEKLY, MONTHLY or YEARLY
+	interval int
+	byDay    []time.Weekday
+	monthDay int // BYMONTHDAY, the day a monthly or yearly task is kept on, 0 for that of its due date
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = map[string]string{
+	"sun": "SU",
+	"mon": "MO",
+	"tue": "TU",
+	"wed": "WE",
+	"thu": "TH",
+	"fri": "FR",
+	"sat": "SA",
+}
+
+// parseRecurrence extracts the first every:<spec> token from the input and
+// returns it as an RRULE string, e.g. every:2w becomes FREQ=WEEKLY;INTERVAL=2.
+// Supported specs: day, week, month, year, weekday, <n>d, <n>w, <n>m, <n>y,
+// comma separated weekdays such as mon,wed,fri, and <n>w:mon,fri for those
+// weekdays every n weeks.
+func parseRecurrence(input string) string {
+	for _, word := range strings.Fields(input) {
+		if !strings.HasPrefix(word, "every:") {
+			continue
+		}
+		if rule := recurrenceRule(strings.ToLower(strings.TrimPrefix(word, "every:"))); rule != "" {
+			return rule
+		}
+	}
+	return ""
+}
+
+func recurrenceRule(spec string) string {
+	switch spec {
+	case "day", "daily":
+		return "FREQ=DAILY;INTERVAL=1"
+	case "week", "weekly":
+		return "FREQ=WEEKLY;INTERVAL=1"
+	case "month", "monthly":
+		return "FREQ=MONTHLY;INTERVAL=1"
+	case "year", "yearly":
+		return "FREQ=YEARLY;INTERVAL=1"
+	case "weekday", "weekdays":
+		return "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,TU,WE,TH,FR"
+	}
+
+	if weeks, days, ok := strings.Cut(spec, ":"); ok {
+		n, err := strconv.Atoi(strings.TrimSuffix(weeks, "w"))
+		rule := recurrenceRule(days)
+		if !strings.HasSuffix(weeks, "w") || err != nil || n < 1 || strings.Contains(days, ":") || !strings.Contains(rule, "BYDAY=") {
+			return ""
+		}
+		return strings.Replace(rule, "INTERVAL=1", fmt.Sprintf("INTERVAL=%d", n), 1)
+	}
+
+	if len(spec) > 1 {
+		if n, err := strconv.Atoi(spec[:len(spec)-1]); err == nil && n > 0 {
+			switch spec[len(spec)-1] {
+			case 'd':
+				return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", n)
+			case 'w':
+				return fmt.Sprintf("FREQ=WEEKLY;INTERVAL=%d", n)
+			case 'm':
+				return fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d", n)
+			case 'y':
+				return fmt.Sprintf("FREQ=YEARLY;INTERVAL=%d", n)
+			}
+		}
+	}
+
+	var days []string
+	for _, name := range strings.Split(spec, ",") {
+		code, ok := weekdayNames[name]
+		if !ok {
+			return ""
+		}
+		days = append(days, code)
+	}
+	return "FREQ=WEEKLY;INTERVAL=1;BYDAY=" + strings.Join(days, ",")
+}
+
+// editedRecurrence returns the rule of an edited task. The every: token of
+// the edit shows the old rule without what it keeps on the side, like the day
+// of month a short month moved the task from, so a token left as it was
+// keeps the old rule whole.
+func editedRecurrence(input, old string) string {
+	rule := parseRecurrence(input)
+	if spec := recurrenceSpec(old); spec != "" && rule == recurrenceRule(spec) {
+		return old
+	}
+	return rule
+}
+
+func removeRecurrence(input string) string {
+	words := strings.Fields(input)
+	var result []string
+	for _, word := range words {
+		if !strings.HasPrefix(word, "every:") {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// parseRule decodes an RRULE string as stored in the recurrence column.
+func parseRule(rule string) (recurrence, error) {
+	r := recurrence{interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			r.freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("invalid interval %q", value)
+			}
+			r.interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[code]
+				if !ok {
+					return r, fmt.Errorf("invalid weekday %q", code)
+				}
+				r.byDay = append(r.byDay, day)
+			}
+		case "BYMONTHDAY":
+			// Only a single day is kept; others (-1, 1,15) are ignored
+			// like the parts not supported at all
+			if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= 31 {
+				r.monthDay = n
+			}
+		}
+	}
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+		return r, nil
+	}
+	return r, fmt.Errorf("unsupported frequency %q", r.freq)
+}
+
+// next returns the first occurrence strictly after t. Weekly rules with
+// weekdays take the rest of t's week, then the week interval weeks on
+// (weeks start on Monday). Monthly and yearly rules keep the day of month,
+// moved to the last day of months too short for it.
+func (r recurrence) next(t time.Time) time.Time {
+	switch r.freq {
+	case "DAILY":
+		return t.AddDate(0, 0, r.interval)
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return t.AddDate(0, 0, 7*r.interval)
+		}
+		for candidate := t.AddDate(0, 0, 1); candidate.Weekday() != time.Monday; candidate = candidate.AddDate(0, 0, 1) {
+			if r.onDay(candidate) {
+				return candidate
+			}
+		}
+		monday := t.AddDate(0, 0, -(int(t.Weekday())+6)%7)
+		for i := 0; i < 7; i++ {
+			if candidate := monday.AddDate(0, 0, 7*r.interval+i); r.onDay(candidate) {
+				return candidate
+			}
+		}
+	case "MONTHLY":
+		return r.addMonths(t, r.interval)
+	case "YEARLY":
+		return r.addMonths(t, 12*r.interval)
+	}
+	return t
+}
+
+func (r recurrence) onDay(t time.Time) bool {
+	for _, day := range r.byDay {
+		if t.Weekday() == day {
+			return true
+		}
+	}
+	return false
+}
+
+// addMonths moves t on by months, onto the rule's day of month, or t's day
+// without one, or the last day of the month when it has fewer days.
+func (r recurrence) addMonths(t time.Time, months int) time.Time {
+	day := r.monthDay
+	if day == 0 {
+		day = t.Day()
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, last)-1)
+}
+
+// withMonthDay sets the BYMONTHDAY part of rule, or removes it when day is 0.
+func withMonthDay(rule string, day int) string {
+	var parts []string
+	for _, part := range strings.Split(rule, ";") {
+		if !strings.HasPrefix(part, "BYMONTHDAY=") {
+			parts = append(parts, part)
+		}
+	}
+	if day != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", day))
+	}
+	return strings.Join(parts, ";")
+}
+
+// nextOccurrence builds the follow-up task for a completed recurring task.
+// The new due date is computed from the old one so a late completion does
+// not shift the schedule; tasks without a due date repeat from now. When a
+// short month moves a monthly or yearly task off its day (Jan 31 to Feb 28),
+// the day is kept in the rule so the task goes back to it after.
+func nextOccurrence(task item, now time.Time) (item, bool) {
+	r, err := parseRule(task.recurrence)
+	if err != nil {
+		return item{}, false
+	}
+
+	base := task.dueAt
+	if base.IsZero() {
+		base = now
+	}
+	rule := task.recurrence
+	if (r.freq == "MONTHLY" || r.freq == "YEARLY") && r.monthDay == 0 {
+		r.monthDay = base.Day()
+	}
+	due := r.next(base)
+	for due.Before(now) {
+		due = r.next(due)
+	}
+	if r.monthDay != 0 {
+		day := r.monthDay
+		if due.Day() == day {
+			day = 0
+		}
+		rule = withMonthDay(rule, day)
+	}
+
+	return item{
+		title:      task.title,
+		tags:       append([]string{}, task.tags...),
+		status:     todo,
+		createdAt:  now,
+		dueAt:      due,
+		recurrence: rule,
+		notes:      task.notes,
+		priority:   task.priority,
+		context:    task.context,
+		project:    task.project,
+		list:       task.list,
+		estimate:   task.estimate,
+		starred:    task.starred,
+	}, true
+}
+
+// recurrenceSpec is the inverse of recurrenceRule: it turns a stored RRULE
+// back into the every:<spec> form accepted in the input.
+func recurrenceSpec(rule string) string {
+	r, err := parseRule(rule)
+	if err != nil {
+		return ""
+	}
+
+	if len(r.byDay) > 0 {
+		var names []string
+		for _, day := range r.byDay {
+			names = append(names, strings.ToLower(day.String()[:3]))
+		}
+		if r.interval > 1 {
+			return fmt.Sprintf("%dw:%s", r.interval, strings.Join(names, ","))
+		}
+		return strings.Join(names, ",")
+	}
+
+	units := map[string][2]string{
+		"DAILY":   {"day", "d"},
+		"WEEKLY":  {"week", "w"},
+		"MONTHLY": {"month", "m"},
+		"YEARLY":  {"year", "y"},
+	}
+	unit := units[r.freq]
+	if r.interval == 1 {
+		return unit[0]
+	}
+	return fmt.Sprintf("%d%s", r.interval, unit[1])
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 0, 0, 0, time.UTC)
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	tests := []struct {
+		rule string
+		from time.Time
+		want time.Time
+	}{
+		{"FREQ=DAILY;INTERVAL=3", date(2026, time.February, 27), date(2026, time.March, 2)},
+		{"FREQ=WEEKLY;INTERVAL=2", date(2026, time.October, 15), date(2026, time.October, 29)},
+		{"FREQ=MONTHLY;INTERVAL=1", date(2026, time.January, 31), date(2026, time.February, 28)},
+		{"FREQ=MONTHLY;INTERVAL=1", date(2028, time.January, 31), date(2028, time.February, 29)},
+		{"FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=31", date(2026, time.February, 28), date(2026, time.March, 31)},
+		{"FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=31", date(2026, time.March, 31), date(2026, time.April, 30)},
+		{"FREQ=MONTHLY;INTERVAL=3", date(2026, time.November, 30), date(2027, time.February, 28)},
+		{"FREQ=MONTHLY;INTERVAL=1", date(2026, time.December, 15), date(2027, time.January, 15)},
+		{"FREQ=YEARLY;INTERVAL=1", date(2028, time.February, 29), date(2029, time.February, 28)},
+		{"FREQ=YEARLY;INTERVAL=4;BYMONTHDAY=29", date(2028, time.February, 29), date(2032, time.February, 29)},
+		{"FREQ=YEARLY;INTERVAL=2", date(2026, time.October, 15), date(2028, time.October, 15)},
+		// Thursday 15 October 2026
+		{"FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,FR", date(2026, time.October, 15), date(2026, time.October, 16)},
+		{"FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,TU", date(2026, time.October, 15), date(2026, time.October, 19)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2026, time.October, 15), date(2026, time.October, 16)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", date(2026, time.October, 16), date(2026, time.October, 26)},
+		{"FREQ=WEEKLY;INTERVAL=3;BYDAY=SU", date(2026, time.October, 18), date(2026, time.November, 8)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", date(2026, time.October, 12), date(2026, time.October, 18)},
+	}
+	for _, tt := range tests {
+		r, err := parseRule(tt.rule)
+		if err != nil {
+			t.Fatalf("parseRule(%q): %v", tt.rule, err)
+		}
+		if got := r.next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s from %s: got %s, want %s", tt.rule, tt.from.Format("Mon 2006-01-02"), got.Format("Mon 2006-01-02"), tt.want.Format("Mon 2006-01-02"))
+		}
+	}
+}
+
+func TestNextOccurrenceKeepsMonthDay(t *testing.T) {
+	task := item{
+		title:      "Pay rent",
+		dueAt:      date(2026, time.January, 31),
+		recurrence: "FREQ=MONTHLY;INTERVAL=1",
+		notes:      "Transfer from savings",
+		priority:   1,
+		estimate:   15,
+		starred:    true,
+	}
+	want := []struct {
+		due  time.Time
+		rule string
+	}{
+		{date(2026, time.February, 28), "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=31"},
+		{date(2026, time.March, 31), "FREQ=MONTHLY;INTERVAL=1"},
+		{date(2026, time.April, 30), "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=31"},
+		{date(2026, time.May, 31), "FREQ=MONTHLY;INTERVAL=1"},
+	}
+	now := date(2026, time.January, 30)
+	for _, w := range want {
+		next, ok := nextOccurrence(task, now)
+		if !ok {
+			t.Fatalf("no next occurrence of %q", task.recurrence)
+		}
+		if !next.dueAt.Equal(w.due) || next.recurrence != w.rule {
+			t.Fatalf("after %s: got %s %q, want %s %q", task.dueAt.Format("2006-01-02"), next.dueAt.Format("2006-01-02"), next.recurrence, w.due.Format("2006-01-02"), w.rule)
+		}
+		if next.notes != task.notes || next.priority != task.priority || next.estimate != task.estimate || next.starred != task.starred {
+			t.Errorf("fields not carried over: %+v", next)
+		}
+		task = next
+	}
+}
+
+func TestRecurrenceSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		rule string
+	}{
+		{"day", "FREQ=DAILY;INTERVAL=1"},
+		{"3d", "FREQ=DAILY;INTERVAL=3"},
+		{"2m", "FREQ=MONTHLY;INTERVAL=2"},
+		{"year", "FREQ=YEARLY;INTERVAL=1"},
+		{"2y", "FREQ=YEARLY;INTERVAL=2"},
+		{"mon,wed,fri", "FREQ=WEEKLY;INTERVAL=1;BYDAY=MO,WE,FR"},
+		{"2w:mon,fri", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+	}
+	for _, tt := range tests {
+		if got := recurrenceRule(tt.spec); got != tt.rule {
+			t.Errorf("recurrenceRule(%q) = %q, want %q", tt.spec, got, tt.rule)
+		}
+		if got := recurrenceSpec(tt.rule); got != tt.spec {
+			t.Errorf("recurrenceSpec(%q) = %q, want %q", tt.rule, got, tt.spec)
+		}
+	}
+	for _, spec := range []string{"2w:week", "0w:mon", "2d:mon", "2w:3w:mon"} {
+		if rule := recurrenceRule(spec); rule != "" {
+			t.Errorf("recurrenceRule(%q) = %q, want none", spec, rule)
+		}
+	}
+}
+
+func TestEditedRecurrence(t *testing.T) {
+	old := "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=31"
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Pay rent every:month !p1", old},
+		{"Pay rent every:2m", "FREQ=MONTHLY;INTERVAL=2"},
+		{"Pay rent", ""},
+	}
+	for _, tt := range tests {
+		if got := editedRecurrence(tt.input, old); got != tt.want {
+			t.Errorf("editedRecurrence(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Vim-style registers: yy yanks the selected task with its subtasks and p
+// puts a copy below it (P above), so tasks can be duplicated. d fills the
+// register too, so d then p moves a task. "a in front of the command uses
+// the named register a instead of the unnamed one. Yanking into the unnamed
+// register also copies the tasks to the system clipboard.
+
+const unnamedRegister = `"`
+
+// isRegister reports whether the key after " names a register.
+func isRegister(key string) bool {
+	return key == unnamedRegister || (len(key) == 1 && key[0] >= 'a' && key[0] <= 'z')
+}
+
+// takeRegister returns the register picked with " for the current command
+// and resets the choice.
+func (m *model) takeRegister() string {
+	name := m.tasksModel.register
+	m.tasksModel.register = ""
+	if name == "" {
+		return unnamedRegister
+	}
+	return name
+}
+
+// fillRegister stores the tasks at the indices with their subtasks, parents
+// before children.
+func (m *model) fillRegister(name string, indices []int) int {
+	seen := make(map[int]bool)
+	var tasks []item
+	for _, index := range indices {
+		for _, i := range append([]int{index}, m.tasksModel.descendants(m.tasksModel.items[index].id)...) {
+			task := m.tasksModel.items[i]
+			if !seen[task.id] {
+				seen[task.id] = true
+				task.tags = append([]string{}, task.tags...)
+				tasks = append(tasks, task)
+			}
+		}
+	}
+	if len(tasks) == 0 {
+		return 0
+	}
+	if m.registers == nil {
+		m.registers = make(map[string][]item)
+	}
+	m.registers[name] = tasks
+	m.registers[unnamedRegister] = tasks // The unnamed register follows the last yank or delete
+	return len(tasks)
+}
+
+// yankTasks yanks the tasks at the indices into the register picked with ".
+func (m *model) yankTasks(indices []int) tea.Cmd {
+	name := m.takeRegister()
+	count := m.fillRegister(name, indices)
+	if count == 0 {
+		return nil
+	}
+	m.showMessage(fmt.Sprintf("Yanked %s into register %s", countTasks(count), name))
+	if name == unnamedRegister {
+		return copyToClipboard(yankText(m.registers[name]))
+	}
+	return nil
+}
+
+// deleteTasks deletes the tasks at the indices, keeping them in the register
+// picked with " so they can be put back elsewhere.
+func (m *model) deleteTasks(indices []int) {
+	m.fillRegister(m.takeRegister(), indices)
+	m.removeTasks(indices)
+}
+
+// put adds count copies of the tasks in the register picked with " after
+// the selected task and its subtasks, or before it, as its siblings.
+func (m *model) put(before bool, count int) {
+	name := m.takeRegister()
+	tasks := m.registers[name]
+	if len(tasks) == 0 {
+		m.showMessage("Register " + name + " is empty")
+		return
+	}
+
+	t := &m.tasksModel
+	parentID, position := 0, len(t.items)
+	if index := t.selectedIndex(); index >= 0 {
+		parentID = t.items[index].parentID
+		position = index
+		if !before {
+			// After the last of the selected task's subtasks
+			for _, i := range t.descendants(t.items[index].id) {
+				position = max(position, i)
+			}
+			position++
+		}
+	}
+
+	snapshot := m.snapshot()
+	var added []item
+copies:
+	for range count {
+		ids := make(map[int]int, len(tasks))
+		for _, task := range tasks {
+			oldID := task.id
+			task.id, task.sortOrder = 0, 0
+			task.tags = append([]string{}, task.tags...)
+			if newParent, ok := ids[task.parentID]; ok {
+				task.parentID = newParent
+			} else {
+				task.parentID = parentID // A root of the yanked tasks
+			}
+			if err := m.store.Save(&task); err != nil {
+				m.showError("save task", err)
+				break copies
+			}
+			ids[oldID] = task.id
+			added = append(added, task)
+			m.fireHook(hookAdd, task)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	m.insertAt(position, parentID, added)
+	m.record("put", snapshot)
+	t.selectID(added[0].id)
+	m.showMessage(fmt.Sprintf("Put %s from register %s", countTasks(len(added)), name))
+}
+
+// insertAt places newly saved tasks at a position of the list, under the
+// parent they were saved with.
+func (m *model) insertAt(position, parentID int, added []item) {
+	t := &m.tasksModel
+	items := append([]item{}, t.items[:position]...)
+	items = append(items, added...)
+	t.items = append(items, t.items[position:]...)
+
+	// Renumber the manual order so the new tasks sit where they were put
+	var changed []item
+	for i := range t.items {
+		if t.items[i].sortOrder != i+1 {
+			t.items[i].sortOrder = i + 1
+			changed = append(changed, t.items[i])
+		}
+	}
+	if err := m.store.SaveOrder(changed); err != nil {
+		m.showError("save order", err)
+	}
+	if parentID != 0 {
+		for _, i := range t.syncParents(added[0].id) {
+			if err := m.store.Update(t.items[i]); err != nil {
+				m.showError("update task", err)
+			}
+		}
+	}
+}
+
+func countTasks(count int) string {
+	if count == 1 {
+		return "1 task"
+	}
+	return fmt.Sprintf("%d tasks", count)
+}
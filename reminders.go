@@ -0,0 +1,290 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Reminders are extra alerts for a task, set in the task input with
+// remind:<when> and kept in their own table so a task can have several:
+//
+//	remind:30m, remind:2h, remind:1d   before the due date
+//	remind:9am, remind:14:30           the next time the clock shows that time
+//	remind:2024-06-01T09:00            at a fixed time
+//
+// They go off through the same check as due tasks, ring the terminal bell and
+// are listed by :reminders.
+
+const remindersMode = "reminders" // List of upcoming reminders
+
+type reminder struct {
+	taskID int
+	at     time.Time     // Fixed time, zero for a reminder relative to the due date
+	before time.Duration // How long before the due date, when at is zero
+}
+
+// time returns when the reminder goes off, zero if it is relative to the due
+// date of a task that has none.
+func (r reminder) time(task item) time.Time {
+	if !r.at.IsZero() {
+		return r.at
+	}
+	if task.dueAt.IsZero() {
+		return time.Time{}
+	}
+	return task.dueAt.Add(-r.before)
+}
+
+// parseReminders extracts every remind:<when> token. Times of day are
+// resolved against now.
+func parseReminders(input string, now time.Time) []reminder {
+	var reminders []reminder
+	for _, word := range strings.Fields(input) {
+		if !strings.HasPrefix(word, "remind:") {
+			continue
+		}
+		value := strings.ToLower(strings.TrimPrefix(word, "remind:"))
+		if d, ok := parseOffset(value); ok {
+			reminders = append(reminders, reminder{before: d})
+		} else if t, err := time.ParseInLocation("2006-01-02t15:04", value, time.Local); err == nil {
+			reminders = append(reminders, reminder{at: t})
+		} else if t, ok := nextTimeOfDay(value, now); ok {
+			reminders = append(reminders, reminder{at: t})
+		}
+	}
+	return reminders
+}
+
+func removeReminders(input string) string {
+	var result []string
+	for _, word := range strings.Fields(input) {
+		if !strings.HasPrefix(word, "remind:") {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// parseOffset parses <n>m, <n>h or <n>d.
+func parseOffset(value string) (time.Duration, bool) {
+	units := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}
+	if len(value) < 2 {
+		return 0, false
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// nextTimeOfDay parses 9am, 9:30pm or 14:30 and returns the next time after
+// now the clock shows it.
+func nextTimeOfDay(value string, now time.Time) (time.Time, bool) {
+	hour, minute, ok := parseClock(value)
+	if !ok {
+		return time.Time{}, false
+	}
+	t := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.Local)
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// formatReminder turns a reminder back into the token that sets it.
+func formatReminder(r reminder) string {
+	if !r.at.IsZero() {
+		return "remind:" + r.at.Format("2006-01-02T15:04")
+	}
+	switch {
+	case r.before%(24*time.Hour) == 0 && r.before != 0:
+		return fmt.Sprintf("remind:%dd", r.before/(24*time.Hour))
+	case r.before%time.Hour == 0 && r.before != 0:
+		return fmt.Sprintf("remind:%dh", r.before/time.Hour)
+	}
+	return fmt.Sprintf("remind:%dm", r.before/time.Minute)
+}
+
+// describeReminder says when a reminder goes off relative to the task.
+func describeReminder(r reminder) string {
+	if !r.at.IsZero() {
+		return "at " + formatTime(r.at, "Mon 2 Jan 15:04")
+	}
+	if r.before == 0 {
+		return "when due"
+	}
+	return strings.TrimPrefix(formatReminder(r), "remind:") + " before due"
+}
+
+// remindersFor returns the reminders of a task.
+func (m model) remindersFor(taskID int) []reminder {
+	var reminders []reminder
+	for _, r := range m.reminders {
+		if r.taskID == taskID {
+			reminders = append(reminders, r)
+		}
+	}
+	return reminders
+}
+
+// setReminders replaces the reminders of a task with the ones in the input.
+func (m *model) setReminders(taskID int, input string) {
+	reminders := parseReminders(input, time.Now())
+	if len(reminders) == 0 && len(m.remindersFor(taskID)) == 0 {
+		return
+	}
+	if err := m.store.SaveReminders(taskID, reminders); err != nil {
+		m.showError("save reminders", err)
+		return
+	}
+	m.refreshReminders()
+}
+
+func (m *model) refreshReminders() {
+	reminders, err := m.store.Reminders()
+	if err != nil {
+		m.showError("load reminders", err)
+		return
+	}
+	m.reminders = reminders
+}
+
+// dueReminders returns the alerts for reminders that went off after since and
+// up to now.
+func (m model) dueReminders(since, now time.Time) []alert {
+	var alerts []alert
+	for _, r := range m.reminders {
+		index := m.tasksModel.indexOf(r.taskID)
+		if index < 0 || m.tasksModel.items[index].status == done {
+			continue
+		}
+		task := m.tasksModel.items[index]
+		if t := r.time(task); !t.IsZero() && t.After(since) && !t.After(now) {
+			alerts = append(alerts, alert{title: "Reminder", body: task.title, task: task})
+		}
+	}
+	return alerts
+}
+
+// bellMsg rings the terminal bell.
+type bellMsg struct{}
+
+// bellDoneMsg takes the bell out of the view again.
+type bellDoneMsg struct{}
+
+// bellFrame is how long the view keeps the bell, longer than the renderer
+// takes to draw a frame.
+const bellFrame = 200 * time.Millisecond
+
+// bell rings the terminal bell. The BEL goes out with the next frame drawn
+// by Bubble Tea's renderer rather than straight to stdout from a Cmd, where
+// it could land in the middle of a frame.
+func bell() tea.Msg {
+	return bellMsg{}
+}
+
+// upcomingReminder is a reminder listed by :reminders.
+type upcomingReminder struct {
+	at       time.Time
+	task     item
+	reminder reminder
+}
+
+func (m model) upcomingReminders(now time.Time) []upcomingReminder {
+	var upcoming []upcomingReminder
+	for _, r := range m.reminders {
+		index := m.tasksModel.indexOf(r.taskID)
+		if index < 0 || m.tasksModel.items[index].status == done {
+			continue
+		}
+		task := m.tasksModel.items[index]
+		if t := r.time(task); t.After(now) {
+			upcoming = append(upcoming, upcomingReminder{at: t, task: task, reminder: r})
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].at.Before(upcoming[j].at) })
+	return upcoming
+}
+
+func (m model) renderReminders() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Upcoming reminders") + "\n\n")
+	upcoming := m.upcomingReminders(time.Now())
+	if len(upcoming) == 0 {
+		s.WriteString(helpStyle.Render("No reminders set. Add remind:30m or remind:9am to a task.") + "\n")
+	}
+	for _, u := range upcoming {
+		s.WriteString(itemStyle.Render(formatTime(u.at, "Mon 2 Jan 15:04")+"  "+u.task.title) + " ")
+		s.WriteString(helpStyle.Render("("+describeReminder(u.reminder)+")") + "\n")
+	}
+	return s.String()
+}
+
+func createRemindersTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS reminders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			at DATETIME,
+			before_seconds INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS reminders_task ON reminders (task_id);
+	`)
+	return err
+}
+
+func (s *sqliteStore) Reminders() ([]reminder, error) {
+	rows, err := s.db.Query(`
+		SELECT r.task_id, r.at, r.before_seconds
+		FROM reminders r JOIN tasks t ON t.id = r.task_id
+		WHERE t.deleted_at IS NULL
+		ORDER BY r.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []reminder
+	for rows.Next() {
+		var r reminder
+		var at sql.NullTime
+		var before sql.NullInt64
+		if err := rows.Scan(&r.taskID, &at, &before); err != nil {
+			return nil, err
+		}
+		if at.Valid {
+			r.at = at.Time
+		}
+		r.before = time.Duration(before.Int64) * time.Second
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
+
+func (s *sqliteStore) SaveReminders(taskID int, reminders []reminder) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM reminders WHERE task_id = ?", taskID); err != nil {
+			return err
+		}
+		for _, r := range reminders {
+			_, err := tx.Exec("INSERT INTO reminders (task_id, at, before_seconds) VALUES (?, ?, ?)",
+				taskID, nullTime(r.at), int64(r.before/time.Second))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Counts and repeat: a number before a command runs it that many times or
+// on that many tasks, vim-style (3j moves down three rows, 5dd deletes five
+// tasks), and . repeats the last change, with its count unless a new one is
+// typed. The commands that take a count live in normalActions rather than the
+// key switch in update so . can run them again.
+//
+// gg and G jump to the first and last task and 12G to the twelfth,
+// ctrl+d and ctrl+u half a screen down and up.
+
+// normalAction is a normal mode command that takes a count.
+type normalAction struct {
+	change bool // Changes tasks, so . repeats it
+	line   bool // The count picks a row, 0 when none was typed
+	run    func(m *model, count int) tea.Cmd
+}
+
+// lastAction is the change . repeats.
+type lastAction struct {
+	sequence string
+	count    int
+}
+
+var normalActions = map[string]normalAction{
+	"j": {run: func(m *model, count int) tea.Cmd {
+		m.tasksModel.selected = min(m.tasksModel.selected+count, max(0, len(m.tasksModel.rows())-1))
+		return nil
+	}},
+	"k": {run: func(m *model, count int) tea.Cmd {
+		m.tasksModel.selected = max(m.tasksModel.selected-count, 0)
+		return nil
+	}},
+	"gg": {line: true, run: func(m *model, count int) tea.Cmd {
+		m.tasksModel.goToRow(max(count, 1))
+		return nil
+	}},
+	"G": {line: true, run: func(m *model, count int) tea.Cmd {
+		if count == 0 {
+			count = len(m.tasksModel.rows())
+		}
+		m.tasksModel.goToRow(count)
+		return nil
+	}},
+	"ctrl+d": {run: func(m *model, count int) tea.Cmd {
+		m.tasksModel.selected = min(m.tasksModel.selected+count*m.halfPage(), max(0, len(m.tasksModel.rows())-1))
+		return nil
+	}},
+	"ctrl+u": {run: func(m *model, count int) tea.Cmd {
+		m.tasksModel.selected = max(m.tasksModel.selected-count*m.halfPage(), 0)
+		return nil
+	}},
+	"gc": {run: func(m *model, count int) tea.Cmd {
+		m.tasksModel.byContext = !m.tasksModel.byContext
+		err := m.store.SaveSetting("group_by_context", fmt.Sprint(m.tasksModel.byContext))
+		if err != nil {
+			m.showError("save setting", err)
+		}
+		return nil
+	}},
+	"gd": {run: func(m *model, count int) tea.Cmd {
+		m.cycleDensity()
+		return nil
+	}},
+	"gf": {run: func(m *model, count int) tea.Cmd {
+		m.toggleFocus()
+		return nil
+	}},
+	"gp": {run: func(m *model, count int) tea.Cmd {
+		cmd, err := m.switchProfile(m.config.nextProfile())
+		if err != nil {
+			m.showError("switch profile", err)
+		}
+		return cmd
+	}},
+	"dd": {change: true, run: func(m *model, count int) tea.Cmd {
+		m.deleteTasks(m.tasksModel.rowIndices(count))
+		return nil
+	}},
+	" ": {change: true, run: func(m *model, count int) tea.Cmd {
+		indices := m.tasksModel.rowIndices(count)
+		if len(indices) == 1 {
+			m.setStatus(indices, toggleStatus(m.tasksModel.items[indices[0]].status))
+		} else if len(indices) > 1 {
+			m.setStatus(indices, toggleAll(m.tasksModel.items, indices))
+		}
+		return nil
+	}},
+	"J": {change: true, run: func(m *model, count int) tea.Cmd {
+		for range count {
+			if index := m.tasksModel.selectedIndex(); index >= 0 {
+				m.moveTask(index, 1)
+			}
+		}
+		return nil
+	}},
+	"K": {change: true, run: func(m *model, count int) tea.Cmd {
+		for range count {
+			if index := m.tasksModel.selectedIndex(); index >= 0 {
+				m.moveTask(index, -1)
+			}
+		}
+		return nil
+	}},
+	"yy": {run: func(m *model, count int) tea.Cmd {
+		return m.yankTasks(m.tasksModel.rowIndices(count))
+	}},
+	"p": {change: true, run: func(m *model, count int) tea.Cmd {
+		m.put(false, count)
+		return nil
+	}},
+	"P": {change: true, run: func(m *model, count int) tea.Cmd {
+		m.put(true, count)
+		return nil
+	}},
+	"*": {change: true, run: func(m *model, count int) tea.Cmd {
+		if indices := m.tasksModel.rowIndices(count); len(indices) > 0 {
+			m.toggleStar(indices)
+		}
+		return nil
+	}},
+	"+": {change: true, run: func(m *model, count int) tea.Cmd {
+		if indices := m.tasksModel.rowIndices(count); len(indices) > 0 {
+			m.planToday(indices)
+		}
+		return nil
+	}},
+	"D": {change: true, run: func(m *model, count int) tea.Cmd {
+		m.duplicateTask(count)
+		return nil
+	}},
+	"mp": {run: func(m *model, count int) tea.Cmd {
+		m.openProjectPicker(m.tasksModel.rowIndices(count))
+		return nil
+	}},
+	"mg": {run: func(m *model, count int) tea.Cmd {
+		m.openGoalPicker(m.tasksModel.rowIndices(count))
+		return nil
+	}},
+	"mi": triageAction(inbox),
+	"mn": triageAction(nextAction),
+	"mw": triageAction(waiting),
+	"ms": triageAction(someday),
+}
+
+// prefixKeys start a command of two keys, such as gg or dd.
+var prefixKeys = map[string]bool{"z": true, "m": true, "y": true, "d": true, "g": true, `"`: true, `\`: true}
+
+// actionAliases map other keys to the sequence in normalActions they run.
+var actionAliases = map[string]string{
+	"down":       "j",
+	"up":         "k",
+	"shift+down": "J",
+	"shift+up":   "K",
+}
+
+func triageAction(list gtdList) normalAction {
+	return normalAction{change: true, run: func(m *model, count int) tea.Cmd {
+		if indices := m.tasksModel.rowIndices(count); len(indices) > 0 {
+			m.triage(indices, list)
+		}
+		return nil
+	}}
+}
+
+// toggleAll completes the tasks unless they are all done already, in which
+// case it reopens them, as space does in visual mode.
+func toggleAll(items []item, indices []int) status {
+	for _, index := range indices {
+		if items[index].status != done {
+			return done
+		}
+	}
+	return todo
+}
+
+// goToRow selects the nth row of the list, counting from 1.
+func (t *tasksModel) goToRow(n int) {
+	t.selected = max(0, min(n, len(t.rows()))-1)
+}
+
+// halfPage is half the number of rows that fit on the screen, how far
+// ctrl+d and ctrl+u move.
+func (m model) halfPage() int {
+	return max(1, (m.height-12)/2/m.tasksModel.taskLines()) // Tabs, header, status bar and footer take about 12 lines
+}
+
+// rowIndices returns the item indices of count rows from the selected one.
+func (t tasksModel) rowIndices(count int) []int {
+	rows := t.rows()
+	var indices []int
+	for i := t.selected; i < t.selected+count && i < len(rows); i++ {
+		if i >= 0 {
+			indices = append(indices, rows[i].index)
+		}
+	}
+	return indices
+}
+
+// addCount adds a typed digit to the count, reporting whether the key was
+// one. 0 only counts after another digit.
+func (t *tasksModel) addCount(key string) bool {
+	if len(key) != 1 || key[0] < '0' || key[0] > '9' || (key == "0" && t.count == 0) {
+		return false
+	}
+	t.count = min(t.count*10+int(key[0]-'0'), 9999)
+	return true
+}
+
+// takeCount returns the typed count, 1 if none, and resets it.
+func (t *tasksModel) takeCount() int {
+	count := max(t.count, 1)
+	t.count = 0
+	return count
+}
+
+// runAction runs a command from normalActions, remembering it for . if it
+// changes tasks. Older completed tasks are loaded when it leaves the cursor
+// on the last row.
+func (m *model) runAction(sequence string) tea.Cmd {
+	action := normalActions[sequence]
+	var cmd tea.Cmd
+	if action.line {
+		count := m.tasksModel.count
+		m.tasksModel.count = 0
+		cmd = action.run(m, count)
+	} else {
+		count := m.tasksModel.takeCount()
+		if action.change {
+			m.tasksModel.lastAction = lastAction{sequence, count}
+		}
+		cmd = action.run(m, count)
+	}
+	return tea.Batch(cmd, m.loadHistoryAtEnd())
+}
+
+// repeatLast runs the last change again, with a new count if one was typed.
+func (m *model) repeatLast() tea.Cmd {
+	last := m.tasksModel.lastAction
+	if last.sequence == "" {
+		m.tasksModel.count = 0
+		m.showMessage("Nothing to repeat")
+		return nil
+	}
+	if m.tasksModel.count > 0 {
+		last.count = m.tasksModel.takeCount()
+		m.tasksModel.lastAction = last
+	}
+	return normalActions[last.sequence].run(m, last.count)
+}
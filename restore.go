@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// :restore lists the backups (see backup.go), newest first, with the number
+// of tasks in each. The selected one is compared with the tasks as they are
+// now: which would come back, which would be lost and which would be changed
+// back. enter, pressed twice, restores it. The current database is backed up
+// first, so a restore can itself be undone by restoring that backup.
+
+const restoreMode = "restore"
+
+const restorePreview = 5 // Titles listed per kind of difference
+
+// restoreState is the backup list being shown.
+type restoreState struct {
+	backups []backupInfo
+	current []item // Live tasks now, to compare with
+	cursor  int
+	confirm bool // Set after the first enter, a second one restores
+}
+
+// backupInfo is a backup as listed by :restore.
+type backupInfo struct {
+	path  string
+	at    time.Time
+	tasks []item // Live tasks in the backup
+	err   error  // Why the backup could not be read
+}
+
+// backupDiff is what restoring a backup would do to the current tasks.
+type backupDiff struct {
+	back    []item // Tasks in the backup only
+	lost    []item // Tasks added since the backup
+	changed []item // Tasks changed since, as they are in the backup
+}
+
+// openRestore lists the backups and shows them.
+func (m *model) openRestore() error {
+	paths, err := filepath.Glob(filepath.Join(m.config.backupDir, "xtui-*.db"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		m.showMessage("No backups in " + m.config.backupDir)
+		return nil
+	}
+	current, err := m.store.Load(liveTasks)
+	if err != nil {
+		return err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths))) // Newest first
+	var backups []backupInfo
+	for _, path := range paths {
+		b := backupInfo{path: path}
+		b.at, err = time.ParseInLocation("20060102-150405", strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "xtui-"), ".db"), time.Local)
+		if err != nil {
+			if info, err := os.Stat(path); err == nil {
+				b.at = info.ModTime()
+			}
+		}
+		b.tasks, b.err = m.readBackup(path)
+		backups = append(backups, b)
+	}
+	m.restore = restoreState{backups: backups, current: current}
+	m.tasksModel.mode = restoreMode
+	return nil
+}
+
+// backupData returns the SQLite database in a backup, decrypting a sealed
+// one with the key of the open database.
+func (m model) backupData(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(vaultMagic)) {
+		return data, nil
+	}
+	if m.vault == nil {
+		return nil, errors.New("encrypted, turn on encrypt in the config to restore it")
+	}
+	plain, err := m.vault.open(data)
+	if err != nil {
+		return nil, errors.New("sealed with another passphrase")
+	}
+	return plain, nil
+}
+
+// readBackup loads the live tasks in a backup. Its database is opened read
+// only, from a decrypted copy in the runtime directory if it is sealed.
+func (m model) readBackup(path string) ([]item, error) {
+	data, err := m.backupData(path)
+	if err != nil {
+		return nil, err
+	}
+	if m.vault != nil {
+		path = m.vault.working + ".restore"
+		defer os.Remove(path)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return (&sqliteStore{db: db}).Load(liveTasks)
+}
+
+// diffBackup compares the tasks in a backup with the current ones.
+func diffBackup(backup, current []item) backupDiff {
+	now := make(map[int]item, len(current))
+	for _, task := range current {
+		now[task.id] = task
+	}
+	var d backupDiff
+	for _, task := range backup {
+		was, ok := now[task.id]
+		switch {
+		case !ok:
+			d.back = append(d.back, task)
+		case formatTaskInput(was) != formatTaskInput(task) || was.status != task.status || was.notes != task.notes || was.parentID != task.parentID:
+			d.changed = append(d.changed, task)
+		}
+		delete(now, task.id)
+	}
+	for _, task := range current {
+		if _, ok := now[task.id]; ok {
+			d.lost = append(d.lost, task)
+		}
+	}
+	return d
+}
+
+// updateRestore handles keys in the backup list.
+func (m *model) updateRestore(key string) tea.Cmd {
+	r := &m.restore
+	switch key {
+	case "esc", "q":
+		m.tasksModel.mode = normalMode
+	case "j", "down":
+		if r.cursor < len(r.backups)-1 {
+			r.cursor++
+		}
+		r.confirm = false
+	case "k", "up":
+		if r.cursor > 0 {
+			r.cursor--
+		}
+		r.confirm = false
+	case "enter": // Restore, asking for a second press first
+		if !r.confirm {
+			r.confirm = true
+			return nil
+		}
+		r.confirm = false
+		b := r.backups[r.cursor]
+		aside, err := m.restoreBackup(b.path)
+		if err != nil {
+			m.showError("restore backup", err)
+			return nil
+		}
+		m.tasksModel.mode = normalMode
+		m.showMessage(fmt.Sprintf("Restored the backup from %s, the tasks before it are in %s", formatTime(b.at, "2006-01-02 15:04"), filepath.Base(aside)))
+		return m.loadTasks()
+	}
+	return nil
+}
+
+// restoreBackup replaces the database with a backup, after backing up the
+// current one, and returns the path of that backup. The database is closed
+// while the backup is copied in, then opened again and everything reloaded.
+func (m *model) restoreBackup(path string) (string, error) {
+	// Read it before taking the new backup, which may prune this one
+	data, err := m.backupData(path)
+	if err != nil {
+		return "", err
+	}
+	aside, err := backupDatabase(m.store, m.vault, m.config, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("backing up the current database: %w", err)
+	}
+
+	target := m.config.databasePath
+	if m.vault != nil {
+		target = m.vault.working
+	}
+	if err := m.store.Close(); err != nil {
+		return "", err
+	}
+	tmp := target + ".tmp"
+	err = os.WriteFile(tmp, data, 0o600)
+	if err == nil {
+		for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+			os.Remove(target + suffix)
+		}
+		err = os.Rename(tmp, target)
+	}
+
+	// Open whatever is there now, the backup or the untouched database
+	store, openErr := openSQLiteStore(target, nil)
+	if openErr != nil {
+		return "", fmt.Errorf("opening the database again, restart Xtui: %w", openErr)
+	}
+	m.store = store
+	if err != nil {
+		return "", err
+	}
+	if m.vault != nil {
+		if err := m.vault.seal(); err != nil {
+			return "", err
+		}
+	}
+
+	m.undoStack, m.redoStack, err = store.LoadHistory()
+	if err != nil {
+		m.showError("load history", err)
+	}
+	m.refreshReminders()
+	m.refreshDependencies()
+	m.refreshPomodoros()
+	m.refreshTimeEntries()
+	m.refreshGoals()
+	m.refreshChecklists()
+	m.tasksModel.selected = 0
+	m.tasksModel.taskQuery, m.tasksModel.queryIDs = "", nil
+	return aside, nil
+}
+
+func (m model) renderRestore() string {
+	r := m.restore
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Backups") + helpStyle.Render("  "+m.config.backupDir) + "\n\n")
+	for i, b := range r.backups {
+		cursor := cursorMark(i == r.cursor)
+		style := itemStyle
+		if i == r.cursor {
+			style = selectedItemStyle
+		}
+		s.WriteString(style.Render(cursor + formatTime(b.at, "Mon 2 Jan 2006 15:04")))
+		if b.err != nil {
+			s.WriteString(overdueStyle.Render("  " + b.err.Error()))
+		} else {
+			s.WriteString(helpStyle.Render(fmt.Sprintf("  %s, %s", countTasks(len(b.tasks)), formatRelativeTime(b.at))))
+		}
+		s.WriteString("\n")
+	}
+
+	b := r.backups[r.cursor]
+	if b.err != nil {
+		return s.String()
+	}
+	d := diffBackup(b.tasks, r.current)
+	s.WriteString("\n")
+	if len(d.back)+len(d.lost)+len(d.changed) == 0 {
+		s.WriteString(helpStyle.Render("Same tasks as now") + "\n")
+	}
+	for _, part := range []struct {
+		label string
+		tasks []item
+	}{
+		{"come back", d.back},
+		{"be lost", d.lost},
+		{"change back", d.changed},
+	} {
+		if len(part.tasks) == 0 {
+			continue
+		}
+		s.WriteString(itemStyle.Render(fmt.Sprintf("%s would %s", countTasks(len(part.tasks)), part.label)) + "\n")
+		for i, task := range part.tasks {
+			if i == restorePreview {
+				s.WriteString(helpStyle.Render(fmt.Sprintf("    and %d more", len(part.tasks)-restorePreview)) + "\n")
+				break
+			}
+			s.WriteString(helpStyle.Render("    "+statusMarker(task.status)+" "+task.title) + "\n")
+		}
+	}
+	if r.confirm {
+		s.WriteString("\n" + overdueStyle.Render("Press enter again to restore this backup, the current tasks are backed up first"))
+	}
+	return s.String()
+}
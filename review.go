@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// :review walks through the open tasks nobody has changed for a while, oldest
+// first, like a GTD weekly review, then the tasks parked in Someday/maybe.
+// Each one is kept (which counts as a change, so it rests until the next
+// review), rescheduled, deleted or archived, and a parked one can be moved to
+// Next actions. Archived tasks leave the list but stay in the database, and
+// every step can be undone once the review is over.
+
+const reviewMode = "review"
+
+const defaultReviewDays = 14
+
+// reviewState is the review in progress.
+type reviewState struct {
+	days        int
+	queue       []item // Stale tasks as loaded when the review started, then someday ones
+	pos         int
+	input       textinput.Model // New due date while rescheduling
+	scheduling  bool
+	kept        int
+	rescheduled int
+	deleted     int
+	archived    int
+	activated   int // Moved from Someday/maybe to Next actions
+}
+
+// startReview queues the open tasks unchanged for the given number of days,
+// then the ones parked in Someday/maybe.
+func (m *model) startReview(days int) error {
+	tasks, err := m.store.Load(liveTasks)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var stale, parked []item
+	for _, task := range tasks {
+		if task.status == done || m.tasksModel.indexOf(task.id) < 0 {
+			continue
+		}
+		if task.list == someday {
+			parked = append(parked, task)
+		} else if task.updatedAt.Before(cutoff) {
+			stale = append(stale, task)
+		}
+	}
+	if len(stale) == 0 && len(parked) == 0 {
+		m.showMessage(fmt.Sprintf("Nothing left alone for %d days", days))
+		return nil
+	}
+	oldest := func(tasks []item) {
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].updatedAt.Before(tasks[j].updatedAt) })
+	}
+	oldest(stale)
+	oldest(parked)
+	queue := append(stale, parked...)
+
+	input := textinput.New()
+	input.Prompt = "Due: "
+	input.Placeholder = "YYYY-MM-DD, +3d or +2w"
+	m.review = reviewState{days: days, queue: queue, input: input}
+	m.tasksModel.mode = reviewMode
+	return nil
+}
+
+// reviewIndex returns the list index of the task under review, -1 if it is
+// gone, e.g. deleted along with its parent.
+func (m model) reviewIndex() int {
+	if m.review.pos >= len(m.review.queue) {
+		return -1
+	}
+	return m.tasksModel.indexOf(m.review.queue[m.review.pos].id)
+}
+
+// nextReview moves on to the next task still in the list, and ends the review
+// after the last one.
+func (m *model) nextReview() {
+	m.review.pos++
+	for m.review.pos < len(m.review.queue) && m.reviewIndex() < 0 {
+		m.review.pos++
+	}
+	if m.review.pos >= len(m.review.queue) {
+		m.endReview()
+	}
+}
+
+func (m *model) endReview() {
+	r := m.review
+	summary := fmt.Sprintf("Review done: %d kept, %d rescheduled, %d deleted, %d archived", r.kept, r.rescheduled, r.deleted, r.archived)
+	if r.activated > 0 {
+		summary += fmt.Sprintf(", %d moved to Next", r.activated)
+	}
+	m.showMessage(summary)
+	m.tasksModel.mode = normalMode
+	m.tasksModel.clampSelection()
+}
+
+// updateReview handles keys while reviewing.
+func (m *model) updateReview(msg tea.KeyMsg) tea.Cmd {
+	if m.review.scheduling {
+		return m.updateReschedule(msg)
+	}
+	index := m.reviewIndex()
+	if index < 0 {
+		m.endReview()
+		return nil
+	}
+	switch msg.String() {
+	case "k", "enter": // Keep, and leave it alone until it goes stale again
+		if err := m.store.Update(m.tasksModel.items[index]); err != nil {
+			m.showError("update task", err)
+		}
+		m.review.kept++
+		m.nextReview()
+	case "r":
+		m.review.scheduling = true
+		m.review.input.Reset()
+		return m.review.input.Focus()
+	case "d":
+		m.removeTasks([]int{index})
+		m.review.deleted++
+		m.nextReview()
+	case "a":
+		m.archiveTask(index)
+		m.review.archived++
+		m.nextReview()
+	case "n": // Take a parked task up again
+		if m.tasksModel.items[index].list == someday {
+			m.triage([]int{index}, nextAction)
+			m.review.activated++
+			m.nextReview()
+		}
+	case "s", "j": // Skip without touching it
+		m.nextReview()
+	case "esc", "q":
+		m.endReview()
+	}
+	return nil
+}
+
+// updateReschedule handles the due date prompt of the review.
+func (m *model) updateReschedule(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.review.scheduling = false
+		m.review.input.Blur()
+		return nil
+	case "enter":
+		due, ok := parseReschedule(m.review.input.Value(), time.Now())
+		if !ok {
+			m.showMessage("Enter a date like 2024-06-01, +3d or +2w")
+			return nil
+		}
+		before := m.snapshot()
+		index := m.reviewIndex()
+		m.tasksModel.items[index].dueAt = due
+		if err := m.store.Update(m.tasksModel.items[index]); err != nil {
+			m.showError("update task", err)
+		}
+		m.record("reschedule", before)
+		m.review.scheduling = false
+		m.review.input.Blur()
+		m.review.rescheduled++
+		m.nextReview()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.review.input, cmd = m.review.input.Update(msg)
+	return cmd
+}
+
+// parseReschedule reads a new due date: a date or date and time as in due:,
+// or +<n>d / +<n>w for the end of the day that many days or weeks from now.
+func parseReschedule(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if due := parseDue("due:" + value); !due.IsZero() {
+		return due, true
+	}
+	if len(value) < 3 || value[0] != '+' {
+		return time.Time{}, false
+	}
+	n, err := strconv.Atoi(value[1 : len(value)-1])
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	switch value[len(value)-1] {
+	case 'w':
+		n *= 7
+	case 'd':
+	default:
+		return time.Time{}, false
+	}
+	day := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, time.Local)
+	return day.AddDate(0, 0, n), true
+}
+
+// archiveTask takes a task and its subtasks out of the list without deleting
+// them.
+func (m *model) archiveTask(index int) {
+	before := m.snapshot()
+	now := time.Now()
+	var archived []item
+	for _, i := range append([]int{index}, m.tasksModel.descendants(m.tasksModel.items[index].id)...) {
+		m.tasksModel.items[i].archivedAt = now
+		archived = append(archived, m.tasksModel.items[i])
+	}
+	if err := m.store.Update(archived...); err != nil {
+		m.showError("archive tasks", err)
+	}
+	m.record("archive", before)
+
+	var remaining []item
+	for _, task := range m.tasksModel.items {
+		if task.archivedAt.IsZero() {
+			remaining = append(remaining, task)
+		}
+	}
+	m.tasksModel.items = remaining
+	m.tasksModel.clampSelection()
+}
+
+func (m model) renderReview() string {
+	var s strings.Builder
+	r := m.review
+	s.WriteString(titleStyle.Render(fmt.Sprintf("Review %d/%d", r.pos+1, len(r.queue))))
+	index := m.reviewIndex()
+	if index < 0 {
+		return s.String()
+	}
+	task := m.tasksModel.items[index]
+	if task.list == someday {
+		s.WriteString(helpStyle.Render("  parked in Someday/maybe") + "\n\n")
+	} else {
+		s.WriteString(helpStyle.Render(fmt.Sprintf("  unchanged for %d days or more", r.days)) + "\n\n")
+	}
+
+	s.WriteString(itemStyle.Render(task.title))
+	for _, tag := range task.tags {
+		s.WriteString(" " + tagStyle.Render(tagLabel(tag)))
+	}
+	s.WriteString("\n\n")
+	details := []string{
+		"Untouched for " + formatDuration(time.Since(r.queue[r.pos].updatedAt)),
+		"created " + formatDate(task.createdAt, "2 Jan 2006"),
+	}
+	if !task.dueAt.IsZero() {
+		details = append(details, formatDueTime(task.dueAt))
+	}
+	s.WriteString(helpStyle.Render(strings.Join(details, " · ")) + "\n")
+	if task.notes != "" {
+		s.WriteString("\n" + notesStyle.Render(task.notes) + "\n")
+	}
+	if r.scheduling {
+		s.WriteString("\n" + r.input.View() + "\n")
+	}
+	return s.String()
+}
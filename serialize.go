@@ -0,0 +1,145 @@
+package main
+
+import "time"
+
+// jsonTask is the serialized form of a task, shared by the persisted undo
+// history and anything else that writes tasks as JSON.
+type jsonTask struct {
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Tags         []string   `json:"tags"`
+	Done         bool       `json:"done"`
+	Doing        bool       `json:"doing,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	DueAt        *time.Time `json:"due_at,omitempty"`
+	Recurrence   string     `json:"recurrence,omitempty"`
+	ParentID     int        `json:"parent_id,omitempty"`
+	Notes        string     `json:"notes,omitempty"`
+	Priority     int        `json:"priority,omitempty"`
+	Context      string     `json:"context,omitempty"`
+	Project      string     `json:"project,omitempty"`
+	List         gtdList    `json:"list,omitempty"`
+	SortOrder    int        `json:"sort_order,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	StartAt      *time.Time `json:"start_at,omitempty"`
+	Estimate     int        `json:"estimate,omitempty"` // Minutes
+	Starred      bool       `json:"starred,omitempty"`
+	PlannedFor   *time.Time `json:"planned_for,omitempty"`
+}
+
+func toJSONTask(task item) jsonTask {
+	t := jsonTask{
+		ID:         task.id,
+		Title:      task.title,
+		Tags:       task.tags,
+		Done:       task.status == done,
+		Doing:      task.status == doing,
+		CreatedAt:  task.createdAt,
+		Recurrence: task.recurrence,
+		ParentID:   task.parentID,
+		Notes:      task.notes,
+		Priority:   task.priority,
+		Context:    task.context,
+		Project:    task.project,
+		List:       task.list,
+		SortOrder:  task.sortOrder,
+		Estimate:   task.estimate,
+		Starred:    task.starred,
+	}
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if !task.completedAt.IsZero() {
+		completedAt := task.completedAt
+		t.CompletedAt = &completedAt
+	}
+	if !task.dueAt.IsZero() {
+		dueAt := task.dueAt
+		t.DueAt = &dueAt
+	}
+	if !task.archivedAt.IsZero() {
+		archivedAt := task.archivedAt
+		t.ArchivedAt = &archivedAt
+	}
+	if !task.snoozedUntil.IsZero() {
+		snoozedUntil := task.snoozedUntil
+		t.SnoozedUntil = &snoozedUntil
+	}
+	if !task.startAt.IsZero() {
+		startAt := task.startAt
+		t.StartAt = &startAt
+	}
+	if !task.plannedFor.IsZero() {
+		plannedFor := task.plannedFor
+		t.PlannedFor = &plannedFor
+	}
+	return t
+}
+
+func (t jsonTask) item() item {
+	task := item{
+		id:         t.ID,
+		title:      t.Title,
+		tags:       t.Tags,
+		status:     todo,
+		createdAt:  t.CreatedAt,
+		recurrence: t.Recurrence,
+		parentID:   t.ParentID,
+		notes:      t.Notes,
+		priority:   clampPriority(t.Priority),
+		context:    t.Context,
+		project:    t.Project,
+		list:       t.List,
+		sortOrder:  t.SortOrder,
+		estimate:   t.Estimate,
+		starred:    t.Starred,
+	}
+	if task.tags == nil {
+		task.tags = []string{}
+	}
+	if task.list == "" {
+		task.list = inbox
+	}
+	if t.Done {
+		task.status = done
+	} else if t.Doing {
+		task.status = doing
+	}
+	if t.CompletedAt != nil {
+		task.completedAt = *t.CompletedAt
+	}
+	if t.DueAt != nil {
+		task.dueAt = *t.DueAt
+	}
+	if t.ArchivedAt != nil {
+		task.archivedAt = *t.ArchivedAt
+	}
+	if t.SnoozedUntil != nil {
+		task.snoozedUntil = *t.SnoozedUntil
+	}
+	if t.StartAt != nil {
+		task.startAt = *t.StartAt
+	}
+	if t.PlannedFor != nil {
+		task.plannedFor = *t.PlannedFor
+	}
+	return task
+}
+
+func toJSONTasks(tasks []item) []jsonTask {
+	result := make([]jsonTask, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, toJSONTask(task))
+	}
+	return result
+}
+
+func fromJSONTasks(tasks []jsonTask) []item {
+	result := make([]item, 0, len(tasks))
+	for _, t := range tasks {
+		result = append(result, t.item())
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package main
+
+import "database/sql"
+
+// Preferences toggled from inside the app are kept in a small key/value
+// table so they survive restarts.
+
+func createSettingsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+	`)
+	return err
+}
+
+func (s *sqliteStore) Setting(key, fallback string) string {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&value)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func (s *sqliteStore) SaveSetting(key, value string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	return err
+}
+
+func (s *sqliteStore) DeleteSettings(keys ...string) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, key := range keys {
+			if _, err := tx.Exec("DELETE FROM settings WHERE key = ?", key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Signing in to the sync server from the User tab. The device flow (RFC
+// 8628) shows a code to enter in the browser and polls until it is approved;
+// alternatively a token created on the server can be pasted. Either way the
+// token is checked against the account endpoint and stored in the OS keyring
+// under the sync URL. Endpoints are relative to the [sync] url:
+//
+//	GET  account       {"email": ..., "plan": ...}
+//	POST device/code   {"device_code", "user_code", "verification_uri", "interval", "expires_in"}
+//	POST device/token  {"access_token"}, or {"error": "authorization_pending"} until approved
+
+// syncAccount is the signed-in account shown on the User tab.
+type syncAccount struct {
+	Email string `json:"email"`
+	Plan  string `json:"plan"`
+}
+
+type deviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	Interval        int    `json:"interval"`   // Seconds between polls
+	ExpiresIn       int    `json:"expires_in"` // Seconds
+}
+
+// signInState is the sign-in screen of the User tab.
+type signInState struct {
+	input   textinput.Model    // Pasted token
+	device  *deviceCode        // Code waiting for approval in the browser
+	cancel  context.CancelFunc // Stops polling for the device token
+	waiting bool               // Checking a token with the server
+	stored  bool               // The token is in the keyring rather than the config
+	err     error
+}
+
+type deviceCodeMsg struct {
+	code deviceCode
+	err  error
+}
+
+type signInMsg struct {
+	token   string
+	account syncAccount
+	err     error
+}
+
+func newTokenInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "Token: "
+	ti.EchoMode = textinput.EchoPassword
+	return ti
+}
+
+// loadSignIn restores the account signed in to in an earlier session. A
+// token in the config takes precedence over the keyring.
+func (m *model) loadSignIn() {
+	m.signIn.input = newTokenInput()
+	if m.config.syncURL != "" && m.config.syncToken == "" {
+		if token, err := keyringGet(m.config.syncURL); err == nil {
+			m.config.syncToken = token
+			m.signIn.stored = true
+		}
+	}
+	json.Unmarshal([]byte(m.store.Setting("sync_account", "{}")), &m.account)
+}
+
+// syncEndpoint resolves path against the configured sync URL.
+func syncEndpoint(cfg config, path string) (string, error) {
+	base, err := url.Parse(cfg.syncURL)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(&url.URL{Path: path}).String(), nil
+}
+
+// fetchAccount checks a token and returns the account it belongs to.
+func fetchAccount(cfg config, token string) (syncAccount, error) {
+	var acct syncAccount
+	endpoint, err := syncEndpoint(cfg, "account")
+	if err != nil {
+		return acct, err
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return acct, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return acct, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return acct, fmt.Errorf("sign in: the server did not accept the token")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return acct, fmt.Errorf("sign in: %s", resp.Status)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&acct)
+	return acct, err
+}
+
+// postForm posts to a device flow endpoint and decodes the JSON reply.
+func postForm(cfg config, path string, form url.Values, reply any) (int, error) {
+	endpoint, err := syncEndpoint(cfg, path)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.PostForm(endpoint, form)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		return resp.StatusCode, fmt.Errorf("sign in: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// requestDeviceCode starts a device flow sign in.
+func requestDeviceCode(cfg config) tea.Cmd {
+	return func() tea.Msg {
+		var code deviceCode
+		status, err := postForm(cfg, "device/code", url.Values{"client_id": {"xtui"}}, &code)
+		if err == nil && (status != http.StatusOK || code.DeviceCode == "") {
+			err = fmt.Errorf("sign in: the server does not support signing in with a code")
+		}
+		return deviceCodeMsg{code: code, err: err}
+	}
+}
+
+// pollDeviceToken waits for the code to be approved in the browser.
+func pollDeviceToken(ctx context.Context, cfg config, code deviceCode) tea.Cmd {
+	return func() tea.Msg {
+		interval := time.Duration(max(code.Interval, 5)) * time.Second
+		var expires <-chan time.Time
+		if code.ExpiresIn > 0 {
+			expires = time.After(time.Duration(code.ExpiresIn) * time.Second)
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-expires:
+				return signInMsg{err: fmt.Errorf("sign in: the code expired, try again")}
+			case <-time.After(interval):
+			}
+
+			var reply struct {
+				AccessToken string `json:"access_token"`
+				Error       string `json:"error"`
+			}
+			_, err := postForm(cfg, "device/token", url.Values{
+				"client_id":   {"xtui"},
+				"device_code": {code.DeviceCode},
+				"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			}, &reply)
+			switch {
+			case err != nil:
+				return signInMsg{err: err}
+			case reply.Error == "authorization_pending":
+				continue
+			case reply.Error == "slow_down":
+				interval += 5 * time.Second
+				continue
+			case reply.Error == "access_denied":
+				return signInMsg{err: fmt.Errorf("sign in: denied in the browser")}
+			case reply.Error != "" || reply.AccessToken == "":
+				return signInMsg{err: fmt.Errorf("sign in: %s", reply.Error)}
+			}
+			acct, err := fetchAccount(cfg, reply.AccessToken)
+			return signInMsg{token: reply.AccessToken, account: acct, err: err}
+		}
+	}
+}
+
+// verifyToken checks a pasted token.
+func verifyToken(cfg config, token string) tea.Cmd {
+	return func() tea.Msg {
+		acct, err := fetchAccount(cfg, token)
+		return signInMsg{token: token, account: acct, err: err}
+	}
+}
+
+// startDeviceSignIn asks the server for a code to show.
+func (m *model) startDeviceSignIn() tea.Cmd {
+	if m.config.syncURL == "" || m.signIn.device != nil || m.signIn.waiting {
+		return nil
+	}
+	m.signIn.err = nil
+	m.signIn.waiting = true
+	return requestDeviceCode(m.config)
+}
+
+// showDeviceCode displays the code and starts polling for approval.
+func (m *model) showDeviceCode(msg deviceCodeMsg) tea.Cmd {
+	m.signIn.waiting = false
+	if msg.err != nil {
+		m.signIn.err = msg.err
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.signIn.device = &msg.code
+	m.signIn.cancel = cancel
+	return pollDeviceToken(ctx, m.config, msg.code)
+}
+
+// startPasteSignIn opens the token input.
+func (m *model) startPasteSignIn() tea.Cmd {
+	if m.config.syncURL == "" {
+		return nil
+	}
+	m.cancelSignIn()
+	m.signIn.err = nil
+	m.signIn.input.SetValue("")
+	m.tasksModel.mode = signInMode
+	return m.signIn.input.Focus()
+}
+
+func (m *model) cancelSignIn() {
+	if m.signIn.cancel != nil {
+		m.signIn.cancel()
+		m.signIn.cancel = nil
+	}
+	m.signIn.device = nil
+	m.tasksModel.mode = normalMode
+	m.signIn.input.Blur()
+}
+
+// updateSignIn handles keys while a token is being pasted.
+func (m *model) updateSignIn(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.cancelSignIn()
+		return nil
+	case "enter":
+		token := strings.TrimSpace(m.signIn.input.Value())
+		m.cancelSignIn()
+		if token == "" {
+			return nil
+		}
+		m.signIn.waiting = true
+		return verifyToken(m.config, token)
+	}
+	var cmd tea.Cmd
+	m.signIn.input, cmd = m.signIn.input.Update(msg)
+	return cmd
+}
+
+// finishSignIn stores the new token and syncs with the account.
+func (m *model) finishSignIn(msg signInMsg) tea.Cmd {
+	m.signIn.waiting = false
+	if msg.err != nil {
+		m.signIn.err = msg.err
+		m.cancelSignIn()
+		return nil
+	}
+	m.cancelSignIn()
+	m.config.syncToken = msg.token
+	m.account = msg.account
+	m.signIn.stored = true
+	if err := keyringSet(m.config.syncURL, msg.token); err != nil {
+		m.signIn.err = fmt.Errorf("signed in, but the token could not be stored: %w", err)
+		m.signIn.stored = false
+	}
+	data, err := json.Marshal(m.account)
+	if err == nil {
+		err = m.store.SaveSetting("sync_account", string(data))
+	}
+	if err != nil {
+		m.showError("save account", err)
+	}
+	return m.startSync()
+}
+
+// signOut forgets the token. The last synced state belongs to the old
+// account, so it is dropped and the next sign in merges everything as new.
+func (m *model) signOut() {
+	if m.config.syncToken == "" {
+		return
+	}
+	if !m.signIn.stored {
+		m.signIn.err = fmt.Errorf("sign out: the token is set in the config or XTUI_SYNC_TOKEN, remove it there")
+		return
+	}
+	if err := keyringDelete(m.config.syncURL); err != nil {
+		m.signIn.err = err
+		return
+	}
+	m.config.syncToken = ""
+	m.account = syncAccount{}
+	m.cloud = syncState{}
+	m.signIn.stored = false
+	m.signIn.err = nil
+	err := m.store.DeleteSettings("sync_account", "cloud_base", "cloud_last_sync")
+	if err == nil {
+		err = m.store.ClearSyncLinks(cloudLinks)
+	}
+	if err != nil {
+		m.showError("clear sync state", err)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Slack workspaces are set up in [slack.<name>] sections, one per workspace,
+// named after its domain (acme for acme.slack.com). With a webhook, the
+// interface posts to the workspace's channel the tasks completed, tasks as
+// they fall due and reminders as they go off, only for tasks with the
+// workspace's tag when it has one, so work tasks go to the work Slack. With
+// a signing secret, xtui -serve-slack serves a slash command at /slack:
+// /xtui call mom tomorrow 5pm adds the task, with the workspace's tag, and
+// /xtui today answers with the digest (see digest.go). Slack signs every
+// request with the secret, and requests that don't verify are refused.
+
+// slackWorkspace is a [slack.<name>] section.
+type slackWorkspace struct {
+	webhook       string // Incoming webhook to post to
+	signingSecret string // Verifies slash command requests
+	tag           string // Only tasks with this tag, empty for all
+}
+
+const slackHelp = "Add a task with /xtui call mom #family tomorrow 5pm, or see the overdue tasks and those for today with /xtui today"
+
+// slackText escapes the characters Slack reads as markup.
+func slackText(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// postSlack posts a message to a Slack incoming webhook.
+func postSlack(webhook, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // The webhook URL is a secret
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// slackPosts posts each line to the webhooks of the workspaces whose tag
+// the task of the line has, in the background.
+func (m model) slackPosts(lines []string, tasks []item) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, name := range slackNames(m.config.slack) {
+		w := m.config.slack[name]
+		if w.webhook == "" {
+			continue
+		}
+		var text []string
+		for i, line := range lines {
+			if w.tag == "" || hasTag(tasks[i], w.tag) {
+				text = append(text, line)
+			}
+		}
+		if len(text) == 0 {
+			continue
+		}
+		webhook, name := w.webhook, name
+		cmds = append(cmds, func() tea.Msg {
+			if err := postSlack(webhook, strings.Join(text, "\n")); err != nil {
+				return errorMsg{"post to Slack " + name, err}
+			}
+			return nil
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// slackEvents posts the tasks completed among the queued hook runs.
+func (m model) slackEvents(runs []hookRun) tea.Cmd {
+	var lines []string
+	var tasks []item
+	for _, run := range runs {
+		if run.event == hookDone {
+			lines = append(lines, ":white_check_mark: Completed: "+slackText(run.task.title))
+			tasks = append(tasks, run.task)
+		}
+	}
+	return m.slackPosts(lines, tasks)
+}
+
+// slackAlerts posts the alerts about tasks, due times and reminders.
+func (m model) slackAlerts(alerts []alert) tea.Cmd {
+	var lines []string
+	var tasks []item
+	for _, a := range alerts {
+		if a.task.id != 0 {
+			lines = append(lines, ":alarm_clock: "+a.title+": "+slackText(a.body))
+			tasks = append(tasks, a.task)
+		}
+	}
+	return m.slackPosts(lines, tasks)
+}
+
+// verifySlack checks the signature Slack puts on a request: an HMAC of the
+// timestamp and body under the signing secret, from the last five minutes.
+func verifySlack(secret string, header http.Header, body []byte, now time.Time) bool {
+	seconds, err := strconv.ParseInt(header.Get("X-Slack-Request-Timestamp"), 10, 64)
+	if err != nil || now.Sub(time.Unix(seconds, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%d:%s", seconds, body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature")))
+}
+
+// slackReply handles a slash command from a workspace and returns the
+// answer.
+func (m *model) slackReply(w slackWorkspace, text string, now time.Time) string {
+	if err := m.reloadTasks(); err != nil {
+		return "Could not read the tasks: " + err.Error()
+	}
+	text = strings.TrimSpace(text)
+	switch strings.ToLower(text) {
+	case "", "help":
+		return slackHelp
+	case "today":
+		_, body := digestMessage(m.tasksModel.items, now)
+		return slackText(body)
+	}
+
+	task := parseTaskInput(text)
+	if task.title == "" {
+		return slackHelp
+	}
+	if w.tag != "" && !hasTag(task, w.tag) {
+		task.tags = append(task.tags, w.tag)
+	}
+	if _, err := m.addTasks([]item{task}); err != nil {
+		return "Could not add the task: " + err.Error()
+	}
+	added := m.tasksModel.items[len(m.tasksModel.items)-1]
+	m.setReminders(added.id, text)
+	return "Added: " + slackText(added.title)
+}
+
+// serveSlack implements the -serve-slack flag: it answers the slash
+// commands of the workspaces with a signing secret at /slack.
+func serveSlack(addr string) error {
+	m := newModel()
+	defer m.close()
+	configured := false
+	for _, w := range m.config.slack {
+		configured = configured || w.signingSecret != ""
+	}
+	if !configured {
+		return errors.New("set signing_secret under a [slack.<workspace>] section in the config")
+	}
+
+	var mu sync.Mutex // Requests are handled one at a time
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slack", func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w, ok := m.config.slack[form.Get("team_domain")]
+		if !ok || w.signingSecret == "" || !verifySlack(w.signingSecret, r.Header, body, time.Now()) {
+			http.Error(rw, "request not signed by a configured workspace", http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		reply := m.slackReply(w, form.Get("text"), time.Now())
+		mu.Unlock()
+		rw.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(rw).Encode(map[string]string{"response_type": "ephemeral", "text": reply})
+	})
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	fmt.Fprintf(os.Stderr, "Serving the slash command at http://%s/slack\n", addr)
+	return serveUntilInterrupted(server)
+}
+
+// slackNames returns the names of the workspaces in order.
+func slackNames(workspaces map[string]slackWorkspace) []string {
+	names := make([]string, 0, len(workspaces))
+	for name := range workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
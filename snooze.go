@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// S snoozes the selected task and its subtasks: they leave the list and the
+// Agenda until the time typed at the prompt, then come back by themselves.
+// The prompt takes the words of due dates (tonight, tomorrow 9am, next monday,
+// in 3 days), a date as in due: or +3d / +2w. A day without a time wakes the
+// task at the start of that day, tonight at 8pm. S on a snoozed task wakes it
+// now, and :snoozed shows the snoozed tasks in the list, marked with when they
+// wake.
+
+const snoozeMode = "snooze"
+
+// snoozed reports whether the task is hidden by a snooze at now.
+func (t item) snoozed(now time.Time) bool {
+	return t.snoozedUntil.After(now)
+}
+
+// parseSnooze reads the time a task is snoozed until.
+func parseSnooze(value string, now time.Time) (time.Time, bool) {
+	until, ok := parseReschedule(value, now)
+	if !ok {
+		var n int
+		until, n = parseDatePhrase(strings.Fields(value), now)
+		ok = n > 0 && n == len(strings.Fields(value))
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+	if isEndOfDay(until) {
+		until = midnight(until) // Wake up with the day, not as it ends
+	}
+	return until, until.After(now)
+}
+
+// startSnooze asks when to wake the selected task, or wakes it if it is
+// snoozed.
+func (m *model) startSnooze() tea.Cmd {
+	index := m.tasksModel.selectedIndex()
+	if index < 0 {
+		return nil
+	}
+	if m.tasksModel.items[index].snoozed(time.Now()) {
+		m.snooze(index, time.Time{})
+		m.showMessage("Woke up " + m.tasksModel.items[index].title)
+		return nil
+	}
+	m.tasksModel.bulkTargets = []int{index}
+	m.tasksModel.mode = snoozeMode
+	m.tasksModel.input.Placeholder = "tonight, tomorrow 9am, next monday, 2024-06-01..."
+	m.tasksModel.input.Focus()
+	return textinput.Blink
+}
+
+// updateSnooze handles the snooze prompt.
+func (m *model) updateSnooze(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+	case "enter":
+		until, ok := parseSnooze(m.tasksModel.input.Value(), time.Now())
+		if !ok {
+			m.showMessage("Enter a time to come like tonight, next monday, 2024-06-01 or +3d")
+			return nil
+		}
+		m.snooze(m.tasksModel.bulkTargets[0], until)
+		m.showMessage("Snoozed until " + formatSnooze(until))
+		m.tasksModel.clampSelection()
+	default:
+		var cmd tea.Cmd
+		m.tasksModel.input, cmd = m.tasksModel.input.Update(msg)
+		return cmd
+	}
+	m.tasksModel.bulkTargets = nil
+	m.tasksModel.mode = normalMode
+	m.tasksModel.input.Reset()
+	m.tasksModel.input.Blur()
+	m.tasksModel.input.Placeholder = inputPlaceholder
+	return nil
+}
+
+// snooze hides a task and its subtasks until a time, or shows them again
+// when it is zero.
+func (m *model) snooze(index int, until time.Time) {
+	before := m.snapshot()
+	var changed []item
+	for _, i := range append([]int{index}, m.tasksModel.descendants(m.tasksModel.items[index].id)...) {
+		m.tasksModel.items[i].snoozedUntil = until
+		changed = append(changed, m.tasksModel.items[i])
+	}
+	if err := m.store.Update(changed...); err != nil {
+		m.showError("snooze task", err)
+	}
+	if until.IsZero() {
+		m.record("wake", before)
+	} else {
+		m.record("snooze", before)
+	}
+}
+
+// countSnoozed returns how many tasks are hidden by a snooze.
+func (t tasksModel) countSnoozed(now time.Time) int {
+	count := 0
+	for _, task := range t.items {
+		if task.snoozed(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// formatSnooze shows when a snoozed task wakes, leaving out the time when it
+// wakes with the day.
+func formatSnooze(until time.Time) string {
+	if until.Equal(midnight(until)) {
+		return formatDate(until, "Mon 2 Jan")
+	}
+	return formatTime(until, "Mon 2 Jan 15:04")
+}
+
+// toggleSnoozed shows or hides the snoozed tasks, for :snoozed.
+func (m *model) toggleSnoozed() {
+	m.tasksModel.showSnoozed = !m.tasksModel.showSnoozed
+	if m.tasksModel.showSnoozed {
+		m.showMessage(fmt.Sprintf("Showing snoozed tasks (%d), :snoozed again to hide them", m.tasksModel.countSnoozed(time.Now())))
+	} else {
+		m.tasksModel.clampSelection()
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+// Someday/maybe is where ideas are parked: ms sends the selected task there
+// (S being snooze), and it leaves the list of all tasks so the backlog shows
+// only what is meant to be done. w shows the list on its own, and :review
+// brings up every parked task after the stale ones, where n moves it back to
+// Next actions.
+
+// inListView reports whether the task belongs in the GTD list being shown,
+// someday tasks only showing in their own.
+func (t tasksModel) inListView(task item) bool {
+	if t.listView == "" {
+		return task.list != someday
+	}
+	return task.list == t.listView
+}
+
+// countSomeday returns how many tasks are parked in Someday/maybe.
+func (t tasksModel) countSomeday() int {
+	count := 0
+	for _, task := range t.items {
+		if task.list == someday {
+			count++
+		}
+	}
+	return count
+}
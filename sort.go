@@ -0,0 +1,64 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort orders cycled with the s key. sortManual keeps the database order.
+const (
+	sortManual    = "manual"
+	sortCreated   = "created"
+	sortCompleted = "completed"
+	sortTitle     = "title"
+	sortDue       = "due"
+	sortPriority  = "priority"
+)
+
+var sortOrders = []string{sortManual, sortCreated, sortCompleted, sortTitle, sortDue, sortPriority}
+
+// nextSortOrder returns the order that follows current in the s cycle.
+func nextSortOrder(current string) string {
+	for i, order := range sortOrders {
+		if order == current {
+			return sortOrders[(i+1)%len(sortOrders)]
+		}
+	}
+	return sortManual
+}
+
+// sortIndices orders indices into t.items in place according to t.sortBy.
+// Siblings are sorted independently so the hierarchy is preserved.
+func (t tasksModel) sortIndices(indices []int) {
+	if t.sortBy == sortManual || t.sortBy == "" {
+		return
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return t.less(t.items[indices[i]], t.items[indices[j]])
+	})
+}
+
+func (t tasksModel) less(a, b item) bool {
+	switch t.sortBy {
+	case sortCreated: // Newest first
+		return a.createdAt.After(b.createdAt)
+	case sortCompleted: // Open tasks first, then most recently completed
+		if (a.status == done) != (b.status == done) {
+			return a.status != done
+		}
+		return a.completedAt.After(b.completedAt)
+	case sortTitle:
+		return strings.ToLower(a.title) < strings.ToLower(b.title)
+	case sortDue: // Earliest deadline first, tasks without one last
+		if a.dueAt.IsZero() != b.dueAt.IsZero() {
+			return !a.dueAt.IsZero()
+		}
+		return a.dueAt.Before(b.dueAt)
+	case sortPriority: // p1 first, tasks without a priority last
+		if (a.priority == 0) != (b.priority == 0) {
+			return a.priority != 0
+		}
+		return a.priority < b.priority
+	}
+	return false
+}
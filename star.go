@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// * stars the selected task, or unstars it, and 3* the three tasks from the
+// selected one. Starred tasks are pinned to the top of the list, and of their
+// siblings under a parent, whatever the sort order, and marked with a star in
+// the star color of the theme. Grouping by context keeps them at the top of
+// their context.
+
+// toggleStar stars the tasks unless they all are already, in which case it
+// unstars them, as one undo step.
+func (m *model) toggleStar(indices []int) {
+	starred := true
+	for _, index := range indices {
+		if !m.tasksModel.items[index].starred {
+			starred = false
+		}
+	}
+
+	before := m.snapshot()
+	var updates []item
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		if task.starred == starred {
+			task.starred = !starred
+			updates = append(updates, *task)
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := m.store.Update(updates...); err != nil {
+		m.showError("update tasks", err)
+	}
+	id := m.tasksModel.items[indices[0]].id
+	if starred {
+		m.record("unstar", before)
+		m.showMessage(fmt.Sprintf("Unstarred %s", countTasks(len(updates))))
+	} else {
+		m.record("star", before)
+		m.showMessage(fmt.Sprintf("Starred %s", countTasks(len(updates))))
+	}
+	m.tasksModel.selectID(id) // The task moves as it is pinned or unpinned
+}
+
+// pinStarred moves the starred tasks among indices to the front, keeping
+// the order of both the starred and the other tasks.
+func (t tasksModel) pinStarred(indices []int) {
+	sort.SliceStable(indices, func(i, j int) bool {
+		return t.items[indices[i]].starred && !t.items[indices[j]].starred
+	})
+}
+
+// starMark is the star after a starred task's title, with the space before
+// it, or "" for other tasks.
+func starMark(task item) string {
+	if !task.starred {
+		return ""
+	}
+	return " " + starStyle.Render(icons.star)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A start date, written start:2024-06-01 (or start:2024-06-01T09:00 or
+// start:+3d) in the task input, is when a task can first be worked on. Until
+// then the task stays out of the list and the Agenda, so a deadline weeks
+// away doesn't crowd today's work; unlike a snooze it is part of the task,
+// edited with e and synced like the due date. :upcoming shows the tasks that
+// haven't started, marked with their start date, and queries take start:.
+
+// parseStart returns the start date in the input, or the zero time. A day
+// without a time starts at midnight.
+func parseStart(input string) time.Time {
+	for _, word := range strings.Fields(input) {
+		value, ok := strings.CutPrefix(word, "start:")
+		if !ok {
+			continue
+		}
+		if t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local); err == nil {
+			return t
+		}
+		if t, ok := parseReschedule(value, time.Now()); ok {
+			return midnight(t)
+		}
+	}
+	return time.Time{}
+}
+
+func removeStart(input string) string {
+	var result []string
+	for _, word := range strings.Fields(input) {
+		if !strings.HasPrefix(word, "start:") {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// formatStart writes a start date the way parseStart reads it.
+func formatStart(t time.Time) string {
+	if t.Equal(midnight(t)) {
+		return "start:" + t.Format("2006-01-02")
+	}
+	return "start:" + t.Format("2006-01-02T15:04")
+}
+
+// showStart shows a start date in the list, without the time when the task
+// starts with the day.
+func showStart(t time.Time) string {
+	if t.Equal(midnight(t)) {
+		return formatDate(t, "2006-01-02")
+	}
+	return formatTime(t, "2006-01-02 15:04")
+}
+
+// notStarted reports whether the task's start date is still to come.
+func (t item) notStarted(now time.Time) bool {
+	return t.startAt.After(now)
+}
+
+// countNotStarted returns how many tasks are waiting for their start date.
+func (t tasksModel) countNotStarted(now time.Time) int {
+	count := 0
+	for _, task := range t.items {
+		if task.notStarted(now) {
+			count++
+		}
+	}
+	return count
+}
+
+// toggleUpcoming shows or hides the tasks that haven't started, for :upcoming.
+func (m *model) toggleUpcoming() {
+	m.tasksModel.showUpcoming = !m.tasksModel.showUpcoming
+	if m.tasksModel.showUpcoming {
+		m.showMessage(fmt.Sprintf("Showing tasks that haven't started (%d), :upcoming again to hide them", m.tasksModel.countNotStarted(time.Now())))
+	} else {
+		m.tasksModel.clampSelection()
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The Stats tab sums up how work gets done: a heatmap of the past year, tasks
+// completed per day and per week, how long tasks stay open, which tags have
+// the most tasks and how many days in a row something was finished, now and
+// at best. The numbers come from SQL aggregates and are drawn as bars.
+//
+// Completion days are taken from the stored timestamps, which keep the local
+// offset they were recorded with.
+
+const (
+	statsDays  = 7  // Days shown in the daily chart
+	statsWeeks = 8  // Weeks shown in the weekly chart
+	statsTags  = 5  // Busiest tags listed
+	barWidth   = 30 // Characters for the longest bar
+)
+
+type taskStats struct {
+	completed     int            // Completed tasks, all time
+	open          int            // Open tasks
+	perDay        map[string]int // Completions by day, YYYY-MM-DD
+	perWeek       map[string]int // Completions by week, keyed by the Monday
+	avgCompletion time.Duration  // From creation to completion
+	tags          []keyCount     // Most used tags first
+	days          []string       // Days with completions, most recent first
+
+	// Completed tasks with an estimate and tracked time, see estimate.go
+	estimatedTasks int
+	estimatedTime  time.Duration
+	trackedTime    time.Duration
+}
+
+// keyCount is a row of a grouped count, e.g. a tag and its number of tasks.
+type keyCount struct {
+	key   string
+	count int
+}
+
+func (m *model) refreshStats() {
+	stats, err := m.store.Stats()
+	if err != nil {
+		m.showError("load stats", err)
+		return
+	}
+	m.stats = stats
+}
+
+// streak counts the days in a row with completions, up to today or, if
+// nothing is finished yet today, up to yesterday.
+func (s taskStats) streak(now time.Time) int {
+	day := now
+	if len(s.days) > 0 && s.days[0] != day.Format("2006-01-02") {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for _, d := range s.days {
+		if d != day.Format("2006-01-02") {
+			break
+		}
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// bestStreak is the longest run of days in a row with completions.
+func (s taskStats) bestStreak() int {
+	best, run := 0, 0
+	var previous time.Time
+	for _, d := range s.days {
+		day, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			continue
+		}
+		if run > 0 && previous.AddDate(0, 0, -1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		best = max(best, run)
+		previous = day
+	}
+	return best
+}
+
+func (m model) renderStats() string {
+	st := m.stats
+	now := time.Now()
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Stats") + "\n\n")
+	streak := st.streak(now)
+	s.WriteString(fmt.Sprintf("%d completed · %d open · %d day streak (best %d)\n", st.completed, st.open, streak, st.bestStreak()))
+	if streak > 0 && st.days[0] != now.Format("2006-01-02") {
+		s.WriteString(helpStyle.Render("Complete a task today to keep the streak going") + "\n")
+	}
+	if st.completed > 0 {
+		s.WriteString(helpStyle.Render("Tasks take "+formatDuration(st.avgCompletion)+" to complete on average") + "\n")
+	}
+
+	s.WriteString("\n" + st.renderHeatmap(now))
+
+	s.WriteString("\n" + titleStyle.Render("Completed per day") + "\n")
+	var labels []string
+	var counts []int
+	for i := statsDays - 1; i >= 0; i-- {
+		day := now.AddDate(0, 0, -i)
+		labels = append(labels, day.Format("Mon 02"))
+		counts = append(counts, st.perDay[day.Format("2006-01-02")])
+	}
+	s.WriteString(renderBars(labels, counts))
+
+	s.WriteString("\n" + titleStyle.Render("Completed per week") + "\n")
+	labels, counts = nil, nil
+	monday := now.AddDate(0, 0, -(int(now.Weekday())+6)%7)
+	for i := statsWeeks - 1; i >= 0; i-- {
+		week := monday.AddDate(0, 0, -7*i)
+		labels = append(labels, week.Format("Jan 02"))
+		counts = append(counts, st.perWeek[week.Format("2006-01-02")])
+	}
+	s.WriteString(renderBars(labels, counts))
+
+	s.WriteString("\n" + titleStyle.Render("Estimates") + "\n" + st.renderEstimates())
+
+	if len(st.tags) > 0 {
+		s.WriteString("\n" + titleStyle.Render("Busiest tags") + "\n")
+		labels, counts = nil, nil
+		for _, t := range st.tags {
+			labels = append(labels, "#"+t.key)
+			counts = append(counts, t.count)
+		}
+		s.WriteString(renderBars(labels, counts))
+	}
+	return s.String()
+}
+
+// renderBars draws a horizontal bar per label, scaled to the largest count.
+func renderBars(labels []string, counts []int) string {
+	width, most := 0, 0
+	for i, label := range labels {
+		width = max(width, lipgloss.Width(label))
+		most = max(most, counts[i])
+	}
+	var s strings.Builder
+	for i, label := range labels {
+		n := 0
+		if most > 0 {
+			n = counts[i] * barWidth / most
+		}
+		if n == 0 && counts[i] > 0 {
+			n = 1 // Every completion shows up
+		}
+		s.WriteString(helpStyle.Render(label + strings.Repeat(" ", width-lipgloss.Width(label)+1)))
+		s.WriteString(barStyle.Render(strings.Repeat("█", n)))
+		s.WriteString(helpStyle.Render(fmt.Sprintf("%s %d", strings.Repeat(" ", barWidth-n), counts[i])) + "\n")
+	}
+	return s.String()
+}
+
+func (s *sqliteStore) Stats() (taskStats, error) {
+	stats := taskStats{perDay: make(map[string]int), perWeek: make(map[string]int)}
+
+	var avgDays float64
+	err := s.db.QueryRow(`
+		SELECT
+			COUNT(CASE WHEN status = 1 THEN 1 END),
+			COUNT(CASE WHEN status != 1 AND archived_at IS NULL THEN 1 END),
+			COALESCE(AVG(CASE WHEN status = 1 AND completed_at IS NOT NULL THEN julianday(completed_at) - julianday(created_at) END), 0)
+		FROM tasks WHERE deleted_at IS NULL
+	`).Scan(&stats.completed, &stats.open, &avgDays)
+	if err != nil {
+		return stats, err
+	}
+	stats.avgCompletion = time.Duration(avgDays * float64(24*time.Hour))
+
+	// Completions per day, most recent first
+	days, err := s.counts(`
+		SELECT substr(completed_at, 1, 10) AS day, COUNT(*)
+		FROM tasks
+		WHERE status = 1 AND completed_at IS NOT NULL AND deleted_at IS NULL
+		GROUP BY day ORDER BY day DESC
+	`)
+	if err != nil {
+		return stats, err
+	}
+	for _, d := range days {
+		stats.perDay[d.key] = d.count
+		stats.days = append(stats.days, d.key)
+	}
+
+	// Completions per week, keyed by the Monday that starts it
+	weeks, err := s.counts(`
+		SELECT date(substr(completed_at, 1, 10), '-' || ((CAST(strftime('%w', substr(completed_at, 1, 10)) AS INTEGER) + 6) % 7) || ' days') AS week, COUNT(*)
+		FROM tasks
+		WHERE status = 1 AND completed_at IS NOT NULL AND deleted_at IS NULL
+		GROUP BY week
+	`)
+	if err != nil {
+		return stats, err
+	}
+	for _, w := range weeks {
+		stats.perWeek[w.key] = w.count
+	}
+
+	// Tags are stored comma separated, split them with a recursive query
+	stats.tags, err = s.counts(`
+		WITH RECURSIVE split(tag, rest) AS (
+			SELECT '', tags || ',' FROM tasks WHERE deleted_at IS NULL AND tags IS NOT NULL AND tags != ''
+			UNION ALL
+			SELECT substr(rest, 1, instr(rest, ',') - 1), substr(rest, instr(rest, ',') + 1) FROM split WHERE rest != ''
+		)
+		SELECT tag, COUNT(*) AS n FROM split WHERE tag != '' GROUP BY tag ORDER BY n DESC, tag LIMIT ?
+	`, statsTags)
+	if err != nil {
+		return stats, err
+	}
+	return stats, s.estimateStats(&stats)
+}
+
+// counts runs a query returning key and count pairs.
+func (s *sqliteStore) counts(query string, args ...interface{}) ([]keyCount, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []keyCount
+	for rows.Next() {
+		var c keyCount
+		if err := rows.Scan(&c.key, &c.count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// Heatmap of completions over the past year, one column per week and one row
+// per weekday, like a GitHub contributions graph.
+
+const heatmapWeeks = 53
+
+// heatmapColors go from no completions to the busiest days.
+var heatmapColors = []lipgloss.AdaptiveColor{
+	adaptive("#EBEDF0", "#2D333B"),
+	adaptive("#9BE9A8", "#0E4429"),
+	adaptive("#40C463", "#006D32"),
+	adaptive("#30A14E", "#26A641"),
+	adaptive("#216E39", "#39D353"),
+}
+
+// heatmapChars stand in for heatmapColors without colors.
+const heatmapChars = ".:+*#"
+
+func (st taskStats) renderHeatmap(now time.Time) string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	start := monday.AddDate(0, 0, -7*(heatmapWeeks-1))
+
+	most, total := 0, 0
+	for day := start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		n := st.perDay[day.Format("2006-01-02")]
+		most = max(most, n)
+		total += n
+	}
+
+	// Month names above the week they start in
+	months := []rune(strings.Repeat(" ", heatmapWeeks+4))
+	free := 0 // First column not taken by the previous name
+	for week := 0; week < heatmapWeeks; week++ {
+		day := start.AddDate(0, 0, 7*week)
+		if (week == 0 || day.Day() <= 7) && week >= free && week+3 <= heatmapWeeks {
+			copy(months[4+week:], []rune(day.Format("Jan")))
+			free = week + 4
+		}
+	}
+
+	var s strings.Builder
+	s.WriteString(helpStyle.Render(strings.TrimRight(string(months), " ")) + "\n")
+	for weekday := 0; weekday < 7; weekday++ {
+		label := "   "
+		if weekday%2 == 0 {
+			label = start.AddDate(0, 0, weekday).Format("Mon")
+		}
+		s.WriteString(helpStyle.Render(label + " "))
+		for week := 0; week < heatmapWeeks; week++ {
+			day := start.AddDate(0, 0, 7*week+weekday)
+			if day.After(today) {
+				break
+			}
+			s.WriteString(heatmapCell(st.perDay[day.Format("2006-01-02")], most))
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString(helpStyle.Render(fmt.Sprintf("%d tasks completed in the last year   less ", total)))
+	for level := range heatmapColors {
+		s.WriteString(heatmapMark(level))
+	}
+	s.WriteString(helpStyle.Render(" more") + "\n")
+	return s.String()
+}
+
+// heatmapCell colors a day by its share of the busiest day.
+func heatmapCell(count, most int) string {
+	level := 0
+	if count > 0 {
+		level = (count*(len(heatmapColors)-1) + most - 1) / most // Round up so every completion shows
+	}
+	return heatmapMark(level)
+}
+
+// heatmapMark draws a level of the heatmap, as a denser character the busier
+// the day in the accessible mode.
+func heatmapMark(level int) string {
+	if accessible {
+		return heatmapChars[level : level+1]
+	}
+	return lipgloss.NewStyle().Foreground(heatmapColors[level]).Render("■")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The status bar under the content is always shown and tells where you are:
+// the input mode, how many of the listed tasks are done or overdue, and
+// which filters narrow the list.
+
+func (m model) renderStatusBar() string {
+	t := m.tasksModel
+	parts := []string{modeStyle.Render(strings.ToUpper(t.mode))}
+
+	// Counts cover the tasks the search, tag, context, list, Focus and query
+	// filters let through, done tasks included even when they are hidden
+	total, completed, overdue := 0, 0, 0
+	var left time.Duration // Estimated work left on the open tasks shown
+	now := time.Now()
+	for _, task := range t.items {
+		if t.tagFilter != "" && !hasTag(task, t.tagFilter) || !matchesQuery(task, t.query) ||
+			t.contextFilter != "" && !strings.EqualFold(task.context, t.contextFilter) ||
+			!t.inListView(task) || t.focus && !task.inFocus(now) ||
+			t.queryIDs != nil && !t.queryIDs[task.id] {
+			continue
+		}
+		total++
+		if task.status == done {
+			completed++
+		}
+		if task.overdue(now) {
+			overdue++
+		}
+		if t.showSnoozed || !task.snoozed(now) {
+			if t.showUpcoming || !task.notStarted(now) {
+				left += m.remaining(task)
+			}
+		}
+	}
+	parts = append(parts, helpStyle.Render(fmt.Sprintf("%d/%d done", completed, total)))
+	if overdue > 0 {
+		parts = append(parts, overdueStyle.Render(fmt.Sprintf("%d overdue", overdue)))
+	}
+	if left > 0 {
+		parts = append(parts, helpStyle.Render("~"+formatEstimate(left)+" left"))
+	}
+
+	if t.tagFilter != "" {
+		parts = append(parts, tagStyle.Render(tagLabel(t.tagFilter))+helpStyle.Render(" filter"))
+	}
+	if t.contextFilter != "" {
+		parts = append(parts, tagStyle.Render("@"+t.contextFilter)+helpStyle.Render(" filter"))
+	}
+	if t.listView != "" {
+		parts = append(parts, helpStyle.Render(t.listView.title()))
+	}
+	if t.focus {
+		parts = append(parts, helpStyle.Render("Focus"))
+	}
+	if t.query != "" {
+		parts = append(parts, helpStyle.Render("/"+t.query))
+	}
+	if t.taskQuery != "" {
+		parts = append(parts, helpStyle.Render(t.taskQuery))
+	}
+	if t.hideDone {
+		parts = append(parts, helpStyle.Render("done hidden"))
+	} else if t.doneCursor.id != 0 {
+		parts = append(parts, helpStyle.Render("older done not loaded, :more"))
+	}
+	if snoozed := t.countSnoozed(now); snoozed > 0 && !t.showSnoozed {
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("%d snoozed", snoozed)))
+	}
+	if upcoming := t.countNotStarted(now); upcoming > 0 && !t.showUpcoming {
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("%d not started", upcoming)))
+	}
+	if parked := t.countSomeday(); parked > 0 && t.listView == "" {
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("%d someday", parked)))
+	}
+	if m.currentView == Trash {
+		parts = append(parts, helpStyle.Render(fmt.Sprintf("%d in trash", len(m.trash.items))))
+	}
+	return strings.Join(parts, helpStyle.Render(" · "))
+}
@@ -0,0 +1,629 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TaskStore is where tasks, preferences and the undo history are kept. The
+// model only goes through this interface, so tasks can live in another
+// backend.
+type TaskStore interface {
+	// Load returns the tasks in scope: live tasks in manual order (archived
+	// ones left out), trashed tasks most recently deleted first, or all of
+	// them.
+	Load(scope taskScope) ([]item, error)
+	// LoadPage returns the live tasks the list starts with, in manual order:
+	// every open task and the limit most recently completed ones, with the
+	// rest of their trees. With a non-zero cursor it returns the next limit
+	// completed tasks before it instead. The cursor to continue from is
+	// zero once no older completed tasks are left.
+	LoadPage(before historyCursor, limit int) ([]item, historyCursor, error)
+	// Search returns the live tasks whose title, tags, notes, context or
+	// project contain text.
+	Search(text string) ([]item, error)
+	// Filter returns the live tasks matching a query, in manual order.
+	Filter(q taskQuery) ([]item, error)
+	// Tags returns every distinct tag, sorted.
+	Tags() ([]string, error)
+	// Contexts returns every distinct context, sorted.
+	Contexts() ([]string, error)
+	// Projects returns the distinct projects of live tasks, sorted.
+	Projects() ([]string, error)
+
+	// Save inserts a task and fills in its id and position. A non-zero id is
+	// kept (used when restoring deleted tasks so subtasks still point at
+	// their parent). New tasks go to the end of the manual order.
+	Save(task *item) error
+	// Update saves changes to existing tasks, all or none of them, and marks
+	// them as changed now.
+	Update(tasks ...item) error
+	// SaveOrder stores the manual positions of the tasks.
+	SaveOrder(tasks []item) error
+
+	// Delete moves tasks to the trash.
+	Delete(ids ...int) error
+	// Restore brings a task back from the trash with its saved fields, or
+	// inserts it again if it was purged in the meantime.
+	Restore(task *item) error
+	// Purge removes tasks for good.
+	Purge(ids ...int) error
+	// PurgeTrash removes tasks trashed before the given time.
+	PurgeTrash(before time.Time) error
+	// OldCompleted returns the live tasks completed before the given time
+	// that have no subtasks completed later or still open.
+	OldCompleted(before time.Time) ([]item, error)
+	// CleanCompleted moves the tasks OldCompleted returns to the trash, or
+	// archives them, in one transaction and returns them.
+	CleanCompleted(before time.Time, archive bool) ([]item, error)
+
+	// Reminders returns the reminders of live tasks.
+	Reminders() ([]reminder, error)
+	// SaveReminders replaces the reminders of a task.
+	SaveReminders(taskID int, reminders []reminder) error
+
+	// Dependencies returns which tasks wait for which.
+	Dependencies() ([]dependency, error)
+	// AddDependency makes a task wait for another.
+	AddDependency(d dependency) error
+	// RemoveDependency drops a dependency.
+	RemoveDependency(d dependency) error
+
+	// Goals returns the goals, soonest deadline first, with the progress of
+	// their tasks.
+	Goals() ([]goal, error)
+	// GoalLinks returns the goal of each linked task, by task id.
+	GoalLinks() (map[int]int, error)
+	// AddGoal adds a goal and fills in its id.
+	AddGoal(g *goal) error
+	// RemoveGoal deletes a goal, unlinking its tasks.
+	RemoveGoal(id int) error
+	// LinkGoal links tasks to a goal, or unlinks them when goalID is 0.
+	LinkGoal(goalID int, taskIDs []int) error
+
+	// Attachments returns the files and links attached to a task.
+	Attachments(taskID int) ([]attachment, error)
+	// AddAttachment attaches a file or link and fills in its id.
+	AddAttachment(a *attachment) error
+	// RemoveAttachment drops an attachment.
+	RemoveAttachment(id int) error
+
+	// SavePomodoro logs a finished pomodoro.
+	SavePomodoro(p pomodoro) error
+	// Pomodoros returns the pomodoros finished since the given time.
+	Pomodoros(since time.Time) ([]pomodoro, error)
+
+	// StartTimeEntry starts tracking time on a task.
+	StartTimeEntry(taskID int, at time.Time) (timeEntry, error)
+	// StopTimeEntry ends a running time entry.
+	StopTimeEntry(id int, at time.Time) error
+	// TimeEntries returns the entries still running or ended since the
+	// given time.
+	TimeEntries(since time.Time) ([]timeEntry, error)
+
+	// ChecklistItems returns the checklist items of all tasks.
+	ChecklistItems() ([]checkItem, error)
+	// AddCheckItem adds an item to a task's checklist and fills in its id.
+	AddCheckItem(c *checkItem) error
+	// UpdateCheckItem saves an item's text and whether it is checked.
+	UpdateCheckItem(c checkItem) error
+	// RemoveCheckItem drops a checklist item.
+	RemoveCheckItem(id int) error
+
+	// AddEvents adds to the history of changes.
+	AddEvents(events []taskEvent) error
+	// TaskEvents returns the latest changes to a task, newest first.
+	TaskEvents(taskID, limit int) ([]taskEvent, error)
+	// Events returns the latest changes to any task, newest first.
+	Events(limit int) ([]taskEvent, error)
+
+	// CalDAVLinks returns the server copy of each task synced over CalDAV,
+	// by task id.
+	CalDAVLinks() (map[int]*caldavLink, error)
+	// SaveCalDAVLink records where a task is on the CalDAV server.
+	SaveCalDAVLink(link *caldavLink) error
+	// DeleteCalDAVLink forgets the server copy of a task.
+	DeleteCalDAVLink(taskID int) error
+
+	// SyncLinks returns the UIDs a sync knows local tasks that still exist
+	// by, by task id.
+	SyncLinks(table linkTable) (map[int]string, error)
+	// SaveSyncLink records the UID of a task for a sync.
+	SaveSyncLink(table linkTable, id int, uid string) error
+	// ClearSyncLinks forgets every UID of a sync, on signing out.
+	ClearSyncLinks(table linkTable) error
+
+	// SaveConflict keeps the value a sync merge did not pick.
+	SaveConflict(taskID int, c syncConflict) error
+	// Conflicts returns the conflicts of live tasks, oldest first.
+	Conflicts() ([]syncConflict, error)
+	// DropConflict forgets a conflict once it is resolved.
+	DropConflict(id int) error
+
+	// Stats aggregates completions and tags for the Stats tab.
+	Stats() (taskStats, error)
+
+	// Setting returns a stored preference, or fallback if it was never set.
+	Setting(key, fallback string) string
+	SaveSetting(key, value string) error
+	// DeleteSettings forgets preferences.
+	DeleteSettings(keys ...string) error
+
+	LoadHistory() (undoStack, redoStack []operation, err error)
+	SaveHistory(undoStack, redoStack []operation) error
+
+	// Backup writes a consistent copy of the database to path, which must
+	// not exist yet.
+	Backup(path string) error
+	Close() error
+}
+
+type taskScope int
+
+const (
+	liveTasks taskScope = iota
+	trashedTasks
+	allTasks
+)
+
+// sqliteStore keeps everything in a SQLite database.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// schemaVersion is stored in the database's user_version once migrate has
+// run. Raise it with every change to the schema, so databases from before the
+// change are backed up before they are upgraded.
+const schemaVersion = 9
+
+// openSQLiteStore opens the database and brings its schema up to date.
+// beforeMigrate is called first when an existing database needs upgrading.
+func openSQLiteStore(path string, beforeMigrate func(s *sqliteStore) error) (*sqliteStore, error) {
+	db, err := openDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	// Ping the database to ensure the connection is valid
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s := &sqliteStore{db: db}
+	var version, tables int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'tasks'").Scan(&tables); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if version < schemaVersion && tables > 0 && beforeMigrate != nil {
+		if err := beforeMigrate(s); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("before migrating: %w", err)
+		}
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sqliteStore) migrate() error {
+	// Create the tasks table if it doesn't exist
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS tasks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			tags TEXT,
+			status INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			completed_at DATETIME,
+			due_at DATETIME,
+			recurrence TEXT,
+			parent_id INTEGER,
+			notes TEXT,
+			priority INTEGER DEFAULT 0,
+			sort_order INTEGER,
+			deleted_at DATETIME
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("creating tasks table: %w", err)
+	}
+
+	// Add columns introduced after the table was first created
+	for _, column := range []struct{ name, definition string }{
+		{"due_at", "DATETIME"},
+		{"recurrence", "TEXT"},
+		{"parent_id", "INTEGER"},
+		{"notes", "TEXT"},
+		{"priority", "INTEGER DEFAULT 0"},
+		{"sort_order", "INTEGER"},
+		{"deleted_at", "DATETIME"},
+		{"updated_at", "DATETIME"},
+		{"archived_at", "DATETIME"},
+		{"context", "TEXT"},
+		{"project", "TEXT"},
+		{"gtd_list", "TEXT"},
+		{"snoozed_until", "DATETIME"},
+		{"start_at", "DATETIME"},
+		{"estimate", "INTEGER DEFAULT 0"},
+		{"starred", "INTEGER DEFAULT 0"},
+		{"planned_for", "DATETIME"},
+	} {
+		if err := ensureColumn(s.db, "tasks", column.name, column.definition); err != nil {
+			return fmt.Errorf("migrating tasks table: %w", err)
+		}
+	}
+
+	// Tasks created before manual ordering keep their insertion order
+	_, err = s.db.Exec("UPDATE tasks SET sort_order = id WHERE sort_order IS NULL")
+	if err != nil {
+		return fmt.Errorf("migrating tasks table: %w", err)
+	}
+	// Tasks from before change tracking were last changed when completed or
+	// created
+	_, err = s.db.Exec("UPDATE tasks SET updated_at = COALESCE(completed_at, created_at) WHERE updated_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("migrating tasks table: %w", err)
+	}
+
+	for _, table := range []struct {
+		name   string
+		create func(*sql.DB) error
+	}{
+		{"settings", createSettingsTable},
+		{"history", createHistoryTable},
+		{"caldav", createCalDAVTable},
+		{"cloud", createCloudTable},
+		{"git_links", createGitLinksTable},
+		{"conflicts", createConflictsTable},
+		{"reminders", createRemindersTable},
+		{"dependencies", createDependenciesTable},
+		{"attachments", createAttachmentsTable},
+		{"pomodoros", createPomodorosTable},
+		{"time_entries", createTimeEntriesTable},
+		{"checklist_items", createChecklistTable},
+		{"task_events", createEventsTable},
+		{"goals", createGoalsTables},
+	} {
+		if err := table.create(s.db); err != nil {
+			return fmt.Errorf("creating %s table: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteStore) Load(scope taskScope) ([]item, error) {
+	switch scope {
+	case trashedTasks:
+		return s.query("deleted_at IS NOT NULL", "deleted_at DESC, id")
+	case allTasks:
+		return s.query("1 = 1", "id")
+	}
+	return s.query("deleted_at IS NULL AND archived_at IS NULL", "sort_order, id")
+}
+
+func (s *sqliteStore) Search(text string) ([]item, error) {
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text) + "%"
+	return s.query(`deleted_at IS NULL AND archived_at IS NULL AND (title LIKE ? ESCAPE '\' OR tags LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\' OR context LIKE ? ESCAPE '\' OR project LIKE ? ESCAPE '\')`,
+		"sort_order, id", pattern, pattern, pattern, pattern, pattern)
+}
+
+// query loads the tasks matching a WHERE condition in the given order.
+func (s *sqliteStore) query(condition, order string, args ...interface{}) ([]item, error) {
+	rows, err := s.db.Query(`
+		SELECT id, title, tags, status, created_at, completed_at, due_at, recurrence, parent_id, notes, priority, sort_order, deleted_at, updated_at, archived_at, context, project, gtd_list, snoozed_until, start_at, estimate, starred, planned_for
+		FROM tasks WHERE `+condition+` ORDER BY `+order, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []item
+	for rows.Next() {
+		var task item
+		var tags sql.NullString
+		var completedAt, dueAt, deletedAt, updatedAt, archivedAt, snoozedUntil, startAt, plannedFor sql.NullTime
+		var recurrence, notes, context, project, list sql.NullString
+		var parentID, sortOrder, estimate sql.NullInt64
+		var starred sql.NullBool
+		err := rows.Scan(&task.id, &task.title, &tags, &task.status, &task.createdAt, &completedAt, &dueAt, &recurrence, &parentID, &notes, &task.priority, &sortOrder, &deletedAt, &updatedAt, &archivedAt, &context, &project, &list, &snoozedUntil, &startAt, &estimate, &starred, &plannedFor)
+		if err != nil {
+			return nil, err
+		}
+		if completedAt.Valid {
+			task.completedAt = completedAt.Time
+		}
+		if dueAt.Valid {
+			task.dueAt = dueAt.Time
+		}
+		if deletedAt.Valid {
+			task.deletedAt = deletedAt.Time
+		}
+		if updatedAt.Valid {
+			task.updatedAt = updatedAt.Time
+		}
+		if archivedAt.Valid {
+			task.archivedAt = archivedAt.Time
+		}
+		if snoozedUntil.Valid {
+			task.snoozedUntil = snoozedUntil.Time
+		}
+		if startAt.Valid {
+			task.startAt = startAt.Time
+		}
+		if plannedFor.Valid {
+			task.plannedFor = plannedFor.Time
+		}
+		task.recurrence = recurrence.String
+		task.parentID = int(parentID.Int64)
+		task.notes = notes.String
+		task.context = context.String
+		task.project = project.String
+		task.list = gtdList(list.String)
+		if task.list == "" {
+			task.list = inbox // Tasks from before the GTD lists
+		}
+		task.sortOrder = int(sortOrder.Int64)
+		task.estimate = int(estimate.Int64)
+		task.starred = starred.Bool
+		if tags.String != "" {
+			task.tags = strings.Split(tags.String, ",")
+		} else {
+			task.tags = []string{}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *sqliteStore) Tags() ([]string, error) {
+	rows, err := s.db.Query("SELECT tags FROM tasks WHERE tags IS NOT NULL AND tags != ''")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var tags []string
+	for rows.Next() {
+		var joined string
+		if err := rows.Scan(&joined); err != nil {
+			return nil, err
+		}
+		for _, tag := range strings.Split(joined, ",") {
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, rows.Err()
+}
+
+func (s *sqliteStore) Contexts() ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT context FROM tasks WHERE context IS NOT NULL AND context != '' ORDER BY context")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contexts []string
+	for rows.Next() {
+		var context string
+		if err := rows.Scan(&context); err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts, rows.Err()
+}
+
+func (s *sqliteStore) Save(task *item) error {
+	tags := strings.Join(task.tags, ",")
+	var completed interface{}
+	if task.status == done {
+		completed = task.completedAt
+	} else {
+		completed = nil
+	}
+	task.updatedAt = time.Now()
+	if task.list == "" {
+		task.list = inbox
+	}
+	res, err := s.db.Exec(`
+		INSERT INTO tasks (id, title, tags, status, created_at, completed_at, due_at, recurrence, parent_id, notes, priority, sort_order, updated_at, archived_at, context, project, gtd_list, snoozed_until, start_at, estimate, starred, planned_for)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM tasks)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, nullInt(task.id), task.title, tags, task.status, task.createdAt, completed, nullTime(task.dueAt), task.recurrence, nullInt(task.parentID), task.notes, task.priority, nullInt(task.sortOrder), task.updatedAt, nullTime(task.archivedAt), task.context, task.project, task.list, nullTime(task.snoozedUntil), nullTime(task.startAt), task.estimate, task.starred, nullTime(task.plannedFor))
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	task.id = int(id)
+	return s.db.QueryRow("SELECT sort_order FROM tasks WHERE id = ?", task.id).Scan(&task.sortOrder)
+}
+
+func (s *sqliteStore) Update(tasks ...item) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, task := range tasks {
+			if err := updateTask(tx, task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func updateTask(tx *sql.Tx, task item) error {
+	tags := strings.Join(task.tags, ",")
+	var completed interface{}
+	if task.status == done {
+		completed = task.completedAt
+	} else {
+		completed = nil
+	}
+	_, err := tx.Exec(`
+		UPDATE tasks
+		SET title = ?, tags = ?, status = ?, completed_at = ?, due_at = ?, recurrence = ?, parent_id = ?, notes = ?, priority = ?, updated_at = ?, archived_at = ?, context = ?, project = ?, gtd_list = ?, snoozed_until = ?, start_at = ?, estimate = ?, starred = ?, planned_for = ?
+		WHERE id = ?
+	`, task.title, tags, task.status, completed, nullTime(task.dueAt), task.recurrence, nullInt(task.parentID), task.notes, task.priority, time.Now(), nullTime(task.archivedAt), task.context, task.project, task.list, nullTime(task.snoozedUntil), nullTime(task.startAt), task.estimate, task.starred, nullTime(task.plannedFor), task.id)
+	return err
+}
+
+func (s *sqliteStore) SaveOrder(tasks []item) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, task := range tasks {
+			if task.sortOrder == 0 {
+				continue // Not yet positioned, the database keeps its default
+			}
+			if _, err := tx.Exec("UPDATE tasks SET sort_order = ? WHERE id = ?", task.sortOrder, task.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) Delete(ids ...int) error {
+	now := time.Now()
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.Exec("UPDATE tasks SET deleted_at = ? WHERE id = ?", now, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) Restore(task *item) error {
+	res, err := s.db.Exec("UPDATE tasks SET deleted_at = NULL WHERE id = ?", task.id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return s.Save(task)
+	}
+	if err := s.Update(*task); err != nil {
+		return err
+	}
+	return s.SaveOrder([]item{*task})
+}
+
+func (s *sqliteStore) Purge(ids ...int) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			if _, err := tx.Exec("DELETE FROM tasks WHERE id = ?", id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM reminders WHERE task_id = ?", id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM dependencies WHERE task_id = ? OR blocked_by = ?", id, id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM attachments WHERE task_id = ?", id); err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM checklist_items WHERE task_id = ?", id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) PurgeTrash(before time.Time) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM reminders WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)", before)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			DELETE FROM dependencies
+			WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+			OR blocked_by IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+		`, before, before)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM attachments WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)", before)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM checklist_items WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)", before)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+		return err
+	})
+}
+
+// nullInt maps 0 to NULL for optional ids.
+func nullInt(n int) interface{} {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// nullTime maps the zero time to NULL so optional timestamps stay empty in the DB.
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// ensureColumn adds a column to an existing table if it is missing, so
+// databases created by older versions keep working.
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"strings"
+)
+
+// While typing a #tag in the task input, the tags already in use that start
+// with what was typed are offered under the input, so #work gets reused
+// instead of a new #wrok. tab takes the highlighted tag, up/down pick another
+// and esc hides them.
+
+const maxTagSuggestions = 8
+
+// tagAtCursor returns where the #word ending at the cursor starts and what
+// follows the #, or -1 if the cursor isn't at the end of one.
+func tagAtCursor(value []rune, pos int) (int, string) {
+	start := pos
+	for start > 0 && value[start-1] != ' ' {
+		start--
+	}
+	if start == pos || value[start] != '#' {
+		return -1, ""
+	}
+	return start, string(value[start+1 : pos])
+}
+
+// suggestTags offers the tags matching the #word being typed.
+func (m *model) suggestTags() {
+	t := &m.tasksModel
+	t.tagSuggestions, t.tagSuggestion = nil, 0
+	start, prefix := tagAtCursor([]rune(t.input.Value()), t.input.Position())
+	if start < 0 {
+		return
+	}
+	tags, err := m.store.Tags()
+	if err != nil {
+		m.showError("load tags", err)
+		return
+	}
+	for _, tag := range tags {
+		if tag != prefix && strings.HasPrefix(strings.ToLower(tag), strings.ToLower(prefix)) {
+			t.tagSuggestions = append(t.tagSuggestions, tag)
+		}
+		if len(t.tagSuggestions) == maxTagSuggestions {
+			break
+		}
+	}
+}
+
+// updateTagSuggestions handles the keys that work on the suggestions, and
+// reports whether the key was one of them.
+func (m *model) updateTagSuggestions(key string) bool {
+	t := &m.tasksModel
+	if len(t.tagSuggestions) == 0 {
+		return false
+	}
+	switch key {
+	case "tab":
+		m.completeTag(t.tagSuggestions[t.tagSuggestion])
+	case "down":
+		t.tagSuggestion = (t.tagSuggestion + 1) % len(t.tagSuggestions)
+	case "up", "shift+tab":
+		t.tagSuggestion = (t.tagSuggestion + len(t.tagSuggestions) - 1) % len(t.tagSuggestions)
+	case "esc":
+		t.tagSuggestions = nil
+	default:
+		return false
+	}
+	return true
+}
+
+// completeTag replaces the #word at the cursor with the tag.
+func (m *model) completeTag(tag string) {
+	t := &m.tasksModel
+	value := []rune(t.input.Value())
+	pos := t.input.Position()
+	start, _ := tagAtCursor(value, pos)
+	completed := []rune("#" + tag)
+	rest := value[pos:]
+	if len(rest) == 0 || rest[0] != ' ' {
+		completed = append(completed, ' ')
+	}
+	t.input.SetValue(string(value[:start]) + string(completed) + string(rest))
+	t.input.SetCursor(start + len(completed))
+	t.tagSuggestions = nil
+}
+
+func (m model) renderTagSuggestions() string {
+	t := m.tasksModel
+	var labels []string
+	for i, tag := range t.tagSuggestions {
+		if i == t.tagSuggestion {
+			labels = append(labels, matchStyle.Render("#"+tag))
+		} else {
+			labels = append(labels, tagStyle.Render("#"+tag))
+		}
+	}
+	return strings.Join(labels, "  ")
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// xtui -telegram runs a Telegram bot until it is stopped, as a daemon next
+// to the interface: every message to it is a task in the syntax of the task
+// input (call mom #family tomorrow 5pm remind:1h), /today answers with the
+// digest (see digest.go), and tasks falling due and reminders are sent back
+// to the chat as they go off, the way the interface notifies about them.
+// The bot is made with @BotFather, its token goes under [telegram], and it
+// only listens to the chat set there; any other chat is told its id, to set
+// it the first time. Updates are fetched by long polling, so no public
+// address is needed.
+
+const (
+	telegramAPI  = "https://api.telegram.org/bot"
+	telegramPoll = 30 * time.Second // Long polling timeout
+)
+
+const telegramHelp = `Send a task to add it, e.g. call mom #family tomorrow 5pm remind:1h
+/today lists the overdue tasks and those for today`
+
+type telegramBot struct {
+	token string
+	http  *http.Client
+}
+
+type telegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *telegramMessage `json:"message"`
+}
+
+type telegramMessage struct {
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+// call calls a Bot API method with JSON parameters and decodes its result.
+func (b telegramBot) call(ctx context.Context, method string, params, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, telegramAPI+b.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.http.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // The URL has the token in it
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	if !reply.OK {
+		return fmt.Errorf("%s: %s", method, reply.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+func (b telegramBot) send(ctx context.Context, chat int64, text string) error {
+	return b.call(ctx, "sendMessage", map[string]any{"chat_id": chat, "text": text}, nil)
+}
+
+// poll fetches messages until ctx is done, waiting a little after errors
+// (e.g. no network) before trying again.
+func (b telegramBot) poll(ctx context.Context, messages chan<- telegramMessage) {
+	var offset int64
+	for ctx.Err() == nil {
+		var updates []telegramUpdate
+		params := map[string]any{
+			"offset":          offset,
+			"timeout":         int(telegramPoll.Seconds()),
+			"allowed_updates": []string{"message"},
+		}
+		if err := b.call(ctx, "getUpdates", params, &updates); err != nil {
+			if ctx.Err() == nil {
+				fmt.Fprintf(os.Stderr, "Error fetching messages: %v\n", err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(10 * time.Second):
+				}
+			}
+			continue
+		}
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			if update.Message != nil && update.Message.Text != "" {
+				select {
+				case messages <- *update.Message:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// reloadTasks reads the tasks and reminders again, as the interface may have
+// changed them.
+func (m *model) reloadTasks() error {
+	tasks, err := m.store.Load(liveTasks)
+	if err != nil {
+		return err
+	}
+	m.tasksModel.items = tasks
+	reminders, err := m.store.Reminders()
+	if err != nil {
+		return err
+	}
+	m.reminders = reminders
+	return nil
+}
+
+// telegramReply handles a message to the bot and returns the answer, empty
+// for none.
+func (m *model) telegramReply(msg telegramMessage, now time.Time) string {
+	chat := m.config.telegramChat
+	if chat == 0 {
+		return fmt.Sprintf("This chat is %d. Set chat = %d under [telegram] in the Xtui config to add tasks from it.", msg.Chat.ID, msg.Chat.ID)
+	}
+	if msg.Chat.ID != chat {
+		return ""
+	}
+	if err := m.reloadTasks(); err != nil {
+		return "Could not read the tasks: " + err.Error()
+	}
+
+	text := strings.TrimSpace(msg.Text)
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(strings.Fields(text)[0], "@") // /today@xtui_bot in groups
+		if command == "/today" {
+			_, body := digestMessage(m.tasksModel.items, now)
+			return body
+		}
+		return telegramHelp
+	}
+
+	task := parseTaskInput(text)
+	if task.title == "" {
+		return telegramHelp
+	}
+	if _, err := m.addTasks([]item{task}); err != nil {
+		return "Could not add the task: " + err.Error()
+	}
+	added := m.tasksModel.items[len(m.tasksModel.items)-1]
+	m.setReminders(added.id, text)
+	reply := "Added: " + added.title
+	switch {
+	case added.dueAt.IsZero():
+	case isEndOfDay(added.dueAt):
+		reply += ", due " + formatDate(added.dueAt, "Mon 2 Jan")
+	default:
+		reply += ", due " + formatDate(added.dueAt, "Mon 2 Jan") + " " + formatClock(added.dueAt)
+	}
+	return reply
+}
+
+// runTelegram implements the -telegram flag.
+func runTelegram() error {
+	m := newModel()
+	defer m.close()
+	if m.config.telegramToken == "" {
+		return errors.New("set token under [telegram] in the config")
+	}
+	bot := telegramBot{token: m.config.telegramToken, http: &http.Client{Timeout: telegramPoll + 10*time.Second}}
+
+	ctx, stop := untilInterrupted()
+	defer stop()
+	messages := make(chan telegramMessage)
+	go bot.poll(ctx, messages)
+
+	// Messages and alerts are handled here, one at a time
+	ticker := time.NewTicker(notifyInterval)
+	defer ticker.Stop()
+	since := time.Now()
+	fmt.Fprintln(os.Stderr, "Telegram bot running, Ctrl+C to stop")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-messages:
+			if reply := m.telegramReply(msg, time.Now()); reply != "" {
+				if err := bot.send(ctx, msg.Chat.ID, reply); err != nil {
+					fmt.Fprintf(os.Stderr, "Error replying: %v\n", err)
+				}
+			}
+		case now := <-ticker.C:
+			if m.config.telegramChat == 0 {
+				continue
+			}
+			if err := m.reloadTasks(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading tasks: %v\n", err)
+				continue
+			}
+			alerts := append(m.dueTasks(since, now), m.dueReminders(since, now)...)
+			since = now
+			for _, a := range alerts {
+				if err := bot.send(ctx, m.config.telegramChat, a.title+": "+a.body); err != nil {
+					fmt.Fprintf(os.Stderr, "Error sending %s: %v\n", strings.ToLower(a.title), err)
+				}
+			}
+		}
+	}
+}
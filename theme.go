@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// theme holds the colors every style is built from. Each color has a light
+// and a dark variant; lipgloss picks one based on the terminal background.
+type theme struct {
+	name        string
+	text        lipgloss.AdaptiveColor // Titles, inactive tabs and the loading screen
+	accent      lipgloss.AdaptiveColor // Selected task
+	selectionBg lipgloss.AdaptiveColor // Visual selection background
+	tag         lipgloss.AdaptiveColor
+	muted       lipgloss.AdaptiveColor // Help text and timestamps
+	activeTab   lipgloss.AdaptiveColor
+	notes       lipgloss.AdaptiveColor
+	matchFg     lipgloss.AdaptiveColor
+	matchBg     lipgloss.AdaptiveColor
+	overdue     lipgloss.AdaptiveColor
+	mode        lipgloss.AdaptiveColor
+	star        lipgloss.AdaptiveColor // Marker of starred tasks
+}
+
+func adaptive(light, dark string) lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{Light: light, Dark: dark}
+}
+
+// themeNames lists the built-in themes in the order the switcher cycles
+// through them.
+var themeNames = []string{"default", "gruvbox", "catppuccin", "nord", "monochrome"}
+
+var themes = map[string]theme{
+	"default": {
+		text:        adaptive("#1A1A1A", "#FFFFFF"),
+		accent:      adaptive("#D75F00", "#FFA500"),
+		selectionBg: adaptive("#E4E4E4", "#3A3A3A"),
+		tag:         adaptive("#008787", "#00FFFF"),
+		muted:       adaptive("#8A8A8A", "#626262"),
+		activeTab:   adaptive("#008700", "#00FF00"),
+		notes:       adaptive("#5F5F5F", "#A0A0A0"),
+		matchFg:     adaptive("#000000", "#000000"),
+		matchBg:     adaptive("#FFD700", "#FFFF00"),
+		overdue:     adaptive("#D70000", "#FF0000"),
+		mode:        adaptive("#D7005F", "#FF69B4"),
+		star:        adaptive("#AF8700", "#FFD700"),
+	},
+	"gruvbox": {
+		text:        adaptive("#3C3836", "#EBDBB2"),
+		accent:      adaptive("#AF3A03", "#FE8019"),
+		selectionBg: adaptive("#EBDBB2", "#3C3836"),
+		tag:         adaptive("#427B58", "#8EC07C"),
+		muted:       adaptive("#928374", "#928374"),
+		activeTab:   adaptive("#79740E", "#B8BB26"),
+		notes:       adaptive("#665C54", "#A89984"),
+		matchFg:     adaptive("#FBF1C7", "#282828"),
+		matchBg:     adaptive("#B57614", "#FABD2F"),
+		overdue:     adaptive("#9D0006", "#FB4934"),
+		mode:        adaptive("#8F3F71", "#D3869B"),
+		star:        adaptive("#B57614", "#FABD2F"),
+	},
+	"catppuccin": {
+		text:        adaptive("#4C4F69", "#CDD6F4"),
+		accent:      adaptive("#FE640B", "#FAB387"),
+		selectionBg: adaptive("#CCD0DA", "#313244"),
+		tag:         adaptive("#179299", "#94E2D5"),
+		muted:       adaptive("#9CA0B0", "#6C7086"),
+		activeTab:   adaptive("#40A02B", "#A6E3A1"),
+		notes:       adaptive("#6C6F85", "#A6ADC8"),
+		matchFg:     adaptive("#EFF1F5", "#1E1E2E"),
+		matchBg:     adaptive("#DF8E1D", "#F9E2AF"),
+		overdue:     adaptive("#D20F39", "#F38BA8"),
+		mode:        adaptive("#EA76CB", "#F5C2E7"),
+		star:        adaptive("#DF8E1D", "#F9E2AF"),
+	},
+	"nord": {
+		text:        adaptive("#2E3440", "#ECEFF4"),
+		accent:      adaptive("#5E81AC", "#88C0D0"),
+		selectionBg: adaptive("#D8DEE9", "#3B4252"),
+		tag:         adaptive("#4C7A8A", "#8FBCBB"),
+		muted:       adaptive("#7B88A1", "#4C566A"),
+		activeTab:   adaptive("#5A7F45", "#A3BE8C"),
+		notes:       adaptive("#4C566A", "#D8DEE9"),
+		matchFg:     adaptive("#2E3440", "#2E3440"),
+		matchBg:     adaptive("#EBCB8B", "#EBCB8B"),
+		overdue:     adaptive("#BF616A", "#BF616A"),
+		mode:        adaptive("#B48EAD", "#B48EAD"),
+		star:        adaptive("#D08770", "#EBCB8B"),
+	},
+	"monochrome": {
+		text:        adaptive("#000000", "#FFFFFF"),
+		accent:      adaptive("#000000", "#FFFFFF"),
+		selectionBg: adaptive("#D0D0D0", "#444444"),
+		tag:         adaptive("#4E4E4E", "#BCBCBC"),
+		muted:       adaptive("#8A8A8A", "#6C6C6C"),
+		activeTab:   adaptive("#000000", "#FFFFFF"),
+		notes:       adaptive("#585858", "#9E9E9E"),
+		matchFg:     adaptive("#FFFFFF", "#000000"),
+		matchBg:     adaptive("#303030", "#D0D0D0"),
+		overdue:     adaptive("#000000", "#FFFFFF"),
+		mode:        adaptive("#4E4E4E", "#BCBCBC"),
+		star:        adaptive("#000000", "#FFFFFF"),
+	},
+}
+
+// loadTheme looks up a built-in theme and applies any color overrides from
+// the [theme] section of the config.
+func loadTheme(name string, overrides map[string]string) (theme, error) {
+	t, ok := themes[name]
+	if !ok {
+		t = themes["default"]
+		t.name = "default"
+		return t, fmt.Errorf("unknown theme %q", name)
+	}
+	t.name = name
+
+	colors := map[string]*lipgloss.AdaptiveColor{
+		"text":         &t.text,
+		"accent":       &t.accent,
+		"selection_bg": &t.selectionBg,
+		"tag":          &t.tag,
+		"muted":        &t.muted,
+		"active_tab":   &t.activeTab,
+		"notes":        &t.notes,
+		"match_fg":     &t.matchFg,
+		"match_bg":     &t.matchBg,
+		"overdue":      &t.overdue,
+		"mode":         &t.mode,
+		"star":         &t.star,
+	}
+	for key, value := range overrides {
+		color, ok := colors[key]
+		if !ok {
+			return t, fmt.Errorf("unknown theme color %q", key)
+		}
+		// A single override is used on both light and dark backgrounds
+		*color = adaptive(value, value)
+	}
+	return t, nil
+}
+
+// detectBackground decides whether the light or dark variant of the theme
+// colors is used. It has to run before Bubble Tea starts reading stdin, since
+// auto detection queries the terminal.
+func detectBackground(setting string) {
+	switch setting {
+	case "light":
+		lipgloss.SetHasDarkBackground(false)
+	case "dark":
+		lipgloss.SetHasDarkBackground(true)
+	default:
+		lipgloss.HasDarkBackground()
+	}
+}
+
+// nextTheme returns the built-in theme that follows name in themeNames.
+func nextTheme(name string) string {
+	for i, n := range themeNames {
+		if n == name {
+			return themeNames[(i+1)%len(themeNames)]
+		}
+	}
+	return themeNames[0]
+}
+
+// setTheme switches the styles to the named theme. Color overrides from the
+// config only apply to the theme named there.
+func (m *model) setTheme(name string) {
+	var overrides map[string]string
+	if name == m.config.theme {
+		overrides = m.config.themeColors
+	}
+	t, err := loadTheme(name, overrides)
+	if err != nil {
+		m.showError("load theme", err)
+	}
+	m.theme = t
+	applyTheme(t)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Times are shown relative to now by default, "2 hours ago" and "due in 3
+// days". times = "absolute" in the [display] section of the config shows
+// them as dates instead, in the layout and date_layout given there (Go
+// layouts, "2006-01-02 15:04" and "2006-01-02" by default), and "locale"
+// uses the numeric date order and clock of the locale: the locale setting,
+// or LC_ALL, LC_TIME or LANG. Either way the times that are always shown as
+// dates, like snoozes and reminders, take the same layouts.
+
+const (
+	relativeTimes = "relative"
+	absoluteTimes = "absolute"
+	localeTimes   = "locale"
+)
+
+// timeLayouts are the Go layouts for a moment, a day and a time of day.
+type timeLayouts struct {
+	moment string
+	date   string
+	clock  string
+}
+
+// localeLayouts are numeric formats by locale, or by language when they
+// don't vary by territory.
+var localeLayouts = map[string]timeLayouts{
+	"en_US": {"01/02/2006 3:04 PM", "01/02/2006", "3:04 PM"},
+	"en_CA": {"2006-01-02 3:04 PM", "2006-01-02", "3:04 PM"},
+	"en":    {"02/01/2006 15:04", "02/01/2006", "15:04"},
+	"fr":    {"02/01/2006 15:04", "02/01/2006", "15:04"},
+	"es":    {"02/01/2006 15:04", "02/01/2006", "15:04"},
+	"it":    {"02/01/2006 15:04", "02/01/2006", "15:04"},
+	"pt":    {"02/01/2006 15:04", "02/01/2006", "15:04"},
+	"de":    {"02.01.2006 15:04", "02.01.2006", "15:04"},
+	"ru":    {"02.01.2006 15:04", "02.01.2006", "15:04"},
+	"pl":    {"02.01.2006 15:04", "02.01.2006", "15:04"},
+	"cs":    {"02.01.2006 15:04", "02.01.2006", "15:04"},
+	"fi":    {"02.01.2006 15.04", "02.01.2006", "15.04"},
+	"nb":    {"02.01.2006 15:04", "02.01.2006", "15:04"},
+	"tr":    {"02.01.2006 15:04", "02.01.2006", "15:04"},
+	"nl":    {"02-01-2006 15:04", "02-01-2006", "15:04"},
+	"da":    {"02.01.2006 15.04", "02.01.2006", "15.04"},
+	"sv":    {"2006-01-02 15:04", "2006-01-02", "15:04"},
+	"ja":    {"2006/01/02 15:04", "2006/01/02", "15:04"},
+	"zh":    {"2006/01/02 15:04", "2006/01/02", "15:04"},
+	"ko":    {"2006. 01. 02. 15:04", "2006. 01. 02.", "15:04"},
+}
+
+// isoLayouts are the absolute layouts when none are configured, and for
+// locales not in localeLayouts.
+var isoLayouts = timeLayouts{"2006-01-02 15:04", "2006-01-02", "15:04"}
+
+// absoluteLayouts are the layouts times are shown in, set from the config by
+// setTimeFormat. Nil when times are relative.
+var absoluteLayouts *timeLayouts
+
+// setTimeFormat applies the [display] time settings.
+func setTimeFormat(cfg config) {
+	switch cfg.times {
+	case absoluteTimes:
+		layouts := timeLayouts{cfg.timeLayout, cfg.dateLayout, clockLayout(cfg.timeLayout)}
+		absoluteLayouts = &layouts
+	case localeTimes:
+		layouts := layoutsFor(cfg.locale)
+		absoluteLayouts = &layouts
+	default:
+		absoluteLayouts = nil
+	}
+}
+
+// clockLayout picks the clock that goes with a layout.
+func clockLayout(layout string) string {
+	if strings.Contains(layout, "PM") || strings.Contains(layout, "pm") {
+		return "3:04 PM"
+	}
+	return "15:04"
+}
+
+// layoutsFor returns the layouts of a locale like de_DE.UTF-8, taken from
+// the environment when it is empty.
+func layoutsFor(locale string) timeLayouts {
+	for _, name := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if locale != "" {
+			break
+		}
+		locale = os.Getenv(name)
+	}
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale = strings.ReplaceAll(locale, "-", "_")
+	if layouts, ok := localeLayouts[locale]; ok {
+		return layouts
+	}
+	language, _, _ := strings.Cut(locale, "_")
+	if layouts, ok := localeLayouts[strings.ToLower(language)]; ok {
+		return layouts
+	}
+	return isoLayouts
+}
+
+// formatTime shows a moment in the given layout, or the configured one when
+// times are absolute.
+func formatTime(t time.Time, layout string) string {
+	if absoluteLayouts != nil {
+		layout = absoluteLayouts.moment
+	}
+	return t.Format(layout)
+}
+
+// formatDate shows a day in the given layout, or the configured one.
+func formatDate(t time.Time, layout string) string {
+	if absoluteLayouts != nil {
+		layout = absoluteLayouts.date
+	}
+	return t.Format(layout)
+}
+
+// formatClock shows a time of day as 15:04, or as the configured layout has
+// it.
+func formatClock(t time.Time) string {
+	if absoluteLayouts != nil {
+		return t.Format(absoluteLayouts.clock)
+	}
+	return t.Format("15:04")
+}
+
+// formatMoment shows a moment in the absolute layouts, as a day when it is
+// the start or end of one.
+func formatMoment(t time.Time) string {
+	if t.Equal(midnight(t)) || isEndOfDay(t) {
+		return t.Format(absoluteLayouts.date)
+	}
+	return t.Format(absoluteLayouts.moment)
+}
+
+func formatRelativeTime(t time.Time) string {
+	if absoluteLayouts != nil {
+		return formatMoment(t)
+	}
+	duration := time.Since(t)
+	if duration < time.Minute {
+		return "just now"
+	}
+	return formatDuration(duration) + " ago"
+}
+
+// formatDueTime describes a deadline relative to now, e.g. "due in 2 days"
+// or "overdue by 3 hours".
+func formatDueTime(t time.Time) string {
+	duration := time.Until(t)
+	if absoluteLayouts != nil {
+		if duration < 0 {
+			return "overdue, due " + formatMoment(t)
+		}
+		return "due " + formatMoment(t)
+	}
+	if duration < 0 {
+		return "overdue by " + formatDuration(-duration)
+	}
+	return "due in " + formatDuration(duration)
+}
+
+// checkLayout makes sure a configured layout shows something of the time.
+func checkLayout(key, layout string) error {
+	if time.Date(2011, 3, 4, 17, 8, 9, 0, time.UTC).Format(layout) == layout {
+		return fmt.Errorf("%s %q is not a Go time layout, write it like \"2006-01-02 15:04\"", key, layout)
+	}
+	return nil
+}
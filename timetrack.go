@@ -0,0 +1,218 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Time tracking: T starts a timer on the selected task and stops it again.
+// Each stretch of work is a row in time_entries, the running one without an
+// end, so a timer keeps running across restarts. The list shows the elapsed
+// time next to the tracked task, the detail view the total, and :report the
+// time per task for today and this week.
+
+const reportMode = "report" // Time tracked per task
+
+type timeEntry struct {
+	id        int
+	taskID    int
+	startedAt time.Time
+	endedAt   time.Time // Zero while the timer runs
+}
+
+// duration returns how long the entry ran within [since, now].
+func (e timeEntry) duration(since, now time.Time) time.Duration {
+	start, end := e.startedAt, e.endedAt
+	if end.IsZero() {
+		end = now
+	}
+	if start.Before(since) {
+		start = since
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// trackTickMsg refreshes the elapsed time of the running timer.
+type trackTickMsg int
+
+func trackTick(seq int) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return trackTickMsg(seq)
+	})
+}
+
+// runningEntry returns the entry of the running timer.
+func (m model) runningEntry() (timeEntry, bool) {
+	for _, e := range m.timeEntries {
+		if e.endedAt.IsZero() {
+			return e, true
+		}
+	}
+	return timeEntry{}, false
+}
+
+// trackedTime sums the time tracked on a task since the given time.
+func (m model) trackedTime(taskID int, since time.Time) time.Duration {
+	var total time.Duration
+	now := time.Now()
+	for _, e := range m.timeEntries {
+		if e.taskID == taskID {
+			total += e.duration(since, now)
+		}
+	}
+	return total
+}
+
+// toggleTracking stops the running timer, and starts one on the selected
+// task unless that task was the one being tracked.
+func (m *model) toggleTracking() tea.Cmd {
+	now := time.Now()
+	running, ok := m.runningEntry()
+	if ok {
+		if err := m.store.StopTimeEntry(running.id, now); err != nil {
+			m.showError("stop timer", err)
+			return nil
+		}
+	}
+	index := m.tasksModel.selectedIndex()
+	if index < 0 || ok && running.taskID == m.tasksModel.items[index].id {
+		m.refreshTimeEntries()
+		if ok {
+			m.showMessage("Tracked " + formatElapsed(running.duration(time.Time{}, now)))
+		}
+		return nil
+	}
+	if _, err := m.store.StartTimeEntry(m.tasksModel.items[index].id, now); err != nil {
+		m.showError("start timer", err)
+	}
+	m.refreshTimeEntries()
+	m.trackSeq++
+	return trackTick(m.trackSeq)
+}
+
+// tickTracking keeps the elapsed time shown in the list current.
+func (m model) tickTracking(msg trackTickMsg) tea.Cmd {
+	if _, ok := m.runningEntry(); !ok || int(msg) != m.trackSeq {
+		return nil
+	}
+	return trackTick(m.trackSeq)
+}
+
+func (m *model) refreshTimeEntries() {
+	entries, err := m.store.TimeEntries(time.Time{})
+	if err != nil {
+		m.showError("load time entries", err)
+		return
+	}
+	m.timeEntries = entries
+}
+
+// formatElapsed formats a tracked time as 1:02:03.
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+func (m model) renderReport() string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	// Weeks start on Monday
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Time tracked") + "\n\n")
+	ids := make(map[int]bool)
+	for _, e := range m.timeEntries {
+		if e.duration(week, now) > 0 {
+			ids[e.taskID] = true
+		}
+	}
+	if len(ids) == 0 {
+		s.WriteString(helpStyle.Render("Nothing tracked this week. Press T on a task to start a timer.") + "\n")
+		return s.String()
+	}
+
+	type row struct {
+		title       string
+		today, week time.Duration
+	}
+	var rows []row
+	var totalToday, totalWeek time.Duration
+	for id := range ids {
+		r := row{title: "(deleted task)", today: m.trackedTime(id, today), week: m.trackedTime(id, week)}
+		if index := m.tasksModel.indexOf(id); index >= 0 {
+			r.title = m.tasksModel.items[index].title
+		}
+		rows = append(rows, r)
+		totalToday += r.today
+		totalWeek += r.week
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].week > rows[j].week })
+
+	s.WriteString(helpStyle.Render(fmt.Sprintf("%9s  %9s  %s", "Today", "Week", "Task")) + "\n")
+	for _, r := range rows {
+		s.WriteString(fmt.Sprintf("%9s  %9s  %s\n", formatElapsed(r.today), formatElapsed(r.week), r.title))
+	}
+	s.WriteString(titleStyle.Render(fmt.Sprintf("%9s  %9s  Total", formatElapsed(totalToday), formatElapsed(totalWeek))) + "\n")
+	return s.String()
+}
+
+func createTimeEntriesTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS time_entries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			task_id INTEGER NOT NULL,
+			started_at DATETIME NOT NULL,
+			ended_at DATETIME
+		);
+	`)
+	return err
+}
+
+func (s *sqliteStore) StartTimeEntry(taskID int, at time.Time) (timeEntry, error) {
+	res, err := s.db.Exec("INSERT INTO time_entries (task_id, started_at) VALUES (?, ?)", taskID, at)
+	if err != nil {
+		return timeEntry{}, err
+	}
+	id, err := res.LastInsertId()
+	return timeEntry{id: int(id), taskID: taskID, startedAt: at}, err
+}
+
+func (s *sqliteStore) StopTimeEntry(id int, at time.Time) error {
+	_, err := s.db.Exec("UPDATE time_entries SET ended_at = ? WHERE id = ?", at, id)
+	return err
+}
+
+func (s *sqliteStore) TimeEntries(since time.Time) ([]timeEntry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, task_id, started_at, ended_at FROM time_entries
+		WHERE ended_at IS NULL OR ended_at >= ?
+		ORDER BY started_at
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []timeEntry
+	for rows.Next() {
+		var e timeEntry
+		var endedAt sql.NullTime
+		if err := rows.Scan(&e.id, &e.taskID, &e.startedAt, &endedAt); err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			e.endedAt = endedAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rivo/uniseg"
+)
+
+// Titles too long for the terminal are cut short with an ellipsis, so the
+// list never runs past the edge of the screen and breaks the layout. titles
+// = "wrap" in the [display] section of the config wraps them onto more lines
+// instead, under the start of the title. Either way the room a title gets is
+// what the window leaves after the detail pane and the rest of the line.
+
+const (
+	truncateTitles = "truncate"
+	wrapTitles     = "wrap"
+)
+
+const minTitleWidth = 20 // Room a title keeps even when the rest of its line is long
+
+// listWidth is the width of the task list, without the outer padding and the
+// detail pane.
+func (m model) listWidth() int {
+	return m.width - 4 - m.paneWidth()
+}
+
+// fitTitle breaks a title into the lines it takes in width columns, cut
+// short or wrapped as configured.
+func (m model) fitTitle(title string, width int) []string {
+	if m.width == 0 || lipgloss.Width(title) <= width { // Size not known yet
+		return []string{title}
+	}
+	width = max(width, minTitleWidth)
+	if m.config.titles == wrapTitles {
+		return wrapText(title, width)
+	}
+	return []string{truncate(title, width)}
+}
+
+// truncate cuts text to width columns, ending it with an ellipsis when it
+// is cut.
+func truncate(text string, width int) string {
+	if lipgloss.Width(text) <= width {
+		return text
+	}
+	head, _ := splitWidth(text, width-1)
+	return strings.TrimRight(head, " ") + "…"
+}
+
+// splitWidth splits text after as many characters as fit in width columns.
+// Characters are grapheme clusters, so an emoji made of several code points
+// like 👩‍💻 or ❤️ is measured and kept whole, and CJK characters count two
+// columns.
+func splitWidth(text string, width int) (head, tail string) {
+	used, rest, state := 0, text, -1
+	for rest != "" {
+		_, next, w, newState := uniseg.FirstGraphemeClusterInString(rest, state)
+		if used+w > width {
+			break
+		}
+		used, rest, state = used+w, next, newState
+	}
+	return text[:len(text)-len(rest)], rest
+}
+
+// wrapText breaks text into lines of at most width columns between words,
+// and inside words too long for a line.
+func wrapText(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case lipgloss.Width(line+" "+word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+		for lipgloss.Width(line) > width {
+			head, tail := splitWidth(line, width)
+			lines = append(lines, head)
+			line = tail
+		}
+	}
+	return append(lines, line)
+}
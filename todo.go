@@ -1,32 +1,57 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
+	"unicode"
 
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	_ "github.com/mattn/go-sqlite3" // SQLite driver
-	"github.com/joho/godotenv"      // Load .env file
 )
 
 const (
 	Tasks = iota
+	Agenda
+	Calendar
+	Board
+	Week
+	Goals
+	Trash
+	Pomodoro
+	Stats
+	Activity
 	User
 	About
 	LoadingScreen
 )
 
 const (
-	normalMode = "normal"
-	insertMode = "insert"
-	undoLimit  = 10 // Limit for undo stack
+	normalMode  = "normal"
+	insertMode  = "insert"
+	detailMode  = "detail"
+	searchMode  = "search"
+	tagMode     = "tag"     // Tag picker
+	contextMode = "context" // Context picker
+	visualMode  = "visual"
+	bulkTagMode = "bulktag" // Typing tag changes for several tasks
+	commandMode = "command" // Typing a : command
+	signInMode  = "signin"  // Pasting a sync token on the User tab
+	undoLimit   = 10        // Limit for undo stack
+
+	inputPlaceholder = "Press enter to add a new todo..."
 )
 
 type model struct {
@@ -35,25 +60,132 @@ type model struct {
 	height      int
 	loadingDone bool
 	tasksModel  tasksModel
-	undoStack   []item // Stack to store deleted tasks for undo functionality
-	db          *sql.DB
+	trash       trashModel
+	activity    activityModel
+	agenda      agendaModel
+	calendar    calendarModel
+	board       boardModel
+	week        weekModel
+	goals       goalsModel
+	undoStack   []operation // Changes that u reverts, most recent last
+	redoStack   []operation // Undone changes that ctrl+r reapplies
+	store       TaskStore
+	vault       *vault  // Nil unless the database is encrypted
+	message     message // Shown above the footer until dismissed
+	config      config
+	theme       theme
+	cloud       syncState // Xtui sync server
+	account     syncAccount
+	signIn      signInState
+	caldav      syncState
+	git         syncState // Sync through a git repository
+
+	reminders     []reminder
+	dependencies  []dependency // Which tasks wait for which
+	pomodoro      pomodoroModel
+	timeEntries   []timeEntry
+	trackSeq      int // Identifies the running timer's ticks
+	stats         taskStats
+	review        reviewState
+	attach        attachState
+	checklists    []checkItem // Checklist items of every task
+	checklist     checklistState
+	taskEvents    []taskEvent       // History of the task in the detail view
+	registers     map[string][]item // Tasks yanked or deleted, by register name
+	finder        finderState
+	restore       restoreState
+	conflicts     conflictsState
+	notifiedUntil time.Time // Due times up to here have been announced
+	ringing       bool      // The view ends with a BEL, see bell
+	lastBackup    time.Time // When the database was last backed up
+	lastDigest    string    // Day the email digest was last sent, YYYY-MM-DD
+	lastSummary   string    // Day the Discord summary was last posted
+	pendingHooks  []hookRun // Hooks to start once the current message is handled
+	plugins       []*plugin
+	pluginView    pluginViewState
+	nextProfile   string // Profile to start again on after quitting
 }
 
 type tasksModel struct {
-	items    []item
-	input    textinput.Model
-	selected int
-	mode     string
+	items        []item
+	input        textinput.Model
+	notes        textarea.Model  // Notes editor for the detail view
+	search       textinput.Model // Query line for search mode
+	query        string          // Active search, empty when not searching
+	tagFilter    string          // Only show tasks with this tag, empty for all
+	tagOptions   []string        // Tags listed in the tag picker
+	tagCursor    int             // Highlighted entry in the tag picker
+	hideDone     bool            // Hide completed tasks from the list
+	showSnoozed  bool            // List snoozed tasks too
+	showUpcoming bool            // List tasks that haven't started too
+	sortBy       string          // One of the sort* orders
+	anchor       int             // Row where visual mode started
+	bulkTargets  []int           // Tasks the bulk tag prompt applies to
+	selected     int             // Index into rows(), not items
+	mode         string
+	collapsed    map[int]bool // Task ids whose subtasks are hidden
+	expanded     map[int]bool // Task ids whose notes are shown in the list
+	parentID     int          // Parent for the task being added, 0 for a top-level task
+	editID       int          // Task being edited in insert mode, 0 when adding a new task
+	pendingKey   string       // First key of a multi-key command such as "za"
+	register     string       // Register picked with " for the next yank, delete or put
+	count        int          // Count typed before a command, 0 for none
+	lastAction   lastAction   // Last change, for .
+
+	command      textinput.Model // Prompt for : commands
+	commandErr   string          // Error from the last : command
+	confirmClean string          // Arguments of the :clean waiting for a second enter
+	completions  []string        // Candidates listed after tab completion
+	history      []string        // Previous : commands, oldest first
+	historyIndex int             // Entry shown while browsing history
+
+	tagSuggestions []string // Tags matching the #word being typed
+	tagSuggestion  int      // Highlighted suggestion
+
+	contextFilter  string   // Only show tasks in this context, empty for all
+	contextOptions []string // Contexts listed in the context picker
+	contextCursor  int      // Highlighted entry in the context picker
+	byContext      bool     // Group the list under a heading per context
+	projectOptions []string // Projects listed in the project picker
+	projectCursor  int      // Highlighted entry in the project picker
+	listView       gtdList  // Only show tasks in this GTD list, empty for all
+	focus          bool     // Only show the tasks for today, see focus.go
+	blockID        int      // Task picking what it waits for in block mode
+	showPane       bool     // Show the detail pane beside the list
+	paneShare      int      // Percent of the width taken by the detail pane
+	density        string   // How much the list shows of each task, see density.go
+
+	taskQuery string       // Query filtering the list, see query.go
+	queryIDs  map[int]bool // Tasks matching taskQuery when it last ran
+
+	doneCursor historyCursor // Where the next page of completed tasks starts, see history.go
 }
 
 type item struct {
-	id          int
-	title       string
-	tags        []string
-	status      status
-	selected    bool
-	createdAt   time.Time // Timestamp for task creation
-	completedAt time.Time // Timestamp for task completion
+	id           int
+	title        string
+	tags         []string
+	status       status
+	selected     bool
+	createdAt    time.Time // Timestamp for task creation
+	completedAt  time.Time // Timestamp for task completion
+	dueAt        time.Time // Deadline, zero if the task has no due date
+	recurrence   string    // RRULE-style repetition rule, empty for one-off tasks
+	parentID     int       // Parent task id, 0 for top-level tasks
+	notes        string    // Free-form multi-line description
+	priority     int       // 1 (highest) to 3, 0 for no priority
+	context      string    // Where or with what the task can be done, from @context
+	project      string    // Project the task belongs to, from +project
+	list         gtdList   // GTD list the task was triaged to, inbox until then
+	sortOrder    int       // Position in the manual order, 0 until first persisted
+	deletedAt    time.Time // When the task was moved to the trash
+	updatedAt    time.Time // Last change, :review brings up tasks left alone for long
+	archivedAt   time.Time // When the task was archived, zero while it is listed
+	snoozedUntil time.Time // Hidden from the list until then, see snooze.go
+	startAt      time.Time // When work on the task can start, see startdate.go
+	estimate     int       // Minutes the task should take, 0 if not estimated
+	starred      bool      // Pinned to the top of the list, see star.go
+	plannedFor   time.Time // Day the task is planned for, zero if none, see focus.go
 }
 
 type status int
@@ -61,109 +193,229 @@ type status int
 const (
 	todo status = iota
 	done
+	doing // Started, the middle column of the board
 )
 
+// Styles are rebuilt from the active theme by applyTheme
 var (
+	titleStyle        lipgloss.Style
+	itemStyle         lipgloss.Style
+	selectedItemStyle lipgloss.Style
+	visualItemStyle   lipgloss.Style
+	tagStyle          lipgloss.Style
+	helpStyle         lipgloss.Style
+	activeTabStyle    lipgloss.Style
+	inactiveTabStyle  lipgloss.Style
+	notesStyle        lipgloss.Style
+	matchStyle        lipgloss.Style
+	overdueStyle      lipgloss.Style
+	modeStyle         lipgloss.Style
+	loadingTextStyle  lipgloss.Style
+	loadingMarkStyle  lipgloss.Style
+	barStyle          lipgloss.Style
+	paneStyle         lipgloss.Style
+	profileStyle      lipgloss.Style
+	starStyle         lipgloss.Style
+)
+
+func applyTheme(t theme) {
 	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FFFFFF"))
+		Bold(true).
+		Foreground(themeColor(t.text))
 
 	itemStyle = lipgloss.NewStyle().
-			PaddingLeft(4)
+		PaddingLeft(4)
 
 	selectedItemStyle = lipgloss.NewStyle().
-				PaddingLeft(4).
-				Foreground(lipgloss.Color("#FFA500")) // Orange color for hover
+		PaddingLeft(4).
+		Bold(accessible).
+		Foreground(themeColor(t.accent)) // Accent color for hover
+
+	visualItemStyle = lipgloss.NewStyle().
+		PaddingLeft(4).
+		Foreground(themeColor(t.accent)).
+		Background(themeColor(t.selectionBg)). // Dim background for visual selection
+		Reverse(accessible)
 
 	tagStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#00FFFF"))
+		Foreground(themeColor(t.tag))
 
 	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262"))
+		Foreground(themeColor(t.muted))
 
 	activeTabStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#00FF00")).
-			Padding(1, 2) // Add padding to make tabs appear larger
+		Bold(true).
+		Underline(accessible).
+		Foreground(themeColor(t.activeTab)).
+		Padding(1, 2) // Add padding to make tabs appear larger
 
 	inactiveTabStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#FFFFFF")).
-				Padding(1, 2) // Add padding to make tabs appear larger
+		Foreground(themeColor(t.text)).
+		Padding(1, 2) // Add padding to make tabs appear larger
+
+	notesStyle = lipgloss.NewStyle().
+		Foreground(themeColor(t.notes))
+
+	matchStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(themeColor(t.matchFg)).
+		Background(themeColor(t.matchBg)). // Highlighted background for search matches
+		Reverse(accessible)
+
+	overdueStyle = lipgloss.NewStyle().
+		Bold(accessible).
+		Foreground(themeColor(t.overdue)) // Warning color for overdue tasks
 
 	modeStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FF69B4"))
+		Bold(true).
+		Foreground(themeColor(t.mode))
 
 	loadingTextStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(lipgloss.Color("#FFFFFF")).
-				Align(lipgloss.Center).
-				Margin(2, 0).
-				Padding(1, 0)
-)
+		Bold(true).
+		Foreground(themeColor(t.text)).
+		Align(lipgloss.Center).
+		Margin(2, 0).
+		Padding(1, 0)
+
+	loadingMarkStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(themeColor(t.accent))
+
+	barStyle = lipgloss.NewStyle().
+		Foreground(themeColor(t.accent)) // Bars in the Stats charts
+
+	paneStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true).
+		BorderForeground(themeColor(t.muted)).
+		PaddingLeft(2) // Detail pane beside the task list
+
+	profileStyle = lipgloss.NewStyle().
+		Foreground(themeColor(t.accent)).
+		Padding(1, 2) // In line with the tabs
+
+	starStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(themeColor(t.star)) // Marker of starred tasks
+}
 
 func newModel() model {
-	// Load .env file
-	err := godotenv.Load()
+	// Load the config file, creating it on first run
+	cfg, err := loadConfig()
 	if err != nil {
-		fmt.Printf("Error loading .env file: %v\n", err)
+		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Get database path from .env
-	dbPath := os.Getenv("DATABASE_PATH")
-	if dbPath == "" {
-		dbPath = "./tui-do.db" // Default value
+	// Make sure the database directory exists
+	err = os.MkdirAll(filepath.Dir(cfg.databasePath), 0o755)
+	if err != nil {
+		fmt.Printf("Error creating database directory: %v\n", err)
+		os.Exit(1)
 	}
 
-	// Open the SQLite database
-	db, err := sql.Open("sqlite3", dbPath)
+	// Decrypt the database if it is kept encrypted
+	vault, dbPath, err := openVault(cfg)
 	if err != nil {
 		fmt.Printf("Error opening database: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Database opened successfully.")
 
-	// Ping the database to ensure the connection is valid
-	err = db.Ping()
+	// Open the SQLite database and bring its schema up to date
+	store, err := openSQLiteStore(dbPath, func(s *sqliteStore) error {
+		_, err := backupDatabase(s, vault, cfg, time.Now())
+		return err
+	})
 	if err != nil {
-		fmt.Printf("Error pinging database: %v\n", err)
+		fmt.Printf("Error opening database: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("Database connection is valid.")
-
-	// Create the tasks table if it doesn't exist
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS tasks (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			title TEXT NOT NULL,
-			tags TEXT,
-			status INTEGER DEFAULT 0,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			completed_at DATETIME
-		);
-	`)
-	if err != nil {
-		fmt.Printf("Error creating table: %v\n", err)
-		os.Exit(1)
+	fmt.Fprintln(os.Stderr, "Database opened successfully.")
+
+	tm := newTasksModel()
+	tm.hideDone = store.Setting("hide_done", strconv.FormatBool(cfg.hideDone)) == "true"
+	tm.sortBy = store.Setting("sort", cfg.sortBy)
+	tm.byContext = store.Setting("group_by_context", "false") == "true"
+	tm.showPane = store.Setting("show_pane", "false") == "true"
+	tm.density = store.Setting("density", cfg.density)
+	tm.paneShare = loadPaneShare(store.Setting("pane_share", strconv.Itoa(defaultPaneShare)))
+	if history := store.Setting("command_history", ""); history != "" {
+		tm.history = strings.Split(history, "\n")
 	}
-	fmt.Println("Table 'tasks' created or already exists.")
 
-	return model{
+	m := model{
 		currentView: LoadingScreen,
-		tasksModel:  newTasksModel(),
-		undoStack:   []item{},
-		db:          db,
+		tasksModel:  tm,
+		calendar:    newCalendarModel(),
+		goals:       newGoalsModel(),
+		store:       store,
+		vault:       vault,
+		config:      cfg,
+
+		notifiedUntil: time.Now(),
+	}
+
+	// Empty trash that is past its retention period
+	if cfg.trashDays > 0 {
+		err = store.PurgeTrash(time.Now().AddDate(0, 0, -cfg.trashDays))
+		if err != nil {
+			m.showError("purge trash", err)
+		}
+	}
+
+	// Restore the previous session's undo stack
+	m.undoStack, m.redoStack, err = store.LoadHistory()
+	if err != nil {
+		m.showError("load history", err)
 	}
+
+	m.refreshReminders()
+	m.refreshDependencies()
+	m.refreshGoals()
+	m.refreshPomodoros()
+	m.refreshTimeEntries()
+	m.refreshChecklists()
+	m.loadSignIn()
+	m.cloud.lastSync, _ = time.Parse(time.RFC3339, store.Setting("cloud_last_sync", ""))
+	m.caldav.lastSync, _ = time.Parse(time.RFC3339, store.Setting("caldav_last_sync", ""))
+	m.git.lastSync, _ = time.Parse(time.RFC3339, store.Setting("git_last_sync", ""))
+	m.lastBackup, _ = time.Parse(time.RFC3339, store.Setting("last_backup", ""))
+	m.lastDigest = store.Setting("last_digest", "")
+	m.lastSummary = store.Setting("last_discord_summary", "")
+	detectBackground(cfg.background)
+	setAccessible(cfg)
+	setIcons(cfg)
+	setTimeFormat(cfg)
+	m.setTheme(store.Setting("theme", cfg.theme))
+	return m
 }
 
 func newTasksModel() tasksModel {
 	ti := textinput.New()
-	ti.Placeholder = "Press enter to add a new todo..."
+	ti.Placeholder = inputPlaceholder
+
+	si := textinput.New()
+	si.Prompt = "/"
+
+	ci := textinput.New()
+	ci.Prompt = ":"
+
+	ta := textarea.New()
+	ta.Placeholder = "Notes..."
+	ta.ShowLineNumbers = false
+	ta.SetWidth(60)
+	ta.SetHeight(8)
+
 	return tasksModel{
-		items: []item{},
-		input: ti,
-		mode:  normalMode,
+		items:     []item{},
+		input:     ti,
+		notes:     ta,
+		search:    si,
+		command:   ci,
+		mode:      normalMode,
+		collapsed: make(map[int]bool),
+		expanded:  make(map[int]bool),
+		paneShare: defaultPaneShare,
 	}
 }
 
@@ -177,186 +429,516 @@ func (m model) Init() tea.Cmd {
 			}
 			return nil
 		},
-		tick(), // Start the ticker
+		tick(),        // Start the ticker
 		m.loadTasks(), // Load tasks from the database
+		autoSync(m.config.syncInterval),
+		scheduleBackup(m.lastBackup, m.config.backupInterval),
+		watchDue(),
+		m.tickTracking(trackTickMsg(m.trackSeq)), // Timer still running from the last session
 	)
 }
 
 func (m model) loadTasks() tea.Cmd {
 	return func() tea.Msg {
-		rows, err := m.db.Query("SELECT id, title, tags, status, created_at, completed_at FROM tasks")
+		tasks, next, err := m.store.LoadPage(historyCursor{}, historyPage)
 		if err != nil {
-			fmt.Printf("Error loading tasks: %v\n", err)
-			return nil
+			return errorMsg{"load tasks", err}
 		}
-		defer rows.Close()
-
-		var tasks []item
-		for rows.Next() {
-			var task item
-			var tags string
-			var completedAt sql.NullTime
-			err := rows.Scan(&task.id, &task.title, &tags, &task.status, &task.createdAt, &completedAt)
-			if err != nil {
-				fmt.Printf("Error scanning task: %v\n", err)
-				continue
-			}
-			if completedAt.Valid {
-				task.completedAt = completedAt.Time
-			}
-			if tags != "" {
-				task.tags = strings.Split(tags, ",")
-			} else {
-				task.tags = []string{}
-			}
-			tasks = append(tasks, task)
-		}
-		return tasks
+		return taskPageMsg{tasks: tasks, first: true, next: next}
 	}
 }
 
-func (m model) saveTask(task item) error {
-	tags := strings.Join(task.tags, ",")
-	var completed interface{}
-	if task.status == done {
-		completed = task.completedAt
-	} else {
-		completed = nil
+// close closes the database, sealing it again if it is encrypted.
+func (m model) close() error {
+	m.stopPlugins()
+	if err := m.store.Close(); err != nil {
+		return err
 	}
-	_, err := m.db.Exec(`
-		INSERT INTO tasks (title, tags, status, created_at, completed_at)
-		VALUES (?, ?, ?, ?, ?)
-	`, task.title, tags, task.status, task.createdAt, completed)
-	return err
-}
-
-func (m model) updateTask(task item) error {
-	tags := strings.Join(task.tags, ",")
-	var completed interface{}
-	if task.status == done {
-		completed = task.completedAt
-	} else {
-		completed = nil
+	if m.vault != nil {
+		return m.vault.close()
 	}
-	_, err := m.db.Exec(`
-		UPDATE tasks
-		SET title = ?, tags = ?, status = ?, completed_at = ?
-		WHERE id = ?
-	`, task.title, tags, task.status, completed, task.id)
-	return err
+	return nil
 }
 
-func (m model) deleteTask(id int) error {
-	_, err := m.db.Exec("DELETE FROM tasks WHERE id = ?", id)
-	return err
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	seq := m.message.seq
+	next, cmd := m.update(msg)
+	n, ok := next.(model)
+	if !ok {
+		return next, cmd
+	}
+	// Start the timer for a message shown while handling msg. Messages shown
+	// before the loading screen is done wait for it to go away.
+	if n.message.seq != seq && n.loadingDone {
+		cmd = tea.Batch(cmd, dismissMessage(n.message.seq))
+	}
+	if len(n.pendingHooks) > 0 {
+		cmd = tea.Batch(cmd, runHooks(hooksDir(), n.pendingHooks), n.pluginEvents(n.pendingHooks), n.discordEvents(n.pendingHooks), n.slackEvents(n.pendingHooks))
+		n.pendingHooks = nil
+	}
+	return n, cmd
 }
 
-func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if m.tasksModel.mode == normalMode {
-			switch msg.String() {
+		// Keys typed into inputs are taken literally, navigation keys go
+		// through the [keys] bindings from the config
+		key := msg.String()
+		if m.tasksModel.mode == normalMode || m.tasksModel.mode == visualMode {
+			key = m.config.resolveKey(key)
+		}
+
+		if m.tasksModel.mode == normalMode && m.tasksModel.pendingKey == "" {
+			switch key {
 			case "ctrl+c", "q":
 				clearScreen()
 				return m, tea.Quit
 			case "l", "right": // Move to the next tab
+				if key == "l" && (m.currentView == Calendar || m.currentView == Board || m.currentView == Week) {
+					break // h and l move within the Calendar, Board and Week tabs
+				}
 				if m.currentView < About {
 					m.currentView++
 				}
+				m.enterView()
+				return m, nil
 			case "h", "left": // Move to the previous tab
+				if key == "h" && (m.currentView == Calendar || m.currentView == Board || m.currentView == Week) {
+					break
+				}
 				if m.currentView > Tasks {
 					m.currentView--
 				}
-			case "d":
-				if len(m.tasksModel.items) > 0 {
-					// Delete the selected task and push it to the undo stack
-					deletedTask := m.tasksModel.items[m.tasksModel.selected]
-					if len(m.undoStack) >= undoLimit {
-						// Remove the oldest item if the stack exceeds the limit
-						m.undoStack = m.undoStack[1:]
+				m.enterView()
+				return m, nil
+			case "u": // Undo the last change
+				m.undo()
+			case "ctrl+r": // Redo the last undone change
+				m.redo()
+			case "ctrl+t": // Switch to the next theme
+				m.setTheme(nextTheme(m.theme.name))
+				err := m.store.SaveSetting("theme", m.theme.name)
+				if err != nil {
+					m.showError("save theme", err)
+				}
+			}
+		}
+
+		if m.currentView == Trash && m.tasksModel.mode == normalMode {
+			m.updateTrash(key)
+		}
+		if m.currentView == Calendar && (m.tasksModel.mode == normalMode || m.tasksModel.mode == insertMode) {
+			return m, m.updateCalendar(msg)
+		}
+		if m.currentView == Agenda && m.tasksModel.mode == normalMode {
+			m.updateAgenda(key)
+		}
+		if m.currentView == Board && m.tasksModel.mode == normalMode {
+			m.updateBoard(key)
+		}
+		if m.currentView == Week && m.tasksModel.mode == normalMode {
+			m.updateWeek(key)
+		}
+		if m.currentView == Goals && (m.tasksModel.mode == normalMode || m.tasksModel.mode == insertMode) {
+			return m, m.updateGoals(msg)
+		}
+		if m.currentView == Activity && m.tasksModel.mode == normalMode {
+			m.updateActivity(key)
+		}
+		if m.currentView == Pomodoro && m.tasksModel.mode == normalMode {
+			return m, m.updatePomodoro(key)
+		}
+		if m.currentView == User && m.tasksModel.mode == normalMode {
+			return m, m.updateUser(key)
+		}
+		if m.currentView == User && m.tasksModel.mode == signInMode {
+			return m, m.updateSignIn(msg)
+		}
+
+		if m.currentView == Tasks {
+			switch m.tasksModel.mode {
+			case normalMode:
+				if m.tasksModel.pendingKey == "" && m.tasksModel.addCount(key) {
+					return m, nil
+				}
+				sequence := m.tasksModel.pendingKey + key
+				if alias, ok := actionAliases[sequence]; ok {
+					sequence = alias
+				}
+				if _, ok := normalActions[sequence]; ok {
+					m.tasksModel.pendingKey = ""
+					return m, m.runAction(sequence)
+				}
+
+				if m.tasksModel.pendingKey != "" {
+					// Second key of a multi-key command
+					m.tasksModel.pendingKey = ""
+					if sequence[0] == '"' && isRegister(key) { // Pick the register for the next command
+						m.tasksModel.register = key
+						return m, nil
 					}
-					m.undoStack = append(m.undoStack, deletedTask)
-					err := m.deleteTask(deletedTask.id)
-					if err != nil {
-						fmt.Printf("Error deleting task: %v\n", err)
+					m.tasksModel.count = 0
+					if sequence[0] == '\\' { // A key added by a plugin
+						return m, m.pluginKey(key)
 					}
-					m.tasksModel.items = append(m.tasksModel.items[:m.tasksModel.selected], m.tasksModel.items[m.tasksModel.selected+1:]...)
-					if len(m.tasksModel.items) == 0 {
-						m.tasksModel.selected = 0 // Reset selected index if no tasks are left
-					} else if m.tasksModel.selected >= len(m.tasksModel.items) {
-						m.tasksModel.selected = len(m.tasksModel.items) - 1
+					switch sequence {
+					case "za": // Expand or collapse the selected task's subtasks
+						if index := m.tasksModel.selectedIndex(); index >= 0 {
+							id := m.tasksModel.items[index].id
+							m.tasksModel.collapsed[id] = !m.tasksModel.collapsed[id]
+						}
+					case "bc": // Complete every task matching the filter
+						m.setStatus(m.tasksModel.filteredIndices(), done)
+					case "bd": // Delete every task matching the filter as one undo step
+						m.removeTasks(m.tasksModel.filteredIndices())
+					case "bt": // Retag every task matching the filter
+						if indices := m.tasksModel.filteredIndices(); len(indices) > 0 {
+							return m, m.startRetag(indices)
+						}
 					}
+					return m, nil
 				}
-			case "u":
-				if len(m.undoStack) > 0 {
-					// Undo the last deletion by restoring the task from the undo stack
-					restoredTask := m.undoStack[len(m.undoStack)-1]
-					err := m.saveTask(restoredTask)
-					if err != nil {
-						fmt.Printf("Error restoring task: %v\n", err)
-					}
-					m.tasksModel.items = append(m.tasksModel.items, restoredTask)
-					m.undoStack = m.undoStack[:len(m.undoStack)-1]
-					m.tasksModel.selected = len(m.tasksModel.items) - 1 // Select the restored task
+
+				if msg.Paste { // Text pasted into the terminal
+					m.pasteTasks(string(msg.Runes))
+					return m, nil
 				}
-			}
-		}
 
-		if m.currentView == Tasks {
-			if m.tasksModel.mode == normalMode {
-				switch msg.String() {
+				if prefixKeys[key] {
+					m.tasksModel.pendingKey = key
+					return m, nil
+				}
+				if key != "." {
+					m.tasksModel.count = 0 // Only the commands in normalActions take a count
+				}
+				switch key {
+				case ".": // Repeat the last change
+					return m, m.repeatLast()
 				case "enter":
 					m.tasksModel.mode = insertMode
 					m.tasksModel.input.Focus()
 					return m, textinput.Blink
+				case "a": // Add a subtask below the selected task
+					if index := m.tasksModel.selectedIndex(); index >= 0 {
+						m.tasksModel.parentID = m.tasksModel.items[index].id
+						m.tasksModel.collapsed[m.tasksModel.parentID] = false
+						m.tasksModel.mode = insertMode
+						m.tasksModel.input.Focus()
+						return m, textinput.Blink
+					}
+				case "E": // Edit the selected task and its notes in $EDITOR
+					return m, m.editInEditor()
+				case "S": // Snooze the selected task, or wake it
+					return m, m.startSnooze()
+				case "T": // Start or stop tracking time on the selected task
+					return m, m.toggleTracking()
+				case "e": // Edit the selected task's title, tags, due date and recurrence
+					if index := m.tasksModel.selectedIndex(); index >= 0 {
+						m.tasksModel.editID = m.tasksModel.items[index].id
+						m.tasksModel.input.SetValue(m.editInput(m.tasksModel.items[index]))
+						m.tasksModel.input.CursorEnd()
+						m.tasksModel.mode = insertMode
+						m.tasksModel.input.Focus()
+						return m, textinput.Blink
+					}
+				case "o": // Open the detail view to edit notes
+					if index := m.tasksModel.selectedIndex(); index >= 0 {
+						m.tasksModel.notes.SetValue(m.tasksModel.items[index].notes)
+						m.loadAttachments(m.tasksModel.items[index].id)
+						m.resetChecklist()
+						m.loadEvents(m.tasksModel.items[index].id)
+						m.tasksModel.mode = detailMode
+						return m, m.tasksModel.notes.Focus()
+					}
+				case "tab": // Show or hide the selected task's notes
+					if index := m.tasksModel.selectedIndex(); index >= 0 {
+						id := m.tasksModel.items[index].id
+						m.tasksModel.expanded[id] = !m.tasksModel.expanded[id]
+					}
+				case ":": // Open the command prompt
+					return m, m.startCommand()
+				case "/": // Search the list as you type
+					m.tasksModel.search.SetValue(m.tasksModel.query)
+					m.tasksModel.search.CursorEnd()
+					m.tasksModel.mode = searchMode
+					return m, m.tasksModel.search.Focus()
+				case "n":
+					m.tasksModel.jumpToMatch(true)
+				case "N":
+					m.tasksModel.jumpToMatch(false)
+				case "t": // Pick a tag to filter by
+					tags, err := m.store.Tags()
+					if err != nil {
+						m.showError("load tags", err)
+					}
+					m.tasksModel.tagOptions = append([]string{""}, tags...) // "" clears the filter
+					m.tasksModel.tagCursor = 0
+					for i, tag := range m.tasksModel.tagOptions {
+						if tag == m.tasksModel.tagFilter {
+							m.tasksModel.tagCursor = i
+						}
+					}
+					m.tasksModel.mode = tagMode
+				case "f": // Follow the first [[id]] link of the selected task
+					m.followLink(false)
+				case "F": // Back to the first task linking to the selected one
+					m.followLink(true)
+				case "O": // Open the first URL in the selected task's title
+					if index := m.tasksModel.selectedIndex(); index >= 0 {
+						if url := firstURL(m.tasksModel.items[index].title); url != "" {
+							return m, openInDefaultApp(url)
+						}
+						m.showMessage("No URL in the title")
+					}
+				case "|": // Show the selected task's details beside the list
+					m.togglePane()
+				case "ctrl+h": // Widen the detail pane
+					m.resizePane(paneShareStep)
+				case "ctrl+l": // Narrow the detail pane, collapsing it at the end
+					m.resizePane(-paneShareStep)
+				case "ctrl+v": // Add a task for each line on the clipboard
+					return m, readClipboard
+				case "A": // List the files and links attached to the selected task
+					m.startAttachments()
+				case "B": // Pick a task the selected one waits for
+					m.startBlocking()
+				case "ctrl+p": // Find a task by fuzzy matching
+					return m, m.openFinder()
+				case "@": // Pick a context to filter by
+					m.openContextPicker()
+				case "c": // Hide or show completed tasks
+					m.tasksModel.hideDone = !m.tasksModel.hideDone
+					err := m.store.SaveSetting("hide_done", fmt.Sprint(m.tasksModel.hideDone))
+					if err != nil {
+						m.showError("save setting", err)
+					}
+					m.tasksModel.clampSelection()
+				case "s": // Cycle through sort orders
+					m.tasksModel.sortBy = nextSortOrder(m.tasksModel.sortBy)
+					err := m.store.SaveSetting("sort", m.tasksModel.sortBy)
+					if err != nil {
+						m.showError("save setting", err)
+					}
+				case "esc": // Clear the search, tag and context filters
+					m.tasksModel.query = ""
+					m.tasksModel.tagFilter = ""
+					m.tasksModel.contextFilter = ""
+					m.tasksModel.taskQuery, m.tasksModel.queryIDs = "", nil
+					m.tasksModel.clampSelection()
+				case "w": // Show all tasks or the next GTD list
+					m.tasksModel.listView = nextListView(m.tasksModel.listView)
+					m.tasksModel.selected = 0
+				case "b": // Bulk operation on the filtered tasks
+					if m.tasksModel.filterActive() {
+						m.tasksModel.pendingKey = "b"
+					}
+				case "v": // Start selecting a range of tasks
+					if len(m.tasksModel.rows()) > 0 {
+						m.tasksModel.anchor = m.tasksModel.selected
+						m.tasksModel.mode = visualMode
+					}
+				}
+			case visualMode:
+				switch key {
 				case "up", "k":
 					if m.tasksModel.selected > 0 {
 						m.tasksModel.selected--
 					}
 				case "down", "j":
-					if m.tasksModel.selected < len(m.tasksModel.items)-1 {
+					if m.tasksModel.selected < len(m.tasksModel.rows())-1 {
 						m.tasksModel.selected++
 					}
-				case " ":
-					if len(m.tasksModel.items) > 0 && m.tasksModel.selected >= 0 && m.tasksModel.selected < len(m.tasksModel.items) {
-						item := &m.tasksModel.items[m.tasksModel.selected]
-						item.status = toggleStatus(item.status)
-						if item.status == done {
-							item.completedAt = time.Now() // Record completion time
+				case "d", "x": // Delete the whole range as one undo step
+					m.deleteTasks(m.tasksModel.visualRange())
+					m.tasksModel.mode = normalMode
+				case " ": // Complete the range, or reopen it if it is all done
+					indices := m.tasksModel.visualRange()
+					newStatus := todo
+					for _, index := range indices {
+						if m.tasksModel.items[index].status != done {
+							newStatus = done
 						}
-						err := m.updateTask(*item)
+					}
+					m.setStatus(indices, newStatus)
+					m.tasksModel.mode = normalMode
+				case "#": // Retag the range
+					return m, m.startRetag(m.tasksModel.visualRange())
+				case "y": // Yank the range
+					cmd = m.yankTasks(m.tasksModel.visualRange())
+					m.tasksModel.mode = normalMode
+					return m, cmd
+				case "m": // Move the range to another project
+					m.tasksModel.mode = normalMode
+					m.openProjectPicker(m.tasksModel.visualRange())
+				case "g": // Link the range to a goal
+					m.tasksModel.mode = normalMode
+					m.openGoalPicker(m.tasksModel.visualRange())
+				case "esc", "v":
+					m.tasksModel.mode = normalMode
+				}
+			case snoozeMode:
+				return m, m.updateSnooze(msg)
+			case bulkTagMode:
+				switch msg.String() {
+				case "esc":
+					m.tasksModel.mode = normalMode
+				case "enter":
+					add, remove := parseRetag(m.tasksModel.input.Value())
+					m.retag(m.tasksModel.bulkTargets, add, remove)
+					m.tasksModel.bulkTargets = nil
+					m.tasksModel.mode = normalMode
+				default:
+					m.tasksModel.input, cmd = m.tasksModel.input.Update(msg)
+					return m, cmd
+				}
+				m.tasksModel.input.Reset()
+				m.tasksModel.input.Blur()
+				m.tasksModel.input.Placeholder = inputPlaceholder
+			case tagMode:
+				switch msg.String() {
+				case "up", "k":
+					if m.tasksModel.tagCursor > 0 {
+						m.tasksModel.tagCursor--
+					}
+				case "down", "j":
+					if m.tasksModel.tagCursor < len(m.tasksModel.tagOptions)-1 {
+						m.tasksModel.tagCursor++
+					}
+				case "enter":
+					m.tasksModel.tagFilter = m.tasksModel.tagOptions[m.tasksModel.tagCursor]
+					m.tasksModel.selected = 0
+					m.tasksModel.mode = normalMode
+				case "esc":
+					m.tasksModel.mode = normalMode
+				}
+			case contextMode:
+				m.updateContextPicker(msg.String())
+			case projectMode:
+				m.updateProjectPicker(msg.String())
+			case goalMode:
+				m.updateGoalPicker(msg.String())
+			case blockMode:
+				m.updateBlocking(msg.String())
+			case attachMode:
+				return m, m.updateAttachments(msg)
+			case finderMode:
+				return m, m.updateFinder(msg)
+			case reviewMode:
+				return m, m.updateReview(msg)
+			case restoreMode:
+				return m, m.updateRestore(msg.String())
+			case conflictsMode:
+				return m, m.updateConflicts(msg.String())
+			case pluginMode:
+				return m, m.updatePluginView(msg.String())
+			case remindersMode, reportMode:
+				switch msg.String() {
+				case "esc", "enter", "q":
+					m.tasksModel.mode = normalMode
+				}
+			case commandMode:
+				return m, m.updateCommand(msg)
+			case searchMode:
+				switch msg.String() {
+				case "esc": // Drop the search and show every task again
+					m.tasksModel.query = ""
+					m.tasksModel.search.Reset()
+					m.tasksModel.search.Blur()
+					m.tasksModel.mode = normalMode
+					m.tasksModel.clampSelection()
+					return m, nil
+				case "enter": // Keep the filter and go back to navigating
+					m.tasksModel.search.Blur()
+					m.tasksModel.mode = normalMode
+					return m, nil
+				default:
+					m.tasksModel.search, cmd = m.tasksModel.search.Update(msg)
+					m.tasksModel.query = m.tasksModel.search.Value()
+					m.tasksModel.selected = 0
+					if len(m.tasksModel.rows()) > 0 && !matchesQuery(m.tasksModel.items[m.tasksModel.selectedIndex()], m.tasksModel.query) {
+						m.tasksModel.jumpToMatch(true)
+					}
+				}
+			case detailMode:
+				if index := m.tasksModel.selectedIndex(); index >= 0 && m.checklist.focused {
+					return m, m.updateChecklist(msg, m.tasksModel.items[index].id)
+				}
+				switch msg.String() {
+				case "tab": // Move to the checklist
+					m.checklist.focused = true
+					m.tasksModel.notes.Blur()
+					return m, nil
+				case "esc": // Save the notes and return to the list
+					if index := m.tasksModel.selectedIndex(); index >= 0 {
+						before := m.snapshot()
+						item := &m.tasksModel.items[index]
+						item.notes = strings.TrimRight(m.tasksModel.notes.Value(), "\n")
+						err := m.store.Update(*item)
 						if err != nil {
-							fmt.Printf("Error updating task: %v\n", err)
+							m.showError("update task", err)
 						}
+						m.record("notes", before)
 					}
+					m.tasksModel.notes.Blur()
+					m.tasksModel.mode = normalMode
+					return m, nil
+				default:
+					m.tasksModel.notes, cmd = m.tasksModel.notes.Update(msg)
+				}
+			case insertMode:
+				if m.updateTagSuggestions(msg.String()) {
+					return m, nil
 				}
-			} else {
 				switch msg.String() {
 				case "esc":
 					m.tasksModel.mode = normalMode
+					m.tasksModel.parentID = 0
+					if m.tasksModel.editID != 0 {
+						// Discard the edit instead of keeping it as a draft
+						m.tasksModel.editID = 0
+						m.tasksModel.input.Reset()
+					}
 					m.tasksModel.input.Blur()
 					return m, nil
 				case "enter":
-					if m.tasksModel.input.Value() != "" {
-						newItem := item{
-							title:     removeTags(m.tasksModel.input.Value()),
-							status:    todo,
-							tags:      parseTags(m.tasksModel.input.Value()),
-							createdAt: time.Now(), // Record creation time
-						}
-						err := m.saveTask(newItem)
-						if err != nil {
-							fmt.Printf("Error saving task: %v\n", err)
+					if m.tasksModel.editID != 0 && m.tasksModel.input.Value() != "" {
+						if index := m.tasksModel.indexOf(m.tasksModel.editID); index >= 0 {
+							before := m.snapshot()
+							item := &m.tasksModel.items[index]
+							var input string
+							item.title, item.dueAt, input = parseEditedTitle(m.tasksModel.input.Value(), item.title)
+							item.tags = parseTags(input)
+							item.startAt = parseStart(input)
+							item.estimate = parseEstimate(input)
+							item.recurrence = editedRecurrence(input, item.recurrence)
+							item.priority = parsePriority(input)
+							item.context = parseContext(input)
+							item.project = parseProject(input)
+							err := m.store.Update(*item)
+							if err != nil {
+								m.showError("update task", err)
+							}
+							m.setReminders(item.id, input)
+							m.record("edit", before)
 						}
-						m.tasksModel.items = append(m.tasksModel.items, newItem)
+						m.tasksModel.editID = 0
+						m.tasksModel.input.Reset()
+						m.tasksModel.mode = normalMode
+						m.tasksModel.input.Blur()
+					} else if m.tasksModel.input.Value() != "" {
+						m.addTask(m.tasksModel.input.Value(), m.tasksModel.parentID)
+						m.tasksModel.parentID = 0
 						m.tasksModel.input.Reset()
 						m.tasksModel.mode = normalMode
 						m.tasksModel.input.Blur()
 					}
+					m.tasksModel.tagSuggestions = nil
 				default:
 					m.tasksModel.input, cmd = m.tasksModel.input.Update(msg)
+					m.suggestTags()
 				}
 			}
 		}
@@ -369,30 +951,109 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg == "loading-done" {
 			m.loadingDone = true
 			m.currentView = Tasks
+			if m.message.text != "" {
+				cmd = dismissMessage(m.message.seq)
+			}
 		}
 
-	case []item:
-		m.tasksModel.items = msg
+	case errorMsg:
+		m.showError(msg.action, msg.err)
+
+	case bellMsg:
+		m.ringing = true
+		return m, tea.Tick(bellFrame, func(time.Time) tea.Msg { return bellDoneMsg{} })
+
+	case bellDoneMsg:
+		m.ringing = false
+
+	case pastedMsg:
+		m.pasteTasks(string(msg))
+
+	case dismissMsg:
+		if int(msg) == m.message.seq {
+			m.message = message{seq: m.message.seq}
+		}
+
+	case taskPageMsg:
+		m.tasksModel.addPage(msg)
 
 	case time.Time:
 		// Triggered by the ticker, refresh the UI
 		return m, tick()
+
+	case cloudSyncMsg:
+		return m, m.finishSync(&m.cloud, msg.result, msg.err)
+
+	case deviceCodeMsg:
+		return m, m.showDeviceCode(msg)
+
+	case signInMsg:
+		return m, m.finishSignIn(msg)
+
+	case caldavSyncMsg:
+		return m, m.finishSync(&m.caldav, msg.result, msg.err)
+
+	case gitSyncMsg:
+		return m, m.finishSync(&m.git, msg.result, msg.err)
+
+	case trackTickMsg:
+		return m, m.tickTracking(msg)
+
+	case pomodoroTickMsg:
+		return m, m.tickPomodoro(msg)
+
+	case notifyMsg:
+		now := time.Time(msg)
+		return m, tea.Batch(m.checkDue(now), m.checkDigest(now), m.checkDiscordSummary(now), watchDue())
+
+	case dailySentMsg:
+		m.finishDaily(msg)
+		return m, nil
+
+	case autoSyncMsg:
+		return m, tea.Batch(m.startSync(), autoSync(m.config.syncInterval))
+
+	case backupMsg:
+		return m, m.startBackup(true)
+
+	case backupDoneMsg:
+		return m, m.finishBackup(msg)
+
+	case pluginReplyMsg:
+		m.applyPluginReply(msg)
+		return m, nil
+
+	case editorMsg:
+		m.finishEditor(msg)
+		return m, nil
+
+	case todoistMsg:
+		if msg.err != nil {
+			m.showError("import from Todoist", msg.err)
+			return m, nil
+		}
+		n, err := m.addTasks(msg.tasks)
+		if err != nil {
+			m.showError("import from Todoist", err)
+		} else {
+			m.showMessage(fmt.Sprintf("Imported %d tasks from Todoist", n))
+		}
 	}
 
 	return m, cmd
 }
 
 func (m model) View() string {
+	if m.ringing {
+		return m.view() + "\a"
+	}
+	return m.view()
+}
+
+func (m model) view() string {
 	if m.currentView == LoadingScreen && !m.loadingDone {
 		// Define the loading text with "||" in orange and bold
-		loadingText := lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Render("XTUI") +
-			lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#FFA500")). // Orange color for "||"
-			Render("||")
+		loadingText := titleStyle.Render("XTUI") + loadingMarkStyle.Render("||")
 
 		// Center the loading text
 		centeredLoadingText := lipgloss.Place(
@@ -403,35 +1064,166 @@ func (m model) View() string {
 			loadingText,
 		)
 
-		return centeredLoadingText
+		return plainMarkers(centeredLoadingText)
 	}
 
 	// Define tabs with larger appearance using padding
 	tabs := lipgloss.JoinHorizontal(
 		lipgloss.Top,
 		m.tab("Tasks", Tasks),
+		m.tab("Agenda", Agenda),
+		m.tab("Calendar", Calendar),
+		m.tab("Board", Board),
+		m.tab("Week", Week),
+		m.tab("Goals", Goals),
+		m.tab("Trash", Trash),
+		m.tab("Pomodoro", Pomodoro),
+		m.tab("Stats", Stats),
+		m.tab("Activity", Activity),
 		m.tab("User", User),
 		m.tab("About", About),
+		m.profileLabel(),
 	)
 
 	var content string
 	switch m.currentView {
 	case Tasks:
-		content = m.renderTasks()
+		content = m.withPane(m.renderTasks())
+	case Agenda:
+		content = m.renderAgenda()
+	case Calendar:
+		content = m.renderCalendar()
+	case Board:
+		content = m.renderBoard()
+	case Week:
+		content = m.renderWeek()
+	case Goals:
+		content = m.renderGoals()
+	case Trash:
+		content = m.renderTrash()
+	case Pomodoro:
+		content = m.renderPomodoro()
+	case Stats:
+		content = m.renderStats()
+	case Activity:
+		content = m.renderActivity()
 	case User:
-		content = "User info and account sign-in/creation status display for cloud sync\n(W.I.P)"
+		content = m.renderUser()
 	case About:
 		content = m.renderAbout()
 	}
 
-	footer := "\nPress 'h' and 'l' to switch tabs | space: toggle | enter: new task | d: delete | u: undo | q: quit"
-	if m.tasksModel.mode == insertMode {
-		footer = "\nesc: normal mode | enter: save task | #tag: add tag"
+	listView := "show all"
+	if next := nextListView(m.tasksModel.listView); next != "" {
+		listView = "show " + strings.ToLower(next.title())
+	}
+	doneToggle := "c: hide done"
+	if m.tasksModel.hideDone {
+		doneToggle = fmt.Sprintf("c: show done (%d hidden)", m.tasksModel.countDone())
+	}
+	footer := "\nPress 'h' and 'l' to switch tabs | space: toggle | enter: new task | e: edit | E: edit in $EDITOR | S: snooze | a: add subtask | za: fold | o: notes and checklist | tab: show notes | /: search | ctrl+p: find | n/N: next/prev match | t: filter by tag | @: filter by context | gc: group by context | gd: density (" + m.tasksModel.density + ") | gg/G/5G: first/last/fifth task | ctrl+d/u: half page | w: " + listView + " | mi/mn/mw/ms: move to inbox/next/waiting/someday | mp: move to project | mg: link to goal | B: waits for | f/F: follow link/backlink | A: attachments | O: open URL | D: duplicate | *: star | +: plan for today | gf: focus | yy/p/P: yank/put (\"a: use register a) | ctrl+v: paste tasks | |: detail pane | ctrl+h/l: resize pane | " + doneToggle + " | s: sort (" + m.tasksModel.sortBy + ") | J/K: move task | v: visual select | bc/bd/bt: complete/delete/retag filtered | dd: delete | 3j, 5dd: count | .: repeat | T: track time | u: undo | ctrl+r: redo | ctrl+t: theme (" + m.theme.name + ") | gp: next profile | :: command | q: quit"
+	switch m.tasksModel.mode {
+	case insertMode:
+		footer = "\nesc: normal mode | enter: save task | #tag: add tag | due:YYYY-MM-DD or tomorrow 5pm: set due date | every:week: repeat | start:YYYY-MM-DD: start date | ~30m: estimate | !p1: priority | @home: context | +project: project | remind:30m: reminder"
+		if m.tasksModel.editID != 0 {
+			footer = "\nesc: cancel edit | enter: save changes | #tag: add tag | due:YYYY-MM-DD or tomorrow 5pm: set due date | every:week: repeat | !p1: priority | @home: context | +project: project | remind:30m: reminder"
+		}
+		if len(m.tasksModel.tagSuggestions) > 0 {
+			footer = "\ntab: complete tag | up/down: pick a tag | esc: hide tags | enter: save"
+		}
+	case detailMode:
+		footer = "\ntab: checklist | esc: save notes and return to the list"
+		if m.checklist.focused {
+			footer = "\nj/k: move | space: check | a: add | e: rename | d: remove | tab: notes"
+		}
+		if m.checklist.typing {
+			footer = "\nenter: save item | esc: cancel"
+		}
+	case searchMode:
+		footer = "\nenter: keep filter | esc: clear search"
+	case commandMode:
+		footer = "\nenter: run | tab: complete | up/down: history | esc: cancel"
+		if len(m.tasksModel.completions) > 0 {
+			footer = "\n" + strings.Join(m.tasksModel.completions, "  ")
+		}
+		if m.tasksModel.commandErr != "" {
+			footer = "\n" + overdueStyle.Render(m.tasksModel.commandErr)
+		}
+	case tagMode, contextMode:
+		footer = "\nj/k: move | enter: apply filter | esc: cancel"
+	case projectMode:
+		footer = "\nj/k: move | enter: move the tasks there | esc: cancel"
+	case goalMode:
+		footer = "\nj/k: move | enter: link the tasks to it | esc: cancel"
+	case attachMode:
+		footer = "\nj/k: move | enter: open | a: attach a file or URL | d: remove | esc: back to the list"
+		if m.attach.adding {
+			footer = "\nenter: attach | esc: cancel"
+		}
+	case finderMode:
+		footer = "\ntype to narrow | up/down: move | enter: go to task | ctrl+x: complete or reopen | esc: close"
+	case blockMode:
+		footer = "\nj/k: move to the task it waits for | enter: wait for it (again to stop) | esc: cancel"
+	case restoreMode:
+		footer = "\nj/k: move | enter: restore | esc: back to the list"
+	case conflictsMode:
+		footer = "\nj/k: move | enter: use the other value | d: keep this one | esc: back to the list"
+	case pluginMode:
+		footer = "\nkeys go to the plugin | esc: back to the list"
+	case remindersMode, reportMode:
+		footer = "\nesc: back to the list"
+	case reviewMode:
+		footer = "\nk: keep | r: reschedule | d: delete | a: archive | s: skip | esc: stop reviewing"
+		if index := m.reviewIndex(); index >= 0 && m.tasksModel.items[index].list == someday {
+			footer = "\nk: keep | n: move to next | r: reschedule | d: delete | a: archive | s: skip | esc: stop reviewing"
+		}
+		if m.review.scheduling {
+			footer = "\nenter: set due date | esc: back"
+		}
+	case visualMode:
+		footer = "\nj/k: extend selection | space: complete | d: delete | #: add tags | y: yank | m: move to project | g: link to goal | esc: cancel"
+	case snoozeMode:
+		footer = "\nenter: snooze until then | esc: cancel"
+	case bulkTagMode:
+		footer = fmt.Sprintf("\nenter: retag %d tasks (#tag or +tag adds, -tag removes) | esc: cancel", len(m.tasksModel.bulkTargets))
+	}
+	if m.currentView == User {
+		footer = "\nPress 'h' and 'l' to switch tabs | " + m.userKeys() + " | q: quit"
+		if m.tasksModel.mode == signInMode {
+			footer = "\nenter: sign in | esc: cancel"
+		}
+	}
+	if m.currentView == Agenda {
+		footer = "\nPress 'h' and 'l' to switch tabs | j/k: move | space: complete | enter: show in list | u: undo | q: quit"
+	}
+	if m.currentView == Calendar {
+		footer = "\n" + m.calendarKeys()
+	}
+	if m.currentView == Board {
+		footer = "\n" + m.boardKeys()
+	}
+	if m.currentView == Week {
+		footer = "\n" + m.weekKeys()
+	}
+	if m.currentView == Goals {
+		footer = "\n" + m.goalsKeys()
+	}
+	if m.currentView == Stats {
+		footer = "\nPress 'h' and 'l' to switch tabs | q: quit"
+	}
+	if m.currentView == Activity {
+		footer = "\nPress 'h' and 'l' to switch tabs | j/k: move | enter: show in list | q: quit"
+	}
+	if m.currentView == Pomodoro {
+		footer = "\nPress 'h' and 'l' to switch tabs | " + m.pomodoroKeys() + " | q: quit"
+	}
+	if m.currentView == Trash {
+		footer = "\nPress 'h' and 'l' to switch tabs | j/k: move | r: restore | x: delete forever | X: empty trash | u: undo | q: quit"
 	}
 
 	// Fixed height for tabs and centered content
-	tabsHeight := 3 // Fixed height for tabs
-	contentHeight := m.height - tabsHeight - 3 // Remaining height for content and footer
+	tabsHeight := 3                            // Fixed height for tabs
+	contentHeight := m.height - tabsHeight - 4 // Remaining height for content and footer
 
 	// Center the content within the available space
 	centeredContent := lipgloss.Place(
@@ -451,12 +1243,18 @@ func (m model) View() string {
 		lipgloss.NewStyle().PaddingTop(2).Render(tabs), // Add padding above tabs
 	)
 
+	footerView := helpStyle.Render(footer)
+	if m.message.text != "" {
+		// The message takes the blank line above the keys
+		footerView = lipgloss.JoinVertical(lipgloss.Center, m.renderMessage(), helpStyle.Render(strings.TrimPrefix(footer, "\n")))
+	}
+	footerView = lipgloss.JoinVertical(lipgloss.Center, m.renderStatusBar(), footerView)
 	centeredFooter := lipgloss.Place(
 		m.width,
-		3, // Fixed height for footer
+		4, // Fixed height for status bar and footer
 		lipgloss.Center,
 		lipgloss.Center,
-		helpStyle.Render(footer),
+		footerView,
 	)
 
 	// Combine centered tabs, centered content, and centered footer
@@ -466,71 +1264,295 @@ func (m model) View() string {
 		centeredFooter,
 	)
 
-	return lipgloss.Place(
+	return plainMarkers(lipgloss.Place(
 		m.width, m.height,
 		lipgloss.Center, lipgloss.Center,
 		lipgloss.NewStyle().Padding(1, 2).Render(body),
-	)
+	))
 }
 
 func (m model) renderTasks() string {
+	if m.tasksModel.mode == detailMode {
+		return m.renderDetail()
+	}
+	if m.tasksModel.mode == tagMode {
+		return m.renderTagPicker()
+	}
+	if m.tasksModel.mode == contextMode {
+		return m.renderContextPicker()
+	}
+	if m.tasksModel.mode == projectMode {
+		return m.renderProjectPicker()
+	}
+	if m.tasksModel.mode == goalMode {
+		return m.renderGoalPicker()
+	}
+	if m.tasksModel.mode == attachMode {
+		return m.renderAttachments()
+	}
+	if m.tasksModel.mode == finderMode {
+		return m.renderFinder()
+	}
+	if m.tasksModel.mode == remindersMode {
+		return m.renderReminders()
+	}
+	if m.tasksModel.mode == reportMode {
+		return m.renderReport()
+	}
+	if m.tasksModel.mode == reviewMode {
+		return m.renderReview()
+	}
+	if m.tasksModel.mode == restoreMode {
+		return m.renderRestore()
+	}
+	if m.tasksModel.mode == conflictsMode {
+		return m.renderConflicts()
+	}
+	if m.tasksModel.mode == pluginMode {
+		return m.renderPluginView()
+	}
+
 	var s strings.Builder
 
-	s.WriteString(titleStyle.Render("Accelerate,Anon") + "\n\n")
+	header := titleStyle.Render("Accelerate,Anon")
+	if m.tasksModel.tagFilter != "" {
+		header += tagStyle.Render("  #" + m.tasksModel.tagFilter)
+	}
+	if m.tasksModel.contextFilter != "" {
+		header += tagStyle.Render("  @" + m.tasksModel.contextFilter)
+	}
+	if m.tasksModel.listView != "" {
+		header += helpStyle.Render("  " + m.tasksModel.listView.title())
+	}
+	if m.tasksModel.query != "" && m.tasksModel.mode != searchMode {
+		header += helpStyle.Render("  /" + m.tasksModel.query)
+	}
+	if m.tasksModel.taskQuery != "" {
+		header += helpStyle.Render("  " + m.tasksModel.taskQuery)
+	}
+	if m.tasksModel.mode == blockMode {
+		header += helpStyle.Render("  what does " + m.titleOf(m.tasksModel.blockID) + " wait for?")
+	}
+	s.WriteString(header + "\n")
+	if m.tasksModel.density != compactDensity {
+		s.WriteString("\n")
+	}
 
-	for i, item := range m.tasksModel.items {
-		// Fixed-width cursor (2 characters)
-		cursor := "  " // Default to two spaces
-		if i == m.tasksModel.selected {
-			cursor = "▸ " // Right-pointing triangle followed by a space
-		}
+	group := "" // Heading of the context group being listed
+	for i, r := range m.tasksModel.rows() {
+		item := m.tasksModel.items[r.index]
 
-		// Fixed-width status marker (3 characters)
-		statusMarker := "[ ]"
-		if item.status == done {
-			statusMarker = "[✓]"
+		// A heading before the first task of each context
+		if m.tasksModel.byContext && r.depth == 0 && (i == 0 || contextHeading(item.context) != group) {
+			group = contextHeading(item.context)
+			if i > 0 {
+				s.WriteString("\n")
+			}
+			s.WriteString(titleStyle.Render(group) + "\n")
 		}
 
-		// Align the task title
-		itemText := fmt.Sprintf("%s %s %s", cursor, statusMarker, item.title)
+		// Cursor and status marker, each padded to a fixed width
+		cursor := cursorMark(i == m.tasksModel.selected)
+		statusMarker := statusMarker(item.status)
+
+		// Indent subtasks under their parent
+		indent := strings.Repeat("  ", r.depth)
+
+		style := itemStyle
 		if i == m.tasksModel.selected {
-			itemText = selectedItemStyle.Render(itemText)
-		} else {
-			itemText = itemStyle.Render(itemText)
+			style = selectedItemStyle
+		} else if m.tasksModel.inVisualRange(i) {
+			style = visualItemStyle
+		}
+		textStyle := style.UnsetPaddingLeft() // Padding only applies before the cursor
+		blocked := m.isBlocked(item.id)
+		if blocked && i != m.tasksModel.selected && !m.tasksModel.inVisualRange(i) {
+			textStyle = helpStyle // Dim tasks that can't be done yet
 		}
-		s.WriteString(itemText)
 
-		// Add tags if present
+		// Align the task title, highlighting search matches
+		suffix := ""
+		if item.priority != 0 {
+			suffix += fmt.Sprintf(" %s%d", icons.priority, item.priority) // Priority level
+		}
+		if item.recurrence != "" {
+			suffix += " ↻" // Mark recurring tasks
+		}
+		if item.status != done && item.planned(time.Now()) {
+			suffix += " ☀" // Planned for today
+		}
+		if item.estimate != 0 {
+			suffix += " ~" + formatEstimate(item.estimated())
+		}
+		if checked, total := m.checklistProgress(item.id); total > 0 {
+			suffix += fmt.Sprintf(" %d/%d", checked, total) // Checklist progress
+		}
+		if blocked {
+			suffix += " ⊘" // Waits for other tasks
+		}
+		if item.notStarted(time.Now()) {
+			suffix += " ▷ " + showStart(item.startAt) // Shown by :upcoming
+		}
+		if item.snoozed(time.Now()) {
+			suffix += " ☾ " + formatSnooze(item.snoozedUntil) // Shown by :snoozed
+		}
+		if m.tasksModel.listView == "" && item.list != inbox {
+			suffix += " · " + strings.ToLower(item.list.title()) // Triaged out of the inbox
+		}
+		if m.tasksModel.collapsed[item.id] && m.tasksModel.hasChildren(item.id) {
+			suffix += fmt.Sprintf(" (+%d)", len(m.tasksModel.descendants(item.id))) // Hidden subtasks
+		}
+		if running, ok := m.runningEntry(); ok && running.taskID == item.id {
+			suffix += " ⏱ " + formatElapsed(running.duration(time.Time{}, time.Now())) // Tracked right now
+		}
+		// Tags, context and project, then the dates
+		var labels []string
 		if len(item.tags) > 0 {
-			tags := fmt.Sprintf(" [%s]", strings.Join(item.tags, ", "))
-			s.WriteString(tagStyle.Render(tags))
+			labels = append(labels, tagStyle.Render(tagLabels(item.tags)))
 		}
-
-		// Show "Completed" for done tasks, no timestamp
-		if item.status == done {
-			s.WriteString(" - Completed")
+		if item.context != "" {
+			labels = append(labels, tagStyle.Render("@"+item.context))
+		}
+		if item.project != "" {
+			labels = append(labels, tagStyle.Render("+"+item.project))
+		}
+		dates := m.listDates(item)
+		details := "" // Follows the title on its line
+		second := ""  // Line below the title in the detailed density
+		if m.tasksModel.density == detailedDensity {
+			if len(labels) > 0 {
+				dates = append([]string{strings.Join(labels, " ")}, dates...)
+			}
+			second = strings.Join(dates, " · ")
 		} else {
-			s.WriteString(fmt.Sprintf(" - Created %s", formatRelativeTime(item.createdAt)))
+			for _, label := range labels {
+				details += " " + label
+			}
+			for _, date := range dates {
+				details += " - " + date
+			}
+		}
+		if item.notes != "" && !m.tasksModel.expanded[item.id] {
+			if second != "" {
+				second += " ✎" // Task has hidden notes
+			} else {
+				details += " ✎"
+			}
+		}
+
+		prefix := style
+		if m.tasksModel.density == compactDensity {
+			prefix = style.UnsetPaddingLeft()
+		}
+		s.WriteString(prefix.Render(fmt.Sprintf("%s %s%s ", cursor, indent, statusMarker)))
+		column := m.tasksModel.titleColumn() + len(indent)
+		star := starMark(item)
+		lines := m.fitTitle(item.title, m.listWidth()-column-lipgloss.Width(star+suffix+details))
+		for j, line := range lines {
+			if j > 0 {
+				s.WriteString(fmt.Sprintf("\n%*s", column, ""))
+			}
+			s.WriteString(renderTitle(line, m.tasksModel.query, textStyle))
+		}
+		s.WriteString(star)
+		if suffix != "" {
+			s.WriteString(textStyle.Render(suffix))
+		}
+		s.WriteString(details)
+		if second != "" {
+			s.WriteString(fmt.Sprintf("\n%*s%s", column, "", second))
 		}
 		s.WriteString("\n")
+
+		// Notes of expanded tasks go below the title
+		if item.notes != "" && m.tasksModel.expanded[item.id] {
+			for _, line := range strings.Split(item.notes, "\n") {
+				s.WriteString(notesStyle.Render(fmt.Sprintf("%*s%s%s", m.tasksModel.titleColumn(), "", indent, line)) + "\n")
+			}
+		}
 	}
 
-	if m.tasksModel.mode == insertMode {
+	if m.tasksModel.mode == insertMode || m.tasksModel.mode == bulkTagMode || m.tasksModel.mode == snoozeMode {
 		s.WriteString("\n" + m.tasksModel.input.View())
+		if m.tasksModel.mode == insertMode && len(m.tasksModel.tagSuggestions) > 0 {
+			s.WriteString("\n" + m.renderTagSuggestions())
+		}
+	}
+	if m.tasksModel.mode == searchMode {
+		s.WriteString("\n" + m.tasksModel.search.View())
+	}
+	if m.tasksModel.mode == commandMode {
+		s.WriteString("\n" + m.tasksModel.command.View())
 	}
 
 	return s.String()
 }
 
-func (m model) renderAbout() string {
-	// Get ASCII art path from .env
-	asciiArtPath := os.Getenv("ASCII_ART_PATH")
-	if asciiArtPath == "" {
-		asciiArtPath = "faqs_ascii.txt" // Default value
+func (m model) renderTagPicker() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Filter by tag") + "\n\n")
+	for i, tag := range m.tasksModel.tagOptions {
+		label := tagLabel(tag)
+		if tag == "" {
+			label = "All tasks"
+		}
+		if i == m.tasksModel.tagCursor {
+			s.WriteString(selectedItemStyle.Render(cursorMark(true)+label) + "\n")
+		} else {
+			s.WriteString(itemStyle.Render(cursorMark(false)+label) + "\n")
+		}
+	}
+	return s.String()
+}
+
+// renderDetail shows the selected task with an editor for its notes.
+func (m model) renderDetail() string {
+	index := m.tasksModel.selectedIndex()
+	if index < 0 {
+		return ""
 	}
+	item := m.tasksModel.items[index]
 
-	// Read the ASCII image from the file
-	asciiArt, err := os.ReadFile(asciiArtPath)
+	var s strings.Builder
+	s.WriteString(titleStyle.Render(item.title) + helpStyle.Render(fmt.Sprintf("  [[%d]]", item.id)) + "\n\n")
+	if len(item.tags) > 0 {
+		s.WriteString(tagStyle.Render(fmt.Sprintf("[%s]", strings.Join(item.tags, ", "))) + "\n")
+	}
+	s.WriteString(helpStyle.Render(fmt.Sprintf("Created %s", formatRelativeTime(item.createdAt))) + "\n")
+	if !item.dueAt.IsZero() {
+		s.WriteString(helpStyle.Render(formatDueTime(item.dueAt)) + "\n")
+	}
+	if tracked := m.trackedTime(item.id, time.Time{}); tracked > 0 {
+		s.WriteString(helpStyle.Render("Tracked "+formatElapsed(tracked)) + "\n")
+	}
+	for _, r := range m.remindersFor(item.id) {
+		s.WriteString(helpStyle.Render("Reminder "+describeReminder(r)) + "\n")
+	}
+	if links := m.renderLinks(item); links != "" {
+		s.WriteString("\n" + links)
+	}
+	if m.attach.taskID == item.id && len(m.attach.items) > 0 {
+		s.WriteString("\n" + helpStyle.Render("Attachments") + "\n")
+		for _, attached := range m.attach.items {
+			s.WriteString("  " + describeAttachment(attached) + "\n")
+		}
+	}
+	if checklist := m.renderChecklist(item.id); checklist != "" {
+		s.WriteString("\n" + checklist)
+	}
+	if len(m.taskEvents) > 0 {
+		s.WriteString("\n" + helpStyle.Render("History") + "\n")
+		for _, e := range m.taskEvents {
+			s.WriteString(helpStyle.Render("  "+describeEvent(e)+" "+formatRelativeTime(e.at)) + "\n")
+		}
+	}
+	s.WriteString("\n" + m.tasksModel.notes.View())
+	return s.String()
+}
+
+func (m model) renderAbout() string {
+	// Read the ASCII image from the path in the config
+	asciiArt, err := os.ReadFile(m.config.asciiArtPath)
 	if err != nil {
 		return "Error loading ASCII art."
 	}
@@ -546,20 +1568,22 @@ built by @crimxnhaze on X`
 	return fmt.Sprintf("%s\n\n%s", string(asciiArt), aboutText)
 }
 
-func formatRelativeTime(t time.Time) string {
-	duration := time.Since(t)
+// overdue reports whether an open task is past its due date.
+func (i item) overdue(now time.Time) bool {
+	return i.status != done && !i.dueAt.IsZero() && i.dueAt.Before(now)
+}
+
+func formatDuration(duration time.Duration) string {
 	switch {
-	case duration < time.Minute:
-		return "just now"
 	case duration < time.Hour:
 		minutes := int(duration.Minutes())
-		return fmt.Sprintf("%d minutes ago", minutes)
+		return fmt.Sprintf("%d minutes", minutes)
 	case duration < 24*time.Hour:
 		hours := int(duration.Hours())
-		return fmt.Sprintf("%d hours ago", hours)
+		return fmt.Sprintf("%d hours", hours)
 	default:
 		days := int(duration.Hours() / 24)
-		return fmt.Sprintf("%d days ago", days)
+		return fmt.Sprintf("%d days", days)
 	}
 }
 
@@ -569,11 +1593,26 @@ func tick() tea.Cmd {
 	})
 }
 
+// enterView refreshes what a tab shows from the database when switching to
+// it.
+func (m *model) enterView() {
+	switch m.currentView {
+	case Goals:
+		m.refreshGoals()
+	case Trash:
+		m.refreshTrash()
+	case Stats:
+		m.refreshStats()
+	case Activity:
+		m.refreshActivity()
+	}
+}
+
 func (m model) tab(name string, section int) string {
 	if m.currentView == section {
-		return activeTabStyle.Render(name)
+		return activeTabStyle.Render(tabLabel(name, section))
 	}
-	return inactiveTabStyle.Render(name)
+	return inactiveTabStyle.Render(tabLabel(name, section))
 }
 
 func clearScreen() {
@@ -599,6 +1638,85 @@ func parseTags(input string) []string {
 	return tags
 }
 
+// editInput is the input an edit of the task starts from, with its reminders.
+func (m model) editInput(task item) string {
+	input := formatTaskInput(task)
+	for _, r := range m.remindersFor(task.id) {
+		input += " " + formatReminder(r)
+	}
+	return input
+}
+
+// formatTaskInput turns a task back into the text accepted by the input, so
+// editing round-trips tags, the due date and the recurrence rule.
+func formatTaskInput(task item) string {
+	words := []string{task.title}
+	for _, tag := range task.tags {
+		words = append(words, "#"+tag)
+	}
+	if !task.dueAt.IsZero() {
+		if isEndOfDay(task.dueAt) {
+			words = append(words, "due:"+task.dueAt.Format("2006-01-02"))
+		} else {
+			words = append(words, "due:"+task.dueAt.Format("2006-01-02T15:04"))
+		}
+	}
+	if !task.startAt.IsZero() {
+		words = append(words, formatStart(task.startAt))
+	}
+	if spec := recurrenceSpec(task.recurrence); spec != "" {
+		words = append(words, "every:"+spec)
+	}
+	if task.estimate != 0 {
+		words = append(words, estimateToken(task.estimate))
+	}
+	if task.priority != 0 {
+		words = append(words, fmt.Sprintf("!p%d", task.priority))
+	}
+	if task.context != "" {
+		words = append(words, "@"+task.context)
+	}
+	if task.project != "" {
+		words = append(words, "+"+task.project)
+	}
+	return strings.Join(words, " ")
+}
+
+// parsePriority returns the level of the first !p1, !p2 or !p3 token, or 0.
+func parsePriority(input string) int {
+	for _, word := range strings.Fields(input) {
+		switch word {
+		case "!p1":
+			return 1
+		case "!p2":
+			return 2
+		case "!p3":
+			return 3
+		}
+	}
+	return 0
+}
+
+// clampPriority brings a priority from outside Xtui into 1 (highest) to 3,
+// or 0 for none.
+func clampPriority(priority int) int {
+	if priority < 0 {
+		return 0
+	}
+	return min(priority, 3)
+}
+
+func removePriority(input string) string {
+	words := strings.Fields(input)
+	var result []string
+	for _, word := range words {
+		if parsePriority(word) == 0 {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
 func removeTags(input string) string {
 	words := strings.Fields(input)
 	var result []string
@@ -610,11 +1728,150 @@ func removeTags(input string) string {
 	return strings.Join(result, " ")
 }
 
-func statusMarker(s status) string {
-	if s == done {
-		return "[✓]"
+// parseContext returns the first @context in the input, or "".
+func parseContext(input string) string {
+	for _, word := range strings.Fields(input) {
+		if isContextToken(word) {
+			return word[1:]
+		}
 	}
-	return "[ ]"
+	return ""
+}
+
+// parseProject returns the first +project in the input, or "".
+func parseProject(input string) string {
+	for _, word := range strings.Fields(input) {
+		if isProjectToken(word) {
+			return word[1:]
+		}
+	}
+	return ""
+}
+
+func isContextToken(word string) bool {
+	return len(word) > 1 && word[0] == '@'
+}
+
+// isProjectToken leaves out offsets like +3d, a project starts with a letter.
+func isProjectToken(word string) bool {
+	return len(word) > 1 && word[0] == '+' && unicode.IsLetter([]rune(word[1:])[0])
+}
+
+func removeContextAndProject(input string) string {
+	words := strings.Fields(input)
+	var result []string
+	for _, word := range words {
+		if !isContextToken(word) && !isProjectToken(word) {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// parseDue extracts the first due:YYYY-MM-DD (or due:YYYY-MM-DDTHH:MM) token.
+// Date-only values are due at the end of that day.
+func parseDue(input string) time.Time {
+	for _, word := range strings.Fields(input) {
+		if !strings.HasPrefix(word, "due:") {
+			continue
+		}
+		value := strings.TrimPrefix(word, "due:")
+		if t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local); err == nil {
+			return t
+		}
+		if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+			return endOfDay(t)
+		}
+	}
+	return time.Time{}
+}
+
+// endOfDay returns the last second of t's day, the due time of a date-only
+// due date. Built from the date rather than added to midnight, so days when
+// the clocks change end at 23:59:59 too.
+func endOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 23, 59, 59, 0, t.Location())
+}
+
+// isEndOfDay reports whether t is a date-only due time as set by parseDue.
+func isEndOfDay(t time.Time) bool {
+	return t.Hour() == 23 && t.Minute() == 59 && t.Second() == 59
+}
+
+// parseTitleAndDue takes the title and due date from the task input: a due:
+// token, or else a date written out in the title such as "tomorrow 5pm".
+func parseTitleAndDue(input string) (string, time.Time) {
+	title := removeContextAndProject(removeReminders(removePriority(removeRecurrence(removeEstimate(removeStart(removeDue(removeTags(input))))))))
+	due := parseDue(input)
+	if due.IsZero() {
+		due, title = parseNaturalDue(title, time.Now())
+	}
+	return title, due
+}
+
+// parseEditedTitle reads the input of a task edited from oldTitle like
+// parseTitleAndDue, except that the words of the old title left as they were
+// stay in the title as they are: "Review Monday notes" keeps its Monday and
+// "Fix issue #123" its #123. Only the words typed in this edit and the tokens
+// after the title are read for dates and tokens, and they are returned as
+// rest for the other parsers.
+func parseEditedTitle(input, oldTitle string) (title string, due time.Time, rest string) {
+	words, old := strings.Fields(input), strings.Fields(oldTitle)
+
+	// The old title words kept are the longest common subsequence
+	lengths := make([][]int, len(words)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(old)+1)
+	}
+	for i := len(words) - 1; i >= 0; i-- {
+		for j := len(old) - 1; j >= 0; j-- {
+			if words[i] == old[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	kept := make([]bool, len(words))
+	var typed []string
+	for i, j := 0, 0; i < len(words); {
+		switch {
+		case j < len(old) && words[i] == old[j]:
+			kept[i] = true
+			i, j = i+1, j+1
+		case j < len(old) && lengths[i][j+1] > lengths[i+1][j]:
+			j++
+		default:
+			typed = append(typed, words[i])
+			i++
+		}
+	}
+
+	rest = strings.Join(typed, " ")
+	title, due = parseTitleAndDue(rest)
+	parsed := strings.Fields(title)
+	var result []string
+	for i, word := range words {
+		if kept[i] {
+			result = append(result, word)
+		} else if len(parsed) > 0 && word == parsed[0] {
+			result = append(result, word)
+			parsed = parsed[1:]
+		}
+	}
+	return strings.Join(append(result, parsed...), " "), due, rest
+}
+
+func removeDue(input string) string {
+	words := strings.Fields(input)
+	var result []string
+	for _, word := range words {
+		if !strings.HasPrefix(word, "due:") {
+			result = append(result, word)
+		}
+	}
+	return strings.Join(result, " ")
 }
 
 func toggleStatus(s status) status {
@@ -625,9 +1882,182 @@ func toggleStatus(s status) status {
 }
 
 func main() {
-	p := tea.NewProgram(newModel())
-	if err := p.Start(); err != nil {
-		fmt.Printf("Error starting app: %v\n", err)
-		os.Exit(1)
+	exportFormat := flag.String("export", "", "write all tasks as `format` ("+strings.Join(formatNames(exporters), ", ")+") and exit")
+	output := flag.String("o", "-", "file for -export, - for stdout")
+	importFormat := flag.String("import", "", "add tasks in `format` ("+strings.Join(formatNames(importers), ", ")+") and exit")
+	input := flag.String("i", "-", "file for -import, - for stdin")
+	list := flag.String("list", "", "print the tasks matching `query`, e.g. \"status:todo tag:work\", and exit")
+	stats := flag.Bool("stats", false, "print task statistics and exit")
+	asJSON := flag.Bool("json", false, "print -list and -stats output as JSON")
+	plain := flag.Bool("plain", false, "print -list and -stats output as tab-separated lines")
+	profile := flag.String("profile", "", "use the database of profile `name` from the [profiles] config section")
+	serveAddr := flag.String("serve-ics", "", "serve due-dated tasks as iCalendar feeds on `addr`, e.g. localhost:8080")
+	slackAddr := flag.String("serve-slack", "", "serve the Slack slash command of the [slack.<workspace>] config sections on `addr`")
+	telegram := flag.Bool("telegram", false, "run the Telegram bot of the [telegram] config section until stopped")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: xtui [flags]\n       xtui [flags] add <task> | --stdin\n       xtui [flags] digest [--email]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *profile != "" {
+		os.Setenv("XTUI_PROFILE", *profile)
+	}
+
+	if flag.Arg(0) == "add" {
+		count, err := runAdd(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error adding tasks: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Added %d tasks.\n", count)
+		return
+	}
+
+	if flag.Arg(0) == "digest" {
+		sent, err := runDigest(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error sending digest: %v\n", err)
+			os.Exit(1)
+		}
+		if sent {
+			fmt.Fprintln(os.Stderr, "Sent the digest.")
+		}
+		return
+	}
+
+	if *serveAddr != "" {
+		if err := serveICS(*serveAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving calendar: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *slackAddr != "" {
+		if err := serveSlack(*slackAddr); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving Slack command: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *telegram {
+		if err := runTelegram(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running Telegram bot: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *importFormat != "" {
+		count, err := runImport(*importFormat, *input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing tasks: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d tasks.\n", count)
+		return
+	}
+
+	format, err := chooseOutput(*asJSON, *plain)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *list != "" {
+		if err := runList(*list, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing tasks: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *stats {
+		if err := runStats(format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error printing stats: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *exportFormat != "" {
+		if err := runExport(*exportFormat, *output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting tasks: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	for {
+		next, err := runInterface()
+		if err != nil {
+			fmt.Printf("Error starting app: %v\n", err)
+			os.Exit(1)
+		}
+		if next == "" {
+			return
+		}
+	}
+}
+
+// runInterface runs the interface until it quits, and returns the profile to
+// start again on when it quit to switch profiles.
+func runInterface() (string, error) {
+	m := newModel()
+	m.startPlugins()
+	p := tea.NewProgram(m)
+
+	// Quit normally when the terminal goes away or the process is stopped,
+	// so the database is closed cleanly
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-signals:
+			p.Quit()
+		case <-stopped:
+		}
+	}()
+
+	// Close the store of the final model, which is another one after a
+	// backup is restored
+	final, err := p.Run()
+	if f, ok := final.(model); ok {
+		m = f
+	}
+	if closeErr := m.close(); closeErr != nil {
+		fmt.Printf("Error closing database: %v\n", closeErr)
+	}
+	if err != nil {
+		return "", err
+	}
+	if final, ok := final.(model); ok {
+		return final.nextProfile, nil
+	}
+	return "", nil
+}
+
+// untilInterrupted returns a context that is done on Ctrl+C or SIGTERM, for
+// the commands that run until stopped, so they close the database cleanly.
+func untilInterrupted() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// serveUntilInterrupted runs the server until Ctrl+C or SIGTERM shuts it
+// down.
+func serveUntilInterrupted(server *http.Server) error {
+	ctx, stop := untilInterrupted()
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
 	}
+	return nil
 }
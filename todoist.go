@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Importers for tasks coming from Todoist: the CSV file Todoist exports for
+// a project (or as part of a backup), and the REST API using a token from
+// the [todoist] section of the config.
+
+const todoistAPI = "https://api.todoist.com/rest/v2/tasks"
+
+// readTodoistCSV reads a Todoist project CSV. Subtasks are given by the
+// INDENT column, labels are @words in CONTENT, and note rows are attached
+// to the task above them. Section rows are skipped.
+func readTodoistCSV(r io.Reader) ([]item, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("not a Todoist CSV: missing %s column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var (
+		tasks   []item
+		parents []int // Task ids by indent level
+	)
+	now := time.Now()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch strings.ToLower(field(record, "TYPE")) {
+		case "task":
+		case "note":
+			if len(tasks) > 0 && field(record, "CONTENT") != "" {
+				last := &tasks[len(tasks)-1]
+				if last.notes != "" {
+					last.notes += "\n"
+				}
+				last.notes += field(record, "CONTENT")
+			}
+			continue
+		default:
+			continue
+		}
+
+		title, tags := todoistLabels(field(record, "CONTENT"))
+		task := item{
+			id:        len(tasks) + 1, // Placeholder, remapped on import
+			title:     title,
+			tags:      tags,
+			status:    todo,
+			createdAt: now,
+			notes:     field(record, "DESCRIPTION"),
+			sortOrder: len(tasks) + 1,
+		}
+		// CSV priorities run from 1 (p1, highest) to 4 (no priority)
+		if p, err := strconv.Atoi(field(record, "PRIORITY")); err == nil && p >= 1 && p <= 3 {
+			task.priority = p
+		}
+		task.dueAt, task.recurrence = todoistDate(field(record, "DATE"))
+
+		indent, err := strconv.Atoi(field(record, "INDENT"))
+		if err != nil || indent < 1 {
+			indent = 1
+		}
+		if indent > len(parents)+1 {
+			indent = len(parents) + 1
+		}
+		parents = parents[:indent-1]
+		if indent > 1 {
+			task.parentID = parents[indent-2]
+		}
+		parents = append(parents, task.id)
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// todoistLabels splits the @labels out of a task's content.
+func todoistLabels(content string) (string, []string) {
+	tags := []string{}
+	var words []string
+	for _, word := range strings.Fields(content) {
+		if strings.HasPrefix(word, "@") && len(word) > 1 {
+			tags = append(tags, word[1:])
+		} else {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), tags
+}
+
+// todoistDate understands the plain dates of Todoist exports and the common
+// "every ..." recurrences. Other natural language dates are dropped.
+func todoistDate(value string) (time.Time, string) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return time.Time{}, ""
+	}
+	if strings.HasPrefix(value, "every ") || strings.HasPrefix(value, "every! ") {
+		return time.Time{}, todoistRecurrence(value)
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02T15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, ""
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return endOfDay(t), ""
+	}
+	return time.Time{}, ""
+}
+
+// todoistRecurrence maps "every day", "every 2 weeks", "every monday" and
+// similar to an RRULE.
+func todoistRecurrence(value string) string {
+	words := strings.Fields(value)[1:]
+	if len(words) == 0 {
+		return ""
+	}
+	n := 1
+	if i, err := strconv.Atoi(words[0]); err == nil && len(words) > 1 {
+		n, words = i, words[1:]
+	}
+	unit := strings.TrimSuffix(words[0], "s")
+	switch unit {
+	case "day", "week", "month", "year":
+		if n == 1 {
+			return recurrenceRule(unit)
+		}
+		if unit == "year" {
+			return fmt.Sprintf("FREQ=YEARLY;INTERVAL=%d", n)
+		}
+		return recurrenceRule(fmt.Sprintf("%d%c", n, unit[0]))
+	case "weekday", "workday":
+		return recurrenceRule("weekday")
+	}
+	var days []string
+	for _, word := range words {
+		word = strings.Trim(word, ",")
+		if word == "and" || word == "" {
+			continue
+		}
+		if len(word) < 3 {
+			return ""
+		}
+		days = append(days, word[:3])
+	}
+	return recurrenceRule(strings.Join(days, ","))
+}
+
+// todoistMsg carries the tasks fetched by importTodoist back to Update.
+type todoistMsg struct {
+	tasks []item
+	err   error
+}
+
+// importTodoist fetches the tasks in the background so the interface stays
+// responsive.
+func importTodoist(token string) tea.Cmd {
+	return func() tea.Msg {
+		tasks, err := fetchTodoist(token)
+		return todoistMsg{tasks: tasks, err: err}
+	}
+}
+
+// todoistTask is a task as returned by the REST API.
+type todoistTask struct {
+	ID          string   `json:"id"`
+	ParentID    string   `json:"parent_id"`
+	Content     string   `json:"content"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	Priority    int      `json:"priority"` // 4 is p1, 1 is no priority
+	IsCompleted bool     `json:"is_completed"`
+	CreatedAt   string   `json:"created_at"`
+	Due         *struct {
+		Date        string `json:"date"`
+		Datetime    string `json:"datetime"`
+		String      string `json:"string"`
+		IsRecurring bool   `json:"is_recurring"`
+	} `json:"due"`
+}
+
+// fetchTodoist downloads the active tasks of the account the token belongs
+// to.
+func fetchTodoist(token string) ([]item, error) {
+	if token == "" {
+		return nil, fmt.Errorf("no Todoist token, set token in the [todoist] section of the config")
+	}
+	req, err := http.NewRequest(http.MethodGet, todoistAPI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("todoist: %s", resp.Status)
+	}
+
+	var remote []todoistTask
+	if err := json.NewDecoder(resp.Body).Decode(&remote); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int, len(remote))
+	for i, t := range remote {
+		ids[t.ID] = i + 1
+	}
+	tasks := make([]item, 0, len(remote))
+	for i, t := range remote {
+		task := item{
+			id:        i + 1, // Placeholder, remapped on import
+			title:     t.Content,
+			tags:      append([]string{}, t.Labels...),
+			status:    todo,
+			createdAt: time.Now(),
+			parentID:  ids[t.ParentID],
+			notes:     t.Description,
+			sortOrder: i + 1,
+		}
+		if created, err := time.Parse(time.RFC3339, t.CreatedAt); err == nil {
+			task.createdAt = created
+		}
+		if t.Priority >= 2 {
+			task.priority = 5 - t.Priority
+		}
+		if t.IsCompleted {
+			task.status = done
+			task.completedAt = time.Now()
+		}
+		if t.Due != nil {
+			if due, err := time.Parse(time.RFC3339, t.Due.Datetime); err == nil {
+				task.dueAt = due
+			} else {
+				task.dueAt, _ = todoistDate(t.Due.Date)
+			}
+			if t.Due.IsRecurring {
+				task.recurrence = todoistRecurrence(strings.ToLower(t.Due.String))
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
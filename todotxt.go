@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// todo.txt format (https://github.com/todotxt/todo.txt):
+//
+//	x 2024-05-02 2024-05-01 (A) Call mom +family @phone due:2024-05-03
+//
+// Priorities A to C map to p1 to p3. The first +project and @context are the
+// task's project and context, further ones become tags (contexts keep their
+// @). Tags are written as projects after the task's own. The due:, t: and
+// rec: extensions carry the due date, start date and recurrence. Notes and subtasks have no
+// equivalent and are not written.
+
+const todotxtDate = "2006-01-02"
+
+func writeTodotxt(w io.Writer, tasks []item) error {
+	out := bufio.NewWriter(w)
+	for _, task := range tasks {
+		var words []string
+		if task.status == done {
+			words = append(words, "x")
+			if !task.completedAt.IsZero() {
+				words = append(words, task.completedAt.Local().Format(todotxtDate))
+			}
+		} else if task.priority != 0 {
+			words = append(words, fmt.Sprintf("(%c)", 'A'+task.priority-1))
+		}
+		if !task.createdAt.IsZero() {
+			words = append(words, task.createdAt.Local().Format(todotxtDate))
+		}
+		words = append(words, task.title)
+		if task.project != "" {
+			words = append(words, "+"+task.project)
+		}
+		if task.context != "" {
+			words = append(words, "@"+task.context)
+		}
+		for _, tag := range task.tags {
+			if strings.HasPrefix(tag, "@") {
+				words = append(words, tag)
+			} else {
+				words = append(words, "+"+tag)
+			}
+		}
+		if !task.dueAt.IsZero() {
+			words = append(words, "due:"+task.dueAt.Local().Format(todotxtDate))
+		}
+		if !task.startAt.IsZero() {
+			words = append(words, "t:"+task.startAt.Local().Format(todotxtDate))
+		}
+		if rec := todotxtRec(task.recurrence); rec != "" {
+			words = append(words, "rec:"+rec)
+		}
+		if task.status == done && task.priority != 0 {
+			// Completed tasks keep their priority as pri: by convention
+			words = append(words, fmt.Sprintf("pri:%c", 'A'+task.priority-1))
+		}
+		fmt.Fprintln(out, strings.Join(words, " "))
+	}
+	return out.Flush()
+}
+
+func readTodotxt(r io.Reader) ([]item, error) {
+	var tasks []item
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		task := item{status: todo, createdAt: time.Now(), sortOrder: len(tasks) + 1}
+
+		if words[0] == "x" {
+			task.status = done
+			task.completedAt = time.Now()
+			words = words[1:]
+			if len(words) > 0 {
+				if t, err := time.ParseInLocation(todotxtDate, words[0], time.Local); err == nil {
+					task.completedAt = t
+					words = words[1:]
+				}
+			}
+		}
+		if len(words) > 0 {
+			if p := todotxtPriority(words[0]); p != 0 {
+				task.priority = p
+				words = words[1:]
+			}
+		}
+		if len(words) > 0 {
+			if t, err := time.ParseInLocation(todotxtDate, words[0], time.Local); err == nil {
+				task.createdAt = t
+				words = words[1:]
+			}
+		}
+
+		var title []string
+		for _, word := range words {
+			key, value, _ := strings.Cut(word, ":")
+			switch {
+			case isProjectToken(word) && task.project == "":
+				task.project = word[1:]
+			case strings.HasPrefix(word, "+") && len(word) > 1:
+				task.tags = append(task.tags, word[1:])
+			case isContextToken(word) && task.context == "":
+				task.context = word[1:]
+			case strings.HasPrefix(word, "@") && len(word) > 1:
+				task.tags = append(task.tags, word)
+			case key == "due" && value != "":
+				task.dueAt = parseDue("due:" + value)
+			case key == "t" && value != "":
+				task.startAt = parseStart("start:" + value)
+			case key == "rec" && value != "":
+				task.recurrence = todotxtRule(value)
+			case key == "pri" && len(value) == 1:
+				task.priority = todotxtPriority("(" + value + ")")
+			default:
+				title = append(title, word)
+			}
+		}
+		task.title = strings.Join(title, " ")
+		if task.tags == nil {
+			task.tags = []string{}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, scanner.Err()
+}
+
+// todotxtPriority maps (A), (B) and (C) to p1 to p3. Lower todo.txt
+// priorities have no equivalent and count as p3.
+func todotxtPriority(word string) int {
+	if len(word) != 3 || word[0] != '(' || word[2] != ')' || word[1] < 'A' || word[1] > 'Z' {
+		return 0
+	}
+	return min(int(word[1]-'A')+1, 3)
+}
+
+// todotxtRule converts a rec: value such as 1w or +2m into an RRULE. The +
+// (strict recurrence) prefix is accepted but not distinguished.
+func todotxtRule(value string) string {
+	value = strings.TrimPrefix(value, "+")
+	if len(value) < 2 {
+		return ""
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n < 1 {
+		return ""
+	}
+	switch value[len(value)-1] {
+	case 'd':
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", n)
+	case 'w':
+		return fmt.Sprintf("FREQ=WEEKLY;INTERVAL=%d", n)
+	case 'm':
+		return fmt.Sprintf("FREQ=MONTHLY;INTERVAL=%d", n)
+	case 'y':
+		return fmt.Sprintf("FREQ=YEARLY;INTERVAL=%d", n)
+	}
+	return ""
+}
+
+// todotxtRec is the inverse of todotxtRule. Rules limited to weekdays cannot
+// be expressed and are dropped.
+func todotxtRec(rule string) string {
+	if rule == "" {
+		return ""
+	}
+	r, err := parseRule(rule)
+	if err != nil || len(r.byDay) > 0 {
+		return ""
+	}
+	units := map[string]string{"DAILY": "d", "WEEKLY": "w", "MONTHLY": "m", "YEARLY": "y"}
+	return fmt.Sprintf("%d%s", r.interval, units[r.freq])
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deleted tasks are only marked with deleted_at and listed in the Trash tab,
+// where they can be restored or purged for good. Trash older than the
+// retention period (trash_days setting) is purged on startup.
+
+const defaultTrashRetentionDays = 30
+
+type trashModel struct {
+	items        []item
+	selected     int
+	confirmEmpty bool // Set after the first X, a second X empties the trash
+}
+
+func (m *model) refreshTrash() {
+	items, err := m.store.Load(trashedTasks)
+	if err != nil {
+		m.showError("load trash", err)
+	}
+	m.trash.items = items
+	m.trash.confirmEmpty = false
+	if m.trash.selected >= len(items) {
+		m.trash.selected = len(items) - 1
+	}
+	if m.trash.selected < 0 {
+		m.trash.selected = 0
+	}
+}
+
+// withDescendants returns the selected trashed task and every trashed task
+// nested below it, so restoring a parent brings its subtasks back too.
+func (t trashModel) withDescendants(id int) []item {
+	var result []item
+	for _, task := range t.items {
+		if task.id == id {
+			result = append(result, task)
+		}
+	}
+	for _, task := range t.items {
+		if task.parentID == id && task.id != id {
+			result = append(result, t.withDescendants(task.id)...)
+		}
+	}
+	return result
+}
+
+func (m *model) updateTrash(key string) {
+	if key != "X" {
+		m.trash.confirmEmpty = false
+	}
+
+	switch key {
+	case "up", "k":
+		if m.trash.selected > 0 {
+			m.trash.selected--
+		}
+	case "down", "j":
+		if m.trash.selected < len(m.trash.items)-1 {
+			m.trash.selected++
+		}
+	case "r", "enter": // Restore the selected task and its subtasks
+		if len(m.trash.items) == 0 {
+			return
+		}
+		before := m.snapshot()
+		for _, task := range m.trash.withDescendants(m.trash.items[m.trash.selected].id) {
+			task.deletedAt = time.Time{}
+			err := m.store.Restore(&task)
+			if err != nil {
+				m.showError("restore task", err)
+				continue
+			}
+			m.tasksModel.items = append(m.tasksModel.items, task)
+		}
+		m.record("restore", before)
+		m.refreshTrash()
+		m.showMessage("Restored from the trash")
+	case "x": // Permanently delete the selected task
+		if len(m.trash.items) == 0 {
+			return
+		}
+		var ids []int
+		for _, task := range m.trash.withDescendants(m.trash.items[m.trash.selected].id) {
+			ids = append(ids, task.id)
+		}
+		err := m.store.Purge(ids...)
+		if err != nil {
+			m.showError("purge tasks", err)
+		}
+		m.refreshTrash()
+	case "X": // Empty the trash, asking for a second press first
+		if !m.trash.confirmEmpty {
+			m.trash.confirmEmpty = true
+			return
+		}
+		var ids []int
+		for _, task := range m.trash.items {
+			ids = append(ids, task.id)
+		}
+		err := m.store.Purge(ids...)
+		if err != nil {
+			m.showError("purge tasks", err)
+		} else {
+			m.showMessage("Trash emptied")
+		}
+		m.refreshTrash()
+	}
+}
+
+func (m model) renderTrash() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Trash") + "\n\n")
+	if len(m.trash.items) == 0 {
+		s.WriteString(helpStyle.Render("Trash is empty."))
+		return s.String()
+	}
+
+	for i, task := range m.trash.items {
+		cursor := cursorMark(i == m.trash.selected)
+		style := itemStyle
+		if i == m.trash.selected {
+			style = selectedItemStyle
+		}
+		s.WriteString(style.Render(fmt.Sprintf("%s %s %s", cursor, statusMarker(task.status), task.title)))
+		s.WriteString(helpStyle.Render(fmt.Sprintf(" - Deleted %s", formatRelativeTime(task.deletedAt))) + "\n")
+	}
+
+	if m.trash.confirmEmpty {
+		s.WriteString("\n" + overdueStyle.Render("Press X again to permanently delete "+strconv.Itoa(len(m.trash.items))+" tasks"))
+	}
+	return s.String()
+}
@@ -0,0 +1,175 @@
+package main
+
+import "time"
+
+// row is a task as it appears in the rendered list: an index into
+// tasksModel.items plus its nesting depth.
+type row struct {
+	index int
+	depth int
+}
+
+// rows flattens the task hierarchy into display order. Children follow
+// their parent and are skipped when the parent is collapsed. Tasks whose
+// parent no longer exists are shown at the top level. Tasks that fail the
+// active filters are dropped unless one of their subtasks passes.
+func (t tasksModel) rows() []row {
+	ids := make(map[int]bool, len(t.items))
+	for _, task := range t.items {
+		ids[task.id] = true
+	}
+
+	children := make(map[int][]int)
+	var roots []int
+	for i, task := range t.items {
+		if task.parentID != 0 && ids[task.parentID] && task.parentID != task.id {
+			children[task.parentID] = append(children[task.parentID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+
+	t.sortIndices(roots)
+	t.pinStarred(roots)
+	if t.byContext {
+		t.groupByContext(roots)
+	}
+	for _, siblings := range children {
+		t.sortIndices(siblings)
+		t.pinStarred(siblings)
+	}
+
+	shown := make(map[int]bool, len(t.items))
+	var keep func(index int) bool
+	keep = func(index int) bool {
+		if visible, ok := shown[index]; ok {
+			return visible
+		}
+		visible := t.matches(t.items[index])
+		for _, child := range children[t.items[index].id] {
+			if keep(child) {
+				visible = true
+			}
+		}
+		shown[index] = visible
+		return visible
+	}
+
+	var result []row
+	var walk func(index, depth int)
+	walk = func(index, depth int) {
+		if !keep(index) {
+			return
+		}
+		result = append(result, row{index: index, depth: depth})
+		id := t.items[index].id
+		if t.collapsed[id] {
+			return
+		}
+		for _, child := range children[id] {
+			walk(child, depth+1)
+		}
+	}
+	for _, index := range roots {
+		walk(index, 0)
+	}
+	return result
+}
+
+// selectedIndex returns the index into items of the task under the cursor,
+// or -1 if the list is empty.
+func (t tasksModel) selectedIndex() int {
+	rows := t.rows()
+	if t.selected < 0 || t.selected >= len(rows) {
+		return -1
+	}
+	return rows[t.selected].index
+}
+
+// clampSelection keeps the cursor inside the visible rows.
+func (t *tasksModel) clampSelection() {
+	count := len(t.rows())
+	if count == 0 {
+		t.selected = 0 // Reset selected index if no tasks are left
+	} else if t.selected >= count {
+		t.selected = count - 1
+	}
+}
+
+// selectID moves the cursor to the task with the given id if it is visible.
+func (t *tasksModel) selectID(id int) {
+	for i, r := range t.rows() {
+		if t.items[r.index].id == id {
+			t.selected = i
+			return
+		}
+	}
+}
+
+func (t tasksModel) indexOf(id int) int {
+	for i, task := range t.items {
+		if task.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (t tasksModel) hasChildren(id int) bool {
+	for _, task := range t.items {
+		if task.parentID == id && task.id != id {
+			return true
+		}
+	}
+	return false
+}
+
+// descendants returns the indices of every task nested below id.
+func (t tasksModel) descendants(id int) []int {
+	var result []int
+	for i, task := range t.items {
+		if task.parentID == id && task.id != id {
+			result = append(result, i)
+			result = append(result, t.descendants(task.id)...)
+		}
+	}
+	return result
+}
+
+// syncParents derives the completion state of the ancestors of id from their
+// children: a parent is done exactly when all of its children are done. It
+// returns the indices of the parents that changed.
+func (t *tasksModel) syncParents(id int) []int {
+	var changed []int
+	index := t.indexOf(id)
+	for index >= 0 && t.items[index].parentID != 0 {
+		parent := t.indexOf(t.items[index].parentID)
+		if parent < 0 {
+			break
+		}
+
+		allDone := true
+		for _, task := range t.items {
+			if task.parentID == t.items[parent].id && task.status != done {
+				allDone = false
+				break
+			}
+		}
+
+		want := t.items[parent].status
+		if allDone {
+			want = done
+		} else if want == done {
+			want = todo
+		}
+		if t.items[parent].status != want {
+			t.items[parent].status = want
+			if want == done {
+				t.items[parent].completedAt = time.Now()
+			}
+			changed = append(changed, parent)
+		}
+		index = parent
+	}
+	return changed
+}
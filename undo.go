@@ -0,0 +1,279 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// operation is one undoable change: the affected tasks as they were before
+// and after it. A task only in before was deleted, a task only in after was
+// added, and a task in both was modified.
+type operation struct {
+	label  string
+	before []item
+	after  []item
+}
+
+// snapshot copies the task list so a mutation can be diffed afterwards.
+func (m model) snapshot() []item {
+	tasks := make([]item, len(m.tasksModel.items))
+	for i, task := range m.tasksModel.items {
+		task.tags = append([]string{}, task.tags...)
+		tasks[i] = task
+	}
+	return tasks
+}
+
+// record compares the task list with a snapshot taken before a mutation and
+// pushes the difference onto the undo stack. Any new change clears redo.
+func (m *model) record(label string, snapshot []item) {
+	previous := make(map[int]item, len(snapshot))
+	for _, task := range snapshot {
+		previous[task.id] = task
+	}
+
+	var op operation
+	op.label = label
+	current := make(map[int]bool, len(m.tasksModel.items))
+	for _, task := range m.tasksModel.items {
+		current[task.id] = true
+		old, existed := previous[task.id]
+		switch {
+		case !existed:
+			op.after = append(op.after, task)
+		case !sameTask(old, task):
+			op.before = append(op.before, old)
+			op.after = append(op.after, task)
+		}
+	}
+	for _, task := range snapshot {
+		if !current[task.id] {
+			op.before = append(op.before, task)
+		}
+	}
+	if len(op.before) == 0 && len(op.after) == 0 {
+		return
+	}
+	m.logChange(label, op.before, op.after)
+
+	if len(m.undoStack) >= undoLimit {
+		// Remove the oldest operation if the stack exceeds the limit
+		m.undoStack = m.undoStack[1:]
+	}
+	m.undoStack = append(m.undoStack, op)
+	m.redoStack = nil
+	m.persistHistory()
+	m.refreshQuery()
+}
+
+func (m *model) persistHistory() {
+	err := m.store.SaveHistory(m.undoStack, m.redoStack)
+	if err != nil {
+		m.showError("save history", err)
+	}
+}
+
+func sameTask(a, b item) bool {
+	return a.title == b.title &&
+		strings.Join(a.tags, ",") == strings.Join(b.tags, ",") &&
+		a.status == b.status &&
+		a.createdAt.Equal(b.createdAt) &&
+		a.completedAt.Equal(b.completedAt) &&
+		a.dueAt.Equal(b.dueAt) &&
+		a.recurrence == b.recurrence &&
+		a.parentID == b.parentID &&
+		a.notes == b.notes &&
+		a.priority == b.priority &&
+		a.context == b.context &&
+		a.project == b.project &&
+		a.list == b.list &&
+		a.sortOrder == b.sortOrder &&
+		a.archivedAt.Equal(b.archivedAt) &&
+		a.snoozedUntil.Equal(b.snoozedUntil) &&
+		a.startAt.Equal(b.startAt) &&
+		a.estimate == b.estimate &&
+		a.starred == b.starred &&
+		a.plannedFor.Equal(b.plannedFor)
+}
+
+// undo reverts the most recent operation and moves it to the redo stack.
+func (m *model) undo() {
+	if len(m.undoStack) == 0 {
+		m.showMessage("Nothing to undo")
+		return
+	}
+	op := m.undoStack[len(m.undoStack)-1]
+	m.undoStack = m.undoStack[:len(m.undoStack)-1]
+	m.apply(op.after, op.before)
+	m.logChange("undo "+op.label, op.after, op.before)
+	m.refreshQuery()
+	m.redoStack = append(m.redoStack, op)
+	m.persistHistory()
+	m.showMessage("Undid " + op.label)
+}
+
+// redo reapplies the most recently undone operation.
+func (m *model) redo() {
+	if len(m.redoStack) == 0 {
+		m.showMessage("Nothing to redo")
+		return
+	}
+	op := m.redoStack[len(m.redoStack)-1]
+	m.redoStack = m.redoStack[:len(m.redoStack)-1]
+	m.apply(op.before, op.after)
+	m.logChange("redo "+op.label, op.before, op.after)
+	m.refreshQuery()
+	m.undoStack = append(m.undoStack, op)
+	m.persistHistory()
+	m.showMessage("Redid " + op.label)
+}
+
+// apply turns the tasks in from into the tasks in to, both in memory and in
+// the database, and selects the first affected task.
+func (m *model) apply(from, to []item) {
+	target := make(map[int]item, len(to))
+	for _, task := range to {
+		target[task.id] = task
+	}
+
+	var removed []int
+	for _, task := range from {
+		if _, ok := target[task.id]; !ok {
+			removed = append(removed, task.id)
+		}
+	}
+	if len(removed) > 0 {
+		err := m.store.Delete(removed...)
+		if err != nil {
+			m.showError("delete tasks", err)
+		}
+	}
+
+	var updated []item
+	for _, task := range to {
+		if index := m.tasksModel.indexOf(task.id); index >= 0 {
+			m.tasksModel.items[index] = task
+			updated = append(updated, task)
+			continue
+		}
+		// Restore with the original id so subtasks still find their parent
+		err := m.store.Restore(&task)
+		if err != nil {
+			m.showError("restore task", err)
+		}
+		m.tasksModel.items = append(m.tasksModel.items, task)
+	}
+	if len(updated) > 0 {
+		err := m.store.Update(updated...)
+		if err == nil {
+			err = m.store.SaveOrder(updated)
+		}
+		if err != nil {
+			m.showError("update tasks", err)
+		}
+	}
+
+	gone := make(map[int]bool, len(removed))
+	for _, id := range removed {
+		gone[id] = true
+	}
+	var remaining []item
+	ordered := true
+	for _, task := range m.tasksModel.items {
+		if !gone[task.id] && task.archivedAt.IsZero() {
+			remaining = append(remaining, task)
+			ordered = ordered && task.sortOrder > 0
+		}
+	}
+	if ordered {
+		// Put reordered and restored tasks back in their manual position
+		sort.SliceStable(remaining, func(i, j int) bool {
+			return remaining[i].sortOrder < remaining[j].sortOrder
+		})
+	}
+	m.tasksModel.items = remaining
+
+	if len(to) > 0 {
+		m.tasksModel.selectID(to[0].id)
+	}
+	m.tasksModel.clampSelection()
+}
+
+// The undo and redo stacks are mirrored into the history table so a task
+// deleted by accident can still be recovered after restarting.
+
+func createHistoryTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			stack TEXT NOT NULL,
+			label TEXT,
+			before TEXT,
+			after TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	return err
+}
+
+// LoadHistory reads the undo and redo stacks saved by the previous session.
+func (s *sqliteStore) LoadHistory() (undoStack, redoStack []operation, err error) {
+	rows, err := s.db.Query("SELECT stack, label, before, after FROM history ORDER BY id")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stack, label, before, after string
+		if err := rows.Scan(&stack, &label, &before, &after); err != nil {
+			return nil, nil, err
+		}
+		var beforeTasks, afterTasks []jsonTask
+		if err := json.Unmarshal([]byte(before), &beforeTasks); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal([]byte(after), &afterTasks); err != nil {
+			return nil, nil, err
+		}
+		op := operation{label: label, before: fromJSONTasks(beforeTasks), after: fromJSONTasks(afterTasks)}
+		if stack == "redo" {
+			redoStack = append(redoStack, op)
+		} else {
+			undoStack = append(undoStack, op)
+		}
+	}
+	return undoStack, redoStack, rows.Err()
+}
+
+// SaveHistory replaces the stored stacks with the current ones.
+func (s *sqliteStore) SaveHistory(undoStack, redoStack []operation) error {
+	return inTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM history"); err != nil {
+			return err
+		}
+		for _, stack := range []struct {
+			name string
+			ops  []operation
+		}{{"undo", undoStack}, {"redo", redoStack}} {
+			for _, op := range stack.ops {
+				before, err := json.Marshal(toJSONTasks(op.before))
+				if err != nil {
+					return err
+				}
+				after, err := json.Marshal(toJSONTasks(op.after))
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec("INSERT INTO history (stack, label, before, after) VALUES (?, ?, ?, ?)",
+					stack.name, op.label, string(before), string(after))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Links written in a task title, as in "review https://github.com/org/repo/pull/123",
+// are underlined in the list and O opens the first one in the default
+// browser.
+
+var urlPattern = regexp.MustCompile(`https?://\S+`)
+
+// findURLs returns the spans of the URLs in text, leaving out punctuation
+// that ends the sentence rather than the link.
+func findURLs(text string) [][]int {
+	spans := urlPattern.FindAllStringIndex(text, -1)
+	for _, span := range spans {
+		span[1] = span[0] + len(strings.TrimRight(text[span[0]:span[1]], ".,;:!?)'\""))
+	}
+	return spans
+}
+
+// firstURL returns the first URL in text, or "".
+func firstURL(text string) string {
+	if spans := findURLs(text); len(spans) > 0 {
+		return text[spans[0][0]:spans[0][1]]
+	}
+	return ""
+}
+
+// renderTitle renders a task title with its URLs underlined and search
+// matches highlighted in the rest.
+func renderTitle(title, query string, style lipgloss.Style) string {
+	var s strings.Builder
+	last := 0
+	for _, span := range findURLs(title) {
+		if span[0] > last {
+			s.WriteString(highlightMatches(title[last:span[0]], query, style))
+		}
+		s.WriteString(style.Underline(true).Render(title[span[0]:span[1]]))
+		last = span[1]
+	}
+	if last < len(title) || last == 0 {
+		s.WriteString(highlightMatches(title[last:], query, style))
+	}
+	return s.String()
+}
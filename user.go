@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// syncState is what the User tab shows about one sync backend.
+type syncState struct {
+	running  bool
+	lastSync time.Time // Zero if never synced
+	result   syncResult
+	err      error
+}
+
+// autoSyncMsg triggers a background sync every sync.interval minutes.
+type autoSyncMsg struct{}
+
+func autoSync(minutes int) tea.Cmd {
+	if minutes <= 0 {
+		return nil
+	}
+	return tea.Tick(time.Duration(minutes)*time.Minute, func(time.Time) tea.Msg {
+		return autoSyncMsg{}
+	})
+}
+
+// updateUser handles keys on the User tab.
+func (m *model) updateUser(key string) tea.Cmd {
+	switch key {
+	case "s": // Sync now
+		return m.startSync()
+	case "i": // Sign in with a code shown in the browser
+		if m.config.syncToken == "" {
+			return m.startDeviceSignIn()
+		}
+	case "p": // Sign in by pasting a token
+		if m.config.syncToken == "" {
+			return m.startPasteSignIn()
+		}
+	case "o": // Sign out
+		m.signOut()
+	case "esc": // Stop waiting for a code to be approved
+		m.cancelSignIn()
+	}
+	return nil
+}
+
+// userKeys lists the keys that apply to the User tab in its current state.
+func (m model) userKeys() string {
+	switch {
+	case m.signIn.device != nil:
+		return "esc: cancel sign in"
+	case m.config.syncURL != "" && m.config.syncToken == "":
+		return "i: sign in with a code | p: paste a token"
+	case m.config.syncURL != "":
+		return "s: sync now | o: sign out"
+	}
+	return "s: sync now"
+}
+
+// startSync starts a sync with every configured backend that isn't already
+// syncing.
+func (m *model) startSync() tea.Cmd {
+	var cmds []tea.Cmd
+	if m.config.syncURL != "" && m.config.syncToken != "" && !m.cloud.running {
+		m.cloud.running = true
+		m.cloud.err = nil
+		cmds = append(cmds, m.syncCloud())
+	}
+	if m.config.caldavURL != "" && !m.caldav.running {
+		m.caldav.running = true
+		m.caldav.err = nil
+		cmds = append(cmds, m.syncCalDAV())
+	}
+	if m.config.gitRepo != "" && !m.git.running {
+		m.git.running = true
+		m.git.err = nil
+		cmds = append(cmds, m.syncGit())
+	}
+	return tea.Batch(cmds...)
+}
+
+// finishSync records the outcome of a background sync and reloads the tasks
+// it may have changed.
+func (m *model) finishSync(state *syncState, result syncResult, err error) tea.Cmd {
+	state.running = false
+	state.result = result
+	state.err = err
+	if err == nil {
+		state.lastSync = time.Now()
+	}
+	return m.loadTasks()
+}
+
+func (m model) renderUser() string {
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Xtui sync") + "\n\n")
+	if m.config.syncURL == "" {
+		s.WriteString("Not configured.\n")
+		s.WriteString(helpStyle.Render("Set url and token in the [sync] section of\n" + m.config.path))
+		s.WriteString("\n")
+	} else {
+		s.WriteString(m.renderAccount())
+	}
+
+	s.WriteString("\n" + titleStyle.Render("CalDAV sync") + "\n\n")
+	if m.config.caldavURL == "" {
+		s.WriteString("Not configured.\n")
+		s.WriteString(helpStyle.Render("Set url, username and password in the [caldav] section of\n" + m.config.path))
+		s.WriteString("\n")
+	} else {
+		s.WriteString("Server   " + m.config.caldavURL + "\n")
+		if m.config.caldavUsername != "" {
+			s.WriteString("Account  " + m.config.caldavUsername + "\n")
+		}
+		s.WriteString(renderSyncState(m.caldav))
+	}
+
+	s.WriteString("\n" + titleStyle.Render("Git sync") + "\n\n")
+	if m.config.gitRepo == "" {
+		s.WriteString("Not configured.\n")
+		s.WriteString(helpStyle.Render("Set repo in the [git] section of\n" + m.config.path))
+		return s.String()
+	}
+	s.WriteString("Repo     " + m.config.gitRepo + "\n")
+	s.WriteString("File     " + m.config.gitFile + "\n")
+	s.WriteString(renderSyncState(m.git))
+	return s.String()
+}
+
+func (m model) renderAccount() string {
+	var s strings.Builder
+	s.WriteString("Server   " + m.config.syncURL + "\n")
+	switch {
+	case m.signIn.device != nil:
+		code := m.signIn.device
+		s.WriteString("\nOpen " + code.VerificationURI + " and enter the code\n\n")
+		s.WriteString("    " + titleStyle.Render(code.UserCode) + "\n\n")
+		s.WriteString(helpStyle.Render("Waiting for approval...") + "\n")
+	case m.tasksModel.mode == signInMode:
+		s.WriteString("\nPaste a token created on the sync server\n\n")
+		s.WriteString(m.signIn.input.View() + "\n")
+	case m.signIn.waiting:
+		s.WriteString("Status   signing in...\n")
+	case m.config.syncToken == "":
+		s.WriteString("Status   not signed in\n")
+	default:
+		if m.account.Email != "" {
+			s.WriteString("Account  " + m.account.Email + "\n")
+		}
+		if m.account.Plan != "" {
+			s.WriteString("Plan     " + m.account.Plan + "\n")
+		}
+		if m.config.syncInterval > 0 {
+			s.WriteString(fmt.Sprintf("Auto     every %d minutes\n", m.config.syncInterval))
+		}
+		s.WriteString(renderSyncState(m.cloud))
+	}
+	if m.signIn.err != nil {
+		s.WriteString(overdueStyle.Render(m.signIn.err.Error()) + "\n")
+	}
+	return s.String()
+}
+
+func renderSyncState(state syncState) string {
+	var s strings.Builder
+	switch {
+	case state.running:
+		s.WriteString("Status   syncing...\n")
+	case state.lastSync.IsZero():
+		s.WriteString("Status   never synced\n")
+	default:
+		s.WriteString("Status   last synced " + formatRelativeTime(state.lastSync) + "\n")
+	}
+
+	r := state.result
+	if !state.lastSync.IsZero() && state.err == nil {
+		s.WriteString(helpStyle.Render(fmt.Sprintf("%d pulled, %d pushed, %d deleted", r.pulled, r.pushed, r.deleted)))
+		if r.conflicts > 0 {
+			s.WriteString(overdueStyle.Render(fmt.Sprintf(", %d conflicts", r.conflicts)))
+		}
+		s.WriteString("\n")
+	}
+	if state.err != nil {
+		s.WriteString(overdueStyle.Render("Sync failed: "+state.err.Error()) + "\n")
+	}
+	return s.String()
+}
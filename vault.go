@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/charmbracelet/x/term"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Optional encryption of the database at rest, turned on with encrypt = true
+// in the [database] section. The file at the database path is then the
+// SQLite database sealed with AES-256-GCM under a key derived from a
+// passphrase. SQLite can't read it directly, so while Xtui runs it works on a
+// decrypted copy in the user's runtime directory, which is sealed back and
+// removed on exit. The process using the copy holds a lock file next to it
+// with its pid, and other processes, the CLI flags included, refuse to open
+// the database until it exits. A copy left behind by a crash, its lock held
+// by a process that is gone, is picked up by the next start, so no changes
+// are lost.
+//
+// Sealed file layout: magic, salt, PBKDF2 iterations, nonce, ciphertext.
+
+const (
+	vaultMagic      = "XTUIENC1"
+	vaultIterations = 600000
+	vaultSaltSize   = 16
+
+	// Most PBKDF2 iterations a sealed file may ask for, so a damaged or
+	// crafted header can't keep Xtui busy for hours
+	vaultMaxIterations = 10 * vaultIterations
+)
+
+var errWrongPassphrase = errors.New("wrong passphrase or damaged database")
+
+type vault struct {
+	path       string // Sealed database
+	working    string // Decrypted copy SQLite works on
+	created    bool   // This process wrote the working copy, rather than recovering it
+	salt       []byte
+	iterations uint32
+	key        []byte
+}
+
+// openVault prepares the database for SQLite and returns the path to open.
+// The vault is nil unless encryption is on. Turning encryption off decrypts
+// the database for good.
+func openVault(cfg config) (*vault, string, error) {
+	header := make([]byte, len(vaultMagic))
+	f, err := os.Open(cfg.databasePath)
+	if err == nil {
+		io.ReadFull(f, header)
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	sealed := string(header) == vaultMagic
+	if !cfg.encrypt && !sealed {
+		return nil, cfg.databasePath, nil
+	}
+
+	v := &vault{path: cfg.databasePath, working: workingCopyPath(cfg.databasePath)}
+	if sealed {
+		passphrase, err := readPassphrase("Passphrase for "+cfg.databasePath+": ", false)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := v.unlock(passphrase); err != nil {
+			return nil, "", err
+		}
+	} else {
+		passphrase, err := readPassphrase("New passphrase for "+cfg.databasePath+": ", true)
+		if err != nil {
+			return nil, "", err
+		}
+		v.salt = make([]byte, vaultSaltSize)
+		if _, err := rand.Read(v.salt); err != nil {
+			return nil, "", err
+		}
+		v.iterations = vaultIterations
+		v.key = deriveKey(passphrase, v.salt, v.iterations)
+	}
+
+	if err := v.lock(); err != nil {
+		return nil, "", err
+	}
+	if err := v.prepare(cfg, sealed); err != nil {
+		v.release()
+		return nil, "", err
+	}
+	if !cfg.encrypt {
+		fmt.Fprintln(os.Stderr, "Database decrypted.")
+		return nil, cfg.databasePath, nil
+	}
+	return v, v.working, nil
+}
+
+// prepare writes the working copy, or recovers the one a crashed run left,
+// and seals or decrypts the database for good when encryption was turned on
+// or off.
+func (v *vault) prepare(cfg config, sealed bool) error {
+	if _, err := os.Stat(v.working); err == nil {
+		fmt.Fprintf(os.Stderr, "Recovering changes left in %s.\n", v.working)
+	} else {
+		wrote, err := v.decrypt(sealed)
+		if err != nil {
+			return err
+		}
+		v.created = true
+		if !wrote {
+			return nil // Nothing to seal until SQLite has written the database
+		}
+	}
+
+	if !cfg.encrypt {
+		// Write the database back in the clear
+		if err := copyFile(v.working, v.path); err != nil {
+			v.discard()
+			return err
+		}
+		v.remove()
+		v.release()
+		return nil
+	}
+	if !sealed {
+		// Don't leave an existing database in the clear until exit
+		if err := v.seal(); err != nil {
+			v.discard()
+			return err
+		}
+	}
+	return nil
+}
+
+// workingCopyPath is the same for every run with the same database, so a
+// copy left by a crash is found again.
+func workingCopyPath(path string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	sum := sha1.Sum([]byte(abs))
+	return filepath.Join(dir, "xtui-"+hex.EncodeToString(sum[:6])+".db")
+}
+
+func (v *vault) lockPath() string {
+	return v.working + ".lock"
+}
+
+// lock takes the working copy for this process, unless another process that
+// is still running has it. A lock left by one that is gone is taken over.
+func (v *vault) lock() error {
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(v.lockPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+		if !os.IsExist(err) || attempt > 0 {
+			return err
+		}
+		data, err := os.ReadFile(v.lockPath())
+		if err != nil {
+			return err
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() && processAlive(pid) {
+			return fmt.Errorf("the database is open in another Xtui (process %d); close it first, or remove %s if it isn't running", pid, v.lockPath())
+		}
+		if err := os.Remove(v.lockPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+}
+
+// release gives up the lock taken by lock.
+func (v *vault) release() {
+	os.Remove(v.lockPath())
+}
+
+// processAlive reports whether a process with the pid is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false // On Windows, FindProcess fails for processes that are gone
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// unlock derives the key from the passphrase and checks it against the
+// sealed file.
+func (v *vault) unlock(passphrase string) error {
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return err
+	}
+	if len(data) < len(vaultMagic)+vaultSaltSize+4 {
+		return errWrongPassphrase
+	}
+	v.salt = data[len(vaultMagic) : len(vaultMagic)+vaultSaltSize]
+	v.iterations = binary.BigEndian.Uint32(data[len(vaultMagic)+vaultSaltSize:])
+	if v.iterations == 0 || v.iterations > vaultMaxIterations {
+		return errWrongPassphrase
+	}
+	v.key = deriveKey(passphrase, v.salt, v.iterations)
+	_, err = v.open(data)
+	return err
+}
+
+// decrypt writes the working copy from the sealed file, or from the
+// unencrypted database when encryption is first turned on, and reports
+// whether there was one to write.
+func (v *vault) decrypt(sealed bool) (bool, error) {
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		// New database, SQLite fills in the empty file only the user can read
+		return false, os.WriteFile(v.working, nil, 0o600)
+	}
+	if err != nil {
+		return false, err
+	}
+	if sealed {
+		if data, err = v.open(data); err != nil {
+			return false, err
+		}
+	}
+	return true, os.WriteFile(v.working, data, 0o600)
+}
+
+func (v *vault) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (v *vault) open(data []byte) ([]byte, error) {
+	aead, err := v.aead()
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(vaultMagic) + vaultSaltSize + 4
+	if len(data) < headerSize+aead.NonceSize() {
+		return nil, errWrongPassphrase
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, errWrongPassphrase
+	}
+	return plain, nil
+}
+
+// seal encrypts the working copy over the database file.
+func (v *vault) seal() error {
+	return v.sealFile(v.working, v.path)
+}
+
+// sealFile encrypts the database at from into to. The file is replaced
+// atomically so a failure leaves the previous version intact.
+func (v *vault) sealFile(from, to string) error {
+	plain, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	aead, err := v.aead()
+	if err != nil {
+		return err
+	}
+	var header bytes.Buffer
+	header.WriteString(vaultMagic)
+	header.Write(v.salt)
+	binary.Write(&header, binary.BigEndian, v.iterations)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := append(bytes.Clone(header.Bytes()), nonce...)
+	sealed = aead.Seal(sealed, nonce, plain, header.Bytes())
+	tmp := to + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, to)
+}
+
+// close seals the working copy, removes it and gives up the lock. The
+// database must be closed first so SQLite has written everything to the
+// file. A copy that can't be sealed is kept for the next start to recover.
+func (v *vault) close() error {
+	if err := v.seal(); err != nil {
+		return err
+	}
+	v.remove()
+	v.release()
+	return nil
+}
+
+// discard removes the working copy if this process wrote it, after a failed
+// start. A recovered copy is left for the next start.
+func (v *vault) discard() {
+	if v.created {
+		v.remove()
+	}
+}
+
+// remove deletes the working copy and SQLite's files next to it. Only the
+// process holding the lock may call it.
+func (v *vault) remove() {
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		os.Remove(v.working + suffix)
+	}
+}
+
+// readPassphrase reads the passphrase from XTUI_PASSPHRASE or, without
+// echo, from the terminal. New passphrases are asked for twice.
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := os.Getenv("XTUI_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return "", errors.New("the database is encrypted, set XTUI_PASSPHRASE when not running in a terminal")
+	}
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	passphrase, err := read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := read("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+// deriveKey derives a 32 byte key with PBKDF2-HMAC-SHA256 (RFC 8018).
+func deriveKey(passphrase string, salt []byte, iterations uint32) []byte {
+	return pbkdf2.Key([]byte(passphrase), salt, int(iterations), 32, sha256.New)
+}
+
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	tmp := to + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, to)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultFirstOpen(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("XTUI_PASSPHRASE", "correct horse")
+	cfg := config{databasePath: filepath.Join(dir, "xtui.db"), encrypt: true}
+
+	v, path, err := openVault(cfg)
+	if err != nil {
+		t.Fatalf("openVault in an empty directory: %v", err)
+	}
+	store, err := openSQLiteStore(path, nil)
+	if err != nil {
+		t.Fatalf("opening the working copy: %v", err)
+	}
+	if err := store.Save(&item{title: "Water the plants"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if err := v.close(); err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+	if _, err := os.Stat(v.working); !os.IsNotExist(err) {
+		t.Errorf("working copy left behind: %v", err)
+	}
+	data, err := os.ReadFile(cfg.databasePath)
+	if err != nil || string(data[:len(vaultMagic)]) != vaultMagic {
+		t.Fatalf("database not sealed: %v", err)
+	}
+
+	v, path, err = openVault(cfg)
+	if err != nil {
+		t.Fatalf("opening the sealed database: %v", err)
+	}
+	defer v.close()
+	store, err = openSQLiteStore(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	tasks, err := store.Load(liveTasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].title != "Water the plants" {
+		t.Errorf("got %v after reopening, want the saved task", tasks)
+	}
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+	t.Setenv("XTUI_PASSPHRASE", "correct horse")
+	cfg := config{databasePath: filepath.Join(dir, "xtui.db"), encrypt: true}
+	v, path, err := openVault(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := openSQLiteStore(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	if err := v.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("XTUI_PASSPHRASE", "battery staple")
+	if _, _, err := openVault(cfg); err != errWrongPassphrase {
+		t.Errorf("got %v, want errWrongPassphrase", err)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The Week tab plans the week: the open tasks not planned for a day yet, then
+// a column per day from Monday to Sunday with the tasks planned for it and
+// what is left of their estimates, so a day with too much on it shows before
+// it comes. h/l pick a column and j/k a task, H/L move the task a day earlier
+// or later (back to the unplanned ones from Monday), r rolls what is not done
+// on the selected day to the next one and [/] go to the previous or next
+// week, t back to this one. Tasks planned for today are the ones + plans in
+// the list, and show in its Focus view.
+
+const weekWidth = 20 // Characters per column
+
+type weekModel struct {
+	start    time.Time // Monday of the week shown, zero for this week
+	column   int       // 0 for the unplanned tasks, then 1 for Monday to 7 for Sunday
+	selected int       // Cursor in the column
+}
+
+// weekColumn is a column with the indices of its tasks.
+type weekColumn struct {
+	day   time.Time // Zero for the unplanned tasks
+	tasks []int
+}
+
+// monday returns the first day of the week that now is in.
+func monday(now time.Time) time.Time {
+	day := midnight(now)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// weekStart returns the Monday of the week shown.
+func (w weekModel) weekStart() time.Time {
+	if w.start.IsZero() {
+		return monday(time.Now())
+	}
+	return w.start
+}
+
+func (m model) weekColumns() []weekColumn {
+	start := m.week.weekStart()
+	columns := []weekColumn{{}}
+	for i := 0; i < 7; i++ {
+		columns = append(columns, weekColumn{day: start.AddDate(0, 0, i)})
+	}
+	for i, task := range m.tasksModel.items {
+		if task.plannedFor.IsZero() {
+			if task.status != done && task.list != someday {
+				columns[0].tasks = append(columns[0].tasks, i)
+			}
+			continue
+		}
+		for c := 1; c < len(columns); c++ {
+			if midnight(task.plannedFor).Equal(columns[c].day) {
+				columns[c].tasks = append(columns[c].tasks, i)
+			}
+		}
+	}
+	return columns
+}
+
+// updateWeek handles keys on the Week tab.
+func (m *model) updateWeek(key string) {
+	w := &m.week
+	columns := m.weekColumns()
+	tasks := columns[w.column].tasks
+
+	switch key {
+	case "h":
+		if w.column > 0 {
+			w.column--
+			w.selected = 0
+		}
+	case "l":
+		if w.column < len(columns)-1 {
+			w.column++
+			w.selected = 0
+		}
+	case "k", "up":
+		if w.selected > 0 {
+			w.selected--
+		}
+	case "j", "down":
+		if w.selected < len(tasks)-1 {
+			w.selected++
+		}
+	case "H", "L": // Plan the task a day earlier or later
+		target := w.column - 1
+		if key == "L" {
+			target = w.column + 1
+		}
+		if w.selected >= len(tasks) || target < 0 || target >= len(columns) {
+			return
+		}
+		id := m.tasksModel.items[tasks[w.selected]].id
+		m.planFor([]int{tasks[w.selected]}, columns[target].day)
+		w.column = target
+		for i, index := range m.weekColumns()[target].tasks {
+			if m.tasksModel.items[index].id == id {
+				w.selected = i
+			}
+		}
+	case "r": // Roll what is left of the day over to the next one
+		if w.column == 0 {
+			return
+		}
+		var open []int
+		for _, index := range tasks {
+			if m.tasksModel.items[index].status != done {
+				open = append(open, index)
+			}
+		}
+		if len(open) == 0 {
+			m.showMessage("Nothing left to roll over")
+			return
+		}
+		next := columns[w.column].day.AddDate(0, 0, 1)
+		m.planFor(open, next)
+		m.showMessage(fmt.Sprintf("Rolled %s over to %s", countTasks(len(open)), formatDate(next, "Mon 2 Jan")))
+		w.selected = 0
+	case "[", "]": // Previous or next week
+		days := -7
+		if key == "]" {
+			days = 7
+		}
+		w.start = w.weekStart().AddDate(0, 0, days)
+		w.selected = 0
+	case "t": // Back to this week
+		w.start = time.Time{}
+		w.selected = 0
+	}
+}
+
+// planFor plans the tasks for a day, or for none when it is zero, as one
+// undo step.
+func (m *model) planFor(indices []int, day time.Time) {
+	before := m.snapshot()
+	var updates []item
+	for _, index := range indices {
+		task := &m.tasksModel.items[index]
+		if !task.plannedFor.Equal(day) {
+			task.plannedFor = day
+			updates = append(updates, *task)
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	if err := m.store.Update(updates...); err != nil {
+		m.showError("update tasks", err)
+	}
+	m.record("plan", before)
+}
+
+func (m model) renderWeek() string {
+	columns := m.weekColumns()
+	w := m.week
+	today := midnight(time.Now())
+
+	// Show as many columns as fit, keeping the selected one in view
+	fit := max(1, (m.width-8)/(weekWidth+2))
+	first := max(0, w.column-fit+1)
+	last := min(len(columns), first+fit)
+
+	var rendered []string
+	for c := first; c < last; c++ {
+		col := columns[c]
+		title := "Unplanned"
+		if !col.day.IsZero() {
+			title = formatDate(col.day, "Mon 2")
+			if col.day.Equal(today) {
+				title = "Today"
+			}
+		}
+		header := fmt.Sprintf("%s (%d)", title, len(col.tasks))
+		var s strings.Builder
+		if c == w.column {
+			s.WriteString(titleStyle.Render(header) + "\n")
+		} else {
+			s.WriteString(helpStyle.Render(header) + "\n")
+		}
+		load := ""
+		if left := m.remainingWork(col.tasks); left > 0 && !col.day.IsZero() {
+			load = "~" + formatEstimate(left)
+		}
+		s.WriteString(helpStyle.Render(load) + "\n\n")
+
+		for i, index := range col.tasks {
+			task := m.tasksModel.items[index]
+			line := truncate(task.title, weekWidth-cursorWidth())
+			switch {
+			case c == w.column && i == w.selected:
+				s.WriteString(selectedItemStyle.UnsetPaddingLeft().Render(cursorMark(true)+line) + "\n")
+			case task.status == done:
+				s.WriteString(helpStyle.Render(cursorMark(false)+line) + "\n")
+			case task.overdue(time.Now()):
+				s.WriteString(overdueStyle.Render(cursorMark(false)+line) + "\n")
+			default:
+				s.WriteString(cursorMark(false) + line + "\n")
+			}
+		}
+		rendered = append(rendered, lipgloss.NewStyle().Width(weekWidth).MarginRight(2).Render(s.String()))
+	}
+
+	start := w.weekStart()
+	var s strings.Builder
+	s.WriteString(titleStyle.Render("Week of "+formatDate(start, "2 January 2006")) + "\n\n")
+	s.WriteString(lipgloss.JoinHorizontal(lipgloss.Top, rendered...) + "\n")
+	if first > 0 || last < len(columns) {
+		s.WriteString(helpStyle.Render(fmt.Sprintf("columns %d-%d of %d", first+1, last, len(columns))) + "\n")
+	}
+	return s.String()
+}
+
+// weekKeys lists the keys that apply to the Week tab.
+func (m model) weekKeys() string {
+	return "←/→: switch tabs | h/l: day | j/k: task | H/L: plan a day earlier/later | r: roll over to the next day | [/]: previous/next week | t: this week | u: undo | q: quit"
+}